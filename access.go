@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Режимы доступа к боту (переменная ACCESS_MODE)
+const (
+	AccessModeOpen      = "open"      // Бот доступен всем, кроме заблокированных
+	AccessModeAllowlist = "allowlist" // Только пользователи и чаты из списка доступа
+	AccessModeInvite    = "invite"    // Список доступа пополняется кодами приглашений
+)
+
+// AccessDecision — результат проверки доступа
+type AccessDecision int
+
+const (
+	AccessAllowed AccessDecision = iota
+	AccessDenied                 // Нет в списке доступа
+	AccessBlocked                // Пользователь или чат заблокирован
+)
+
+// accessStoreName — имя файла состояния доступа в хранилище
+const accessStoreName = "access"
+
+// AccessEntry описывает, кто и когда добавил ID в список доступа или блокировок
+type AccessEntry struct {
+	By     int64     `json:"by,omitempty"`
+	At     time.Time `json:"at"`
+	Invite string    `json:"invite,omitempty"`
+}
+
+// Invite — код приглашения, открывающий доступ пользователю или групповому чату
+type Invite struct {
+	Code      string    `json:"code"`
+	MaxUses   int       `json:"max_uses"`
+	Uses      int       `json:"uses"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// accessState — сохраняемая часть политики доступа.
+// ID пользователей положительные, ID групповых чатов отрицательные, поэтому они хранятся вместе.
+type accessState struct {
+	Allowed map[int64]AccessEntry `json:"allowed"`
+	Blocked map[int64]AccessEntry `json:"blocked"`
+	Invites map[string]*Invite    `json:"invites"`
+}
+
+// AccessPolicy решает, может ли пользователь или групповой чат пользоваться ботом
+type AccessPolicy struct {
+	mu        sync.Mutex
+	store     *Store
+	mode      string
+	allowlist map[int64]bool // Список доступа из конфигурации
+	state     accessState
+}
+
+// NewAccessPolicy загружает состояние доступа из хранилища; nil означает хранение только в памяти
+func NewAccessPolicy(store *Store, mode string, allowlist map[int64]bool) *AccessPolicy {
+	p := &AccessPolicy{store: store}
+	if err := store.Load(accessStoreName, &p.state); err != nil {
+		log.Printf("Ошибка загрузки состояния доступа: %v", err)
+	}
+	if p.state.Allowed == nil {
+		p.state.Allowed = make(map[int64]AccessEntry)
+	}
+	if p.state.Blocked == nil {
+		p.state.Blocked = make(map[int64]AccessEntry)
+	}
+	if p.state.Invites == nil {
+		p.state.Invites = make(map[string]*Invite)
+	}
+	p.Configure(mode, allowlist)
+	return p
+}
+
+// accessModeFromEnv читает режим доступа из ACCESS_MODE
+func accessModeFromEnv() string {
+	switch mode := os.Getenv("ACCESS_MODE"); mode {
+	case AccessModeAllowlist, AccessModeInvite:
+		return mode
+	case "", AccessModeOpen:
+		return AccessModeOpen
+	default:
+		log.Printf("Неизвестный режим доступа %q, бот открыт для всех", mode)
+		return AccessModeOpen
+	}
+}
+
+// accessAllowlistFromEnv читает список доступа из ACCESS_ALLOWLIST; администраторы из ADMIN_IDS
+// попадают в него автоматически, чтобы не потерять доступ в закрытых режимах
+func accessAllowlistFromEnv() map[int64]bool {
+	allowlist := parseIDList("ACCESS_ALLOWLIST")
+	for id := range parseIDList("ADMIN_IDS") {
+		allowlist[id] = true
+	}
+	return allowlist
+}
+
+// parseIDList разбирает список ID вида "123,-100456" из переменной окружения name
+func parseIDList(name string) map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, field := range strings.FieldsFunc(os.Getenv(name), func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			log.Printf("Неверный ID %q в %s", field, name)
+			continue
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+// Configure меняет режим и список доступа из конфигурации, например при /reload
+func (p *AccessPolicy) Configure(mode string, allowlist map[int64]bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.mode = mode
+	p.allowlist = allowlist
+}
+
+// Mode возвращает текущий режим доступа
+func (p *AccessPolicy) Mode() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.mode
+}
+
+// Check проверяет доступ пользователя в чате. В личном чате chatID совпадает с userID;
+// в группе достаточно, чтобы в списке доступа был пользователь или сам чат.
+func (p *AccessPolicy) Check(userID, chatID int64) AccessDecision {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, blocked := p.state.Blocked[userID]; blocked {
+		return AccessBlocked
+	}
+	if _, blocked := p.state.Blocked[chatID]; blocked {
+		return AccessBlocked
+	}
+	if p.mode == AccessModeOpen || p.allowedLocked(userID) || p.allowedLocked(chatID) {
+		return AccessAllowed
+	}
+	return AccessDenied
+}
+
+func (p *AccessPolicy) allowedLocked(id int64) bool {
+	if p.allowlist[id] {
+		return true
+	}
+	_, allowed := p.state.Allowed[id]
+	return allowed
+}
+
+// Allow добавляет пользователя или чат в список доступа
+func (p *AccessPolicy) Allow(id, by int64) {
+	p.update(func() {
+		p.state.Allowed[id] = AccessEntry{By: by, At: time.Now()}
+	})
+}
+
+// Revoke удаляет пользователя или чат из списка доступа
+func (p *AccessPolicy) Revoke(id int64) {
+	p.update(func() {
+		delete(p.state.Allowed, id)
+	})
+}
+
+// Block блокирует пользователя или чат независимо от режима доступа
+func (p *AccessPolicy) Block(id, by int64) {
+	p.update(func() {
+		p.state.Blocked[id] = AccessEntry{By: by, At: time.Now()}
+	})
+}
+
+// Unblock снимает блокировку
+func (p *AccessPolicy) Unblock(id int64) {
+	p.update(func() {
+		delete(p.state.Blocked, id)
+	})
+}
+
+// CreateInvite создает код приглашения на maxUses активаций
+func (p *AccessPolicy) CreateInvite(by int64, maxUses int) (Invite, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return Invite{}, err
+	}
+
+	invite := &Invite{
+		Code:      hex.EncodeToString(buf),
+		MaxUses:   maxUses,
+		CreatedBy: by,
+		CreatedAt: time.Now(),
+	}
+	p.update(func() {
+		p.state.Invites[invite.Code] = invite
+	})
+	return *invite, nil
+}
+
+// Redeem активирует код приглашения и открывает доступ пользователю или чату id
+func (p *AccessPolicy) Redeem(code string, id int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.mode != AccessModeInvite {
+		return newLocalizedError("access.error.invites_disabled")
+	}
+
+	invite, exists := p.state.Invites[strings.TrimSpace(code)]
+	if !exists || invite.Uses >= invite.MaxUses {
+		return newLocalizedError("access.error.invalid_code")
+	}
+
+	invite.Uses++
+	if invite.Uses >= invite.MaxUses {
+		delete(p.state.Invites, invite.Code)
+	}
+	p.state.Allowed[id] = AccessEntry{By: invite.CreatedBy, At: time.Now(), Invite: invite.Code}
+	p.saveLocked()
+	return nil
+}
+
+// Counts возвращает размеры списка доступа и списка блокировок
+func (p *AccessPolicy) Counts() (allowed, blocked int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	allowed = len(p.state.Allowed)
+	for id := range p.allowlist {
+		if _, exists := p.state.Allowed[id]; !exists {
+			allowed++
+		}
+	}
+	return allowed, len(p.state.Blocked)
+}
+
+func (p *AccessPolicy) update(change func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	change()
+	p.saveLocked()
+}
+
+func (p *AccessPolicy) saveLocked() {
+	if err := p.store.Save(accessStoreName, p.state); err != nil {
+		log.Printf("Ошибка сохранения состояния доступа: %v", err)
+	}
+}
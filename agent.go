@@ -1,25 +1,81 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Agent представляет интеллектуального агента
 type Agent struct {
-	conversationHistory map[int64][]ConversationEntry
+	mu                  sync.Mutex
+	conversationHistory map[string]*Conversation
+	locales             map[int64]string
 	tools               map[string]Tool
-	yandexGPT           *YandexGPTClient
-	useYandexGPT        bool
+	documents           *DocumentStore
+	knowledge           *KnowledgeBase
+	settings            *SettingsStore
+	users               *UserRegistry
+	access              *AccessPolicy
+	metrics             *Metrics
+	reminders           *ReminderScheduler
+	notes               *NotesStore
+	rates               RatesProvider
+	cache               *ResponseCache
+	moderator           Moderator // nil — модерация выключена
+	redactor            *Redactor // nil — персональные данные не заменяются
+	timezone            *time.Location // Пояс для пользователей, которые не указали свой
+	tokens              TokenCounter   // Счетчик токенов для упаковки истории в контекст модели
+	contextTokens       int            // Бюджет токенов всего запроса к модели
+	router              *ModelRouter   // Выбор модели Yandex GPT для запроса
+	providers           map[string]LLMProvider
+	provider            string // Имя активного бэкенда модели
+}
+
+// Conversation — история разговора и краткое содержание ее ранней части
+type Conversation struct {
+	Entries        []ConversationEntry
+	Summary        string    // Краткое содержание реплик, вытесненных из контекста модели
+	SummaryUpdated time.Time // Когда краткое содержание последний раз дополнялось
 }
 
 // ConversationEntry представляет запись в истории разговора
 type ConversationEntry struct {
-	Message   string
-	Response  string
-	Timestamp time.Time
+	UserID       int64
+	Author       string
+	Message      string
+	Response     string
+	Tool         string // Инструмент, который ответил; general — модель или общий ответ
+	Model        string // Модель, которую выбрала маршрутизация, если отвечала модель
+	ModelVersion string // Версия модели из ответа API, если отвечала модель
+	Timestamp    time.Time
+}
+
+// MessageContext описывает, от кого пришло сообщение и в какую историю его записывать
+type MessageContext struct {
+	UserID         int64
+	ConversationID string // Ключ истории: пользователь, групповой чат или ветка пользователя в чате
+	Author         string // Имя автора, чтобы модель различала участников группы
+	Locale         string // Язык интерфейса клиента, например из Telegram
+	// OnDelta получает фрагменты ответа модели по мере генерации; nil — нужен только ответ целиком
+	OnDelta func(text string)
+}
+
+// Reply — ответ агента вместе с инструментом и моделью, которые его подготовили
+type Reply struct {
+	Text         string
+	Tool         string
+	Model        string // Пусто, если ответ подготовлен без языковой модели
+	ModelVersion string
+}
+
+// userConversationID возвращает ключ истории личного диалога с пользователем
+func userConversationID(userID int64) string {
+	return strconv.FormatInt(userID, 10)
 }
 
 // Tool представляет инструмент, который может использовать агент
@@ -27,14 +83,37 @@ type Tool struct {
 	Name        string
 	Description string
 	Handler     func(string, int64) (string, error)
+	// Parameters и Call задаются у инструментов, которые модель может вызывать сама:
+	// Parameters — JSON Schema аргументов, Call получает аргументы от модели
+	Parameters map[string]any
+	Call       func(json.RawMessage, int64) (string, error)
+	// Mutating — инструмент меняет данные пользователя, поэтому в инлайн-режиме не вызывается:
+	// инлайн-запросы приходят на каждое нажатие клавиши и не означают, что пользователь что-то отправил
+	Mutating bool
 }
 
 // NewAgent создает новый экземпляр агента
 func NewAgent() *Agent {
 	agent := &Agent{
-		conversationHistory: make(map[int64][]ConversationEntry),
+		conversationHistory: make(map[string]*Conversation),
+		locales:             make(map[int64]string),
 		tools:               make(map[string]Tool),
-		useYandexGPT:        false,
+		documents:           NewDocumentStore(),
+		settings:            NewSettingsStore(nil),
+		users:               NewUserRegistry(nil),
+		access:              NewAccessPolicy(nil, AccessModeOpen, nil),
+		metrics:             NewMetrics(),
+		reminders:           NewReminderScheduler(nil, systemClock{}),
+		notes:               NewNotesStore(nil),
+		rates:               NewCBRRates(""),
+		redactor:            NewRedactor(DefaultPIIModes()),
+		cache:               NewResponseCache(nil, ResponseCacheConfig{TTL: defaultResponseCacheTTL, MaxEntries: defaultResponseCacheSize}),
+		timezone:            time.Local,
+		tokens:              EstimateTokenCounter{},
+		contextTokens:       defaultContextWindowTokens,
+		router:              NewModelRouter(),
+		providers:           make(map[string]LLMProvider),
+		provider:            builtinProviderName,
 	}
 
 	// Регистрируем доступные инструменты
@@ -45,15 +124,10 @@ func NewAgent() *Agent {
 
 // NewAgentWithYandexGPT создает агента с поддержкой Yandex GPT
 func NewAgentWithYandexGPT(apiKey, folderID string) *Agent {
-	agent := &Agent{
-		conversationHistory: make(map[int64][]ConversationEntry),
-		tools:               make(map[string]Tool),
-		yandexGPT:           NewYandexGPTClient(apiKey, folderID),
-		useYandexGPT:        true,
-	}
-
-	// Регистрируем доступные инструменты
-	agent.registerTools()
+	agent := NewAgent()
+	client := NewYandexGPTClient(apiKey, folderID)
+	agent.RegisterProvider(client)
+	agent.SetProvider(client.Name())
 
 	return agent
 }
@@ -62,79 +136,354 @@ func NewAgentWithYandexGPT(apiKey, folderID string) *Agent {
 func (a *Agent) registerTools() {
 	a.tools["weather"] = Tool{
 		Name:        "weather",
-		Description: "Получить информацию о погоде",
+		Description: T(defaultLocale, "tool.weather.description"),
 		Handler:     a.handleWeatherRequest,
 	}
 
 	a.tools["time"] = Tool{
 		Name:        "time",
-		Description: "Получить текущее время",
+		Description: T(defaultLocale, "tool.time.description"),
 		Handler:     a.handleTimeRequest,
 	}
 
 	a.tools["calculate"] = Tool{
 		Name:        "calculate",
-		Description: "Выполнить математические вычисления",
+		Description: T(defaultLocale, "tool.calculate.description"),
 		Handler:     a.handleCalculateRequest,
 	}
 
+	a.tools["convert"] = Tool{
+		Name:        "convert",
+		Description: T(defaultLocale, "tool.convert.description"),
+		Handler:     a.handleConvertRequest,
+		Parameters:  convertToolParameters(),
+		Call:        a.callConvertTool,
+	}
+
+	a.tools["remind"] = Tool{
+		Name:        "remind",
+		Description: T(defaultLocale, "tool.remind.description"),
+		Handler:     a.handleRemindRequest,
+		Mutating:    true,
+	}
+
+	a.tools["notes"] = Tool{
+		Name:        "notes",
+		Description: T(defaultLocale, "tool.notes.description"),
+		Handler: func(message string, userID int64) (string, error) {
+			return a.handleNotesText(NoteKindNote, message, userID)
+		},
+		Parameters: notesToolParameters(NoteKindNote),
+		Call: func(args json.RawMessage, userID int64) (string, error) {
+			return a.callNotesTool(NoteKindNote, args, userID)
+		},
+		Mutating: true,
+	}
+
+	a.tools["todo"] = Tool{
+		Name:        "todo",
+		Description: T(defaultLocale, "tool.todo.description"),
+		Handler: func(message string, userID int64) (string, error) {
+			return a.handleNotesText(NoteKindTodo, message, userID)
+		},
+		Parameters: notesToolParameters(NoteKindTodo),
+		Call: func(args json.RawMessage, userID int64) (string, error) {
+			return a.callNotesTool(NoteKindTodo, args, userID)
+		},
+		Mutating: true,
+	}
+
 	a.tools["help"] = Tool{
 		Name:        "help",
-		Description: "Показать доступные команды",
+		Description: T(defaultLocale, "tool.help.description"),
 		Handler:     a.handleHelpRequest,
 	}
 }
 
-// ProcessMessage обрабатывает входящее сообщение
+// ProcessMessage обрабатывает входящее сообщение из личного диалога
 func (a *Agent) ProcessMessage(message string, userID int64) (string, error) {
-	log.Printf("Обработка сообщения от пользователя %d: %s", userID, message)
+	return a.ProcessMessageInContext(MessageContext{
+		UserID:         userID,
+		ConversationID: userConversationID(userID),
+	}, message)
+}
+
+// ProcessMessageInContext обрабатывает сообщение в рамках указанной истории разговора
+func (a *Agent) ProcessMessageInContext(mc MessageContext, message string) (string, error) {
+	reply, err := a.ProcessMessageReply(mc, message)
+	return reply.Text, err
+}
+
+// ProcessMessageReply обрабатывает сообщение как ProcessMessageInContext и возвращает ответ
+// вместе с инструментом и моделью. Брать их из истории нельзя: разговор общий для всех каналов,
+// и последней репликой может оказаться ответ на другое сообщение.
+func (a *Agent) ProcessMessageReply(mc MessageContext, message string) (Reply, error) {
+	if mc.ConversationID == "" {
+		mc.ConversationID = userConversationID(mc.UserID)
+	}
+	userID := mc.UserID
+	if mc.Locale != "" {
+		a.RememberLocale(userID, mc.Locale)
+	}
+	locale := a.LocaleFor(userID)
+	a.metrics.Messages.Add(1)
+
+	log.Printf("Обработка сообщения от пользователя %d (%s): %s", userID, mc.ConversationID, message)
+
+	// Заблокированное сообщение не попадает ни в историю, ни к модели
+	inputVerdict := a.moderate(message, ModerationInput, userID)
+	if inputVerdict.Action == ModerationBlock {
+		return Reply{Text: moderationNotice(locale, ModerationInput, inputVerdict, "")}, nil
+	}
+
+	// Собираем контекст до того, как добавить новое сообщение
+	history := a.history(mc.ConversationID)
 
 	// Добавляем сообщение в историю
-	a.addToHistory(userID, message, "")
+	a.addToHistory(mc, message, "")
 
 	// Определяем, какой инструмент использовать
 	toolName := a.determineTool(message)
 	
 	var response string
+	var model, modelVersion string
 	var err error
 
-	// Если включен Yandex GPT и это не специальная команда, используем его
-	if a.useYandexGPT && a.yandexGPT != nil && toolName == "general" {
-		response, err = a.yandexGPT.GenerateResponse(message, userID)
+	// Фрагменты загруженных документов и базы знаний, относящиеся к вопросу
+	var chunks []DocumentChunk
+	var passages []SearchResult
+	if toolName == "general" {
+		chunks = a.documents.Relevant(mc.ConversationID, message, documentContextLimit)
+		passages = a.searchKnowledge(message)
+	}
+
+	// Если подключена модель и это не специальная команда, используем ее
+	if provider := a.llm(); provider != nil && toolName == "general" {
+		// Персональные настройки пользователя применяются к каждому запросу
+		settings := a.settings.Get(userID)
+
+		var system []string
+		if instructions := settings.Instructions(); instructions != "" {
+			system = append(system, instructions)
+		}
+		if len(passages) > 0 {
+			system = append(system, knowledgeContext(passages))
+		}
+		if len(chunks) > 0 {
+			system = append(system, documentContext(chunks))
+		}
+		options := settings.GenerationOptions()
+		a.routeModel(&options, ModelRequest{UserID: userID, Message: message, Setting: settings.Model, WithContext: len(chunks) > 0 || len(passages) > 0})
+
+		// В запрос попадают последние реплики в пределах бюджета токенов, ранние — кратким содержанием
+		history, summary := a.fitContext(provider, mc, history, message, system, options)
+		if summary != "" {
+			system = append(system, summaryContext(summary))
+		}
+		prompt := buildPrompt(mc, history, message)
+		var completion Completion
+		completion, err = a.cachedGenerate(provider, withSystemPrompt(prompt, system), options, userID, mc.OnDelta)
+		response, model, modelVersion = completion.Text, completion.Model, completion.ModelVersion
 		if err != nil {
-			log.Printf("Ошибка Yandex GPT, переключаемся на встроенные инструменты: %v", err)
+			log.Printf("Ошибка модели %s, переключаемся на встроенные инструменты: %v", provider.Name(), err)
 			// Fallback на встроенные инструменты
-			response = a.generateGeneralResponse(message)
+			response = a.generateGeneralResponse(message, locale)
 			err = nil // Сбрасываем ошибку, так как мы обработали её
+		} else {
+			// Ответ модели проверяется до того, как к нему добавятся источники
+			outputVerdict := a.moderate(response, ModerationOutput, userID)
+			response = moderationNotice(locale, ModerationOutput, outputVerdict, response)
+			if len(passages) > 0 && outputVerdict.Action != ModerationBlock {
+				response += "\n\n" + knowledgeSources(locale, passages)
+			}
 		}
 	} else if tool, exists := a.tools[toolName]; exists {
 		response, err = tool.Handler(message, userID)
+	} else if len(chunks) > 0 {
+		// Без модели показываем самый подходящий фрагмент документа
+		response = T(locale, "documents.excerpt", Params{"name": chunks[0].DocumentName, "text": chunks[0].Text})
+	} else if len(passages) > 0 {
+		// Без модели показываем самый подходящий фрагмент базы знаний
+		response = passages[0].Text + "\n\n" + knowledgeSources(locale, passages[:1])
 	} else {
 		// Если инструмент не найден, используем общий ответ
-		response = a.generateGeneralResponse(message)
+		response = a.generateGeneralResponse(message, locale)
 	}
 
 	if err != nil {
 		log.Printf("Ошибка при обработке сообщения: %v", err)
-		return "Извините, произошла ошибка при обработке вашего запроса.", nil
+		a.metrics.Errors.Add(1)
+		return Reply{Text: T(locale, "error.generic")}, nil
 	}
 
+	// Предупреждение о сообщении пользователя дописываем к любому ответу
+	response = moderationNotice(locale, ModerationInput, inputVerdict, response)
+
 	// Обновляем историю с ответом
-	a.updateLastResponse(userID, response)
+	a.updateLastResponse(mc, message, response, toolName, model, modelVersion)
 
-	return response, nil
+	return Reply{Text: response, Tool: toolName, Model: model, ModelVersion: modelVersion}, nil
+}
+
+// Documents возвращает хранилище документов, загруженных в разговоры
+func (a *Agent) Documents() *DocumentStore {
+	return a.documents
+}
+
+// Settings возвращает хранилище персональных настроек пользователей
+func (a *Agent) Settings() *SettingsStore {
+	return a.settings
+}
+
+// Users возвращает реестр пользователей, которые писали боту
+func (a *Agent) Users() *UserRegistry {
+	return a.users
+}
+
+// SetUserRegistry заменяет реестр пользователей, например на сохраняемый на диск
+func (a *Agent) SetUserRegistry(users *UserRegistry) {
+	a.users = users
+}
+
+// Access возвращает политику доступа к боту
+func (a *Agent) Access() *AccessPolicy {
+	return a.access
+}
+
+// SetAccessPolicy заменяет политику доступа, например на сохраняемую на диск
+func (a *Agent) SetAccessPolicy(access *AccessPolicy) {
+	a.access = access
+}
+
+// Metrics возвращает счетчики работы агента
+func (a *Agent) Metrics() *Metrics {
+	return a.metrics
+}
+
+// Reminders возвращает планировщик напоминаний
+func (a *Agent) Reminders() *ReminderScheduler {
+	return a.reminders
+}
+
+// SetReminderScheduler заменяет планировщик напоминаний, например на сохраняемый на диск
+func (a *Agent) SetReminderScheduler(reminders *ReminderScheduler) {
+	a.reminders = reminders
+}
+
+// Notes возвращает хранилище заметок и списков дел
+func (a *Agent) Notes() *NotesStore {
+	return a.notes
+}
+
+// SetNotesStore заменяет хранилище заметок, например на сохраняемое на диск
+func (a *Agent) SetNotesStore(notes *NotesStore) {
+	a.notes = notes
+}
+
+// SetRatesProvider заменяет источник курсов валют
+func (a *Agent) SetRatesProvider(rates RatesProvider) {
+	a.rates = rates
+}
+
+// ResponseCache возвращает кэш ответов модели
+func (a *Agent) ResponseCache() *ResponseCache {
+	return a.cache
+}
+
+// SetResponseCache заменяет кэш ответов модели; nil отключает кэш
+func (a *Agent) SetResponseCache(cache *ResponseCache) {
+	a.cache = cache
+}
+
+// SetSettingsStore заменяет хранилище настроек, например на сохраняемое на диск
+func (a *Agent) SetSettingsStore(settings *SettingsStore) {
+	a.settings = settings
+}
+
+// SetKnowledgeBase подключает базу знаний, фрагменты которой добавляются в запросы к модели
+func (a *Agent) SetKnowledgeBase(kb *KnowledgeBase) {
+	a.knowledge = kb
+}
+
+// searchKnowledge ищет фрагменты базы знаний; ошибки поиска не мешают ответу
+func (a *Agent) searchKnowledge(message string) []SearchResult {
+	if a.knowledge == nil {
+		return nil
+	}
+
+	// Запрос может уйти в облачный API эмбеддингов, поэтому персональные данные заменяются
+	results, err := a.knowledge.Search(a.redactText(message))
+	if err != nil {
+		log.Printf("Ошибка поиска по базе знаний: %v", err)
+		return nil
+	}
+	return results
+}
+
+// withSystemPrompt добавляет в начало диалога системное сообщение из нескольких частей
+func withSystemPrompt(prompt []YandexGPTMessage, parts []string) []YandexGPTMessage {
+	if len(parts) == 0 {
+		return prompt
+	}
+	system := YandexGPTMessage{Role: "system", Text: strings.Join(parts, "\n\n")}
+	return append([]YandexGPTMessage{system}, prompt...)
+}
+
+// history возвращает копию истории разговора
+func (a *Agent) history(conversationID string) []ConversationEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conversation := a.conversationHistory[conversationID]
+	if conversation == nil {
+		return nil
+	}
+	return append([]ConversationEntry(nil), conversation.Entries...)
+}
+
+// buildPrompt формирует сообщения для модели из истории разговора и нового сообщения
+func buildPrompt(mc MessageContext, history []ConversationEntry, message string) []YandexGPTMessage {
+	prompt := make([]YandexGPTMessage, 0, len(history)*2+1)
+	for _, entry := range history {
+		if entry.Response == "" {
+			continue
+		}
+		prompt = append(prompt,
+			YandexGPTMessage{Role: "user", Text: withAuthor(entry.Author, entry.Message)},
+			YandexGPTMessage{Role: "assistant", Text: entry.Response},
+		)
+	}
+
+	return append(prompt, YandexGPTMessage{Role: "user", Text: withAuthor(mc.Author, message)})
+}
+
+// withAuthor подписывает сообщение именем автора, если оно известно
+func withAuthor(author, message string) string {
+	if author == "" {
+		return message
+	}
+	return author + ": " + message
 }
 
 // determineTool определяет, какой инструмент использовать на основе сообщения
 func (a *Agent) determineTool(message string) string {
 	message = strings.ToLower(message)
 
-	// Ключевые слова для определения инструментов
+	// Ключевые слова для определения инструментов.
+	// Напоминания проверяем первыми: "напомни про погоду" — это напоминание, а не прогноз.
+	if isReminderRequest(message) {
+		return "remind"
+	}
+	if tool := notesToolFor(message); tool != "" {
+		return tool
+	}
+	if isConversionRequest(message) {
+		return "convert"
+	}
 	if strings.Contains(message, "погода") || strings.Contains(message, "weather") {
 		return "weather"
 	}
-	if strings.Contains(message, "время") || strings.Contains(message, "time") || 
-	   strings.Contains(message, "сколько времени") {
+	if isTimeQuestion(message) {
 		return "time"
 	}
 	if strings.Contains(message, "вычисли") || strings.Contains(message, "calculate") || 
@@ -145,99 +494,244 @@ func (a *Agent) determineTool(message string) string {
 	   strings.Contains(message, "команды") || strings.HasPrefix(message, "/help") {
 		return "help"
 	}
+	if isMathExpression(message) {
+		return "calculate"
+	}
 
 	return "general"
 }
 
-// generateGeneralResponse генерирует общий ответ
-func (a *Agent) generateGeneralResponse(message string) string {
-	responses := []string{
-		"Интересный вопрос! Можете уточнить, что именно вас интересует?",
-		"Я понимаю ваш вопрос. Могу помочь с информацией о погоде, времени, вычислениями или другими задачами.",
-		"Хорошо! Для более точного ответа используйте команды: /help - список команд, /weather - погода, /time - время.",
-		"Я готов помочь! Попробуйте спросить о погоде, времени или попросите выполнить вычисления.",
+// AnswerWithTool отвечает встроенным инструментом, не затрагивая историю и данные пользователя:
+// вместо инструментов, которые что-то меняют, возвращает подсказку написать боту в личный чат.
+// Третье значение равно false, если сообщение не подходит ни одному инструменту.
+func (a *Agent) AnswerWithTool(message string, userID int64) (string, string, bool) {
+	toolName := a.determineTool(message)
+	tool, exists := a.tools[toolName]
+	if !exists {
+		return toolName, "", false
+	}
+	if tool.Mutating {
+		return toolName, T(a.LocaleFor(userID), "inline.private_only"), true
 	}
 
+	response, err := tool.Handler(message, userID)
+	if err != nil {
+		log.Printf("Ошибка инструмента %s: %v", toolName, err)
+		return toolName, "", false
+	}
+
+	return toolName, response, true
+}
+
+// AnswerWithLLM отвечает через модель, не затрагивая историю
+func (a *Agent) AnswerWithLLM(message string, userID int64) (string, error) {
+	provider := a.llm()
+	if provider == nil {
+		return a.generateGeneralResponse(message, a.LocaleFor(userID)), nil
+	}
+
+	locale := a.LocaleFor(userID)
+	inputVerdict := a.moderate(message, ModerationInput, userID)
+	if inputVerdict.Action == ModerationBlock {
+		return moderationNotice(locale, ModerationInput, inputVerdict, ""), nil
+	}
+
+	settings := a.settings.Get(userID)
+	prompt := []YandexGPTMessage{{Role: "user", Text: message}}
+	var system []string
+	if instructions := settings.Instructions(); instructions != "" {
+		system = append(system, instructions)
+	}
+	options := settings.GenerationOptions()
+	// Модели доступны только инструменты, которые ничего не меняют
+	options.Tools = a.functionTools(false)
+	a.routeModel(&options, ModelRequest{UserID: userID, Message: message, Setting: settings.Model})
+	response, err := a.generate(provider, withSystemPrompt(prompt, system), options, userID)
+	if err != nil {
+		return "", err
+	}
+	response = moderationNotice(locale, ModerationOutput, a.moderate(response, ModerationOutput, userID), response)
+	return moderationNotice(locale, ModerationInput, inputVerdict, response), nil
+}
+
+// generalResponseCount — количество общих ответов в каталоге строк (general.0 … general.N-1)
+const generalResponseCount = 4
+
+// generateGeneralResponse генерирует общий ответ
+func (a *Agent) generateGeneralResponse(message, locale string) string {
 	// Простая логика выбора ответа на основе длины сообщения
-	index := len(message) % len(responses)
-	return responses[index]
+	index := len(message) % generalResponseCount
+	return T(locale, fmt.Sprintf("general.%d", index))
+}
+
+// RememberLocale запоминает язык интерфейса пользователя, например из Telegram
+func (a *Agent) RememberLocale(userID int64, languageCode string) {
+	if languageCode == "" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.locales[userID] = normalizeLocale(languageCode)
+}
+
+// LocaleFor возвращает локаль пользователя: язык из настроек или последний известный язык клиента
+func (a *Agent) LocaleFor(userID int64) string {
+	if locale, ok := a.settings.Get(userID).Locale(); ok {
+		return locale
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if locale, exists := a.locales[userID]; exists {
+		return locale
+	}
+	return defaultLocale
+}
+
+// LocationFor возвращает часовой пояс пользователя для разбора и вывода времени:
+// пояс из настроек или пояс по умолчанию
+func (a *Agent) LocationFor(userID int64) *time.Location {
+	if loc, ok := a.settings.Get(userID).Location(); ok {
+		return loc
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.timezone
+}
+
+// SetDefaultTimezone задает пояс для пользователей, которые не указали свой
+func (a *Agent) SetDefaultTimezone(loc *time.Location) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.timezone = loc
 }
 
 // addToHistory добавляет сообщение в историю разговора
-func (a *Agent) addToHistory(userID int64, message, response string) {
-	if a.conversationHistory[userID] == nil {
-		a.conversationHistory[userID] = make([]ConversationEntry, 0)
+func (a *Agent) addToHistory(mc MessageContext, message, response string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := mc.ConversationID
+	if a.conversationHistory[key] == nil {
+		a.conversationHistory[key] = &Conversation{}
 	}
+	conversation := a.conversationHistory[key]
 
-	a.conversationHistory[userID] = append(a.conversationHistory[userID], ConversationEntry{
+	conversation.Entries = append(conversation.Entries, ConversationEntry{
+		UserID:    mc.UserID,
+		Author:    mc.Author,
 		Message:   message,
 		Response:  response,
 		Timestamp: time.Now(),
 	})
 
-	// Ограничиваем историю последними 10 сообщениями
-	if len(a.conversationHistory[userID]) > 10 {
-		a.conversationHistory[userID] = a.conversationHistory[userID][len(a.conversationHistory[userID])-10:]
+	// Размер контекста модели ограничивает fitContext; здесь только защита памяти,
+	// если модель не подключена и историю некому сжимать
+	if len(conversation.Entries) > maxHistoryEntries {
+		conversation.Entries = conversation.Entries[len(conversation.Entries)-maxHistoryEntries:]
 	}
 }
 
-// updateLastResponse обновляет последний ответ в истории
-func (a *Agent) updateLastResponse(userID int64, response string) {
-	if history, exists := a.conversationHistory[userID]; exists && len(history) > 0 {
-		history[len(history)-1].Response = response
+// ResetConversation удаляет историю разговора вместе с кратким содержанием
+func (a *Agent) ResetConversation(conversationID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.conversationHistory, conversationID)
+}
+
+// updateLastResponse записывает ответ в последнюю запись пользователя с этим сообщением.
+// В групповом чате между вопросом и ответом могут появиться сообщения других участников.
+func (a *Agent) updateLastResponse(mc MessageContext, message, response, tool, model, modelVersion string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conversation := a.conversationHistory[mc.ConversationID]
+	if conversation == nil {
+		return
+	}
+	history := conversation.Entries
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].UserID == mc.UserID && history[i].Message == message && history[i].Response == "" {
+			history[i].Response = response
+			history[i].Tool = tool
+			history[i].Model = model
+			history[i].ModelVersion = modelVersion
+			return
+		}
 	}
 }
 
 // Обработчики инструментов
 
 func (a *Agent) handleWeatherRequest(message string, userID int64) (string, error) {
-	return "🌤️ К сожалению, я пока не подключен к сервису погоды. Но могу сказать, что сегодня отличный день для прогулки!", nil
-}
-
-func (a *Agent) handleTimeRequest(message string, userID int64) (string, error) {
-	currentTime := time.Now().Format("15:04:05")
-	currentDate := time.Now().Format("02.01.2006")
-	return fmt.Sprintf("🕐 Текущее время: %s\n📅 Дата: %s", currentTime, currentDate), nil
+	return T(a.LocaleFor(userID), "tool.weather.answer"), nil
 }
 
 func (a *Agent) handleCalculateRequest(message string, userID int64) (string, error) {
-	// Простой калькулятор для базовых операций
-	message = strings.ToLower(message)
-	
-	// Ищем числа и операции
-	if strings.Contains(message, "+") {
-		return "➕ Для сложения используйте формат: '2 + 3'. Я пока учусь математике! 😊", nil
-	}
-	if strings.Contains(message, "-") {
-		return "➖ Для вычитания используйте формат: '5 - 2'. Я пока учусь математике! 😊", nil
-	}
-	if strings.Contains(message, "*") || strings.Contains(message, "×") {
-		return "✖️ Для умножения используйте формат: '3 * 4'. Я пока учусь математике! 😊", nil
+	locale := a.LocaleFor(userID)
+
+	expression := extractExpression(message)
+	if expression == "" {
+		return T(locale, "tool.calculate.usage"), nil
 	}
-	if strings.Contains(message, "/") || strings.Contains(message, "÷") {
-		return "➗ Для деления используйте формат: '8 / 2'. Я пока учусь математике! 😊", nil
+
+	result, err := evaluateExpression(expression)
+	if err != nil {
+		return T(locale, "tool.calculate.error", Params{
+			"expression": expression,
+			"error":      localizeError(locale, err),
+		}), nil
 	}
-	
-	return "🧮 Для вычислений используйте команду /calculate или напишите 'вычисли 2+2'", nil
-}
 
-func (a *Agent) handleHelpRequest(message string, userID int64) (string, error) {
-	helpText := `🤖 *Доступные команды:*
+	return T(locale, "tool.calculate.result", Params{
+		"expression": expression,
+		"result":     formatNumber(result),
+	}), nil
+}
 
-/start - Начать работу с ботом
-/help - Показать это сообщение
-/weather - Информация о погоде
-/time - Текущее время
-/calculate - Математические вычисления
+func (a *Agent) handleRemindRequest(message string, userID int64) (string, error) {
+	locale := a.LocaleFor(userID)
+	loc := a.LocationFor(userID)
 
-*Примеры вопросов:*
-• "Какая погода?"
-• "Сколько времени?"
-• "Вычисли 2+2"
-• "Помощь"
+	spec, err := parseReminder(message, a.reminders.Now(), loc)
+	if err != nil {
+		return "❌ " + localizeError(locale, err) + "\n\n" + T(locale, "remind.usage"), nil
+	}
 
-Я готов помочь вам! 😊`
+	reminder, err := a.reminders.Add(Reminder{
+		UserID:     userID,
+		ChatID:     userID, // Напоминания приходят в личный чат с ботом
+		Text:       spec.Text,
+		At:         spec.At,
+		Recurrence: spec.Recurrence,
+		Location:   loc.String(),
+		Locale:     locale,
+	})
+	if err != nil {
+		return "❌ " + localizeError(locale, err), nil
+	}
 
-	return helpText, nil
+	log.Printf("Пользователь %d создал напоминание %d на %s", userID, reminder.ID, reminder.At.Format(time.RFC3339))
+	if reminder.Recurrence != nil {
+		return T(locale, "remind.created_recurring", Params{
+			"schedule": describeRecurrence(locale, *reminder.Recurrence),
+			"when":     formatReminderTime(locale, reminder.At.In(loc)),
+			"text":     reminder.Text,
+		}), nil
+	}
+	return T(locale, "remind.created", Params{
+		"when": formatReminderTime(locale, reminder.At.In(loc)),
+		"text": reminder.Text,
+	}), nil
 }
 
+func (a *Agent) handleHelpRequest(message string, userID int64) (string, error) {
+	return T(a.LocaleFor(userID), "help.text"), nil
+}
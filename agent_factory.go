@@ -3,24 +3,166 @@ package main
 import (
 	"log"
 	"os"
+	"sync"
 )
 
+var (
+	sharedAgentOnce     sync.Once
+	sharedAgentInstance *Agent
+)
+
+// sharedAgent возвращает общего для всего процесса агента,
+// чтобы история разговоров не терялась между запросами
+func sharedAgent() *Agent {
+	sharedAgentOnce.Do(func() {
+		sharedAgentInstance = createAgent()
+	})
+	return sharedAgentInstance
+}
+
 // createAgent создает агента на основе конфигурации
 func createAgent() *Agent {
+	agent := createBaseAgent()
+	store := NewStore(dataDir())
+	agent.SetSettingsStore(NewSettingsStore(store))
+	agent.SetUserRegistry(NewUserRegistry(store))
+	agent.SetAccessPolicy(NewAccessPolicy(store, accessModeFromEnv(), accessAllowlistFromEnv()))
+	agent.SetReminderScheduler(NewReminderScheduler(store, systemClock{}))
+	agent.SetNotesStore(NewNotesStore(store))
+	agent.SetRatesProvider(NewCBRRates(os.Getenv("CBR_RATES_URL")))
+	agent.SetDefaultTimezone(defaultTimezone())
+	agent.SetContextWindow(createTokenCounter(), contextWindowFromEnv())
+
+	// Кэш ответов сохраняется на диск только по запросу: иначе он живет до перезапуска
+	var cacheStore *Store
+	if os.Getenv("RESPONSE_CACHE_DISK") == "true" {
+		cacheStore = store
+	}
+	agent.SetResponseCache(NewResponseCache(cacheStore, responseCacheConfigFromEnv()))
+	agent.SetRedactor(NewRedactor(piiModesFromEnv()))
+	agent.SetModerator(createModerator(agent))
+
+	if kb := createKnowledgeBase(); kb != nil {
+		agent.SetKnowledgeBase(kb)
+	}
+
+	return agent
+}
+
+// createLocalAgent создает агента для подкоманд eval и repl: бэкенды, модерация и защита
+// данных настраиваются как в боте, но настройки, заметки и напоминания живут только в памяти
+func createLocalAgent() *Agent {
+	agent := createBaseAgent()
+	agent.SetRatesProvider(NewCBRRates(os.Getenv("CBR_RATES_URL")))
+	agent.SetDefaultTimezone(defaultTimezone())
+	agent.SetContextWindow(createTokenCounter(), contextWindowFromEnv())
+	agent.SetRedactor(NewRedactor(piiModesFromEnv()))
+	agent.SetModerator(createModerator(agent))
+	return agent
+}
+
+// createBaseAgent создает агента с Yandex GPT или встроенного агента
+func createBaseAgent() *Agent {
+	agent := NewAgent()
+	configureProviders(agent)
+	return agent
+}
+
+// configureProviders регистрирует бэкенды модели и выбирает активный по конфигурации.
+// Вызывается и при /reload, поэтому каждый раз заново читает переменные окружения.
+func configureProviders(agent *Agent) {
+	agent.SetModelRouter(modelRouterFromEnv())
+
+	apiKey := os.Getenv("YANDEX_GPT_API_KEY")
+	folderID := os.Getenv("YANDEX_GPT_FOLDER_ID")
+	configured := apiKey != "" && folderID != ""
+
+	// Регистрируем Yandex GPT, даже если он выключен, чтобы его можно было включить через /provider
+	var yandexGPT *YandexGPTClient
+	if configured {
+		yandexGPT = NewYandexGPTClient(apiKey, folderID)
+		if err := useCassette("yandex", yandexGPT.SetTransport); err != nil {
+			log.Printf("Yandex GPT недоступен: %v", err)
+			configured = false
+		}
+	}
+	if configured {
+		agent.RegisterProvider(yandexGPT)
+	} else {
+		// После /reload без ключей прежний клиент не должен оставаться доступным
+		agent.UnregisterProvider((&YandexGPTClient{}).Name())
+	}
+
 	// Проверяем, нужно ли использовать Yandex GPT
-	useYandexGPT := os.Getenv("USE_YANDEX_GPT")
-	if useYandexGPT == "true" {
-		apiKey := os.Getenv("YANDEX_GPT_API_KEY")
-		folderID := os.Getenv("YANDEX_GPT_FOLDER_ID")
-		
-		if apiKey != "" && folderID != "" {
+	if os.Getenv("USE_YANDEX_GPT") == "true" {
+		if configured {
 			log.Printf("Создаем агента с Yandex GPT")
-			return NewAgentWithYandexGPT(apiKey, folderID)
-		} else {
-			log.Printf("Yandex GPT включен, но API ключ или Folder ID не установлены. Используем встроенного агента.")
+			agent.SetProvider(yandexGPT.Name())
+			return
 		}
+		log.Printf("Yandex GPT включен, но API ключ или Folder ID не установлены. Используем встроенного агента.")
 	}
-	
+
 	log.Printf("Создаем встроенного агента")
-	return NewAgent()
+	agent.SetProvider(builtinProviderName)
+}
+
+// createKnowledgeBase создает базу знаний из каталога KNOWLEDGE_DIR.
+// Возвращает nil, если база знаний не настроена или не загрузилась.
+func createKnowledgeBase() *KnowledgeBase {
+	dir := os.Getenv("KNOWLEDGE_DIR")
+	if dir == "" {
+		return nil
+	}
+
+	indexPath := os.Getenv("KNOWLEDGE_INDEX_PATH")
+	if indexPath == "" {
+		indexPath = "data/knowledge_index.json"
+	}
+
+	var embedder Embedder = HashingEmbedder{}
+	apiKey := os.Getenv("YANDEX_GPT_API_KEY")
+	folderID := os.Getenv("YANDEX_GPT_FOLDER_ID")
+	if os.Getenv("KNOWLEDGE_EMBEDDER") != "hashing" && apiKey != "" && folderID != "" {
+		yandexEmbedder := NewYandexEmbedder(apiKey, folderID, os.Getenv("YANDEX_EMBEDDINGS_URL"))
+		if err := useCassette("yandex", yandexEmbedder.SetTransport); err != nil {
+			log.Printf("Эмбеддер Yandex недоступен, используем локальный: %v", err)
+		} else {
+			embedder = yandexEmbedder
+		}
+	}
+
+	log.Printf("Загружаем базу знаний из %s (эмбеддер %s)", dir, embedder.Name())
+	kb, err := NewKnowledgeBase(dir, indexPath, embedder)
+	if err != nil {
+		log.Printf("Ошибка загрузки базы знаний, продолжаем без нее: %v", err)
+		return nil
+	}
+	return kb
+}
+
+// createSpeechRecognizer создает распознаватель речи на основе конфигурации.
+// Возвращает nil, если распознавание отключено.
+func createSpeechRecognizer() SpeechRecognizer {
+	if os.Getenv("USE_SPEECHKIT") != "true" {
+		return nil
+	}
+
+	// По умолчанию используем тот же ключ, что и для Yandex GPT
+	apiKey := os.Getenv("YANDEX_SPEECHKIT_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("YANDEX_GPT_API_KEY")
+	}
+	if apiKey == "" {
+		log.Printf("SpeechKit включен, но API ключ не установлен. Голосовые сообщения обрабатываться не будут.")
+		return nil
+	}
+
+	log.Printf("Создаем распознаватель речи Yandex SpeechKit")
+	speech := NewYandexSpeechKit(apiKey, os.Getenv("YANDEX_GPT_FOLDER_ID"), os.Getenv("YANDEX_SPEECHKIT_URL"))
+	if err := useCassette("yandex", speech.SetTransport); err != nil {
+		log.Printf("SpeechKit недоступен, голосовые сообщения обрабатываться не будут: %v", err)
+		return nil
+	}
+	return speech
 }
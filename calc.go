@@ -0,0 +1,248 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// calcOperators содержит символы операций, которые понимает калькулятор
+const calcOperators = "+-*/^%×÷"
+
+// isCalcRune проверяет, может ли символ входить в арифметическое выражение
+func isCalcRune(r rune) bool {
+	return unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune(calcOperators+"().,", r)
+}
+
+// isMathExpression проверяет, что сообщение целиком является арифметическим выражением
+func isMathExpression(message string) bool {
+	message = strings.TrimRight(strings.TrimSpace(message), "=? ")
+	if message == "" {
+		return false
+	}
+
+	hasDigit, hasOperator := false, false
+	for i, r := range message {
+		if !isCalcRune(r) {
+			return false
+		}
+		if unicode.IsDigit(r) {
+			hasDigit = true
+		}
+		// Унарный минус в начале выражения операцией не считаем
+		if strings.ContainsRune(calcOperators, r) && !(i == 0 && r == '-') {
+			hasOperator = true
+		}
+	}
+
+	return hasDigit && hasOperator
+}
+
+// extractExpression выделяет из сообщения самый длинный фрагмент, похожий на выражение
+func extractExpression(message string) string {
+	best := ""
+	var current strings.Builder
+
+	flush := func() {
+		candidate := strings.TrimSpace(current.String())
+		if strings.IndexFunc(candidate, unicode.IsDigit) >= 0 && len(candidate) > len(best) {
+			best = candidate
+		}
+		current.Reset()
+	}
+
+	for _, r := range message {
+		if isCalcRune(r) {
+			current.WriteRune(r)
+			continue
+		}
+		flush()
+	}
+	flush()
+
+	return strings.TrimRight(best, "=?., ")
+}
+
+// evaluateExpression вычисляет арифметическое выражение
+func evaluateExpression(expr string) (float64, error) {
+	p := &calcParser{input: []rune(normalizeExpression(expr))}
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, newLocalizedError("calc.error.unexpected", Params{"char": string(p.input[p.pos])})
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, newLocalizedError("calc.error.undefined")
+	}
+
+	return value, nil
+}
+
+// formatNumber форматирует число без лишних нулей
+func formatNumber(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'g', 10, 64)
+}
+
+// normalizeExpression приводит выражение к виду, понятному парсеру
+func normalizeExpression(expr string) string {
+	replacer := strings.NewReplacer("×", "*", "÷", "/", ",", ".")
+	return replacer.Replace(expr)
+}
+
+// calcParser разбирает выражение методом рекурсивного спуска
+type calcParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *calcParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *calcParser) peek() rune {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// parseExpression разбирает сложение и вычитание
+func (p *calcParser) parseExpression() (float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseTerm()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+// parseTerm разбирает умножение, деление и остаток
+func (p *calcParser) parseTerm() (float64, error) {
+	left, err := p.parsePower()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parsePower()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, newLocalizedError("calc.error.div_by_zero")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, newLocalizedError("calc.error.div_by_zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+// parsePower разбирает возведение в степень (правоассоциативно)
+func (p *calcParser) parsePower() (float64, error) {
+	base, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	if p.peek() == '^' {
+		p.pos++
+		exponent, err := p.parsePower()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+
+	return base, nil
+}
+
+// parseUnary разбирает унарные плюс и минус
+func (p *calcParser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary разбирает числа и выражения в скобках
+func (p *calcParser) parsePrimary() (float64, error) {
+	r := p.peek()
+
+	if r == '(' {
+		p.pos++
+		value, err := p.parseExpression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, newLocalizedError("calc.error.paren")
+		}
+		p.pos++
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if start == p.pos {
+		if r == 0 {
+			return 0, newLocalizedError("calc.error.end")
+		}
+		return 0, newLocalizedError("calc.error.unexpected", Params{"char": string(r)})
+	}
+
+	value, err := strconv.ParseFloat(string(p.input[start:p.pos]), 64)
+	if err != nil {
+		return 0, newLocalizedError("calc.error.number", Params{"number": string(p.input[start:p.pos])})
+	}
+	return value, nil
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Режимы кассеты HTTP-запросов
+const (
+	CassetteRecord = "record" // Запросы уходят в сеть, пары запрос/ответ записываются в файл
+	CassetteReplay = "replay" // Ответы берутся из файла, сеть не используется
+)
+
+// telegramTokenPattern находит токен бота в адресах Bot API: /bot123456:ABC.../getMe
+var telegramTokenPattern = regexp.MustCompile(`bot\d+:[A-Za-z0-9_-]+`)
+
+// cassetteRequestHeaders — заголовки запроса, которые попадают в запись; Authorization не записывается
+var cassetteRequestHeaders = []string{"Content-Type"}
+
+// CassetteRequest — записанный запрос
+type CassetteRequest struct {
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	BodyBase64 string            `json:"body_base64,omitempty"` // Двоичное тело, например аудио
+}
+
+// CassetteResponse — записанный ответ
+type CassetteResponse struct {
+	Status     int               `json:"status"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	BodyBase64 string            `json:"body_base64,omitempty"`
+}
+
+// CassetteInteraction — пара запрос/ответ
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// Cassette — http.RoundTripper, который записывает запросы и ответы в файл или
+// воспроизводит их. Секреты (токены, ключи, каталог) заменяются в записи метками
+// вида {{TELEGRAM_BOT_TOKEN}}, поэтому файлы можно хранить в репозитории.
+// Воспроизведение не требует настоящих ключей: запросы сравниваются после той же замены.
+type Cassette struct {
+	path    string
+	mode    string
+	inner   http.RoundTripper
+	secrets map[string]string // Метка → значение
+
+	mu           sync.Mutex
+	interactions []CassetteInteraction
+	used         []bool
+}
+
+// NewCassette открывает кассету. В режиме воспроизведения файл должен существовать,
+// при записи новые пары дописываются к уже записанным.
+// secrets — значения, которые нужно скрыть, по именам меток.
+func NewCassette(path, mode string, secrets map[string]string) (*Cassette, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("неизвестный режим кассеты %q", mode)
+	}
+	c := &Cassette{path: path, mode: mode, inner: http.DefaultTransport, secrets: make(map[string]string)}
+	for name, value := range secrets {
+		if value != "" {
+			c.secrets[name] = value
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err) && mode == CassetteRecord:
+	case err != nil:
+		return nil, fmt.Errorf("ошибка чтения кассеты: %v", err)
+	default:
+		if err := json.Unmarshal(data, &c.interactions); err != nil {
+			return nil, fmt.Errorf("ошибка разбора кассеты %s: %v", path, err)
+		}
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// RoundTrip выполняет запрос через сеть с записью или отвечает из кассеты
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := c.recordRequest(req, body)
+
+	if c.mode == CassetteReplay {
+		response, ok := c.find(recorded)
+		if !ok {
+			return nil, fmt.Errorf("в кассете %s нет ответа на %s %s", filepath.Base(c.path), recorded.Method, recorded.URL)
+		}
+		return c.httpResponse(req, response)
+	}
+
+	resp, err := c.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	response := CassetteResponse{Status: resp.StatusCode, Headers: make(map[string]string)}
+	for name := range resp.Header {
+		if name != "Set-Cookie" {
+			response.Headers[name] = c.scrub(resp.Header.Get(name))
+		}
+	}
+	response.Body, response.BodyBase64 = c.encodeBody(data)
+
+	if err := c.append(CassetteInteraction{Request: recorded, Response: response}); err != nil {
+		log.Printf("Ошибка записи кассеты: %v", err)
+	}
+	return resp, nil
+}
+
+// Client возвращает HTTP-клиент, который ходит через кассету
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Remaining возвращает число записанных пар, которые еще не воспроизводились
+func (c *Cassette) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	remaining := 0
+	for _, used := range c.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// scrub заменяет секреты метками
+func (c *Cassette) scrub(text string) string {
+	for name, value := range c.secrets {
+		text = strings.ReplaceAll(text, value, "{{"+name+"}}")
+	}
+	return telegramTokenPattern.ReplaceAllString(text, "bot{{TELEGRAM_BOT_TOKEN}}")
+}
+
+func (c *Cassette) encodeBody(data []byte) (string, string) {
+	if len(data) == 0 {
+		return "", ""
+	}
+	if utf8.Valid(data) {
+		return c.scrub(string(data)), ""
+	}
+	return "", base64.StdEncoding.EncodeToString(data)
+}
+
+func (c *Cassette) recordRequest(req *http.Request, body []byte) CassetteRequest {
+	recorded := CassetteRequest{Method: req.Method, URL: c.scrub(req.URL.String())}
+	for _, name := range cassetteRequestHeaders {
+		if value := req.Header.Get(name); value != "" {
+			if recorded.Headers == nil {
+				recorded.Headers = make(map[string]string)
+			}
+			recorded.Headers[name] = value
+		}
+	}
+	recorded.Body, recorded.BodyBase64 = c.encodeBody(body)
+	return recorded
+}
+
+// find ищет первую невоспроизведенную пару с тем же методом, адресом и телом.
+// Если тело отличается (например, у multipart случайная граница), подходит
+// первая пара с тем же методом и адресом.
+func (c *Cassette) find(req CassetteRequest) (CassetteResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fallback := -1
+	for i, interaction := range c.interactions {
+		recorded := interaction.Request
+		if c.used[i] || recorded.Method != req.Method || recorded.URL != req.URL {
+			continue
+		}
+		if recorded.Body == req.Body && recorded.BodyBase64 == req.BodyBase64 {
+			c.used[i] = true
+			return interaction.Response, true
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	if fallback < 0 {
+		return CassetteResponse{}, false
+	}
+	c.used[fallback] = true
+	return c.interactions[fallback].Response, true
+}
+
+func (c *Cassette) httpResponse(req *http.Request, recorded CassetteResponse) (*http.Response, error) {
+	body := []byte(recorded.Body)
+	if recorded.BodyBase64 != "" {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(recorded.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("поврежденное тело ответа в кассете: %v", err)
+		}
+	}
+
+	header := make(http.Header)
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// append дописывает пару и сразу сохраняет файл, чтобы запись не терялась при остановке бота
+func (c *Cassette) append(interaction CassetteInteraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+
+	// Без экранирования HTML тела запросов (a=1&b=2) остаются читаемыми в файле
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(c.interactions); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, buf.Bytes(), 0o644)
+}
+
+var (
+	cassettesMu sync.Mutex
+	cassettes   = make(map[string]*Cassette)
+)
+
+// cassetteSecrets — секреты из окружения, которые не должны попасть в кассеты
+func cassetteSecrets() map[string]string {
+	secrets := make(map[string]string)
+	for _, name := range []string{"TELEGRAM_BOT_TOKEN", "YANDEX_GPT_API_KEY", "YANDEX_GPT_FOLDER_ID", "YANDEX_SPEECHKIT_API_KEY"} {
+		secrets[name] = os.Getenv(name)
+	}
+	return secrets
+}
+
+// cassetteTransport возвращает кассету с именем name из каталога HTTP_CASSETTE_DIR
+// в режиме HTTP_CASSETTE_MODE или nil, если кассеты не включены.
+// Кассета открывается один раз, чтобы /reload не терял запись.
+func cassetteTransport(name string) (http.RoundTripper, error) {
+	mode := os.Getenv("HTTP_CASSETTE_MODE")
+	if mode == "" {
+		return nil, nil
+	}
+	dir := os.Getenv("HTTP_CASSETTE_DIR")
+	if dir == "" {
+		dir = filepath.Join("testdata", "cassettes")
+	}
+	path := filepath.Join(dir, name+".json")
+
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	if cassette, ok := cassettes[path]; ok {
+		return cassette, nil
+	}
+	cassette, err := NewCassette(path, mode, cassetteSecrets())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия кассеты %s: %v", path, err)
+	}
+	log.Printf("HTTP-запросы %s идут через кассету %s (%s)", name, path, mode)
+	cassettes[path] = cassette
+	return cassette, nil
+}
+
+// useCassette подключает кассету name через setTransport, если кассеты включены.
+// Если кассету открыть не удалось, клиент нельзя использовать: запросы ушли бы в сеть мимо записи.
+func useCassette(name string, setTransport func(http.RoundTripper)) error {
+	transport, err := cassetteTransport(name)
+	if err != nil {
+		return err
+	}
+	if transport != nil {
+		setTransport(transport)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// testBotToken — токен, с которым воспроизводится кассета telegram.json;
+// в записи он заменен меткой, поэтому подходит любой токен этого вида
+const testBotToken = "987654321:AAreplayTOKENreplayTOKENreplay000"
+
+// requestLog запоминает запросы, которые прошли через транспорт
+type requestLog struct {
+	inner http.RoundTripper
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+func (l *requestLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	l.mu.Lock()
+	l.requests = append(l.requests, req)
+	l.bodies = append(l.bodies, string(body))
+	l.mu.Unlock()
+	return l.inner.RoundTrip(req)
+}
+
+// stubTransport отвечает одним и тем же телом на любой запрос
+type stubTransport struct {
+	body string
+}
+
+func (s stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+func openReplayCassette(t *testing.T, name string, secrets map[string]string) *Cassette {
+	t.Helper()
+	cassette, err := NewCassette(filepath.Join("testdata", "fixtures", name), CassetteReplay, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cassette
+}
+
+func TestYandexGPTReplay(t *testing.T) {
+	// Ключ и каталог отличаются от тех, с которыми шла запись: в кассете они заменены метками
+	cassette := openReplayCassette(t, "yandex_gpt.json", map[string]string{
+		"YANDEX_GPT_API_KEY":   "replay-api-key",
+		"YANDEX_GPT_FOLDER_ID": "b1greplayfolder",
+	})
+	client := NewYandexGPTClient("replay-api-key", "b1greplayfolder")
+	client.SetTransport(cassette)
+
+	tests := []struct {
+		name     string
+		messages []YandexGPTMessage
+		want     Completion
+		wantErr  string
+	}{
+		{
+			name: "ответ модели",
+			messages: []YandexGPTMessage{
+				{Role: "system", Text: "Отвечай кратко."},
+				{Role: "user", Text: "Какая столица Франции?"},
+			},
+			want: Completion{Text: "Столица Франции — Париж.", InputTokens: 19, CompletionTokens: 8, Model: "yandexgpt-lite", ModelVersion: "23.10.2024"},
+		},
+		{
+			name:     "фильтр содержимого",
+			messages: []YandexGPTMessage{{Role: "user", Text: "Как взломать соседский Wi-Fi?"}},
+			want:     Completion{Filtered: true, InputTokens: 14, CompletionTokens: 7, Model: "yandexgpt-lite", ModelVersion: "23.10.2024"},
+		},
+		{
+			name:     "ошибка API",
+			messages: []YandexGPTMessage{{Role: "user", Text: "Привет!"}},
+			wantErr:  "API вернул ошибку 429",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.Generate(tt.messages, DefaultGenerationOptions())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ошибка = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Text != tt.want.Text || got.Filtered != tt.want.Filtered ||
+				got.InputTokens != tt.want.InputTokens || got.CompletionTokens != tt.want.CompletionTokens ||
+				got.Model != tt.want.Model || got.ModelVersion != tt.want.ModelVersion {
+				t.Errorf("Generate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if remaining := cassette.Remaining(); remaining != 0 {
+		t.Errorf("не воспроизведено записей: %d", remaining)
+	}
+}
+
+func TestTelegramReplay(t *testing.T) {
+	t.Setenv("DATA_DIR", t.TempDir())
+	cassette := openReplayCassette(t, "telegram.json", nil)
+	requests := &requestLog{inner: cassette}
+
+	tb, err := NewTelegramBotWithClient(testBotToken, &http.Client{Transport: requests}, createAgent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.bot.Self.UserName != "chat_agent_bot" {
+		t.Errorf("getMe: имя бота %q", tb.bot.Self.UserName)
+	}
+
+	tb.HandleUpdate(tgbotapi.Update{
+		UpdateID: 1,
+		Message: &tgbotapi.Message{
+			MessageID: 7,
+			From:      &tgbotapi.User{ID: 12345, FirstName: "Ivan", LanguageCode: "ru"},
+			Chat:      &tgbotapi.Chat{ID: 12345, Type: "private"},
+			Text:      "2+2*3",
+		},
+	})
+
+	var methods []string
+	var answer url.Values
+	for i, req := range requests.requests {
+		method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		methods = append(methods, method)
+		if !strings.Contains(req.URL.Path, testBotToken) {
+			t.Errorf("запрос %s ушел без токена бота: %s", method, req.URL.Path)
+		}
+		if method == "sendMessage" {
+			answer, _ = url.ParseQuery(requests.bodies[i])
+		}
+	}
+	if got := strings.Join(methods, ","); got != "getMe,sendChatAction,sendMessage" {
+		t.Errorf("методы Bot API: %s", got)
+	}
+	if answer.Get("chat_id") != "12345" || answer.Get("text") != "🧮 `2+2*3` = 8" {
+		t.Errorf("sendMessage: %v", answer)
+	}
+	if remaining := cassette.Remaining(); remaining != 0 {
+		t.Errorf("не воспроизведено записей: %d", remaining)
+	}
+}
+
+func TestCassetteReplayUnknownRequest(t *testing.T) {
+	cassette := openReplayCassette(t, "yandex_gpt.json", nil)
+	req, _ := http.NewRequest(http.MethodGet, "https://llm.api.cloud.yandex.net/operations/1", nil)
+	if _, err := cassette.RoundTrip(req); err == nil {
+		t.Error("запрос, которого нет в кассете, должен завершиться ошибкой, а не уйти в сеть")
+	}
+}
+
+func TestCassetteRecordScrubsSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yandex.json")
+	secrets := map[string]string{"YANDEX_GPT_API_KEY": "secret-api-key", "YANDEX_GPT_FOLDER_ID": "b1gsecretfolder"}
+	cassette, err := NewCassette(path, CassetteRecord, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette.inner = stubTransport{body: `{"folder":"b1gsecretfolder","bot":"bot123:ABC_def"}`}
+
+	client := NewYandexGPTClient("secret-api-key", "b1gsecretfolder")
+	client.SetTransport(cassette)
+	// Ответ заглушки не похож на ответ модели, нам нужна только запись
+	client.Generate([]YandexGPTMessage{{Role: "user", Text: "Привет"}}, DefaultGenerationOptions())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-api-key", "b1gsecretfolder", "123:ABC_def"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("секрет %q попал в кассету:\n%s", secret, data)
+		}
+	}
+	for _, label := range []string{"{{YANDEX_GPT_FOLDER_ID}}", "bot{{TELEGRAM_BOT_TOKEN}}"} {
+		if !bytes.Contains(data, []byte(label)) {
+			t.Errorf("в кассете нет метки %s:\n%s", label, data)
+		}
+	}
+}
+
+func TestCassetteTransportErrors(t *testing.T) {
+	t.Setenv("HTTP_CASSETTE_DIR", t.TempDir())
+
+	t.Setenv("HTTP_CASSETTE_MODE", "")
+	if transport, err := cassetteTransport("yandex"); transport != nil || err != nil {
+		t.Errorf("без HTTP_CASSETTE_MODE: %v, %v", transport, err)
+	}
+
+	t.Setenv("HTTP_CASSETTE_MODE", CassetteReplay)
+	if _, err := cassetteTransport("missing"); err == nil {
+		t.Error("воспроизведение без файла кассеты должно возвращать ошибку")
+	}
+
+	t.Setenv("HTTP_CASSETTE_MODE", "rewind")
+	if _, err := cassetteTransport("yandex"); err == nil {
+		t.Error("неизвестный режим должен возвращать ошибку")
+	}
+
+	set := false
+	if err := useCassette("yandex", func(http.RoundTripper) { set = true }); err == nil || set {
+		t.Errorf("useCassette с ошибкой подключил транспорт: %v", err)
+	}
+}
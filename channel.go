@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// channelAddressesStoreName — имя файла с адресами пользователей каналов в хранилище
+const channelAddressesStoreName = "channel_addresses"
+
+// channelIDNamespace — старший бит идентификаторов пользователей и чатов из каналов кроме Telegram.
+// Идентификаторы Telegram меньше 2^53, поэтому пространства не пересекаются.
+const channelIDNamespace = int64(1) << 62
+
+// Channel — мессенджер, через который агент получает сообщения и отправляет ответы.
+// Идентификаторы чатов и сообщений — строки в формате самой платформы.
+type Channel interface {
+	// Name возвращает имя канала: telegram, webhook, mattermost
+	Name() string
+	// Start запускает прием сообщений и передает их в handle. Каналы с опросом сервера
+	// работают до остановки приема, каналы с вебхуком запоминают handle и сразу возвращают управление.
+	Start(handle func(InboundMessage)) error
+	// Send отправляет ответ в чат
+	Send(chatID string, message OutboundMessage) error
+	// SendTyping показывает в чате, что бот печатает
+	SendTyping(chatID string) error
+}
+
+// InboundMessage — входящее сообщение из канала
+type InboundMessage struct {
+	Channel     string
+	ChatID      string
+	UserID      string
+	UserName    string
+	MessageID   string
+	Text        string
+	Locale      string
+	Group       bool         // Сообщение из группового чата
+	Addressed   bool         // Сообщение обращено к боту: в личном чате всегда, в группе — упоминание или ответ
+	Attachments []Attachment // Файлы, присланные вместе с сообщением
+	Raw         interface{}  // Исходное обновление платформы, например tgbotapi.Update
+}
+
+// OutboundMessage — ответ в канал
+type OutboundMessage struct {
+	Text        string
+	Markdown    bool         // Текст размечен Markdown; если платформа не примет разметку, отправляется как есть
+	ReplyTo     string       // Сообщение, на которое отвечаем (ветка в группе)
+	Buttons     []string     // Быстрые ответы
+	Attachments []Attachment // Файлы
+}
+
+// Attachment — файл во входящем сообщении или в ответе.
+// Содержимое передается в Data или доступно по ссылке URL.
+type Attachment struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Data     []byte `json:"data,omitempty"` // В JSON — base64
+}
+
+// ChannelUserID возвращает идентификатор пользователя агента для пользователя канала.
+// Идентификаторы Telegram используются как есть, чтобы не терять уже сохраненные данные,
+// а идентификаторы других каналов переводятся в отдельное пространство с учетом имени канала:
+// пользователи разных платформ с одинаковыми ID не получат общую историю и настройки.
+func ChannelUserID(channel, nativeID string) int64 {
+	if channel == telegramChannelName {
+		if id, err := strconv.ParseInt(nativeID, 10, 64); err == nil {
+			return id
+		}
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(channel + ":" + nativeID))
+	return channelIDNamespace | int64(hash.Sum64()&uint64(channelIDNamespace-1))
+}
+
+// isChannelUserID сообщает, что идентификатор принадлежит пользователю канала кроме Telegram:
+// написать ему через Bot API нельзя
+func isChannelUserID(userID int64) bool {
+	return userID&channelIDNamespace != 0
+}
+
+// channelChatID возвращает идентификатор группового чата канала для политики доступа
+func channelChatID(channel, nativeChatID string) int64 {
+	return ChannelUserID(channel, "chat:"+nativeChatID)
+}
+
+// ChannelAddress — чат канала, куда можно написать пользователю, например для напоминаний
+type ChannelAddress struct {
+	Channel string `json:"channel"`
+	ChatID  string `json:"chat_id"`
+}
+
+// ChannelHub связывает каналы с агентом: проверяет доступ, ведет историю в пространстве
+// канала, показывает набор текста и отправляет ответ туда, откуда пришло сообщение.
+// Telegram обслуживает TelegramBot со своими командами, кнопками и инлайн-режимом,
+// а через ChannelHub работают остальные каналы.
+type ChannelHub struct {
+	agent *Agent
+	store *Store
+
+	mu        sync.Mutex
+	channels  map[string]Channel
+	addresses map[int64]ChannelAddress // Пользователь агента → чат для сообщений от бота
+}
+
+// NewChannelHub создает диспетчер каналов; адреса пользователей сохраняются в store (nil — только в памяти)
+func NewChannelHub(agent *Agent, store *Store) *ChannelHub {
+	h := &ChannelHub{
+		agent:     agent,
+		store:     store,
+		channels:  make(map[string]Channel),
+		addresses: make(map[int64]ChannelAddress),
+	}
+	if err := store.Load(channelAddressesStoreName, &h.addresses); err != nil {
+		log.Printf("Ошибка загрузки адресов каналов: %v", err)
+	}
+	return h
+}
+
+// Register добавляет канал
+func (h *ChannelHub) Register(channel Channel) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.channels[channel.Name()] = channel
+}
+
+// Channels возвращает имена подключенных каналов
+func (h *ChannelHub) Channels() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(h.channels))
+	for name := range h.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (h *ChannelHub) channel(name string) Channel {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.channels[name]
+}
+
+// Start запускает прием сообщений во всех каналах
+func (h *ChannelHub) Start() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, channel := range h.channels {
+		channel := channel
+		go func() {
+			if err := channel.Start(func(in InboundMessage) { h.Handle(channel, in) }); err != nil {
+				log.Printf("Канал %s остановлен: %v", channel.Name(), err)
+			}
+		}()
+		log.Printf("Канал %s подключен", channel.Name())
+	}
+}
+
+// ServeHTTP передает запрос вебхуку канала: /channels/<имя>
+func (h *ChannelHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/channels/"), "/")
+	handler, ok := h.channel(name).(http.Handler)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// Handle обрабатывает входящее сообщение канала до отправки ответа
+func (h *ChannelHub) Handle(channel Channel, in InboundMessage) {
+	in.Channel = channel.Name()
+	userID := ChannelUserID(in.Channel, in.UserID)
+	chatID := userID
+	if in.Group {
+		chatID = channelChatID(in.Channel, in.ChatID)
+	}
+
+	log.Printf("Получено сообщение %s от %s (%d) в чате %s: %s", in.Channel, in.UserName, userID, in.ChatID, in.Text)
+
+	mc := MessageContext{UserID: userID, ConversationID: userConversationID(userID), Locale: in.Locale}
+	if in.Group {
+		mc.Author = in.UserName
+		mc.ConversationID = fmt.Sprintf("%s:chat:%s", in.Channel, in.ChatID)
+		if groupContextMode() == groupContextPerUser {
+			mc.ConversationID = fmt.Sprintf("%s:chat:%s:user:%d", in.Channel, in.ChatID, userID)
+		}
+	}
+	h.agent.RememberLocale(userID, in.Locale)
+	locale := h.agent.LocaleFor(userID)
+	reply := func(text string) {
+		message := OutboundMessage{Text: text, Markdown: true}
+		if in.Group {
+			message.ReplyTo = in.MessageID
+		}
+		if err := channel.Send(in.ChatID, message); err != nil {
+			log.Printf("Ошибка отправки сообщения в %s: %v", in.Channel, err)
+		}
+	}
+
+	switch h.agent.Access().Check(userID, chatID) {
+	case AccessBlocked:
+		log.Printf("Сообщение от %d в %s проигнорировано: блокировка", userID, in.Channel)
+		return
+	case AccessDenied:
+		log.Printf("Доступ запрещен пользователю %d в чате %d", userID, chatID)
+		if in.Addressed {
+			reply(h.accessDeniedText(locale, in.Group, userID, chatID))
+		}
+		return
+	}
+	h.agent.Users().Touch(userID, in.UserName)
+	h.remember(userID, ChannelAddress{Channel: in.Channel, ChatID: in.ChatID}, in.Group)
+
+	if !in.Addressed {
+		return
+	}
+	if err := channel.SendTyping(in.ChatID); err != nil {
+		log.Printf("Ошибка отправки статуса в %s: %v", in.Channel, err)
+	}
+
+	for _, attachment := range in.Attachments {
+		reply(h.addDocument(mc.ConversationID, locale, attachment))
+	}
+	text := strings.TrimSpace(in.Text)
+	if text == "" {
+		return
+	}
+
+	response, err := h.agent.ProcessMessageInContext(mc, text)
+	if err != nil {
+		log.Printf("Ошибка обработки сообщения из %s: %v", in.Channel, err)
+		response = T(locale, "error.request")
+	}
+	reply(response)
+}
+
+// accessDeniedText объясняет отказ так же, как бот в Telegram; ID указываются в пространстве канала
+func (h *ChannelHub) accessDeniedText(locale string, group bool, userID, chatID int64) string {
+	invite := h.agent.Access().Mode() == AccessModeInvite
+	if group {
+		return T(locale, "access.denied.group", Params{"id": chatID})
+	}
+	if invite {
+		return T(locale, "access.denied.invite", Params{"id": userID})
+	}
+	return T(locale, "access.denied.allowlist", Params{"id": userID})
+}
+
+// addDocument сохраняет присланный файл для вопросов по нему и возвращает текст ответа
+func (h *ChannelHub) addDocument(conversationID, locale string, attachment Attachment) string {
+	name := attachment.Name
+	if !isSupportedDocument(name) {
+		return T(locale, "docs.unsupported", Params{"name": name})
+	}
+	if attachment.Data == nil {
+		return T(locale, "docs.download_failed")
+	}
+	if int64(len(attachment.Data)) > maxDocumentFileSize {
+		return T(locale, "docs.too_large", Params{"name": name, "mb": maxDocumentFileSize >> 20})
+	}
+
+	text, err := extractDocumentText(name, attachment.Data)
+	if err != nil {
+		return T(locale, "docs.read_failed", Params{"name": name, "error": localizeError(locale, err)})
+	}
+	stored, err := h.agent.Documents().Add(conversationID, name, text)
+	if err != nil {
+		return "📄 " + localizeError(locale, err)
+	}
+	log.Printf("Документ %s загружен в разговор %s: %d символов", name, conversationID, stored.Size)
+	return T(locale, "docs.uploaded", Params{"name": name, "chars": TN(locale, "docs.chars", stored.Size)})
+}
+
+// remember запоминает, куда писать пользователю. Личный чат важнее группового:
+// адрес из группы сохраняется, только если личного еще нет.
+func (h *ChannelHub) remember(userID int64, address ChannelAddress, group bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current, exists := h.addresses[userID]
+	if exists && (current == address || group) {
+		return
+	}
+	h.addresses[userID] = address
+	if err := h.store.Save(channelAddressesStoreName, h.addresses); err != nil {
+		log.Printf("Ошибка сохранения адресов каналов: %v", err)
+	}
+}
+
+// Address возвращает чат, куда можно написать пользователю агента
+func (h *ChannelHub) Address(userID int64) (ChannelAddress, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	address, ok := h.addresses[userID]
+	return address, ok
+}
+
+// Deliver возвращает доставку напоминаний: пользователям каналов — в их канал,
+// остальным — через fallback (бот в Telegram или nil)
+func (h *ChannelHub) Deliver(fallback func(Reminder) error) func(Reminder) error {
+	return func(reminder Reminder) error {
+		address, ok := h.Address(reminder.UserID)
+		channel := h.channel(address.Channel)
+		if !ok || channel == nil {
+			if fallback != nil {
+				return fallback(reminder)
+			}
+			return fmt.Errorf("нет канала для пользователя %d", reminder.UserID)
+		}
+		log.Printf("Отправляем напоминание %d пользователю %d через %s", reminder.ID, reminder.UserID, address.Channel)
+		err := channel.Send(address.ChatID, OutboundMessage{
+			Text:     T(reminder.Locale, "remind.delivery", Params{"text": reminder.Text}),
+			Markdown: true,
+		})
+		if err != nil {
+			return fmt.Errorf("отправка в %s: %w", address.Channel, err)
+		}
+		return nil
+	}
+}
+
+// configuredChannels создает каналы, для которых заданы настройки в окружении
+func configuredChannels() []Channel {
+	var channels []Channel
+	if channel := webhookChannelFromEnv(); channel != nil {
+		channels = append(channels, channel)
+	}
+	if channel := mattermostChannelFromEnv(); channel != nil {
+		channels = append(channels, channel)
+	}
+	return channels
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// mattermostChannelName — имя канала Mattermost
+const mattermostChannelName = "mattermost"
+
+// mattermostWebhook — запрос исходящего вебхука Mattermost (JSON или форма)
+type mattermostWebhook struct {
+	Token       string `json:"token"`
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	PostID      string `json:"post_id"`
+	Text        string `json:"text"`
+	TriggerWord string `json:"trigger_word"`
+}
+
+// mattermostPost — сообщение для POST /api/v4/posts
+type mattermostPost struct {
+	ChannelID string   `json:"channel_id"`
+	Message   string   `json:"message"`
+	RootID    string   `json:"root_id,omitempty"`
+	FileIDs   []string `json:"file_ids,omitempty"`
+}
+
+// MattermostChannel — канал Mattermost и совместимых платформ: сообщения приходят через
+// исходящий вебхук (по слову-триггеру), ответы публикуются через REST API от имени бота.
+// Ответ в канале публикуется в ветке исходного сообщения.
+type MattermostChannel struct {
+	baseURL      string
+	token        string // Токен доступа бота для REST API
+	webhookToken string // Токен исходящего вебхука для проверки входящих запросов
+	client       *http.Client
+	handle       func(InboundMessage)
+}
+
+// NewMattermostChannel создает канал для сервера baseURL
+func NewMattermostChannel(baseURL, token, webhookToken string) *MattermostChannel {
+	return &MattermostChannel{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		token:        token,
+		webhookToken: webhookToken,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// mattermostChannelFromEnv создает канал по MATTERMOST_URL, MATTERMOST_BOT_TOKEN и
+// MATTERMOST_WEBHOOK_TOKEN или возвращает nil
+func mattermostChannelFromEnv() Channel {
+	baseURL := os.Getenv("MATTERMOST_URL")
+	if baseURL == "" {
+		return nil
+	}
+	token, webhookToken := os.Getenv("MATTERMOST_BOT_TOKEN"), os.Getenv("MATTERMOST_WEBHOOK_TOKEN")
+	if token == "" || webhookToken == "" {
+		log.Println("⚠️  Канал mattermost не подключен: нужны MATTERMOST_BOT_TOKEN и MATTERMOST_WEBHOOK_TOKEN")
+		return nil
+	}
+	return NewMattermostChannel(baseURL, token, webhookToken)
+}
+
+// Name возвращает имя канала
+func (c *MattermostChannel) Name() string {
+	return mattermostChannelName
+}
+
+// Start запоминает обработчик: сообщения приходят через ServeHTTP
+func (c *MattermostChannel) Start(handle func(InboundMessage)) error {
+	c.handle = handle
+	return nil
+}
+
+// ServeHTTP принимает исходящий вебхук и обрабатывает сообщение в фоне.
+// Пустой ответ {} означает, что Mattermost ничего не публикует сам.
+func (c *MattermostChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload mattermostWebhook
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&payload); err != nil {
+			http.Error(w, "Неверный JSON", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Неверная форма", http.StatusBadRequest)
+			return
+		}
+		payload = mattermostWebhook{
+			Token:       r.PostForm.Get("token"),
+			ChannelID:   r.PostForm.Get("channel_id"),
+			ChannelName: r.PostForm.Get("channel_name"),
+			UserID:      r.PostForm.Get("user_id"),
+			UserName:    r.PostForm.Get("user_name"),
+			PostID:      r.PostForm.Get("post_id"),
+			Text:        r.PostForm.Get("text"),
+			TriggerWord: r.PostForm.Get("trigger_word"),
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(payload.Token), []byte(c.webhookToken)) != 1 {
+		http.Error(w, "Неверный токен", http.StatusUnauthorized)
+		return
+	}
+	if c.handle == nil {
+		http.Error(w, "Канал не запущен", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Личные каналы Mattermost называются по двум пользователям: user1__user2
+	group := !strings.Contains(payload.ChannelName, "__")
+	go c.handle(InboundMessage{
+		Channel:   mattermostChannelName,
+		ChatID:    payload.ChannelID,
+		UserID:    payload.UserID,
+		UserName:  payload.UserName,
+		MessageID: payload.PostID,
+		Text:      strings.TrimSpace(strings.TrimPrefix(payload.Text, payload.TriggerWord)),
+		Group:     group,
+		Addressed: true, // Вебхук срабатывает только на слово-триггер
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("{}"))
+}
+
+// Send публикует ответ; файлы загружаются заранее, быстрые ответы добавляются списком
+func (c *MattermostChannel) Send(chatID string, message OutboundMessage) error {
+	post := mattermostPost{ChannelID: chatID, Message: message.Text, RootID: message.ReplyTo}
+	if len(message.Buttons) > 0 {
+		post.Message += "\n\n• " + strings.Join(message.Buttons, "\n• ")
+	}
+	for _, attachment := range message.Attachments {
+		id, err := c.uploadFile(chatID, attachment)
+		if err != nil {
+			return err
+		}
+		post.FileIDs = append(post.FileIDs, id)
+	}
+	return c.request(http.MethodPost, "/api/v4/posts", post, nil)
+}
+
+// SendTyping показывает в канале, что бот печатает
+func (c *MattermostChannel) SendTyping(chatID string) error {
+	return c.request(http.MethodPost, "/api/v4/users/me/typing", map[string]string{"channel_id": chatID}, nil)
+}
+
+// uploadFile загружает файл в канал и возвращает его ID для поста
+func (c *MattermostChannel) uploadFile(chatID string, attachment Attachment) (string, error) {
+	data := attachment.Data
+	if data == nil {
+		var err error
+		if data, err = fetchFile(c.client, attachment.URL, maxDocumentFileSize); err != nil {
+			return "", err
+		}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("channel_id", chatID)
+	part, err := writer.CreateFormFile("files", attachment.Name)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	writer.Close()
+
+	var result struct {
+		FileInfos []struct {
+			ID string `json:"id"`
+		} `json:"file_infos"`
+	}
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/v4/files", &body)
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := c.do(req, &result); err != nil {
+		return "", err
+	}
+	if len(result.FileInfos) == 0 {
+		return "", fmt.Errorf("Mattermost не вернул ID файла %s", attachment.Name)
+	}
+	return result.FileInfos[0].ID, nil
+}
+
+func (c *MattermostChannel) request(method, path string, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга JSON: %v", err)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, result)
+}
+
+func (c *MattermostChannel) do(req *http.Request, result interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка HTTP запроса: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения ответа: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Mattermost API вернул ошибку %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("ошибка парсинга JSON ответа: %v", err)
+	}
+	return nil
+}
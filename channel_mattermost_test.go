@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mattermostStub — локальный сервер Mattermost, запоминающий запросы к REST API
+type mattermostStub struct {
+	mu       sync.Mutex
+	posts    []mattermostPost
+	typing   []string
+	files    []string
+	auth     []string
+	failPost bool
+}
+
+func newMattermostStub(t *testing.T) (*mattermostStub, *httptest.Server) {
+	stub := &mattermostStub{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.auth = append(stub.auth, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v4/posts":
+			if stub.failPost {
+				http.Error(w, `{"message":"forbidden"}`, http.StatusForbidden)
+				return
+			}
+			var post mattermostPost
+			if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+				t.Errorf("неверное тело поста: %v", err)
+			}
+			stub.posts = append(stub.posts, post)
+			w.Write([]byte(`{"id":"post1"}`))
+		case "/api/v4/users/me/typing":
+			var payload map[string]string
+			json.NewDecoder(r.Body).Decode(&payload)
+			stub.typing = append(stub.typing, payload["channel_id"])
+			w.Write([]byte(`{"status":"OK"}`))
+		case "/api/v4/files":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("неверная форма файла: %v", err)
+				return
+			}
+			file, header, err := r.FormFile("files")
+			if err != nil {
+				t.Errorf("нет файла в форме: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			stub.files = append(stub.files, r.FormValue("channel_id")+"/"+header.Filename+":"+string(data))
+			w.Write([]byte(`{"file_infos":[{"id":"file1"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+// serveMattermostWebhook отправляет вебхук в канал и ждет переданное обработчику сообщение
+func serveMattermostWebhook(t *testing.T, channel *MattermostChannel, contentType, body string) (*httptest.ResponseRecorder, *InboundMessage) {
+	t.Helper()
+	received := make(chan InboundMessage, 1)
+	channel.Start(func(message InboundMessage) { received <- message })
+
+	req := httptest.NewRequest(http.MethodPost, "/mattermost", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	channel.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+	select {
+	case message := <-received:
+		return rec, &message
+	case <-time.After(time.Second):
+		t.Fatal("обработчик не получил сообщение")
+		return rec, nil
+	}
+}
+
+func TestMattermostWebhookJSON(t *testing.T) {
+	channel := NewMattermostChannel("http://mattermost.invalid", "bot-token", "hook-token")
+	body := `{"token":"hook-token","channel_id":"ch1","channel_name":"town-square","user_id":"u1",` +
+		`"user_name":"anna","post_id":"p1","text":"бот какая погода?","trigger_word":"бот"}`
+	rec, message := serveMattermostWebhook(t, channel, "application/json", body)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "{}" {
+		t.Fatalf("ответ %d %q, ожидался 200 {}", rec.Code, rec.Body.String())
+	}
+	want := InboundMessage{
+		Channel:   mattermostChannelName,
+		ChatID:    "ch1",
+		UserID:    "u1",
+		UserName:  "anna",
+		MessageID: "p1",
+		Text:      "какая погода?",
+		Group:     true,
+		Addressed: true,
+	}
+	if message.Channel != want.Channel || message.ChatID != want.ChatID || message.UserID != want.UserID ||
+		message.UserName != want.UserName || message.MessageID != want.MessageID || message.Text != want.Text ||
+		message.Group != want.Group || message.Addressed != want.Addressed {
+		t.Errorf("сообщение %+v, ожидалось %+v", *message, want)
+	}
+}
+
+func TestMattermostWebhookForm(t *testing.T) {
+	channel := NewMattermostChannel("http://mattermost.invalid", "bot-token", "hook-token")
+	form := url.Values{
+		"token":        {"hook-token"},
+		"channel_id":   {"dm1"},
+		"channel_name": {"u1__bot"},
+		"user_id":      {"u1"},
+		"post_id":      {"p2"},
+		"text":         {"!ask привет"},
+		"trigger_word": {"!ask"},
+	}
+	_, message := serveMattermostWebhook(t, channel, "application/x-www-form-urlencoded", form.Encode())
+	if message == nil {
+		t.Fatal("сообщение из формы не обработано")
+	}
+	if message.Text != "привет" || message.ChatID != "dm1" || message.Group {
+		t.Errorf("сообщение %+v, ожидался личный чат dm1 с текстом «привет»", *message)
+	}
+}
+
+func TestMattermostWebhookRejects(t *testing.T) {
+	channel := NewMattermostChannel("http://mattermost.invalid", "bot-token", "hook-token")
+
+	req := httptest.NewRequest(http.MethodPost, "/mattermost", strings.NewReader(`{"token":"hook-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	channel.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("незапущенный канал: код %d, ожидался 503", rec.Code)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{"метод GET", http.MethodGet, "application/json", "", http.StatusMethodNotAllowed},
+		{"неверный токен", http.MethodPost, "application/json", `{"token":"wrong","text":"hi"}`, http.StatusUnauthorized},
+		{"без токена", http.MethodPost, "application/x-www-form-urlencoded", "text=hi", http.StatusUnauthorized},
+		{"неверный JSON", http.MethodPost, "application/json", `{"token":`, http.StatusBadRequest},
+	}
+	channel.Start(func(message InboundMessage) {
+		t.Errorf("отклоненный вебхук дошел до обработчика: %+v", message)
+	})
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/mattermost", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		channel.ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: код %d, ожидался %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+}
+
+func TestMattermostSend(t *testing.T) {
+	stub, server := newMattermostStub(t)
+	channel := NewMattermostChannel(server.URL+"/", "bot-token", "hook-token")
+
+	err := channel.Send("ch1", OutboundMessage{
+		Text:        "Готово",
+		ReplyTo:     "p1",
+		Buttons:     []string{"Да", "Нет"},
+		Attachments: []Attachment{{Name: "report.txt", Data: []byte("отчет")}},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := channel.SendTyping("ch1"); err != nil {
+		t.Fatalf("SendTyping: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.files) != 1 || stub.files[0] != "ch1/report.txt:отчет" {
+		t.Errorf("загруженные файлы %q", stub.files)
+	}
+	if len(stub.posts) != 1 {
+		t.Fatalf("опубликовано %d постов, ожидался 1", len(stub.posts))
+	}
+	post := stub.posts[0]
+	if post.ChannelID != "ch1" || post.RootID != "p1" || post.Message != "Готово\n\n• Да\n• Нет" {
+		t.Errorf("пост %+v", post)
+	}
+	if len(post.FileIDs) != 1 || post.FileIDs[0] != "file1" {
+		t.Errorf("файлы поста %q, ожидался file1", post.FileIDs)
+	}
+	if len(stub.typing) != 1 || stub.typing[0] != "ch1" {
+		t.Errorf("индикатор набора в каналах %q", stub.typing)
+	}
+	for _, auth := range stub.auth {
+		if auth != "Bearer bot-token" {
+			t.Errorf("заголовок Authorization %q", auth)
+		}
+	}
+}
+
+func TestMattermostSendError(t *testing.T) {
+	stub, server := newMattermostStub(t)
+	stub.failPost = true
+	channel := NewMattermostChannel(server.URL, "bot-token", "hook-token")
+
+	err := channel.Send("ch1", OutboundMessage{Text: "Готово"})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Send при ошибке API вернул %v, ожидалась ошибка с кодом 403", err)
+	}
+}
+
+func TestChannelUserIDNamespace(t *testing.T) {
+	if id := ChannelUserID(telegramChannelName, "123456789"); id != 123456789 || isChannelUserID(id) {
+		t.Errorf("ID Telegram = %d, ожидался 123456789 вне пространства каналов", id)
+	}
+
+	mattermost := ChannelUserID(mattermostChannelName, "u1")
+	if !isChannelUserID(mattermost) || mattermost <= 0 {
+		t.Errorf("ID Mattermost %d вне пространства каналов", mattermost)
+	}
+	if mattermost != ChannelUserID(mattermostChannelName, "u1") {
+		t.Error("ID одного пользователя различаются между вызовами")
+	}
+	if mattermost == ChannelUserID(webhookChannelName, "u1") {
+		t.Error("одинаковые ID разных каналов совпали")
+	}
+	if mattermost == ChannelUserID(mattermostChannelName, "u2") {
+		t.Error("ID разных пользователей совпали")
+	}
+	if chat := channelChatID(mattermostChannelName, "u1"); chat == mattermost || !isChannelUserID(chat) {
+		t.Errorf("ID чата %d совпал с ID пользователя или вне пространства каналов", chat)
+	}
+	if id := ChannelUserID(telegramChannelName, "not-a-number"); !isChannelUserID(id) {
+		t.Errorf("нечисловой ID Telegram %d не переведен в пространство каналов", id)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramChannelName — имя канала Telegram; его идентификаторы не переводятся в отдельное пространство
+const telegramChannelName = "telegram"
+
+// TelegramChannel — канал Telegram поверх Bot API: опрос обновлений, ответы с разметкой,
+// клавиатурой быстрых ответов и файлами, статус «печатает»
+type TelegramChannel struct {
+	bot *tgbotapi.BotAPI
+}
+
+// NewTelegramChannel создает канал для бота
+func NewTelegramChannel(bot *tgbotapi.BotAPI) *TelegramChannel {
+	return &TelegramChannel{bot: bot}
+}
+
+// Name возвращает имя канала
+func (c *TelegramChannel) Name() string {
+	return telegramChannelName
+}
+
+// Start получает обновления длинным опросом и обрабатывает каждое в отдельной горутине.
+// Исходное обновление передается в Raw: команды, кнопки и инлайн-запросы разбирает TelegramBot.
+func (c *TelegramChannel) Start(handle func(InboundMessage)) error {
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+
+	for update := range c.bot.GetUpdatesChan(u) {
+		go handle(telegramInbound(update))
+	}
+	return nil
+}
+
+// telegramInbound переводит обновление Telegram во входящее сообщение канала
+func telegramInbound(update tgbotapi.Update) InboundMessage {
+	in := InboundMessage{Channel: telegramChannelName, Raw: update}
+	message := update.Message
+	if message == nil || message.From == nil {
+		return in
+	}
+
+	in.ChatID = strconv.FormatInt(message.Chat.ID, 10)
+	in.UserID = strconv.FormatInt(message.From.ID, 10)
+	in.UserName = displayName(message.From)
+	in.MessageID = strconv.Itoa(message.MessageID)
+	in.Text = message.Text
+	in.Locale = message.From.LanguageCode
+	in.Group = isGroupChat(message.Chat)
+	in.Addressed = !in.Group
+	return in
+}
+
+// Send отправляет ответ. Текст с разметкой, которую Telegram не принял, отправляется повторно без нее.
+func (c *TelegramChannel) Send(chatID string, message OutboundMessage) error {
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный чат Telegram %q", chatID)
+	}
+	replyTo, _ := strconv.Atoi(message.ReplyTo)
+
+	if message.Text != "" || len(message.Buttons) > 0 {
+		msg := tgbotapi.NewMessage(id, message.Text)
+		msg.ReplyToMessageID = replyTo
+		if message.Markdown {
+			msg.ParseMode = "Markdown"
+		}
+		if len(message.Buttons) > 0 {
+			var row []tgbotapi.KeyboardButton
+			for _, button := range message.Buttons {
+				row = append(row, tgbotapi.NewKeyboardButton(button))
+			}
+			keyboard := tgbotapi.NewReplyKeyboard(row)
+			keyboard.ResizeKeyboard = true
+			msg.ReplyMarkup = keyboard
+		}
+
+		if _, err := c.bot.Send(msg); err != nil {
+			if msg.ParseMode == "" {
+				return err
+			}
+			// Ответы модели и имена файлов могут ломать разметку — пробуем отправить без нее
+			msg.ParseMode = ""
+			if _, retryErr := c.bot.Send(msg); retryErr != nil {
+				return err
+			}
+		}
+	}
+
+	for _, attachment := range message.Attachments {
+		var file tgbotapi.RequestFileData = tgbotapi.FileBytes{Name: attachment.Name, Bytes: attachment.Data}
+		if attachment.Data == nil {
+			file = tgbotapi.FileURL(attachment.URL)
+		}
+		document := tgbotapi.NewDocument(id, file)
+		document.ReplyToMessageID = replyTo
+		if _, err := c.bot.Send(document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendTyping показывает индикатор «печатает»
+func (c *TelegramChannel) SendTyping(chatID string) error {
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный чат Telegram %q", chatID)
+	}
+	_, err = c.bot.Request(tgbotapi.NewChatAction(id, tgbotapi.ChatTyping))
+	return err
+}
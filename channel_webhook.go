@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// webhookChannelName — имя универсального канала вебхуков
+const webhookChannelName = "webhook"
+
+// webhookSignatureHeader — заголовок с подписью тела запроса: sha256=<hex HMAC-SHA256>
+const webhookSignatureHeader = "X-Chatagent-Signature"
+
+// maxWebhookBodySize ограничивает входящий запрос: сообщение с файлами в base64
+const maxWebhookBodySize = maxDocumentFileSize*4/3 + 1<<20
+
+// WebhookInbound — входящее сообщение от платформы: POST /channels/webhook
+type WebhookInbound struct {
+	ChatID      string       `json:"chat_id"`
+	UserID      string       `json:"user_id"`
+	UserName    string       `json:"user_name,omitempty"`
+	MessageID   string       `json:"message_id,omitempty"`
+	Text        string       `json:"text"`
+	Locale      string       `json:"locale,omitempty"`
+	Group       bool         `json:"group,omitempty"`
+	Addressed   *bool        `json:"addressed,omitempty"` // По умолчанию true
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// WebhookOutbound — событие, которое бот отправляет платформе: ответ (message) или статус (typing)
+type WebhookOutbound struct {
+	Type        string       `json:"type"`
+	ChatID      string       `json:"chat_id"`
+	Text        string       `json:"text,omitempty"`
+	Markdown    bool         `json:"markdown,omitempty"`
+	ReplyTo     string       `json:"reply_to,omitempty"`
+	Buttons     []string     `json:"buttons,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// WebhookChannel — универсальный канал для любой платформы чатов: сообщения приходят
+// POST-запросами в формате WebhookInbound, ответы и статусы уходят POST-запросами на url.
+// Оба направления подписываются HMAC-SHA256 общим секретом.
+type WebhookChannel struct {
+	url    string
+	secret []byte
+	client *http.Client
+	files  *http.Client // Скачивание вложений по ссылкам
+	handle func(InboundMessage)
+}
+
+// NewWebhookChannel создает канал, который отправляет события на url
+func NewWebhookChannel(url, secret string) *WebhookChannel {
+	return &WebhookChannel{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: 30 * time.Second},
+		files:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// webhookChannelFromEnv создает канал по WEBHOOK_CHANNEL_URL и WEBHOOK_CHANNEL_SECRET или возвращает nil
+func webhookChannelFromEnv() Channel {
+	url := os.Getenv("WEBHOOK_CHANNEL_URL")
+	if url == "" {
+		return nil
+	}
+	secret := os.Getenv("WEBHOOK_CHANNEL_SECRET")
+	if secret == "" {
+		log.Println("⚠️  Канал webhook не подключен: не задан WEBHOOK_CHANNEL_SECRET")
+		return nil
+	}
+	return NewWebhookChannel(url, secret)
+}
+
+// Name возвращает имя канала
+func (c *WebhookChannel) Name() string {
+	return webhookChannelName
+}
+
+// Start запоминает обработчик: сообщения приходят через ServeHTTP
+func (c *WebhookChannel) Start(handle func(InboundMessage)) error {
+	c.handle = handle
+	return nil
+}
+
+// ServeHTTP принимает подписанное сообщение, отвечает 202 и обрабатывает его в фоне
+func (c *WebhookChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "Ошибка чтения запроса", http.StatusBadRequest)
+		return
+	}
+	if !hmac.Equal([]byte(r.Header.Get(webhookSignatureHeader)), []byte(c.sign(body))) {
+		http.Error(w, "Неверная подпись", http.StatusUnauthorized)
+		return
+	}
+
+	var payload WebhookInbound
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Неверный JSON", http.StatusBadRequest)
+		return
+	}
+	if payload.ChatID == "" || payload.UserID == "" {
+		http.Error(w, "Нужны chat_id и user_id", http.StatusBadRequest)
+		return
+	}
+	if c.handle == nil {
+		http.Error(w, "Канал не запущен", http.StatusServiceUnavailable)
+		return
+	}
+
+	in := InboundMessage{
+		Channel:     webhookChannelName,
+		ChatID:      payload.ChatID,
+		UserID:      payload.UserID,
+		UserName:    payload.UserName,
+		MessageID:   payload.MessageID,
+		Text:        payload.Text,
+		Locale:      payload.Locale,
+		Group:       payload.Group,
+		Addressed:   payload.Addressed == nil || *payload.Addressed,
+		Attachments: payload.Attachments,
+	}
+	go func() {
+		c.fetchAttachments(in.Attachments)
+		c.handle(in)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// fetchAttachments скачивает вложения, переданные ссылками
+func (c *WebhookChannel) fetchAttachments(attachments []Attachment) {
+	for i := range attachments {
+		attachment := &attachments[i]
+		if attachment.Data != nil || attachment.URL == "" {
+			continue
+		}
+		data, err := fetchFile(c.files, attachment.URL, maxDocumentFileSize)
+		if err != nil {
+			log.Printf("Ошибка скачивания вложения %s: %v", attachment.Name, err)
+			continue
+		}
+		attachment.Data = data
+	}
+}
+
+// Send отправляет ответ платформе
+func (c *WebhookChannel) Send(chatID string, message OutboundMessage) error {
+	return c.post(WebhookOutbound{
+		Type:        "message",
+		ChatID:      chatID,
+		Text:        message.Text,
+		Markdown:    message.Markdown,
+		ReplyTo:     message.ReplyTo,
+		Buttons:     message.Buttons,
+		Attachments: message.Attachments,
+	})
+}
+
+// SendTyping сообщает платформе, что бот печатает
+func (c *WebhookChannel) SendTyping(chatID string) error {
+	return c.post(WebhookOutbound{Type: "typing", ChatID: chatID})
+}
+
+func (c *WebhookChannel) post(event WebhookOutbound) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга JSON: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader, c.sign(body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка HTTP запроса: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("платформа вернула ошибку %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign возвращает подпись тела запроса
+func (c *WebhookChannel) sign(body []byte) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// fetchFile скачивает файл по ссылке, ограничивая его размер
+func fetchFile(client *http.Client, url string, maxSize int64) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка HTTP запроса: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("сервер вернул ошибку %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("файл больше %d байт", maxSize)
+	}
+	return data, nil
+}
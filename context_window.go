@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения контекста модели по умолчанию
+const (
+	defaultContextWindowTokens = 8000 // Бюджет всего запроса: системный промпт, история, вопрос и ответ
+	contextSummaryTokens       = 500  // Сколько токенов отводится под краткое содержание разговора
+	messageTokenOverhead       = 4    // Служебные токены роли и разделителей у каждого сообщения
+	maxHistoryEntries          = 200  // Жесткий предел истории в памяти, если модель не подключена
+)
+
+// defaultTokenizerURL — адрес токенизатора Yandex Foundation Models
+const defaultTokenizerURL = "https://llm.api.cloud.yandex.net/foundationModels/v1/tokenize"
+
+// tokenCacheSize — сколько подсчитанных текстов помнит токенизатор
+const tokenCacheSize = 4096
+
+// TokenCounter считает токены текста для модели
+type TokenCounter interface {
+	// CountTokens возвращает число токенов текста в словаре модели model
+	CountTokens(text, model string) (int, error)
+}
+
+// EstimateTokenCounter оценивает число токенов локально, без обращения к API.
+// Оценка с запасом: токен YandexGPT в среднем длиннее трех символов.
+type EstimateTokenCounter struct{}
+
+// CountTokens возвращает оценку числа токенов
+func (EstimateTokenCounter) CountTokens(text, model string) (int, error) {
+	return (utf8.RuneCountInString(text) + 2) / 3, nil
+}
+
+// YandexTokenizer считает токены через API токенизатора Yandex Foundation Models.
+// Результаты кэшируются, а при ошибке API используется локальная оценка.
+type YandexTokenizer struct {
+	apiKey     string
+	folderID   string
+	baseURL    string
+	httpClient *http.Client
+	fallback   TokenCounter
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+// YandexTokenizeRequest представляет запрос к токенизатору
+type YandexTokenizeRequest struct {
+	ModelURI string `json:"modelUri"`
+	Text     string `json:"text"`
+}
+
+// YandexTokenizeResponse представляет ответ токенизатора
+type YandexTokenizeResponse struct {
+	Tokens []struct {
+		ID      string `json:"id"`
+		Text    string `json:"text"`
+		Special bool   `json:"special"`
+	} `json:"tokens"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+// NewYandexTokenizer создает клиент токенизатора; пустой baseURL означает адрес по умолчанию
+func NewYandexTokenizer(apiKey, folderID, baseURL string) *YandexTokenizer {
+	if baseURL == "" {
+		baseURL = defaultTokenizerURL
+	}
+	return &YandexTokenizer{
+		apiKey:   apiKey,
+		folderID: folderID,
+		baseURL:  baseURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		fallback: EstimateTokenCounter{},
+		cache:    make(map[string]int),
+	}
+}
+
+// SetTransport заменяет транспорт HTTP-клиента, например на кассету с записанными ответами
+func (t *YandexTokenizer) SetTransport(transport http.RoundTripper) {
+	t.httpClient.Transport = transport
+}
+
+// CountTokens возвращает число токенов из кэша или запрашивает его у API
+func (t *YandexTokenizer) CountTokens(text, model string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	key := model + "\x00" + text
+
+	t.mu.Lock()
+	count, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok {
+		return count, nil
+	}
+
+	count, err := t.tokenize(text, model)
+	if err != nil {
+		log.Printf("Ошибка токенизатора, используем оценку: %v", err)
+		return t.fallback.CountTokens(text, model)
+	}
+
+	t.mu.Lock()
+	// Кэш не вытесняет записи по одной: при переполнении он просто начинается заново
+	if len(t.cache) >= tokenCacheSize {
+		t.cache = make(map[string]int)
+	}
+	t.cache[key] = count
+	t.mu.Unlock()
+	return count, nil
+}
+
+func (t *YandexTokenizer) tokenize(text, model string) (int, error) {
+	jsonData, err := json.Marshal(YandexTokenizeRequest{
+		ModelURI: ModelURI(t.folderID, model),
+		Text:     text,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка маршалинга JSON: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", t.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Api-Key "+t.apiKey)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка HTTP запроса: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения ответа: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("API вернул ошибку %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response YandexTokenizeResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("ошибка парсинга JSON ответа: %v", err)
+	}
+	return len(response.Tokens), nil
+}
+
+// createTokenCounter выбирает счетчик токенов по CONTEXT_TOKENIZER:
+// yandex — API токенизатора (нужны ключ и каталог Yandex GPT), иначе локальная оценка
+func createTokenCounter() TokenCounter {
+	apiKey := os.Getenv("YANDEX_GPT_API_KEY")
+	folderID := os.Getenv("YANDEX_GPT_FOLDER_ID")
+	if os.Getenv("CONTEXT_TOKENIZER") == "yandex" {
+		if apiKey != "" && folderID != "" {
+			tokenizer := NewYandexTokenizer(apiKey, folderID, os.Getenv("YANDEX_TOKENIZER_URL"))
+			if err := useCassette("yandex", tokenizer.SetTransport); err != nil {
+				log.Printf("Токенизатор Yandex недоступен, используем оценку: %v", err)
+				return EstimateTokenCounter{}
+			}
+			return tokenizer
+		}
+		log.Printf("Токенизатор Yandex включен, но API ключ или Folder ID не установлены. Используем оценку.")
+	}
+	return EstimateTokenCounter{}
+}
+
+// contextWindowFromEnv читает бюджет токенов из CONTEXT_WINDOW_TOKENS
+func contextWindowFromEnv() int {
+	value := os.Getenv("CONTEXT_WINDOW_TOKENS")
+	if value == "" {
+		return defaultContextWindowTokens
+	}
+	tokens, err := strconv.Atoi(value)
+	if err != nil || tokens <= 0 {
+		log.Printf("Неверный бюджет контекста %q, используем %d", value, defaultContextWindowTokens)
+		return defaultContextWindowTokens
+	}
+	return tokens
+}
+
+// SetContextWindow задает счетчик токенов и бюджет всего запроса к модели
+func (a *Agent) SetContextWindow(counter TokenCounter, tokens int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tokens = counter
+	a.contextTokens = tokens
+}
+
+// countTokens считает токены сообщения вместе со служебными; ошибка счетчика заменяется оценкой
+func (a *Agent) countTokens(counter TokenCounter, text, model string) int {
+	count, err := counter.CountTokens(text, model)
+	if err != nil {
+		count, _ = EstimateTokenCounter{}.CountTokens(text, model)
+	}
+	return count + messageTokenOverhead
+}
+
+// fitContext отбирает из истории последние реплики, которые помещаются в бюджет токенов
+// вместе с системным промптом, новым сообщением и ответом модели. Реплики, которые не
+// поместились, сжимаются моделью в краткое содержание разговора, а из истории удаляются.
+// Возвращает отобранные реплики и краткое содержание для системного промпта.
+func (a *Agent) fitContext(provider LLMProvider, mc MessageContext, history []ConversationEntry, message string, system []string, options GenerationOptions) ([]ConversationEntry, string) {
+	a.mu.Lock()
+	counter, window := a.tokens, a.contextTokens
+	summary := ""
+	if conversation := a.conversationHistory[mc.ConversationID]; conversation != nil {
+		summary = conversation.Summary
+	}
+	a.mu.Unlock()
+
+	// Отвеченные реплики; запись без ответа — это текущее сообщение или вопрос, на который еще отвечают
+	answered := make([]ConversationEntry, 0, len(history))
+	for _, entry := range history {
+		if entry.Response != "" {
+			answered = append(answered, entry)
+		}
+	}
+
+	budget := window - options.MaxTokens - contextSummaryTokens -
+		a.countTokens(counter, strings.Join(system, "\n\n"), options.Model) -
+		a.countTokens(counter, withAuthor(mc.Author, message), options.Model)
+
+	start := a.fitHistory(counter, answered, budget, options.Model)
+	if start == 0 {
+		return answered, summary
+	}
+	// Сжимаем с запасом, чтобы следующие реплики не требовали нового сжатия каждый раз
+	start = a.fitHistory(counter, answered, budget/2, options.Model)
+
+	overflow := answered[:start]
+	updated, err := a.summarize(provider, summary, overflow, options)
+	if err != nil {
+		// Без нового краткого содержания ранние реплики просто не попадают в запрос
+		log.Printf("Ошибка сжатия истории разговора %s: %v", mc.ConversationID, err)
+		return answered[start:], summary
+	}
+
+	a.storeSummary(mc.ConversationID, summary, updated, overflow)
+	log.Printf("История разговора %s сжата: %d реплик в кратком содержании", mc.ConversationID, len(overflow))
+	return answered[start:], updated
+}
+
+// fitHistory возвращает индекс первой реплики, с которой последние реплики помещаются в budget
+func (a *Agent) fitHistory(counter TokenCounter, entries []ConversationEntry, budget int, model string) int {
+	start := len(entries)
+	for start > 0 {
+		entry := entries[start-1]
+		cost := a.countTokens(counter, withAuthor(entry.Author, entry.Message), model) +
+			a.countTokens(counter, entry.Response, model)
+		if cost > budget {
+			break
+		}
+		budget -= cost
+		start--
+	}
+	return start
+}
+
+// summaryPrompt — системный промпт для сжатия ранней части разговора
+const summaryPrompt = "Ты ведешь краткое содержание разговора пользователя с ассистентом. " +
+	"Объедини прежнее краткое содержание с новыми репликами в один связный текст. " +
+	"Сохрани факты, имена, числа, даты, договоренности и предпочтения пользователя, " +
+	"опусти приветствия и повторы. Пиши сжато, без вступлений и пояснений."
+
+// summarize просит модель дополнить краткое содержание репликами, которые вытесняются из контекста
+func (a *Agent) summarize(provider LLMProvider, summary string, entries []ConversationEntry, options GenerationOptions) (string, error) {
+	var dialog strings.Builder
+	if summary != "" {
+		dialog.WriteString("Прежнее краткое содержание:\n" + summary + "\n\n")
+	}
+	dialog.WriteString("Новые реплики:\n")
+	for _, entry := range entries {
+		dialog.WriteString("Пользователь: " + withAuthor(entry.Author, entry.Message) + "\n")
+		dialog.WriteString("Ассистент: " + entry.Response + "\n")
+	}
+
+	options.Temperature = 0.2
+	options.MaxTokens = contextSummaryTokens
+	options.Tools = nil
+
+	// Реплики уходят во внешний API, поэтому персональные данные заменяются и здесь
+	redaction := a.Redactor().NewRedaction()
+	completion, err := provider.Generate(redaction.RedactMessages([]YandexGPTMessage{
+		{Role: "system", Text: summaryPrompt},
+		{Role: "user", Text: dialog.String()},
+	}), options)
+	if err != nil {
+		a.metrics.Errors.Add(1)
+		return "", err
+	}
+	a.metrics.RecordCompletion(completion)
+	if completion.Filtered {
+		return "", fmt.Errorf("модель отказалась сжимать историю: сработал фильтр содержимого")
+	}
+
+	text := strings.TrimSpace(redaction.Reveal(completion.Text))
+	if text == "" {
+		return "", fmt.Errorf("модель вернула пустое краткое содержание")
+	}
+	return text, nil
+}
+
+// storeSummary сохраняет краткое содержание вместе с разговором и удаляет из истории
+// реплики, которые в него вошли. Если историю уже сжал параллельный запрос, ничего не меняется.
+func (a *Agent) storeSummary(conversationID, previous, summary string, entries []ConversationEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conversation := a.conversationHistory[conversationID]
+	if conversation == nil || conversation.Summary != previous {
+		return
+	}
+
+	// Сжатые реплики — начало отвеченной истории в том же порядке. Снимаем их по порядку,
+	// сравнивая записи целиком: время у разных реплик может совпасть
+	next := 0
+	kept := conversation.Entries[:0]
+	for _, entry := range conversation.Entries {
+		if next < len(entries) && entry == entries[next] {
+			next++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	conversation.Entries = kept
+	conversation.Summary = summary
+	conversation.SummaryUpdated = time.Now()
+}
+
+// summaryContext формирует часть системного промпта с кратким содержанием разговора
+func summaryContext(summary string) string {
+	return "Краткое содержание предыдущей части разговора:\n" + summary
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Реплика из 30 символов стоит 10 токенов по оценке и 4 служебных: вопрос с ответом — 28
+var (
+	testQuestion = strings.Repeat("в", 30)
+	testAnswer   = strings.Repeat("о", 30)
+)
+
+const testEntryTokens = 28
+
+func newContextTestAgent(t *testing.T, entries []ConversationEntry, fitEntries int) (*Agent, MessageContext, GenerationOptions) {
+	t.Helper()
+	t.Setenv("DATA_DIR", t.TempDir())
+	agent := createAgent()
+	options := DefaultGenerationOptions()
+	options.MaxTokens = 100
+	// Бюджет: ответ, краткое содержание, пустой системный промпт (4) и вопрос «вопрос» (6)
+	agent.SetContextWindow(EstimateTokenCounter{}, options.MaxTokens+contextSummaryTokens+4+6+fitEntries*testEntryTokens)
+
+	mc := MessageContext{UserID: 7, ConversationID: userConversationID(7)}
+	agent.conversationHistory[mc.ConversationID] = &Conversation{Entries: append([]ConversationEntry(nil), entries...)}
+	return agent, mc, options
+}
+
+func answeredEntries(n int, at time.Time) []ConversationEntry {
+	entries := make([]ConversationEntry, n)
+	for i := range entries {
+		// Вопросы различаются первой буквой, длина у всех одинаковая
+		message := string(rune('А'+i)) + testQuestion[len("в"):]
+		entries[i] = ConversationEntry{UserID: 7, Message: message, Response: testAnswer, Timestamp: at.Add(time.Duration(i) * time.Second)}
+	}
+	return entries
+}
+
+func TestFitContextWithinBudget(t *testing.T) {
+	entries := answeredEntries(3, time.Now())
+	agent, mc, options := newContextTestAgent(t, entries, 3)
+	provider := funcProvider{name: "stub", respond: func([]YandexGPTMessage, GenerationOptions) (Completion, error) {
+		t.Fatal("история помещается в бюджет, сжатие не нужно")
+		return Completion{}, nil
+	}}
+
+	history, summary := agent.fitContext(provider, mc, agent.history(mc.ConversationID), "вопрос", nil, options)
+	if len(history) != 3 || summary != "" {
+		t.Errorf("fitContext вернул %d реплик и краткое содержание %q", len(history), summary)
+	}
+}
+
+func TestFitContextSummarizesOverflow(t *testing.T) {
+	entries := answeredEntries(5, time.Now())
+	// Текущее сообщение без ответа в запрос истории не входит и не сжимается
+	current := ConversationEntry{UserID: 7, Message: "вопрос", Timestamp: time.Now()}
+	agent, mc, options := newContextTestAgent(t, append(entries, current), 3)
+
+	var summarized string
+	provider := funcProvider{name: "stub", respond: func(messages []YandexGPTMessage, options GenerationOptions) (Completion, error) {
+		summarized = messages[len(messages)-1].Text
+		if options.MaxTokens != contextSummaryTokens || len(options.Tools) != 0 {
+			t.Errorf("параметры сжатия: %+v", options)
+		}
+		return Completion{Text: "  Пользователь спрашивал про А, Б, В и Г.  "}, nil
+	}}
+
+	history, summary := agent.fitContext(provider, mc, agent.history(mc.ConversationID), "вопрос", nil, options)
+	// Бюджет на три реплики, а сжимается с запасом до половины: остается одна
+	if len(history) != 1 || history[0] != entries[4] {
+		t.Fatalf("в запрос попали %+v, want последнюю реплику", history)
+	}
+	if summary != "Пользователь спрашивал про А, Б, В и Г." {
+		t.Errorf("краткое содержание %q", summary)
+	}
+	for _, entry := range entries[:4] {
+		if !strings.Contains(summarized, entry.Message) {
+			t.Errorf("в сжатие не попала реплика %q", entry.Message)
+		}
+	}
+	if strings.Contains(summarized, entries[4].Message) {
+		t.Error("в сжатие попала реплика, которая помещается в контекст")
+	}
+
+	conversation := agent.conversationHistory[mc.ConversationID]
+	if conversation.Summary != summary || len(conversation.Entries) != 2 ||
+		conversation.Entries[0] != entries[4] || conversation.Entries[1] != current {
+		t.Errorf("после сжатия в истории %+v, краткое содержание %q", conversation.Entries, conversation.Summary)
+	}
+
+	// Следующий запрос получает сохраненное краткое содержание без нового сжатия
+	provider.respond = func([]YandexGPTMessage, GenerationOptions) (Completion, error) {
+		t.Fatal("повторное сжатие")
+		return Completion{}, nil
+	}
+	if _, again := agent.fitContext(provider, mc, agent.history(mc.ConversationID), "вопрос", nil, options); again != summary {
+		t.Errorf("краткое содержание при следующем запросе %q", again)
+	}
+}
+
+func TestFitContextSummaryError(t *testing.T) {
+	entries := answeredEntries(5, time.Now())
+	agent, mc, options := newContextTestAgent(t, entries, 3)
+	provider := funcProvider{name: "stub", respond: func([]YandexGPTMessage, GenerationOptions) (Completion, error) {
+		return Completion{}, errors.New("API недоступно")
+	}}
+
+	history, summary := agent.fitContext(provider, mc, agent.history(mc.ConversationID), "вопрос", nil, options)
+	if len(history) != 1 || summary != "" {
+		t.Errorf("при ошибке сжатия: %d реплик, %q", len(history), summary)
+	}
+	if got := len(agent.conversationHistory[mc.ConversationID].Entries); got != 5 {
+		t.Errorf("при ошибке сжатия история изменилась: %d реплик", got)
+	}
+}
+
+func TestStoreSummarySameTimestamp(t *testing.T) {
+	// Реплики пришли в одну и ту же секунду: сжимается только первая
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	entries := answeredEntries(3, at)
+	for i := range entries {
+		entries[i].Timestamp = at
+	}
+	agent, mc, _ := newContextTestAgent(t, entries, 3)
+
+	agent.storeSummary(mc.ConversationID, "", "краткое содержание", entries[:1])
+	conversation := agent.conversationHistory[mc.ConversationID]
+	if len(conversation.Entries) != 2 || conversation.Entries[0] != entries[1] || conversation.Entries[1] != entries[2] {
+		t.Errorf("после сжатия одной реплики осталось %+v", conversation.Entries)
+	}
+
+	// Историю уже сжал параллельный запрос: краткое содержание не совпадает с прежним
+	agent.storeSummary(mc.ConversationID, "", "другое", entries[1:2])
+	if len(conversation.Entries) != 2 || conversation.Summary != "краткое содержание" {
+		t.Errorf("устаревшее сжатие изменило историю: %+v, %q", conversation.Entries, conversation.Summary)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Категории единиц: переводить можно только внутри одной категории
+const (
+	UnitLength      = "length"
+	UnitMass        = "mass"
+	UnitTemperature = "temperature"
+	UnitVolume      = "volume"
+	UnitData        = "data"
+	UnitSpeed       = "speed"
+	UnitCurrency    = "currency"
+)
+
+// unit — единица измерения или валюта.
+// Значение в базовой единице категории равно value*Factor + Offset.
+// Форма со звездочкой — основа слова, к которой допускается до трех букв окончания.
+type unit struct {
+	Code     string
+	Category string
+	Factor   float64
+	Offset   float64
+	RU, EN   string // Обозначения в ответе
+	Forms    []string
+}
+
+// symbol возвращает обозначение единицы на языке пользователя
+func (u unit) symbol(locale string) string {
+	if normalizeLocale(locale) == LocaleEN {
+		return u.EN
+	}
+	return u.RU
+}
+
+// measurementUnits — таблица единиц; базовые единицы: метр, килограмм, градус Цельсия,
+// литр, байт и метр в секунду. Объемы данных считаются в двоичных кратных (1 КБ = 1024 байта).
+var measurementUnits = []unit{
+	{Code: "mm", Category: UnitLength, Factor: 0.001, RU: "мм", EN: "mm", Forms: []string{"мм", "миллиметр*", "mm", "millimeter*", "millimetre*"}},
+	{Code: "cm", Category: UnitLength, Factor: 0.01, RU: "см", EN: "cm", Forms: []string{"см", "сантиметр*", "cm", "centimeter*", "centimetre*"}},
+	{Code: "m", Category: UnitLength, Factor: 1, RU: "м", EN: "m", Forms: []string{"м", "метр*", "m", "meter*", "metre*"}},
+	{Code: "km", Category: UnitLength, Factor: 1000, RU: "км", EN: "km", Forms: []string{"км", "километр*", "km", "kilometer*", "kilometre*"}},
+	{Code: "in", Category: UnitLength, Factor: 0.0254, RU: "дюйм.", EN: "in", Forms: []string{"дюйм*", "inch*", "\""}},
+	{Code: "ft", Category: UnitLength, Factor: 0.3048, RU: "фут.", EN: "ft", Forms: []string{"фут*", "ft", "foot", "feet"}},
+	{Code: "yd", Category: UnitLength, Factor: 0.9144, RU: "ярд.", EN: "yd", Forms: []string{"ярд*", "yd", "yard*"}},
+	{Code: "mi", Category: UnitLength, Factor: 1609.344, RU: "миль", EN: "mi", Forms: []string{"мил*", "mi", "mile*"}},
+	{Code: "nmi", Category: UnitLength, Factor: 1852, RU: "мор. миль", EN: "nmi", Forms: []string{"морск* мил*", "nmi", "nautical mile*"}},
+
+	{Code: "mg", Category: UnitMass, Factor: 1e-6, RU: "мг", EN: "mg", Forms: []string{"мг", "миллиграмм*", "mg", "milligram*"}},
+	{Code: "g", Category: UnitMass, Factor: 0.001, RU: "г", EN: "g", Forms: []string{"г", "гр", "грамм*", "g", "gram*", "gramme*"}},
+	{Code: "kg", Category: UnitMass, Factor: 1, RU: "кг", EN: "kg", Forms: []string{"кг", "килограмм*", "кило", "kg", "kilogram*", "kilo*"}},
+	{Code: "t", Category: UnitMass, Factor: 1000, RU: "т", EN: "t", Forms: []string{"т", "тонн*", "t", "ton*", "tonne*"}},
+	{Code: "lb", Category: UnitMass, Factor: 0.45359237, RU: "фунт.", EN: "lb", Forms: []string{"фунт*", "lb", "lbs", "pound*"}},
+	{Code: "oz", Category: UnitMass, Factor: 0.028349523125, RU: "унц.", EN: "oz", Forms: []string{"унци*", "oz", "ounce*"}},
+
+	{Code: "C", Category: UnitTemperature, Factor: 1, RU: "°C", EN: "°C", Forms: []string{"°c", "℃", "цельси*", "градус* цельси*", "градус* по цельси*", "по цельси*", "celsius", "degree* celsius"}},
+	{Code: "F", Category: UnitTemperature, Factor: 5.0 / 9, Offset: -32 * 5.0 / 9, RU: "°F", EN: "°F", Forms: []string{"°f", "℉", "f", "фаренгейт*", "градус* фаренгейт*", "градус* по фаренгейт*", "по фаренгейт*", "fahrenheit", "degree* fahrenheit"}},
+	{Code: "K", Category: UnitTemperature, Factor: 1, Offset: -273.15, RU: "K", EN: "K", Forms: []string{"кельвин*", "градус* кельвин*", "kelvin*"}},
+
+	{Code: "ml", Category: UnitVolume, Factor: 0.001, RU: "мл", EN: "ml", Forms: []string{"мл", "миллилитр*", "ml", "milliliter*", "millilitre*"}},
+	{Code: "l", Category: UnitVolume, Factor: 1, RU: "л", EN: "l", Forms: []string{"л", "литр*", "l", "liter*", "litre*"}},
+	{Code: "m3", Category: UnitVolume, Factor: 1000, RU: "м³", EN: "m³", Forms: []string{"м³", "м3", "кубометр*", "кубическ* метр*", "m³", "m3", "cubic meter*", "cubic metre*"}},
+	{Code: "gal", Category: UnitVolume, Factor: 3.785411784, RU: "гал.", EN: "gal", Forms: []string{"галлон*", "gal", "gallon*"}},
+	{Code: "pt", Category: UnitVolume, Factor: 0.473176473, RU: "пинт", EN: "pt", Forms: []string{"пинт*", "pt", "pint*"}},
+	{Code: "floz", Category: UnitVolume, Factor: 0.0295735295625, RU: "жидк. унц.", EN: "fl oz", Forms: []string{"жидк* унци*", "fl oz", "fluid ounce*"}},
+
+	{Code: "bit", Category: UnitData, Factor: 0.125, RU: "бит", EN: "bit", Forms: []string{"бит*", "bit*"}},
+	{Code: "B", Category: UnitData, Factor: 1, RU: "байт", EN: "B", Forms: []string{"байт*", "b", "byte*"}},
+	{Code: "KB", Category: UnitData, Factor: 1 << 10, RU: "КБ", EN: "KB", Forms: []string{"кб", "килобайт*", "kb", "kib", "kilobyte*"}},
+	{Code: "MB", Category: UnitData, Factor: 1 << 20, RU: "МБ", EN: "MB", Forms: []string{"мб", "мегабайт*", "mb", "mib", "megabyte*"}},
+	{Code: "GB", Category: UnitData, Factor: 1 << 30, RU: "ГБ", EN: "GB", Forms: []string{"гб", "гигабайт*", "gb", "gib", "gigabyte*"}},
+	{Code: "TB", Category: UnitData, Factor: 1 << 40, RU: "ТБ", EN: "TB", Forms: []string{"тб", "терабайт*", "tb", "tib", "terabyte*"}},
+	{Code: "Mbit", Category: UnitData, Factor: 1e6 / 8, RU: "Мбит", EN: "Mbit", Forms: []string{"мбит", "мегабит*", "mbit", "megabit*"}},
+	{Code: "Gbit", Category: UnitData, Factor: 1e9 / 8, RU: "Гбит", EN: "Gbit", Forms: []string{"гбит", "гигабит*", "gbit", "gigabit*"}},
+
+	{Code: "m/s", Category: UnitSpeed, Factor: 1, RU: "м/с", EN: "m/s", Forms: []string{"м/с", "метр* в секунду", "m/s", "meter* per second", "metre* per second"}},
+	{Code: "km/h", Category: UnitSpeed, Factor: 1 / 3.6, RU: "км/ч", EN: "km/h", Forms: []string{"км/ч", "км/час", "километр* в час", "км в час", "km/h", "kmh", "kph", "kilometer* per hour", "kilometre* per hour"}},
+	{Code: "mph", Category: UnitSpeed, Factor: 0.44704, RU: "миль/ч", EN: "mph", Forms: []string{"миль/ч", "мил* в час", "mph", "mile* per hour"}},
+	{Code: "kn", Category: UnitSpeed, Factor: 0.514444, RU: "уз.", EN: "kn", Forms: []string{"узел", "узл*", "kn", "knot*"}},
+}
+
+// currencyUnits — валюты с названиями; остальные валюты ЦБ узнаются по коду ISO
+var currencyUnits = []unit{
+	{Code: "RUB", Forms: []string{"рубл*", "руб", "р", "₽", "ruble*", "rouble*"}},
+	{Code: "USD", Forms: []string{"доллар*", "бакс*", "$", "dollar*", "buck*"}},
+	{Code: "EUR", Forms: []string{"евро", "€", "euro*"}},
+	{Code: "CNY", Forms: []string{"юан*", "yuan*", "renminbi"}},
+	{Code: "GBP", Forms: []string{"фунт*", "фунт* стерлинг*", "£", "pound*", "pound* sterling"}},
+	{Code: "JPY", Forms: []string{"иен*", "йен*", "¥", "yen"}},
+	{Code: "KZT", Forms: []string{"тенге", "tenge"}},
+	{Code: "TRY", Forms: []string{"лир*", "lira*"}},
+	{Code: "CHF", Forms: []string{"франк*", "швейцарск* франк*", "franc*", "swiss franc*"}},
+	{Code: "BYN", Forms: []string{"белорусск* рубл*", "belarusian ruble*"}},
+	{Code: "UAH", Forms: []string{"гривн*", "hryvni*"}},
+	{Code: "AED", Forms: []string{"дирхам*", "dirham*"}},
+	{Code: "INR", Forms: []string{"рупи*", "rupee*"}},
+}
+
+// cbrCurrencyCodes — коды валют, курсы которых публикует ЦБ
+var cbrCurrencyCodes = strings.Fields(`AUD AZN GBP AMD BYN BGN BRL HUF VND HKD GEL DKK AED USD EUR
+	EGP INR IDR KZT CAD QAR KGS CNY MDL NZD NOK PLN RON XDR SGD TJS THB TRY TMT UZS UAH CZK SEK
+	CHF RSD ZAR KRW JPY RUB`)
+
+// matchesForm проверяет, что фраза совпадает с формой единицы слово в слово
+func matchesForm(phrase, form string) bool {
+	words, forms := strings.Fields(phrase), strings.Fields(form)
+	if len(words) != len(forms) || len(words) == 0 {
+		return false
+	}
+	for i, word := range words {
+		if stem, ok := strings.CutSuffix(forms[i], "*"); ok {
+			if !strings.HasPrefix(word, stem) || len([]rune(word))-len([]rune(stem)) > 3 {
+				return false
+			}
+			continue
+		}
+		if word != forms[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupUnits возвращает все единицы, которые может означать фраза: "фунт" — и масса, и валюта
+func lookupUnits(phrase string) []unit {
+	phrase = strings.ToLower(strings.Trim(strings.TrimSpace(phrase), ".,!?"))
+	var found []unit
+	for _, u := range measurementUnits {
+		for _, form := range u.Forms {
+			if matchesForm(phrase, form) {
+				found = append(found, u)
+				break
+			}
+		}
+	}
+	for _, c := range currencyUnits {
+		for _, form := range c.Forms {
+			if matchesForm(phrase, form) {
+				found = append(found, currencyUnit(c.Code))
+				break
+			}
+		}
+	}
+	for _, code := range cbrCurrencyCodes {
+		if strings.EqualFold(phrase, code) {
+			found = append(found, currencyUnit(code))
+		}
+	}
+	return found
+}
+
+func currencyUnit(code string) unit {
+	return unit{Code: code, Category: UnitCurrency, RU: code, EN: code}
+}
+
+// pickUnits выбирает пару единиц одной категории из возможных значений двух фраз
+func pickUnits(from, to []unit) (unit, unit, bool) {
+	for _, f := range from {
+		for _, t := range to {
+			if f.Category == t.Category {
+				return f, t, true
+			}
+		}
+	}
+	return unit{}, unit{}, false
+}
+
+// conversion — разобранный запрос на перевод величины
+type conversion struct {
+	Amount   float64
+	From, To unit
+}
+
+// conversionAmount находит число и, возможно, символ валюты перед ним: "5", "2,5", "$100"
+var conversionAmount = regexp.MustCompile(`([$€£¥₽]\s*)?(-?\d+(?:[.,]\d+)?)`)
+
+// conversionConnectors разделяют исходную и целевую единицы: "5 миль в км", "10 kg to lb"
+var conversionConnectors = map[string]bool{"в": true, "во": true, "to": true, "in": true, "into": true, "=": true, "->": true, "→": true}
+
+// parseConversion разбирает запросы "сколько 5 миль в км", "100 USD в рублях", "$20 to eur"
+func parseConversion(message string) (conversion, bool) {
+	text := strings.ToLower(strings.TrimSpace(message))
+	match := conversionAmount.FindStringSubmatchIndex(text)
+	if match == nil {
+		return conversion{}, false
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(text[match[4]:match[5]], ",", ".", 1), 64)
+	if err != nil {
+		return conversion{}, false
+	}
+
+	symbol := ""
+	if match[2] >= 0 {
+		symbol = strings.TrimSpace(text[match[2]:match[3]])
+	}
+	words := strings.Fields(strings.TrimRight(text[match[1]:], "?!. "))
+
+	// Единицы могут сами содержать предлог ("миль в час"), поэтому пробуем каждый
+	// разделитель, пока обе части не окажутся единицами одной категории
+	for i, word := range words {
+		if !conversionConnectors[word] {
+			continue
+		}
+		fromPhrase := strings.Join(words[:i], " ")
+		if symbol != "" {
+			if fromPhrase != "" {
+				break
+			}
+			fromPhrase = symbol
+		}
+		from, to, ok := pickUnits(lookupUnits(fromPhrase), lookupUnits(strings.Join(words[i+1:], " ")))
+		if ok {
+			return conversion{Amount: amount, From: from, To: to}, true
+		}
+	}
+	return conversion{}, false
+}
+
+// isConversionRequest проверяет, что сообщение просит перевести величину или валюту
+func isConversionRequest(message string) bool {
+	_, ok := parseConversion(message)
+	return ok
+}
+
+// convert переводит величину и возвращает готовый ответ; для валют указывает курс и его дату
+func (a *Agent) convert(locale string, c conversion) (string, error) {
+	params := Params{
+		"amount": formatQuantity(c.Amount),
+		"from":   c.From.symbol(locale),
+		"to":     c.To.symbol(locale),
+	}
+
+	if c.From.Category != UnitCurrency {
+		base := c.Amount*c.From.Factor + c.From.Offset
+		params["result"] = formatQuantity((base - c.To.Offset) / c.To.Factor)
+		return T(locale, "convert.result", params), nil
+	}
+
+	table, err := a.rates.Rates()
+	if err != nil {
+		return "", newLocalizedError("convert.error.rates")
+	}
+	fromRate, ok := table.Rate(c.From.Code)
+	if !ok {
+		return "", newLocalizedError("convert.error.currency", Params{"code": c.From.Code})
+	}
+	toRate, ok := table.Rate(c.To.Code)
+	if !ok {
+		return "", newLocalizedError("convert.error.currency", Params{"code": c.To.Code})
+	}
+
+	params["result"] = formatQuantity(c.Amount * fromRate / toRate)
+	params["rate"] = formatQuantity(fromRate / toRate)
+	params["date"] = table.Date.Format(T(locale, "format.date"))
+	return T(locale, "convert.currency", params), nil
+}
+
+// formatQuantity округляет результат: до сотых для больших величин
+// и до четырех значащих цифр для малых
+func formatQuantity(value float64) string {
+	if value == 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return formatNumber(value)
+	}
+	decimals := 2
+	if abs := math.Abs(value); abs < 1 {
+		decimals = min(3-int(math.Floor(math.Log10(abs))), 12)
+	}
+	text := strconv.FormatFloat(value, 'f', decimals, 64)
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	return text
+}
+
+func (a *Agent) handleConvertRequest(message string, userID int64) (string, error) {
+	locale := a.LocaleFor(userID)
+
+	c, ok := parseConversion(message)
+	if !ok {
+		return T(locale, "convert.usage"), nil
+	}
+	answer, err := a.convert(locale, c)
+	if err != nil {
+		return "❌ " + localizeError(locale, err), nil
+	}
+	return answer, nil
+}
+
+// convertToolParameters — JSON Schema аргументов инструмента convert для модели
+func convertToolParameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"amount": map[string]any{"type": "number", "description": "Величина, которую нужно перевести"},
+			"from":   map[string]any{"type": "string", "description": "Исходная единица или код валюты: km, mi, lb, °F, GB, km/h, USD"},
+			"to":     map[string]any{"type": "string", "description": "Целевая единица или код валюты: m, kg, °C, MB, mph, RUB"},
+		},
+		"required": []string{"amount", "from", "to"},
+	}
+}
+
+// callConvertTool выполняет перевод, запрошенный моделью, чтобы она не придумывала курсы
+func (a *Agent) callConvertTool(args json.RawMessage, userID int64) (string, error) {
+	var request struct {
+		Amount float64 `json:"amount"`
+		From   string  `json:"from"`
+		To     string  `json:"to"`
+	}
+	if err := json.Unmarshal(args, &request); err != nil {
+		return "", err
+	}
+
+	fromUnits, toUnits := lookupUnits(request.From), lookupUnits(request.To)
+	if len(fromUnits) == 0 {
+		return "", newLocalizedError("convert.error.unit", Params{"unit": request.From})
+	}
+	if len(toUnits) == 0 {
+		return "", newLocalizedError("convert.error.unit", Params{"unit": request.To})
+	}
+	from, to, ok := pickUnits(fromUnits, toUnits)
+	if !ok {
+		return "", newLocalizedError("convert.error.mismatch", Params{"from": request.From, "to": request.To})
+	}
+	return a.convert(a.LocaleFor(userID), conversion{Amount: request.Amount, From: from, To: to})
+}
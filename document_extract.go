@@ -0,0 +1,383 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxDocumentFileSize — максимальный размер загружаемого файла
+const maxDocumentFileSize = 10 << 20
+
+// maxDecompressedSize — сколько всего байт можно распаковать из одного документа,
+// чтобы маленький файл со множеством сжатых потоков не занял всю память
+const maxDecompressedSize = maxDocumentFileSize * 5
+
+// supportedDocumentTypes — поддерживаемые расширения файлов
+var supportedDocumentTypes = map[string]bool{
+	".txt":  true,
+	".md":   true,
+	".pdf":  true,
+	".docx": true,
+}
+
+// isSupportedDocument проверяет, что файл можно разобрать
+func isSupportedDocument(fileName string) bool {
+	return supportedDocumentTypes[strings.ToLower(filepath.Ext(fileName))]
+}
+
+// extractDocumentText извлекает текст из файла по его расширению
+func extractDocumentText(fileName string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".txt", ".md":
+		return extractPlainText(data)
+	case ".docx":
+		return extractDocxText(data)
+	case ".pdf":
+		return extractPDFText(data)
+	default:
+		return "", newLocalizedError("extract.error.format", Params{"ext": filepath.Ext(fileName)})
+	}
+}
+
+// extractPlainText проверяет кодировку текстового файла
+func extractPlainText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return "", newLocalizedError("extract.error.utf8")
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
+
+// extractDocxText извлекает текст из word/document.xml внутри .docx
+func extractDocxText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", newLocalizedError("extract.error.docx")
+	}
+
+	for _, file := range archive.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return "", newLocalizedError("extract.error.docx")
+		}
+		defer reader.Close()
+
+		return parseDocxXML(io.LimitReader(reader, maxDecompressedSize))
+	}
+
+	return "", newLocalizedError("extract.error.no_body")
+}
+
+// parseDocxXML собирает текст из элементов w:t, разделяя абзацы переводом строки
+func parseDocxXML(reader io.Reader) (string, error) {
+	decoder := xml.NewDecoder(reader)
+	var builder strings.Builder
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", newLocalizedError("extract.error.docx")
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				builder.WriteString("\t")
+			case "br":
+				builder.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				builder.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				builder.Write(t)
+			}
+		}
+	}
+
+	return builder.String(), nil
+}
+
+var (
+	pdfStreamPattern = regexp.MustCompile(`>>\s*stream\r?\n`)
+	pdfEndStream     = []byte("endstream")
+)
+
+// pdfCIDFontMarkers — признаки составных (CID) шрифтов. Строки в таких шрифтах — номера
+// глифов, а не коды символов, и без разбора CMap из них получается мусор.
+var pdfCIDFontMarkers = [][]byte{
+	[]byte("/Type0"),
+	[]byte("/CIDFontType"),
+	[]byte("/Identity-H"),
+	[]byte("/Identity-V"),
+}
+
+// pdfDictionaryBefore возвращает содержимое словаря, закрывающие >> которого стоят перед end.
+// Словарь ищется с конца с учетом вложенных << >>, чтобы не захватить соседние объекты.
+func pdfDictionaryBefore(data []byte, end int) string {
+	depth := 0
+	for i := end - 2; i >= 0; i-- {
+		switch {
+		case data[i] == '>' && data[i+1] == '>':
+			depth++
+			i--
+		case data[i] == '<' && data[i+1] == '<':
+			depth--
+			if depth == 0 {
+				return string(data[i+2 : end-2])
+			}
+			i--
+		}
+	}
+	return ""
+}
+
+// hasPDFCIDFonts проверяет, встречаются ли в данных PDF признаки CID-шрифтов
+func hasPDFCIDFonts(data []byte) bool {
+	for _, marker := range pdfCIDFontMarkers {
+		if bytes.Contains(data, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractPDFText извлекает текст из потоков содержимого PDF.
+// Поддерживаются несжатые потоки и FlateDecode. Документы с CID-шрифтами
+// (Identity-H и т. п.) не поддерживаются: вместо мусора возвращается ошибка.
+func extractPDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", newLocalizedError("extract.error.not_pdf")
+	}
+	if hasPDFCIDFonts(data) {
+		return "", newLocalizedError("extract.error.pdf_cid")
+	}
+
+	var builder strings.Builder
+	budget := int64(maxDecompressedSize) // Остаток лимита распаковки на все потоки вместе
+	for _, match := range pdfStreamPattern.FindAllIndex(data, -1) {
+		dictionary := pdfDictionaryBefore(data, match[0]+2)
+		start := match[1]
+		end := bytes.Index(data[start:], pdfEndStream)
+		if end == -1 {
+			continue
+		}
+		stream := data[start : start+end]
+
+		// Изображения, шрифты и прочие двоичные потоки пропускаем
+		if strings.Contains(dictionary, "/Subtype") || strings.Contains(dictionary, "/Length1") {
+			continue
+		}
+		if strings.Contains(dictionary, "/FlateDecode") {
+			if budget <= 0 {
+				// Лимит исчерпан: остальные сжатые потоки не распаковываем
+				break
+			}
+			reader, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			decoded, err := io.ReadAll(io.LimitReader(reader, budget))
+			reader.Close()
+			budget -= int64(len(decoded))
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			stream = decoded
+			// Описания шрифтов могут лежать в сжатых потоках объектов (PDF 1.5+)
+			if hasPDFCIDFonts(stream) {
+				return "", newLocalizedError("extract.error.pdf_cid")
+			}
+		} else if strings.Contains(dictionary, "/Filter") {
+			continue
+		}
+
+		builder.WriteString(parsePDFContent(stream))
+	}
+
+	text := builder.String()
+	if !looksLikeText(text) {
+		return "", newLocalizedError("extract.error.pdf")
+	}
+	return text, nil
+}
+
+// parsePDFContent извлекает строки, выводимые операторами Tj, TJ, ' и "
+func parsePDFContent(content []byte) string {
+	var builder strings.Builder
+	var pending []string
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '(':
+			text, next := readPDFLiteral(content, i)
+			pending = append(pending, text)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			text, next := readPDFHex(content, i)
+			pending = append(pending, text)
+			i = next
+		case c == '<' || c == '>':
+			i++
+		case isPDFOperatorStart(c):
+			start := i
+			for i < len(content) && isPDFOperatorStart(content[i]) {
+				i++
+			}
+			operator := string(content[start:i])
+			i--
+
+			switch operator {
+			case "Tj", "TJ":
+				builder.WriteString(strings.Join(pending, ""))
+			case "'", "\"":
+				builder.WriteString("\n" + strings.Join(pending, ""))
+			case "Td", "TD", "T*", "ET":
+				builder.WriteString("\n")
+			}
+			pending = pending[:0]
+		}
+	}
+
+	return builder.String()
+}
+
+// isPDFOperatorStart проверяет, может ли символ входить в имя оператора PDF
+func isPDFOperatorStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '*' || c == '\'' || c == '"'
+}
+
+// readPDFLiteral читает строку вида (текст) с учетом вложенных скобок и экранирования
+func readPDFLiteral(content []byte, start int) (string, int) {
+	var out []byte
+	depth := 0
+
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '\\':
+			if i+1 >= len(content) {
+				return decodePDFString(out), i
+			}
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case '(', ')', '\\':
+				out = append(out, e)
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					j := i
+					for ; j < len(content) && j < i+3 && content[j] >= '0' && content[j] <= '7'; j++ {
+						value = value*8 + int(content[j]-'0')
+					}
+					out = append(out, byte(value))
+					i = j - 1
+				}
+			}
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(out), i
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return decodePDFString(out), len(content)
+}
+
+// readPDFHex читает строку вида <48656C6C6F>
+func readPDFHex(content []byte, start int) (string, int) {
+	end := bytes.IndexByte(content[start:], '>')
+	if end == -1 {
+		return "", len(content)
+	}
+
+	var digits []byte
+	for _, c := range content[start+1 : start+end] {
+		if unicode.Is(unicode.ASCII_Hex_Digit, rune(c)) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(out, digits); err != nil {
+		return "", start + end
+	}
+	return decodePDFString(out), start + end
+}
+
+// decodePDFString декодирует строку PDF: UTF-16BE с BOM или однобайтовую кодировку
+func decodePDFString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// looksLikeText проверяет, что извлеченный текст в основном состоит из читаемых символов
+func looksLikeText(text string) bool {
+	total, readable := 0, 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsPunct(r) {
+			readable++
+		}
+	}
+	return total > 0 && readable*10 >= total*8
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF собирает минимальный PDF из объектов; поток с ключом Filter сжимается FlateDecode
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	for i, object := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func pdfStream(content string) string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)
+}
+
+func pdfFlateStream(content string) string {
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	writer.Write([]byte(content))
+	writer.Close()
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", buf.Len(), buf.String())
+}
+
+func buildDocx(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// errorKey возвращает ключ локализованной ошибки или текст обычной
+func errorKey(err error) string {
+	if localized, ok := err.(*LocalizedError); ok {
+		return localized.Key
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func TestExtractPDFText(t *testing.T) {
+	simpleFont := "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{
+			name: "строки Tj и TJ",
+			data: buildPDF(simpleFont, pdfStream("BT /F1 12 Tf (Hello, World!) Tj 0 -14 Td [(Sec) -20 (ond line)] TJ ET")),
+			want: "Hello, World!\nSecond line\n",
+		},
+		{
+			name: "экранирование в строках",
+			data: buildPDF(pdfStream(`BT (a\(b\) \101\102) Tj ET`)),
+			want: "a(b) AB\n",
+		},
+		{
+			name: "UTF-16BE в шестнадцатеричной строке",
+			data: buildPDF(pdfStream("BT <FEFF041F04400438043204350442> Tj ET")),
+			want: "Привет\n",
+		},
+		{
+			name: "сжатый поток FlateDecode",
+			data: buildPDF(pdfFlateStream("BT (Compressed text) Tj ET")),
+			want: "Compressed text\n",
+		},
+		{
+			name: "вложенные словари в словаре потока",
+			data: buildPDF(simpleFont, "<< /Length 24 /Resources << /Font << /F1 1 0 R >> >> >>\nstream\nBT (Nested dict) Tj ET\nendstream"),
+			want: "Nested dict\n",
+		},
+		{
+			name:    "CID-шрифт Identity-H",
+			data:    buildPDF("<< /Type /Font /Subtype /Type0 /BaseFont /Arial /Encoding /Identity-H >>", pdfStream("BT <041F04400438> Tj ET")),
+			wantErr: "extract.error.pdf_cid",
+		},
+		{
+			name:    "CID-шрифт в сжатом потоке объектов",
+			data:    buildPDF(pdfFlateStream("<< /Type /Font /Subtype /Type0 /Encoding /Identity-H >>"), pdfStream("BT <0C1A0C3F> Tj ET")),
+			wantErr: "extract.error.pdf_cid",
+		},
+		{
+			name:    "нет текстового слоя",
+			data:    buildPDF(pdfStream("q 100 0 0 100 0 0 cm /Im1 Do Q")),
+			wantErr: "extract.error.pdf",
+		},
+		{
+			name:    "не PDF",
+			data:    []byte("<html>не документ</html>"),
+			wantErr: "extract.error.not_pdf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractPDFText(tt.data)
+			if key := errorKey(err); key != tt.wantErr {
+				t.Fatalf("ошибка = %q, want %q", key, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extractPDFText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDocxText(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Первый</w:t></w:r><w:r><w:t xml:space="preserve"> абзац</w:t></w:r></w:p>
+<w:p><w:r><w:t>Колонка</w:t><w:tab/><w:t>значение</w:t><w:br/><w:t>строка</w:t></w:r></w:p>
+</w:body></w:document>`
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{
+			name: "абзацы, табуляция и перенос",
+			data: buildDocx(t, map[string]string{"word/document.xml": body, "word/styles.xml": "<w:styles/>"}),
+			want: "Первый абзац\nКолонка\tзначение\nстрока\n",
+		},
+		{
+			name:    "нет основного документа",
+			data:    buildDocx(t, map[string]string{"word/styles.xml": "<w:styles/>"}),
+			wantErr: "extract.error.no_body",
+		},
+		{
+			name:    "битый XML",
+			data:    buildDocx(t, map[string]string{"word/document.xml": "<w:document><w:p>"}),
+			wantErr: "extract.error.docx",
+		},
+		{
+			name:    "не zip",
+			data:    []byte("plain text"),
+			wantErr: "extract.error.docx",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractDocxText(tt.data)
+			if key := errorKey(err); key != tt.wantErr {
+				t.Fatalf("ошибка = %q, want %q", key, tt.wantErr)
+			}
+			if strings.TrimLeft(got, "\n") != tt.want {
+				t.Errorf("extractDocxText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDocumentTextByExtension(t *testing.T) {
+	if got, err := extractDocumentText("notes.TXT", []byte("\xef\xbb\xbfстрока\r\nвторая")); err != nil || got != "строка\nвторая" {
+		t.Errorf("txt: %q, %v", got, err)
+	}
+	if _, err := extractDocumentText("latin1.txt", []byte{0xcf, 0xf0, 0xe8}); errorKey(err) != "extract.error.utf8" {
+		t.Errorf("txt не в UTF-8: %v", err)
+	}
+	if _, err := extractDocumentText("table.xlsx", nil); errorKey(err) != "extract.error.format" {
+		t.Errorf("xlsx: %v", err)
+	}
+}
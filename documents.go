@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxDocumentsPerChat — сколько документов можно загрузить в один чат
+	maxDocumentsPerChat = 5
+	// maxDocumentTextSize — максимальный объем извлеченного текста документа (в символах)
+	maxDocumentTextSize = 200000
+	// documentChunkSize — примерный размер фрагмента документа (в символах)
+	documentChunkSize = 1000
+	// documentChunkOverlap — перекрытие соседних фрагментов, чтобы не терять контекст на стыках
+	documentChunkOverlap = 150
+	// documentContextLimit — сколько символов фрагментов добавляется в запрос к модели
+	documentContextLimit = 6000
+)
+
+// Document представляет загруженный пользователем документ
+type Document struct {
+	Name       string
+	UploadedAt time.Time
+	Chunks     []string
+	Size       int
+}
+
+// DocumentChunk представляет найденный фрагмент документа
+type DocumentChunk struct {
+	DocumentName string
+	Text         string
+	Score        int
+}
+
+// DocumentStore хранит документы, загруженные в разговоры
+type DocumentStore struct {
+	mu        sync.Mutex
+	documents map[string][]*Document
+}
+
+// NewDocumentStore создает хранилище документов
+func NewDocumentStore() *DocumentStore {
+	return &DocumentStore{
+		documents: make(map[string][]*Document),
+	}
+}
+
+// Add разбивает текст на фрагменты и сохраняет документ в разговоре
+func (s *DocumentStore) Add(conversationID, name, text string) (*Document, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, newLocalizedError("docs.error.no_text")
+	}
+	if runes := []rune(text); len(runes) > maxDocumentTextSize {
+		text = string(runes[:maxDocumentTextSize])
+	}
+
+	document := &Document{
+		Name:       name,
+		UploadedAt: time.Now(),
+		Chunks:     splitIntoChunks(text, documentChunkSize, documentChunkOverlap),
+		Size:       len([]rune(text)),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	documents := s.documents[conversationID]
+	// Документ с тем же именем заменяем новой версией
+	for i, existing := range documents {
+		if existing.Name == name {
+			documents[i] = document
+			return document, nil
+		}
+	}
+	if len(documents) >= maxDocumentsPerChat {
+		return nil, newLocalizedError("docs.error.limit", Params{"max": maxDocumentsPerChat})
+	}
+
+	s.documents[conversationID] = append(documents, document)
+	return document, nil
+}
+
+// List возвращает документы разговора
+func (s *DocumentStore) List(conversationID string) []*Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Document(nil), s.documents[conversationID]...)
+}
+
+// Remove удаляет документ по номеру (начиная с 1) и возвращает его имя
+func (s *DocumentStore) Remove(conversationID string, number int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	documents := s.documents[conversationID]
+	if number < 1 || number > len(documents) {
+		return "", newLocalizedError("docs.error.not_found", Params{"number": number})
+	}
+
+	name := documents[number-1].Name
+	s.documents[conversationID] = append(documents[:number-1], documents[number:]...)
+	return name, nil
+}
+
+// Clear удаляет все документы разговора и возвращает их количество
+func (s *DocumentStore) Clear(conversationID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.documents[conversationID])
+	delete(s.documents, conversationID)
+	return count
+}
+
+// Relevant возвращает фрагменты, наиболее подходящие к вопросу, в пределах лимита символов
+func (s *DocumentStore) Relevant(conversationID, query string, limit int) []DocumentChunk {
+	queryTerms := termSet(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	documents := s.documents[conversationID]
+	var candidates []DocumentChunk
+	for _, document := range documents {
+		for _, chunk := range document.Chunks {
+			score := 0
+			for term := range termSet(chunk) {
+				if queryTerms[term] {
+					score++
+				}
+			}
+			if score > 0 {
+				candidates = append(candidates, DocumentChunk{
+					DocumentName: document.Name,
+					Text:         chunk,
+					Score:        score,
+				})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	var result []DocumentChunk
+	total := 0
+	for _, candidate := range candidates {
+		size := len([]rune(candidate.Text))
+		if total+size > limit {
+			break
+		}
+		result = append(result, candidate)
+		total += size
+	}
+
+	return result
+}
+
+// splitIntoChunks разбивает текст на фрагменты по границам абзацев с перекрытием
+func splitIntoChunks(text string, size, overlap int) []string {
+	var chunks []string
+	var current []rune
+	added := false // Есть ли во фрагменте что-то кроме перекрытия с предыдущим
+
+	flush := func() {
+		if chunk := strings.TrimSpace(string(current)); added && chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		// Переносим хвост предыдущего фрагмента в начало следующего
+		if len(current) > overlap {
+			current = append([]rune(nil), current[len(current)-overlap:]...)
+		} else {
+			current = current[:0]
+		}
+		added = false
+	}
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		runes := []rune(paragraph)
+		// Слишком длинный абзац режем на части
+		for len(runes) > size {
+			current = append(current, runes[:size]...)
+			runes = runes[size:]
+			added = true
+			flush()
+		}
+
+		if added && len(current)+len(runes) > size {
+			flush()
+		}
+		current = append(current, runes...)
+		current = append(current, '\n')
+		added = true
+	}
+	flush()
+
+	return chunks
+}
+
+// termSet разбивает текст на нормализованные термины для поиска.
+// Слова обрезаются до 5 букв — этого достаточно, чтобы склонения совпадали.
+func termSet(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(word)
+		if len(runes) < 3 {
+			continue
+		}
+		if len(runes) > 5 {
+			runes = runes[:5]
+		}
+		terms[string(runes)] = true
+	}
+	return terms
+}
+
+// documentContext формирует инструкцию для модели с фрагментами документов
+func documentContext(chunks []DocumentChunk) string {
+	var builder strings.Builder
+	builder.WriteString("Пользователь загрузил документы. Отвечай на вопрос, опираясь на фрагменты ниже, ")
+	builder.WriteString("и указывай, из какого документа взята информация. ")
+	builder.WriteString("Если в фрагментах нет ответа, так и скажи.\n")
+
+	for _, chunk := range chunks {
+		fmt.Fprintf(&builder, "\n[%s]\n%s\n", chunk.DocumentName, chunk.Text)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitIntoChunks(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		size, overlap int
+		want          []string
+	}{
+		{
+			name: "короткий текст — один фрагмент",
+			text: "первый абзац\n\nвторой абзац",
+			size: 100, overlap: 10,
+			want: []string{"первый абзац\nвторой абзац"},
+		},
+		{
+			name: "абзацы не разрываются",
+			text: "aaaa\nbbbb\ncccc",
+			size: 10, overlap: 0,
+			want: []string{"aaaa\nbbbb", "cccc"},
+		},
+		{
+			name: "перекрытие переносит хвост предыдущего фрагмента",
+			text: "aaaa\nbbbb\ncccc",
+			size: 10, overlap: 3,
+			want: []string{"aaaa\nbbbb", "bb\ncccc"},
+		},
+		{
+			name: "длинный абзац режется по размеру в символах",
+			text: strings.Repeat("я", 25),
+			size: 10, overlap: 0,
+			want: []string{strings.Repeat("я", 10), strings.Repeat("я", 10), strings.Repeat("я", 5)},
+		},
+		{
+			name: "пустой текст",
+			text: "\n  \n",
+			size: 10, overlap: 2,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitIntoChunks(tt.text, tt.size, tt.overlap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitIntoChunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
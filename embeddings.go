@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+// EmbeddingKind определяет, что именно векторизуется: фрагмент документа или запрос
+type EmbeddingKind int
+
+const (
+	EmbeddingDocument EmbeddingKind = iota
+	EmbeddingQuery
+)
+
+// Embedder превращает текст в вектор для семантического поиска
+type Embedder interface {
+	// Name возвращает идентификатор модели; индекс пересобирается, если он изменился
+	Name() string
+	// Embed возвращает вектор текста
+	Embed(text string, kind EmbeddingKind) ([]float64, error)
+}
+
+// defaultEmbeddingsURL — адрес API эмбеддингов Yandex Foundation Models
+const defaultEmbeddingsURL = "https://llm.api.cloud.yandex.net/foundationModels/v1/textEmbedding"
+
+// YandexEmbedder получает эмбеддинги через Yandex Foundation Models
+type YandexEmbedder struct {
+	apiKey     string
+	folderID   string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// YandexEmbeddingRequest представляет запрос к API эмбеддингов
+type YandexEmbeddingRequest struct {
+	ModelURI string `json:"modelUri"`
+	Text     string `json:"text"`
+}
+
+// YandexEmbeddingResponse представляет ответ API эмбеддингов
+type YandexEmbeddingResponse struct {
+	Embedding    []float64 `json:"embedding"`
+	NumTokens    string    `json:"numTokens"`
+	ModelVersion string    `json:"modelVersion"`
+}
+
+// NewYandexEmbedder создает клиент эмбеддингов; пустой baseURL означает адрес по умолчанию
+func NewYandexEmbedder(apiKey, folderID, baseURL string) *YandexEmbedder {
+	if baseURL == "" {
+		baseURL = defaultEmbeddingsURL
+	}
+	return &YandexEmbedder{
+		apiKey:   apiKey,
+		folderID: folderID,
+		baseURL:  baseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetTransport заменяет транспорт HTTP-клиента, например на кассету с записанными ответами
+func (e *YandexEmbedder) SetTransport(transport http.RoundTripper) {
+	e.httpClient.Transport = transport
+}
+
+// Name возвращает идентификатор модели эмбеддингов
+func (e *YandexEmbedder) Name() string {
+	return "yandex/text-search"
+}
+
+// Embed получает вектор текста; документы и запросы векторизуются разными моделями
+func (e *YandexEmbedder) Embed(text string, kind EmbeddingKind) ([]float64, error) {
+	model := "text-search-doc"
+	if kind == EmbeddingQuery {
+		model = "text-search-query"
+	}
+
+	jsonData, err := json.Marshal(YandexEmbeddingRequest{
+		ModelURI: fmt.Sprintf("emb://%s/%s/latest", e.folderID, model),
+		Text:     text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга JSON: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", e.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Api-Key "+e.apiKey)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка HTTP запроса: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответа: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API вернул ошибку %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response YandexEmbeddingResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON ответа: %v", err)
+	}
+	if len(response.Embedding) == 0 {
+		return nil, fmt.Errorf("пустой эмбеддинг в ответе")
+	}
+
+	return normalizeVector(response.Embedding), nil
+}
+
+// hashingEmbedderDimensions — размерность векторов локального эмбеддера
+const hashingEmbedderDimensions = 512
+
+// HashingEmbedder строит векторы хешированием терминов. Не требует сети,
+// поэтому подходит для тестов и работы без доступа к Yandex Cloud.
+type HashingEmbedder struct{}
+
+// Name возвращает идентификатор эмбеддера
+func (HashingEmbedder) Name() string {
+	return fmt.Sprintf("hashing/%d", hashingEmbedderDimensions)
+}
+
+// Embed раскладывает термины текста по корзинам вектора
+func (HashingEmbedder) Embed(text string, kind EmbeddingKind) ([]float64, error) {
+	vector := make([]float64, hashingEmbedderDimensions)
+	for term := range termSet(text) {
+		hash := fnv.New32a()
+		hash.Write([]byte(term))
+		sum := hash.Sum32()
+
+		// Старший бит хеша задает знак, чтобы коллизии взаимно гасились
+		sign := 1.0
+		if sum&(1<<31) != 0 {
+			sign = -1.0
+		}
+		vector[sum%hashingEmbedderDimensions] += sign
+	}
+	return normalizeVector(vector), nil
+}
+
+// normalizeVector приводит вектор к единичной длине
+func normalizeVector(vector []float64) []float64 {
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// cosineSimilarity считает косинусное сходство нормализованных векторов
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHashingEmbedderRanking(t *testing.T) {
+	embed := func(text string) []float64 {
+		vector, err := HashingEmbedder{}.Embed(text, EmbeddingDocument)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vector
+	}
+
+	query := embed("Как оформить отпуск?")
+	vacation := embed("Отпуск оформляется заявлением в кадровой системе за две недели")
+	office := embed("Офис открыт с девяти утра, пропуск выдает охрана")
+
+	var norm float64
+	for _, value := range vacation {
+		norm += value * value
+	}
+	if math.Abs(norm-1) > 1e-9 {
+		t.Errorf("вектор не нормализован: длина² = %v", norm)
+	}
+	if score := cosineSimilarity(vacation, embed("Отпуск оформляется заявлением в кадровой системе за две недели")); math.Abs(score-1) > 1e-9 {
+		t.Errorf("одинаковый текст: сходство %v, want 1", score)
+	}
+	// Склонения совпадают за счет обрезки слов до 5 букв
+	if related, unrelated := cosineSimilarity(query, vacation), cosineSimilarity(query, office); related <= unrelated {
+		t.Errorf("сходство с релевантным текстом %v не больше, чем с нерелевантным %v", related, unrelated)
+	}
+	if score := cosineSimilarity(query, []float64{1, 0}); score != 0 {
+		t.Errorf("векторы разной длины: сходство %v, want 0", score)
+	}
+}
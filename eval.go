@@ -0,0 +1,515 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// evalChannelName — канал, в пространстве которого заводятся пользователи прогона:
+// их ID не пересекаются с ID Telegram и других каналов
+const evalChannelName = "eval"
+
+// evalUserID возвращает пользователя для случая с номером index, по одному на случай,
+// чтобы истории разных случаев не смешивались
+func evalUserID(index int) int64 {
+	return ChannelUserID(evalChannelName, strconv.Itoa(index))
+}
+
+// EvalCase — случай корпуса: сообщение и ожидания к ответу.
+// В корпусе JSONL случай занимает одну строку:
+//
+//	{"id": "calc", "message": "вычисли 2+2", "expect_tool": "calculate", "contains": ["4"]}
+type EvalCase struct {
+	ID          string   `json:"id"`
+	History     []string `json:"history,omitempty"` // Сообщения, которые отправляются перед проверяемым
+	Message     string   `json:"message"`
+	Locale      string   `json:"locale,omitempty"`
+	ExpectTool  string   `json:"expect_tool,omitempty"`
+	Contains    []string `json:"contains,omitempty"`     // Подстроки, которые должны быть в ответе (без учета регистра)
+	NotContains []string `json:"not_contains,omitempty"` // Подстроки, которых в ответе быть не должно
+	Matches     []string `json:"matches,omitempty"`      // Регулярные выражения, которым ответ должен соответствовать
+	NotMatches  []string `json:"not_matches,omitempty"`  // Регулярные выражения, которым ответ соответствовать не должен
+	Judge       string   `json:"judge,omitempty"`        // Критерий для оценки ответа моделью
+}
+
+// EvalResult — результат одного случая
+type EvalResult struct {
+	ID         string   `json:"id"`
+	Message    string   `json:"message"`
+	Tool       string   `json:"tool"`
+	Response   string   `json:"response"`
+	Passed     bool     `json:"passed"`
+	Failures   []string `json:"failures,omitempty"`
+	Judge      string   `json:"judge,omitempty"` // Вердикт модели-судьи или причина, по которой проверка пропущена
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// EvalReport — отчет прогона; сохраняется, чтобы следующий прогон сравнить с ним
+type EvalReport struct {
+	StartedAt time.Time    `json:"started_at"`
+	Provider  string       `json:"provider"`
+	Corpus    string       `json:"corpus"`
+	Passed    int          `json:"passed"`
+	Failed    int          `json:"failed"`
+	Results   []EvalResult `json:"results"`
+}
+
+// LoadEvalCorpus читает корпус JSONL; пустые строки и строки с # пропускаются
+func LoadEvalCorpus(path string) ([]EvalCase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения корпуса: %v", err)
+	}
+	defer file.Close()
+
+	var cases []EvalCase
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c EvalCase
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&c); err != nil {
+			return nil, fmt.Errorf("строка %d: %v", line, err)
+		}
+		if c.Message == "" {
+			return nil, fmt.Errorf("строка %d: нет поля message", line)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line-%d", line)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("строка %d: повторяется id %q", line, c.ID)
+		}
+		seen[c.ID] = true
+		for _, expr := range append(append([]string(nil), c.Matches...), c.NotMatches...) {
+			if _, err := regexp.Compile(expr); err != nil {
+				return nil, fmt.Errorf("строка %d: %v", line, err)
+			}
+		}
+		cases = append(cases, c)
+	}
+	return cases, scanner.Err()
+}
+
+// evalJudgePrompt — системный промпт модели-судьи
+const evalJudgePrompt = "Ты проверяешь ответы ассистента. Оцени, соответствует ли ответ критерию. " +
+	"Первым словом ответь PASS или FAIL, затем в одном предложении объясни почему."
+
+// Evaluator прогоняет корпус через агента
+type Evaluator struct {
+	agent *Agent
+	judge LLMProvider // nil — проверки судьи пропускаются
+}
+
+// NewEvaluator создает прогон; judge может быть nil
+func NewEvaluator(agent *Agent, judge LLMProvider) *Evaluator {
+	return &Evaluator{agent: agent, judge: judge}
+}
+
+// Run прогоняет случаи по порядку. Каждый случай выполняется от отдельного пользователя.
+func (e *Evaluator) Run(cases []EvalCase) []EvalResult {
+	results := make([]EvalResult, 0, len(cases))
+	for i, c := range cases {
+		results = append(results, e.runCase(evalUserID(i), c))
+	}
+	return results
+}
+
+func (e *Evaluator) runCase(userID int64, c EvalCase) EvalResult {
+	if c.Locale != "" {
+		e.agent.RememberLocale(userID, c.Locale)
+	}
+	for _, message := range c.History {
+		e.agent.ProcessMessage(message, userID)
+	}
+
+	result := EvalResult{ID: c.ID, Message: c.Message, Tool: e.agent.determineTool(c.Message)}
+	started := time.Now()
+	response, err := e.agent.ProcessMessage(c.Message, userID)
+	result.DurationMS = time.Since(started).Milliseconds()
+	result.Response = response
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("ошибка агента: %v", err))
+	}
+
+	fail := func(format string, args ...any) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+	if c.ExpectTool != "" && result.Tool != c.ExpectTool {
+		fail("инструмент %s, ожидался %s", result.Tool, c.ExpectTool)
+	}
+	lower := strings.ToLower(response)
+	for _, substring := range c.Contains {
+		if !strings.Contains(lower, strings.ToLower(substring)) {
+			fail("нет подстроки %q", substring)
+		}
+	}
+	for _, substring := range c.NotContains {
+		if strings.Contains(lower, strings.ToLower(substring)) {
+			fail("есть запрещенная подстрока %q", substring)
+		}
+	}
+	for _, expr := range c.Matches {
+		if !regexp.MustCompile(expr).MatchString(response) {
+			fail("не соответствует %q", expr)
+		}
+	}
+	for _, expr := range c.NotMatches {
+		if regexp.MustCompile(expr).MatchString(response) {
+			fail("соответствует запрещенному %q", expr)
+		}
+	}
+	if c.Judge != "" {
+		passed, verdict := e.judgeResponse(c, response)
+		result.Judge = verdict
+		if !passed {
+			fail("судья: %s", verdict)
+		}
+	}
+
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// judgeResponse просит модель оценить ответ по критерию случая.
+// Без модели проверка пропускается и не считается проваленной.
+func (e *Evaluator) judgeResponse(c EvalCase, response string) (bool, string) {
+	if e.judge == nil {
+		return true, "пропущено: модель для оценки не подключена"
+	}
+
+	options := DefaultGenerationOptions()
+	options.Temperature = 0
+	options.MaxTokens = 200
+	completion, err := e.judge.Generate([]YandexGPTMessage{
+		{Role: "system", Text: evalJudgePrompt},
+		{Role: "user", Text: fmt.Sprintf("Критерий: %s\n\nВопрос пользователя: %s\n\nОтвет ассистента: %s", c.Judge, c.Message, response)},
+	}, options)
+	if err != nil {
+		return false, fmt.Sprintf("ошибка модели: %v", err)
+	}
+
+	verdict := strings.TrimSpace(completion.Text)
+	return strings.HasPrefix(strings.ToUpper(verdict), "PASS"), verdict
+}
+
+// NewEvalReport подсчитывает итоги прогона
+func NewEvalReport(provider, corpus string, startedAt time.Time, results []EvalResult) EvalReport {
+	report := EvalReport{StartedAt: startedAt, Provider: provider, Corpus: corpus, Results: results}
+	for _, result := range results {
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+	return report
+}
+
+// WriteEvalReport печатает результаты случаев и итог
+func WriteEvalReport(w io.Writer, report EvalReport) {
+	for _, result := range report.Results {
+		mark := "✅"
+		if !result.Passed {
+			mark = "❌"
+		}
+		fmt.Fprintf(w, "%s %s [%s, %d мс]\n", mark, result.ID, result.Tool, result.DurationMS)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "     - %s\n", failure)
+		}
+		if result.Passed && result.Judge != "" {
+			fmt.Fprintf(w, "     · судья: %s\n", result.Judge)
+		}
+	}
+	fmt.Fprintf(w, "\nИтого: %d из %d пройдено (бэкенд %s)\n", report.Passed, report.Passed+report.Failed, report.Provider)
+}
+
+// WriteEvalDiff печатает отличия от прошлого прогона: смену статуса, инструмента,
+// новые и удаленные случаи. Возвращает число регрессий — случаев, которые перестали проходить.
+func WriteEvalDiff(w io.Writer, previous, current EvalReport) int {
+	before := make(map[string]EvalResult, len(previous.Results))
+	for _, result := range previous.Results {
+		before[result.ID] = result
+	}
+
+	var lines []string
+	regressions := 0
+	for _, result := range current.Results {
+		old, ok := before[result.ID]
+		delete(before, result.ID)
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("  + %s: новый случай", result.ID))
+		case old.Passed && !result.Passed:
+			regressions++
+			lines = append(lines, fmt.Sprintf("  ↓ %s: перестал проходить", result.ID))
+		case !old.Passed && result.Passed:
+			lines = append(lines, fmt.Sprintf("  ↑ %s: исправлен", result.ID))
+		}
+		if ok && old.Tool != result.Tool {
+			lines = append(lines, fmt.Sprintf("  ~ %s: инструмент %s → %s", result.ID, old.Tool, result.Tool))
+		}
+	}
+	removed := make([]string, 0, len(before))
+	for id := range before {
+		removed = append(removed, id)
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		lines = append(lines, fmt.Sprintf("  - %s: удален из корпуса", id))
+	}
+
+	fmt.Fprintf(w, "\nСравнение с прогоном %s: было %d из %d, стало %d из %d\n",
+		previous.StartedAt.Format("2006-01-02 15:04"), previous.Passed, previous.Passed+previous.Failed,
+		current.Passed, current.Passed+current.Failed)
+	if len(lines) == 0 {
+		fmt.Fprintln(w, "  без изменений")
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	return regressions
+}
+
+// loadEvalReport читает отчет прошлого прогона; false, если его нет
+func loadEvalReport(path string) (EvalReport, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return EvalReport{}, false
+	}
+	var report EvalReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		log.Printf("Отчет прошлого прогона %s не прочитан: %v", path, err)
+		return EvalReport{}, false
+	}
+	return report, true
+}
+
+func saveEvalReport(path string, report EvalReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// RecordedProvider записывает ответы модели в файл и воспроизводит их, чтобы прогоны
+// были повторяемыми и не тратили токены. Без вложенного бэкенда работает только
+// воспроизведение: запрос, которого нет в записи, возвращает ошибку.
+type RecordedProvider struct {
+	inner LLMProvider
+	path  string
+
+	mu      sync.Mutex
+	records map[string]Completion
+	dirty   bool
+}
+
+// NewRecordedProvider загружает записи из path; inner может быть nil
+func NewRecordedProvider(path string, inner LLMProvider) (*RecordedProvider, error) {
+	p := &RecordedProvider{inner: inner, path: path, records: make(map[string]Completion)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && inner != nil {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения записи ответов: %v", err)
+	}
+	if err := json.Unmarshal(data, &p.records); err != nil {
+		return nil, fmt.Errorf("ошибка разбора записи ответов: %v", err)
+	}
+	return p, nil
+}
+
+// Name возвращает имя записанного бэкенда, чтобы ключи кэша и отчеты совпадали
+func (p *RecordedProvider) Name() string {
+	if p.inner != nil {
+		return p.inner.Name()
+	}
+	return "recorded"
+}
+
+// Generate воспроизводит записанный ответ или получает и записывает новый
+func (p *RecordedProvider) Generate(messages []YandexGPTMessage, options GenerationOptions) (Completion, error) {
+	key, err := recordKey(messages, options)
+	if err != nil {
+		return Completion{}, err
+	}
+
+	p.mu.Lock()
+	completion, ok := p.records[key]
+	p.mu.Unlock()
+	if ok {
+		return completion, nil
+	}
+	if p.inner == nil {
+		return Completion{}, fmt.Errorf("нет записанного ответа на запрос %s", key[:12])
+	}
+
+	completion, err = p.inner.Generate(messages, options)
+	if err != nil {
+		return Completion{}, err
+	}
+	p.mu.Lock()
+	p.records[key] = completion
+	p.dirty = true
+	p.mu.Unlock()
+	return completion, nil
+}
+
+// Save сохраняет новые записи в файл
+func (p *RecordedProvider) Save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p.path, data, 0o644); err != nil {
+		return fmt.Errorf("ошибка сохранения записи ответов: %v", err)
+	}
+	p.dirty = false
+	return nil
+}
+
+// recordKey — хеш диалога и параметров генерации
+func recordKey(messages []YandexGPTMessage, options GenerationOptions) (string, error) {
+	tools := make([]string, 0, len(options.Tools))
+	for _, tool := range options.Tools {
+		tools = append(tools, tool.Name)
+	}
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(struct {
+		Model       string
+		Temperature float64
+		MaxTokens   int
+		Tools       []string
+		Messages    []YandexGPTMessage
+	}{options.Model, options.Temperature, options.MaxTokens, tools, messages})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// runEval выполняет подкоманду eval и возвращает код завершения:
+// 0 — все случаи прошли, 1 — есть проваленные, 2 — ошибка запуска.
+//
+//	chatagent eval [-provider yandexgpt] [-record file | -replay file] [-out report.json] corpus.jsonl
+func runEval(args []string) int {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	provider := flags.String("provider", "", "бэкенд модели: yandexgpt или builtin (по умолчанию из конфигурации)")
+	record := flags.String("record", "", "записывать ответы модели в файл")
+	replay := flags.String("replay", "", "воспроизводить ответы модели из файла без обращения к API")
+	out := flags.String("out", filepath.Join(dataDir(), "eval_report.json"), "файл отчета")
+	baseline := flags.String("baseline", "", "отчет для сравнения (по умолчанию прошлый отчет из -out)")
+	verbose := flags.Bool("v", false, "показывать журнал агента")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || (*record != "" && *replay != "") {
+		fmt.Fprintln(os.Stderr, "Использование: chatagent eval [-provider имя] [-record файл | -replay файл] [-out отчет] корпус.jsonl")
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	cases, err := LoadEvalCorpus(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	agent, recorded, err := createEvalAgent(*provider, *record, *replay)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	startedAt := time.Now()
+	results := NewEvaluator(agent, agent.llm()).Run(cases)
+	report := NewEvalReport(agent.ProviderName(), flags.Arg(0), startedAt, results)
+	if recorded != nil {
+		if err := recorded.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	WriteEvalReport(os.Stdout, report)
+	if *baseline == "" {
+		*baseline = *out
+	}
+	if previous, ok := loadEvalReport(*baseline); ok {
+		WriteEvalDiff(os.Stdout, previous, report)
+	}
+	if err := saveEvalReport(*out, report); err != nil {
+		fmt.Fprintf(os.Stderr, "Отчет не сохранен: %v\n", err)
+	}
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// createEvalAgent создает агента для прогона без кэша ответов, чтобы случаи не влияли друг на друга.
+// С -record или -replay активный бэкенд оборачивается записью ответов.
+func createEvalAgent(provider, record, replay string) (*Agent, *RecordedProvider, error) {
+	agent := createLocalAgent()
+	agent.SetResponseCache(nil)
+
+	if provider != "" {
+		if err := agent.SetProvider(provider); err != nil {
+			return nil, nil, fmt.Errorf("неизвестный бэкенд %s", provider)
+		}
+	}
+
+	var recorded *RecordedProvider
+	if path := record + replay; path != "" {
+		var inner LLMProvider
+		if record != "" {
+			inner = agent.llm()
+			if inner == nil {
+				return nil, nil, fmt.Errorf("для записи ответов нужен бэкенд модели, а выбран %s", builtinProviderName)
+			}
+		}
+		var err error
+		recorded, err = NewRecordedProvider(path, inner)
+		if err != nil {
+			return nil, nil, err
+		}
+		agent.RegisterProvider(recorded)
+		agent.SetProvider(recorded.Name())
+	}
+	return agent, recorded, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Форматы выгрузки разговоров
+const (
+	ExportJSON     = "json"
+	ExportMarkdown = "md"
+	ExportHTML     = "html"
+)
+
+// exportFormats — поддерживаемые форматы и MIME-типы файлов
+var exportFormats = map[string]string{
+	ExportJSON:     "application/json; charset=utf-8",
+	ExportMarkdown: "text/markdown; charset=utf-8",
+	ExportHTML:     "text/html; charset=utf-8",
+}
+
+// parseExportFormat приводит название формата к одному из поддерживаемых; пустое — Markdown
+func parseExportFormat(name string) (string, bool) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), ".")) {
+	case "", "md", "markdown":
+		return ExportMarkdown, true
+	case "json":
+		return ExportJSON, true
+	case "html", "htm":
+		return ExportHTML, true
+	}
+	return "", false
+}
+
+// ConversationExport — выгрузка одного разговора
+type ConversationExport struct {
+	ConversationID string        `json:"conversation_id"`
+	Summary        string        `json:"summary,omitempty"`
+	Entries        []ExportEntry `json:"entries"`
+}
+
+// ExportEntry — реплика разговора в выгрузке
+type ExportEntry struct {
+	UserID       int64     `json:"user_id"`
+	Author       string    `json:"author,omitempty"`
+	Message      string    `json:"message"`
+	Response     string    `json:"response"`
+	Tool         string    `json:"tool,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	ModelVersion string    `json:"model_version,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// exportDocument — содержимое файла выгрузки
+type exportDocument struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	Conversations []ConversationExport `json:"conversations"`
+}
+
+// ExportConversation выгружает разговор; false, если разговора нет
+func (a *Agent) ExportConversation(conversationID string) (ConversationExport, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conversation := a.conversationHistory[conversationID]
+	if conversation == nil {
+		return ConversationExport{}, false
+	}
+	return exportConversation(conversationID, conversation), true
+}
+
+// ExportUserConversations выгружает личный разговор пользователя и его ветки в групповых чатах.
+// Общие истории групп не выгружаются: в них есть сообщения других участников.
+func (a *Agent) ExportUserConversations(userID int64) []ConversationExport {
+	private := userConversationID(userID)
+	branch := fmt.Sprintf(":user:%d", userID)
+	return a.exportConversations(func(id string) bool {
+		return id == private || strings.HasSuffix(id, branch)
+	})
+}
+
+// ExportAllConversations выгружает все разговоры
+func (a *Agent) ExportAllConversations() []ConversationExport {
+	return a.exportConversations(func(string) bool { return true })
+}
+
+func (a *Agent) exportConversations(match func(string) bool) []ConversationExport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	exports := make([]ConversationExport, 0)
+	for id, conversation := range a.conversationHistory {
+		if match(id) {
+			exports = append(exports, exportConversation(id, conversation))
+		}
+	}
+	sort.Slice(exports, func(i, j int) bool { return exports[i].ConversationID < exports[j].ConversationID })
+	return exports
+}
+
+// exportConversation копирует разговор; вопрос без ответа еще обрабатывается и не выгружается
+func exportConversation(id string, conversation *Conversation) ConversationExport {
+	export := ConversationExport{ConversationID: id, Summary: conversation.Summary, Entries: make([]ExportEntry, 0, len(conversation.Entries))}
+	for _, entry := range conversation.Entries {
+		if entry.Response == "" {
+			continue
+		}
+		export.Entries = append(export.Entries, ExportEntry{
+			UserID:       entry.UserID,
+			Author:       entry.Author,
+			Message:      entry.Message,
+			Response:     entry.Response,
+			Tool:         entry.Tool,
+			Model:        entry.Model,
+			ModelVersion: entry.ModelVersion,
+			Timestamp:    entry.Timestamp,
+		})
+	}
+	return export
+}
+
+// RenderExport формирует файл выгрузки в указанном формате.
+// Время в Markdown и HTML показывается в поясе loc, подписи — на языке locale.
+func RenderExport(format, locale string, loc *time.Location, conversations []ConversationExport) ([]byte, error) {
+	document := exportDocument{ExportedAt: time.Now(), Conversations: conversations}
+	switch format {
+	case ExportJSON:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("ошибка маршалинга JSON: %v", err)
+		}
+		return buf.Bytes(), nil
+	case ExportMarkdown:
+		return renderExportMarkdown(locale, loc, document), nil
+	case ExportHTML:
+		return renderExportHTML(locale, loc, document)
+	}
+	return nil, fmt.Errorf("неизвестный формат выгрузки %q", format)
+}
+
+// exportFileName возвращает имя файла выгрузки, например chat-123-20240115-1430.md
+func exportFileName(name, format string, now time.Time) string {
+	return fmt.Sprintf("chat-%s-%s.%s", strings.NewReplacer(":", "-").Replace(name), now.Format("20060102-1504"), format)
+}
+
+// exportAuthor возвращает подпись автора реплики
+func exportAuthor(locale string, entry ExportEntry) string {
+	if entry.Author != "" {
+		return entry.Author
+	}
+	return T(locale, "export.user")
+}
+
+// exportMeta возвращает подпись ответа: инструмент, модель и ее версию
+func exportMeta(locale string, entry ExportEntry) string {
+	var parts []string
+	if entry.Tool != "" {
+		parts = append(parts, T(locale, "export.tool", Params{"tool": entry.Tool}))
+	}
+	if model := modelLabel(entry.Model, entry.ModelVersion); model != "" {
+		parts = append(parts, T(locale, "export.model", Params{"model": model}))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// modelLabel возвращает подпись модели вида «yandexgpt/rc (23.10.2024)»
+func modelLabel(model, version string) string {
+	switch {
+	case model != "" && version != "":
+		return model + " (" + version + ")"
+	case model != "":
+		return model
+	}
+	return version
+}
+
+func renderExportMarkdown(locale string, loc *time.Location, document exportDocument) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", T(locale, "export.title"))
+	fmt.Fprintf(&b, "_%s_\n", T(locale, "export.exported", Params{"time": document.ExportedAt.In(loc).Format("2006-01-02 15:04:05 MST")}))
+
+	for _, conversation := range document.Conversations {
+		fmt.Fprintf(&b, "\n## %s\n", conversation.ConversationID)
+		if conversation.Summary != "" {
+			fmt.Fprintf(&b, "\n> **%s** %s\n", T(locale, "export.summary"), strings.ReplaceAll(conversation.Summary, "\n", "\n> "))
+		}
+		if len(conversation.Entries) == 0 {
+			fmt.Fprintf(&b, "\n%s\n", T(locale, "export.empty"))
+		}
+		for _, entry := range conversation.Entries {
+			fmt.Fprintf(&b, "\n### %s\n\n", entry.Timestamp.In(loc).Format("2006-01-02 15:04:05"))
+			fmt.Fprintf(&b, "**%s:** %s\n\n", exportAuthor(locale, entry), entry.Message)
+			fmt.Fprintf(&b, "**%s:** %s\n", T(locale, "export.bot"), entry.Response)
+			if meta := exportMeta(locale, entry); meta != "" {
+				fmt.Fprintf(&b, "\n_%s_\n", meta)
+			}
+		}
+	}
+	return []byte(b.String())
+}
+
+// exportHTMLTemplate — самостоятельная страница без внешних стилей и скриптов
+var exportHTMLTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"lines": func(text string) []string { return strings.Split(text, "\n") },
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 820px; margin: 2em auto; padding: 0 1em; color: #222; background: #fafafa; }
+h1 { font-size: 1.6em; } h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ddd; padding-bottom: .3em; }
+.exported, .meta, time { color: #777; font-size: .85em; }
+.summary { background: #fff8e1; border-left: 4px solid #ffc107; padding: .6em 1em; }
+.turn { margin: 1.2em 0; }
+.message, .response { border-radius: 12px; padding: .6em 1em; margin: .3em 0; }
+.message { background: #e3f2fd; margin-right: 15%; }
+.response { background: #fff; border: 1px solid #e0e0e0; margin-left: 15%; }
+.author { font-weight: 600; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="exported">{{.Exported}}</p>
+{{range .Conversations}}
+<h2>{{.ID}}</h2>
+{{if .Summary}}<p class="summary"><strong>{{$.SummaryLabel}}</strong> {{range $i, $line := lines .Summary}}{{if $i}}<br>{{end}}{{$line}}{{end}}</p>{{end}}
+{{if not .Turns}}<p>{{$.Empty}}</p>{{end}}
+{{range .Turns}}<div class="turn">
+<time>{{.Time}}</time>
+<div class="message"><span class="author">{{.Author}}:</span> {{range $i, $line := lines .Message}}{{if $i}}<br>{{end}}{{$line}}{{end}}</div>
+<div class="response"><span class="author">{{$.Bot}}:</span> {{range $i, $line := lines .Response}}{{if $i}}<br>{{end}}{{$line}}{{end}}</div>
+{{if .Meta}}<div class="meta">{{.Meta}}</div>{{end}}
+</div>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+func renderExportHTML(locale string, loc *time.Location, document exportDocument) ([]byte, error) {
+	type turn struct {
+		Time, Author, Message, Response, Meta string
+	}
+	type conversation struct {
+		ID, Summary string
+		Turns       []turn
+	}
+	page := struct {
+		Lang, Title, Exported, SummaryLabel, Bot, Empty string
+		Conversations                                   []conversation
+	}{
+		Lang:         locale,
+		Title:        T(locale, "export.title"),
+		Exported:     T(locale, "export.exported", Params{"time": document.ExportedAt.In(loc).Format("2006-01-02 15:04:05 MST")}),
+		SummaryLabel: T(locale, "export.summary"),
+		Bot:          T(locale, "export.bot"),
+		Empty:        T(locale, "export.empty"),
+	}
+	for _, c := range document.Conversations {
+		item := conversation{ID: c.ConversationID, Summary: c.Summary}
+		for _, entry := range c.Entries {
+			item.Turns = append(item.Turns, turn{
+				Time:     entry.Timestamp.In(loc).Format("2006-01-02 15:04:05"),
+				Author:   exportAuthor(locale, entry),
+				Message:  entry.Message,
+				Response: entry.Response,
+				Meta:     exportMeta(locale, entry),
+			})
+		}
+		page.Conversations = append(page.Conversations, item)
+	}
+
+	var buf bytes.Buffer
+	if err := exportHTMLTemplate.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("ошибка формирования HTML: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// userTokenValid проверяет токен для действий от имени пользователя userID:
+// HMAC от user_id на секрете WS_AUTH_SECRET (его выдают самому пользователю)
+// или токен администратора ADMIN_API_TOKEN, с которым доверенный бэкенд действует за любого пользователя
+func userTokenValid(token string, userID int64) bool {
+	if token == "" {
+		return false
+	}
+	if secret := os.Getenv("WS_AUTH_SECRET"); secret != "" && hmac.Equal([]byte(token), []byte(WebSocketToken(secret, userID))) {
+		return true
+	}
+	admin := os.Getenv("ADMIN_API_TOKEN")
+	return admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1
+}
+
+// requestToken возвращает токен из заголовка Authorization: Bearer.
+// allowQuery разрешает параметр ?token= — браузер не может задать заголовки для WebSocket.
+func requestToken(r *http.Request, allowQuery bool) string {
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return bearer
+	}
+	if allowQuery {
+		return r.URL.Query().Get("token")
+	}
+	return ""
+}
+
+// authorizeUser проверяет токен и доступ пользователя userID к боту.
+// При отказе отвечает клиенту ошибкой и возвращает false.
+func (s *HTTPServer) authorizeUser(w http.ResponseWriter, r *http.Request, userID int64, allowQuery bool) bool {
+	if os.Getenv("WS_AUTH_SECRET") == "" && os.Getenv("ADMIN_API_TOKEN") == "" {
+		http.Error(w, "API отключено: не задан WS_AUTH_SECRET или ADMIN_API_TOKEN", http.StatusForbidden)
+		return false
+	}
+	if !userTokenValid(requestToken(r, allowQuery), userID) {
+		http.Error(w, "Неверный токен", http.StatusUnauthorized)
+		return false
+	}
+	// HTTP-клиенты проверяются как пользователи в личном чате
+	switch s.agent.Access().Check(userID, userID) {
+	case AccessBlocked:
+		http.Error(w, "Пользователь заблокирован", http.StatusForbidden)
+		return false
+	case AccessDenied:
+		http.Error(w, "Доступ запрещен", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -13,6 +13,7 @@ import (
 type HTTPClient struct {
 	client  *http.Client
 	baseURL string
+	agent   *Agent // Агент для запросов без baseURL; nil — общий агент процесса
 }
 
 // NewHTTPClient создает новый HTTP клиент
@@ -25,10 +26,15 @@ func NewHTTPClient(baseURL string) *HTTPClient {
 	}
 }
 
-// Request представляет структуру запроса
+// Request представляет структуру запроса.
+// ConversationID и Author задают только вызовы внутри процесса (бот Telegram):
+// из тела HTTP-запроса они не читаются, иначе клиент мог бы писать в чужие разговоры.
 type Request struct {
-	Message string `json:"message"`
-	UserID  int64  `json:"user_id"`
+	Message        string `json:"message"`
+	UserID         int64  `json:"user_id"`
+	ConversationID string `json:"-"`
+	Author         string `json:"-"`
+	Locale         string `json:"locale,omitempty"`
 }
 
 // Response представляет структуру ответа
@@ -40,11 +46,14 @@ type Response struct {
 
 // SendRequest отправляет запрос к внешнему API
 func (c *HTTPClient) SendRequest(message string, userID int64) (*Response, error) {
-	req := Request{
+	return c.Send(Request{
 		Message: message,
 		UserID:  userID,
-	}
+	})
+}
 
+// Send отправляет запрос с указанием истории разговора
+func (c *HTTPClient) Send(req Request) (*Response, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка маршалинга JSON: %v", err)
@@ -52,7 +61,7 @@ func (c *HTTPClient) SendRequest(message string, userID int64) (*Response, error
 
 	// Если baseURL не установлен, используем встроенный агент
 	if c.baseURL == "" {
-		return c.processWithBuiltinAgent(message, userID)
+		return c.processWithBuiltinAgent(req)
 	}
 
 	resp, err := c.client.Post(c.baseURL+"/chat", "application/json", bytes.NewBuffer(jsonData))
@@ -75,13 +84,21 @@ func (c *HTTPClient) SendRequest(message string, userID int64) (*Response, error
 }
 
 // processWithBuiltinAgent обрабатывает запрос с помощью встроенного агента
-func (c *HTTPClient) processWithBuiltinAgent(message string, userID int64) (*Response, error) {
-	// Создаем агента с YandexGPT если доступен
-	agent := createAgent()
-	answer, err := agent.ProcessMessage(message, userID)
+func (c *HTTPClient) processWithBuiltinAgent(req Request) (*Response, error) {
+	// Используем общего агента (с YandexGPT, если доступен)
+	agent := c.agent
+	if agent == nil {
+		agent = sharedAgent()
+	}
+	answer, err := agent.ProcessMessageInContext(MessageContext{
+		UserID:         req.UserID,
+		ConversationID: req.ConversationID,
+		Author:         req.Author,
+		Locale:         req.Locale,
+	}, req.Message)
 	if err != nil {
 		return &Response{
-			Answer: "Извините, произошла ошибка при обработке вашего запроса.",
+			Answer: T(req.Locale, "error.generic"),
 			Status: "error",
 			Error:  err.Error(),
 		}, nil
@@ -1,22 +1,30 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // HTTPServer представляет HTTP сервер для API
 type HTTPServer struct {
 	port       string
+	agent      *Agent
 	httpClient *HTTPClient
+	channels   *ChannelHub // Вебхуки каналов: /channels/<имя>
 }
 
 // NewHTTPServer создает новый HTTP сервер
 func NewHTTPServer(port string) *HTTPServer {
 	return &HTTPServer{
 		port:       port,
+		agent:      sharedAgent(),
 		httpClient: NewHTTPClient(""), // Используем агента с Yandex GPT
 	}
 }
@@ -25,7 +33,15 @@ func NewHTTPServer(port string) *HTTPServer {
 func (s *HTTPServer) Start() error {
 	http.HandleFunc("/chat", s.handleChat)
 	http.HandleFunc("/health", s.handleHealth)
-	http.HandleFunc("/", s.handleRoot)
+	http.HandleFunc("/settings", s.handleSettings)
+	http.HandleFunc("/metrics", s.handleMetrics)
+	http.HandleFunc("/export", s.handleExport)
+	http.HandleFunc("/ws", s.handleWebSocket)
+	if s.channels != nil {
+		http.Handle("/channels/", s.channels)
+	}
+	http.HandleFunc("/api", s.handleAPIDocs)
+	http.Handle("/", webUIHandler())
 
 	log.Printf("HTTP сервер запущен на порту %s", s.port)
 	return http.ListenAndServe(":"+s.port, nil)
@@ -43,9 +59,17 @@ func (s *HTTPServer) handleChat(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Неверный JSON", http.StatusBadRequest)
 		return
 	}
+	if req.Locale == "" {
+		req.Locale = requestLocale(r)
+	}
+	// Клиент HTTP API пишет только в личный разговор пользователя
+	req.ConversationID = userConversationID(req.UserID)
+	if !s.authorizeUser(w, r, req.UserID, false) {
+		return
+	}
 
 	// Обрабатываем запрос через HTTP клиент
-	response, err := s.httpClient.SendRequest(req.Message, req.UserID)
+	response, err := s.httpClient.Send(req)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Ошибка обработки: %v", err), http.StatusInternalServerError)
 		return
@@ -55,6 +79,153 @@ func (s *HTTPServer) handleChat(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// SettingsRequest представляет запрос на изменение настроек; пустые поля не меняются
+type SettingsRequest struct {
+	UserID       int64  `json:"user_id"`
+	Persona      string `json:"persona,omitempty"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	Length       string `json:"length,omitempty"`
+	Language     string `json:"language,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Creativity   string `json:"creativity,omitempty"`
+	Timezone     string `json:"timezone,omitempty"`
+	Reset        bool   `json:"reset,omitempty"`
+	ClearPrompt  bool   `json:"clear_system_prompt,omitempty"` // Удалить свой промпт: пустой system_prompt означает «не менять»
+}
+
+// SettingsResponse представляет настройки пользователя
+type SettingsResponse struct {
+	UserID   int64         `json:"user_id"`
+	Settings UserSettings  `json:"settings"`
+	Fields   []SettingInfo `json:"fields,omitempty"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// SettingInfo описывает настройку и ее допустимые значения с подписями на языке клиента
+type SettingInfo struct {
+	Key     string          `json:"key"`
+	Label   string          `json:"label"`
+	Options []SettingOption `json:"options"`
+}
+
+// SettingOption — допустимое значение настройки
+type SettingOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// settingInfos возвращает описание настроек для формы, например в веб-чате
+func settingInfos(locale string) []SettingInfo {
+	infos := make([]SettingInfo, 0, len(settingFields))
+	for _, field := range settingFields {
+		info := SettingInfo{Key: field.Key, Label: T(locale, "settings.field."+field.Key)}
+		for _, option := range field.Options {
+			info.Options = append(info.Options, SettingOption{Value: option, Label: T(locale, "settings.option."+field.Key+"."+option)})
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// handleSettings обрабатывает запросы к /settings: GET ?user_id=N читает настройки, POST/PUT изменяет
+func (s *HTTPServer) handleSettings(w http.ResponseWriter, r *http.Request) {
+	store := s.agent.Settings()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		userID, err := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Неверный user_id", http.StatusBadRequest)
+			return
+		}
+		if !s.authorizeUser(w, r, userID, false) {
+			return
+		}
+		json.NewEncoder(w).Encode(SettingsResponse{
+			UserID:   userID,
+			Settings: store.Get(userID),
+			Fields:   settingInfos(requestLocale(r)),
+			Status:   "success",
+		})
+
+	case http.MethodPost, http.MethodPut:
+		var req SettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Неверный JSON", http.StatusBadRequest)
+			return
+		}
+		if !s.authorizeUser(w, r, req.UserID, false) {
+			return
+		}
+
+		settings, err := store.Update(req.UserID, func(settings *UserSettings) error {
+			if req.Reset {
+				*settings = DefaultUserSettings()
+			}
+			if req.ClearPrompt {
+				settings.ClearSystemPrompt()
+			}
+			if req.SystemPrompt != "" {
+				if err := settings.SetSystemPrompt(req.SystemPrompt); err != nil {
+					return err
+				}
+			}
+			if req.Timezone != "" {
+				if err := settings.SetTimezone(req.Timezone); err != nil {
+					return err
+				}
+			}
+			for key, value := range map[string]string{
+				"persona":    req.Persona,
+				"length":     req.Length,
+				"language":   req.Language,
+				"model":      req.Model,
+				"creativity": req.Creativity,
+			} {
+				if value == "" {
+					continue
+				}
+				if err := settings.Set(key, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(SettingsResponse{
+				UserID:   req.UserID,
+				Settings: store.Get(req.UserID),
+				Status:   "error",
+				Error:    localizeError(requestLocale(r), err),
+			})
+			return
+		}
+
+		json.NewEncoder(w).Encode(SettingsResponse{
+			UserID:   req.UserID,
+			Settings: settings,
+			Status:   "success",
+		})
+
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// requestLocale определяет язык клиента по заголовку Accept-Language
+func requestLocale(r *http.Request) string {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return ""
+	}
+	// Берем первый язык из списка вида "en-US,en;q=0.9,ru;q=0.8"
+	tag := strings.TrimSpace(strings.SplitN(strings.SplitN(header, ",", 2)[0], ";", 2)[0])
+	return normalizeLocale(tag)
+}
+
 // handleHealth обрабатывает запросы к /health
 func (s *HTTPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -64,8 +235,107 @@ func (s *HTTPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleRoot обрабатывает запросы к корневому пути
-func (s *HTTPServer) handleRoot(w http.ResponseWriter, r *http.Request) {
+// handleMetrics отдает счетчики агента в текстовом формате Prometheus
+func (s *HTTPServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics := s.agent.Metrics()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	for _, metric := range []struct {
+		name, help, kind string
+		value            int64
+	}{
+		{"chatagent_messages_total", "Обработанные сообщения", "counter", metrics.Messages.Load()},
+		{"chatagent_errors_total", "Ошибки модели и обработки", "counter", metrics.Errors.Load()},
+		{"chatagent_llm_requests_total", "Успешные запросы к модели", "counter", metrics.LLMRequests.Load()},
+		{"chatagent_input_tokens_total", "Токены запросов к модели", "counter", metrics.InputTokens.Load()},
+		{"chatagent_completion_tokens_total", "Токены ответов модели", "counter", metrics.CompletionTokens.Load()},
+		{"chatagent_response_cache_hits_total", "Ответы из кэша без обращения к модели", "counter", metrics.CacheHits.Load()},
+		{"chatagent_response_cache_misses_total", "Кэшируемые запросы, отправленные модели", "counter", metrics.CacheMisses.Load()},
+		{"chatagent_moderation_blocked_total", "Сообщения и ответы, заблокированные модерацией", "counter", metrics.ModerationBlocked.Load()},
+		{"chatagent_moderation_flagged_total", "Нарушения с предупреждением или записью в журнал", "counter", metrics.ModerationFlagged.Load()},
+		{"chatagent_response_cache_entries", "Ответы в кэше", "gauge", int64(s.agent.ResponseCache().Len())},
+		{"chatagent_uptime_seconds", "Время работы", "gauge", int64(metrics.Uptime().Seconds())},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+	}
+
+	// Расход по моделям и решения маршрутизации — с метками
+	models := metrics.Models()
+	for _, metric := range []struct {
+		name, help string
+		value      func(ModelUsage) int64
+	}{
+		{"chatagent_model_requests_total", "Успешные запросы к модели по моделям", func(u ModelUsage) int64 { return u.Requests }},
+		{"chatagent_model_input_tokens_total", "Токены запросов по моделям", func(u ModelUsage) int64 { return u.InputTokens }},
+		{"chatagent_model_completion_tokens_total", "Токены ответов по моделям", func(u ModelUsage) int64 { return u.CompletionTokens }},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		for _, usage := range models {
+			fmt.Fprintf(w, "%s{model=%q} %d\n", metric.name, usage.Model, metric.value(usage))
+		}
+	}
+	fmt.Fprintf(w, "# HELP chatagent_model_routes_total Выбор модели маршрутизацией\n# TYPE chatagent_model_routes_total counter\n")
+	for _, route := range metrics.Routes() {
+		fmt.Fprintf(w, "chatagent_model_routes_total{tier=%q,reason=%q} %d\n", route.Tier, route.Reason, route.Count)
+	}
+}
+
+// handleExport выгружает разговоры одного пользователя (?user_id=N) или всех.
+// Формат задается параметром format: json (по умолчанию), md или html.
+// Доступ только с токеном администратора ADMIN_API_TOKEN в заголовке Authorization: Bearer.
+func (s *HTTPServer) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	token := os.Getenv("ADMIN_API_TOKEN")
+	if token == "" {
+		http.Error(w, "Выгрузка отключена: не задан ADMIN_API_TOKEN", http.StatusForbidden)
+		return
+	}
+	provided, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		http.Error(w, "Требуется токен администратора", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ExportJSON
+	}
+	format, ok := parseExportFormat(format)
+	if !ok {
+		http.Error(w, "Неизвестный формат: json, md или html", http.StatusBadRequest)
+		return
+	}
+
+	var conversations []ConversationExport
+	name := "all"
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		userID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			http.Error(w, "Неверный user_id", http.StatusBadRequest)
+			return
+		}
+		conversations = s.agent.ExportUserConversations(userID)
+		name = value
+	} else {
+		conversations = s.agent.ExportAllConversations()
+	}
+
+	data, err := RenderExport(format, requestLocale(r), time.UTC, conversations)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка выгрузки: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Выгрузка разговоров (%s, %s): %d", name, format, len(conversations))
+	w.Header().Set("Content-Type", exportFormats[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFileName(name, format, time.Now())))
+	w.Write(data)
+}
+
+// handleAPIDocs отдает страницу с документацией API
+func (s *HTTPServer) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 	fmt.Fprintf(w, `
 <!DOCTYPE html>
@@ -80,8 +350,15 @@ func (s *HTTPServer) handleRoot(w http.ResponseWriter, r *http.Request) {
     
     <h2>Доступные эндпоинты:</h2>
     <ul>
-        <li><strong>POST /chat</strong> - Отправить сообщение агенту</li>
+        <li><strong>GET /</strong> - Веб-чат</li>
+        <li><strong>POST /chat</strong> - Отправить сообщение агенту (заголовок Authorization: Bearer &lt;токен&gt;)</li>
         <li><strong>GET /health</strong> - Проверка состояния сервиса</li>
+        <li><strong>GET /settings?user_id=N</strong> - Настройки пользователя</li>
+        <li><strong>POST /settings</strong> - Изменить настройки пользователя</li>
+        <li><strong>GET /metrics</strong> - Метрики в формате Prometheus</li>
+        <li><strong>GET /export?user_id=N&amp;format=json</strong> - Выгрузка разговоров (для администраторов)</li>
+        <li><strong>GET /ws?user_id=N&amp;token=...</strong> - Чат через WebSocket</li>
+        <li><strong>POST /channels/webhook, /channels/mattermost</strong> - Сообщения из подключенных каналов</li>
     </ul>
     
     <h2>Пример запроса к /chat:</h2>
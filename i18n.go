@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Поддерживаемые локали
+const (
+	LocaleRU = "ru"
+	LocaleEN = "en"
+)
+
+// defaultLocale используется, если язык пользователя не поддерживается
+const defaultLocale = LocaleRU
+
+// Params задает значения параметров вида {name} в строках каталога
+type Params map[string]interface{}
+
+// catalog содержит строки интерфейса для каждой локали
+var catalog = map[string]map[string]string{
+	LocaleRU: messagesRU,
+	LocaleEN: messagesEN,
+}
+
+// pluralSuffixes перечисляет формы, которые должен содержать ключ множественного числа в каждой локали
+var pluralSuffixes = map[string][]string{
+	LocaleRU: {"one", "few", "many"},
+	LocaleEN: {"one", "other"},
+}
+
+// normalizeLocale приводит код языка Telegram (ru, en-US, uk) к поддерживаемой локали
+func normalizeLocale(code string) string {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i != -1 {
+		code = code[:i]
+	}
+
+	switch code {
+	case LocaleRU, "uk", "be", "kk":
+		return LocaleRU
+	case LocaleEN:
+		return LocaleEN
+	case "":
+		return defaultLocale
+	}
+	// Для остальных языков английский понятнее, чем русский
+	return LocaleEN
+}
+
+// T возвращает строку каталога с подставленными параметрами.
+// Если ключа нет в локали, используется локаль по умолчанию, а затем сам ключ.
+func T(locale, key string, params ...Params) string {
+	text, ok := lookupMessage(locale, key)
+	if !ok {
+		return key
+	}
+	if len(params) > 0 {
+		text = applyParams(text, params[0])
+	}
+	return text
+}
+
+// TN возвращает строку с учетом формы множественного числа для n.
+// Параметр {n} подставляется автоматически.
+func TN(locale, key string, n int, params ...Params) string {
+	merged := Params{"n": n}
+	if len(params) > 0 {
+		for name, value := range params[0] {
+			merged[name] = value
+		}
+	}
+	return T(locale, key+"."+pluralForm(locale, n), merged)
+}
+
+// lookupMessage ищет строку в локали с откатом на локаль по умолчанию
+func lookupMessage(locale, key string) (string, bool) {
+	if text, ok := catalog[normalizeLocale(locale)][key]; ok {
+		return text, true
+	}
+	text, ok := catalog[defaultLocale][key]
+	return text, ok
+}
+
+// pluralForm выбирает форму множественного числа по правилам CLDR
+func pluralForm(locale string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+
+	switch normalizeLocale(locale) {
+	case LocaleRU:
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
+
+// applyParams подставляет параметры вида {name}
+func applyParams(text string, params Params) string {
+	if len(params) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// LocalizedError — ошибка, текст которой для пользователя берется из каталога
+type LocalizedError struct {
+	Key    string
+	Params Params
+}
+
+// newLocalizedError создает ошибку с ключом каталога
+func newLocalizedError(key string, params ...Params) *LocalizedError {
+	err := &LocalizedError{Key: key}
+	if len(params) > 0 {
+		err.Params = params[0]
+	}
+	return err
+}
+
+// Error возвращает текст ошибки в локали по умолчанию (для логов)
+func (e *LocalizedError) Error() string {
+	return T(defaultLocale, e.Key, e.Params)
+}
+
+// Localize возвращает текст ошибки в нужной локали
+func (e *LocalizedError) Localize(locale string) string {
+	return T(locale, e.Key, e.Params)
+}
+
+// localizeError возвращает текст любой ошибки для пользователя
+func localizeError(locale string, err error) string {
+	if localized, ok := err.(*LocalizedError); ok {
+		return localized.Localize(locale)
+	}
+	return err.Error()
+}
+
+// missingTranslations возвращает ключи, которых не хватает в каждой локали.
+// Ключ множественного числа должен содержать все формы своей локали.
+func missingTranslations() map[string][]string {
+	keys := make(map[string]bool)
+	for _, messages := range catalog {
+		for key := range messages {
+			keys[pluralBase(key)] = true
+		}
+	}
+
+	missing := make(map[string][]string)
+	for locale, messages := range catalog {
+		for key := range keys {
+			if isPluralKey(key) {
+				for _, suffix := range pluralSuffixes[locale] {
+					if _, ok := messages[key+"."+suffix]; !ok {
+						missing[locale] = append(missing[locale], key+"."+suffix)
+					}
+				}
+				continue
+			}
+			if _, ok := messages[key]; !ok {
+				missing[locale] = append(missing[locale], key)
+			}
+		}
+		sort.Strings(missing[locale])
+	}
+
+	for locale, keys := range missing {
+		if len(keys) == 0 {
+			delete(missing, locale)
+		}
+	}
+	return missing
+}
+
+// pluralCategories перечисляет все формы множественного числа из всех локалей
+var pluralCategories = []string{"one", "few", "many", "other"}
+
+// pluralBase отрезает от ключа суффикс формы множественного числа
+func pluralBase(key string) string {
+	for _, suffix := range pluralCategories {
+		if strings.HasSuffix(key, "."+suffix) {
+			return strings.TrimSuffix(key, "."+suffix)
+		}
+	}
+	return key
+}
+
+// isPluralKey проверяет, что ключ задан формами множественного числа
+func isPluralKey(base string) bool {
+	for _, messages := range catalog {
+		for _, suffix := range pluralCategories {
+			if _, ok := messages[base+"."+suffix]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,346 @@
+package main
+
+// messagesEN — строки интерфейса на английском языке
+var messagesEN = map[string]string{
+	// Common
+	"error.generic":   "Sorry, something went wrong while processing your request.",
+	"error.request":   "❌ Sorry, something went wrong while processing your request.",
+	"format.date":     "Jan 2, 2006",
+	"format.datetime": "Jan 2, 2006 15:04",
+
+	// Telegram commands
+	"start.text": "🤖 Hi! I'm your personal assistant agent.\n\n" +
+		"I can help with:\n" +
+		"• Weather information\n" +
+		"• The current time\n" +
+		"• Math calculations\n" +
+		"• And much more!\n\n" +
+		"Just send me a question or use /help to see the list of commands.",
+	"calculate.usage": "🧮 To calculate, send an expression, for example:\n" +
+		"• 2 + 3\n" +
+		"• 10 - 5\n" +
+		"• 4 * 6\n" +
+		"• 15 / 3",
+	"command.unknown": "❓ Unknown command. Use /help to see the available commands.",
+
+	"keyboard.weather":   "🌤️ Weather",
+	"keyboard.time":      "🕐 Time",
+	"keyboard.calculate": "🧮 Calculate",
+	"keyboard.help":      "❓ Help",
+
+	// Agent tools
+	"tool.weather.description":   "Get weather information",
+	"tool.time.description":      "Get the current time",
+	"tool.calculate.description": "Perform math calculations",
+	"tool.convert.description":   "Convert a quantity to other units (length, mass, temperature, volume, data, speed) or an amount to another currency at the Bank of Russia rate",
+	"tool.remind.description":    "Set a reminder",
+	"tool.notes.description":     "User notes: save, list, delete or search notes",
+	"tool.todo.description":      "User to-do list: add a task with tags and a due date, list, complete, delete or search tasks",
+	"tool.help.description":      "Show available commands",
+
+	"tool.weather.answer":    "🌤️ Unfortunately, I'm not connected to a weather service yet. But it looks like a great day for a walk!",
+	"tool.time.answer":       "🕐 Current time: {time}\n📅 Date: {date}, {weekday}\n🌍 Time zone: {zone}",
+	"tool.calculate.usage":   "🧮 To calculate, use the /calculate command or write 'calculate 2+2'",
+	"tool.calculate.error":   "🧮 Could not calculate `{expression}`: {error}",
+	"tool.calculate.result":  "🧮 `{expression}` = {result}",
+	"calc.error.div_by_zero": "division by zero",
+	"calc.error.unexpected":  "unexpected character “{char}”",
+	"calc.error.end":         "unexpected end of expression",
+	"calc.error.paren":       "missing closing parenthesis",
+	"calc.error.number":      "invalid number “{number}”",
+	"calc.error.undefined":   "the result is undefined",
+
+	"help.text": "🤖 *Available commands:*\n\n" +
+		"/start - Start using the bot\n" +
+		"/help - Show this message\n" +
+		"/weather - Weather information\n" +
+		"/time - Current time\n" +
+		"/calculate - Math calculations\n" +
+		"/convert - Unit and currency conversion\n" +
+		"/docs - Uploaded documents\n" +
+		"/settings - Personal settings\n" +
+		"/reminders - Reminders\n" +
+		"/notes - Notes\n" +
+		"/todo - To-do list\n" +
+		"/timezone - Time zone\n" +
+		"/export - Export the conversation history\n\n" +
+		"*Example questions:*\n" +
+		"• \"What's the weather?\"\n" +
+		"• \"What time is it?\"\n" +
+		"• \"What time is it in Tokyo?\"\n" +
+		"• \"What day will it be in 45 days?\"\n" +
+		"• \"Calculate 2+2\"\n" +
+		"• \"5 miles to km\"\n" +
+		"• \"100 USD to RUB\"\n" +
+		"• \"Remind me in an hour to call mom\"\n" +
+		"• \"Help\"\n\n" +
+		"📄 Send a .txt, .md, .pdf or .docx file and ask questions about it.\n\n" +
+		"I'm ready to help! 😊",
+
+	"general.0": "Interesting question! Could you clarify what exactly you are interested in?",
+	"general.1": "I understand your question. I can help with weather, time, calculations and other tasks.",
+	"general.2": "Okay! For a more precise answer use the commands: /help - list of commands, /weather - weather, /time - time.",
+	"general.3": "I'm ready to help! Try asking about the weather or the time, or ask me to calculate something.",
+
+	"documents.excerpt": "📄 From the document “{name}”:\n\n{text}",
+	"knowledge.sources": "📚 Sources:",
+
+	// Inline mode
+	"inline.title.calculate": "🧮 Calculator",
+	"inline.title.weather":   "🌤️ Weather",
+	"inline.title.time":      "🕐 Time",
+	"inline.title.help":      "❓ Help",
+	"inline.title.llm":       "🤖 Assistant answer",
+	"inline.private_only":    "🔒 Reminders, notes and tasks are available only in a private chat with the bot.",
+
+	// Voice messages
+	"voice.disabled":           "🎤 Voice message recognition is not configured.",
+	"voice.too_long":           "🎤 The message is too long. I can recognize recordings up to {seconds} seconds.",
+	"voice.download_failed":    "❌ Could not download the voice message.",
+	"voice.recognize_failed":   "❌ Could not recognize the voice message.",
+	"voice.empty":              "🎤 I couldn't make out the speech. Please try recording the message again.",
+	"voice.transcript":         "🎤 “{transcript}”\n\n{answer}",
+	"voice.unsupported_format": "🎤 The {format} format is not supported, please send a voice message.",
+
+	// Documents
+	"docs.unsupported":      "📄 The file format of “{name}” is not supported. I can read .txt, .md, .pdf and .docx.",
+	"docs.too_large":        "📄 The file “{name}” is too large. The maximum size is {mb} MB.",
+	"docs.download_failed":  "❌ Could not download the file.",
+	"docs.read_failed":      "📄 Could not read “{name}”: {error}",
+	"docs.uploaded":         "📄 The document “{name}” is uploaded ({chars}). Ask me questions about it!\nList of documents: /docs",
+	"docs.chars.one":        "{n} character",
+	"docs.chars.other":      "{n} characters",
+	"docs.cleared.one":      "🗑️ Deleted {n} document",
+	"docs.cleared.other":    "🗑️ Deleted {n} documents",
+	"docs.delete_usage":     "Specify the document number, for example: /docs delete 1",
+	"docs.delete_not_num":   "The document number must be a number, for example: /docs delete 1",
+	"docs.deleted":          "🗑️ The document “{name}” is deleted",
+	"docs.empty":            "📚 No documents yet. Send a .txt, .md, .pdf or .docx file and I'll answer questions about it.",
+	"docs.list_title":       "📚 *Uploaded documents:*",
+	"docs.list_item":        "{index}. {name} — {chars}, uploaded {date}",
+	"docs.list_footer":      "Delete a document: /docs delete N\nDelete all: /docs clear",
+	"docs.error.no_text":    "no text found in the document",
+	"docs.error.limit":      "you can upload at most {max} documents, delete some via /docs",
+	"docs.error.not_found":  "there is no document number {number}",
+	"extract.error.format":  "the {ext} file format is not supported",
+	"extract.error.utf8":    "the text file must be UTF-8 encoded",
+	"extract.error.docx":    "the .docx file is corrupted",
+	"extract.error.no_body": "the .docx file has no main document",
+	"extract.error.not_pdf": "the file does not look like a PDF",
+	"extract.error.pdf":     "could not extract text from the PDF (it may be a scan or use non-standard fonts)",
+	"extract.error.pdf_cid": "PDFs with composite (CID) fonts are not supported: save the document as .docx or .txt",
+
+	// Settings
+	"settings.title":               "⚙️ *Assistant settings*\n\nTap a setting to change it.",
+	"settings.custom_prompt":       "*Custom prompt:* {prompt}",
+	"settings.choose_value":        "{title}\n\nChoose a value:",
+	"settings.custom_prompt_hint":  "Set a custom prompt with `/settings prompt <text>`",
+	"settings.prompt_usage":        "Example: /settings prompt You are a lawyer, cite the relevant laws.",
+	"settings.prompt_saved":        "✅ Your custom system prompt is saved and enabled.",
+	"settings.foreign_menu":        "This settings menu belongs to another user. Open your own: /settings",
+	"settings.saved":               "✅ Saved",
+	"settings.reset":               "🔄 Settings reset",
+	"settings.button.reset":        "🔄 Reset",
+	"settings.button.close":        "✖️ Close",
+	"settings.button.back":         "⬅️ Back",
+	"settings.error.unknown":       "unknown setting “{key}”",
+	"settings.error.invalid":       "invalid value “{value}” for the setting “{key}”",
+	"settings.error.prompt_empty":  "the prompt cannot be empty",
+	"settings.error.prompt_length": "the prompt is longer than {max} characters",
+
+	"settings.field.persona":    "🎭 Persona",
+	"settings.field.length":     "📏 Answer length",
+	"settings.field.language":   "🌐 Language",
+	"settings.field.model":      "🧠 Model",
+	"settings.field.creativity": "🎨 Creativity",
+
+	"settings.option.persona.default":    "Default",
+	"settings.option.persona.assistant":  "Assistant",
+	"settings.option.persona.teacher":    "Teacher",
+	"settings.option.persona.programmer": "Programmer",
+	"settings.option.persona.friend":     "Friend",
+	"settings.option.persona.custom":     "Custom prompt",
+	"settings.option.length.normal":      "Normal",
+	"settings.option.length.short":       "Short",
+	"settings.option.length.long":        "Detailed",
+	"settings.option.language.auto":      "Same as question",
+	"settings.option.language.ru":        "Русский",
+	"settings.option.language.en":        "English",
+	"settings.option.model.auto":         "Automatic",
+	"settings.option.model.lite":         "YandexGPT Lite",
+	"settings.option.model.pro":          "YandexGPT Pro",
+	"settings.option.creativity.medium":  "Medium",
+	"settings.option.creativity.low":     "Low",
+	"settings.option.creativity.high":    "High",
+
+	// Admin commands
+	"admin.stats": "📊 *Statistics*\n\n" +
+		"Users: {users}\n" +
+		"Access mode: {mode}, allowed: {allowed}, blocked: {blocked}\n" +
+		"Messages: {messages}\n" +
+		"Model requests: {requests}\n" +
+		"Tokens: {input} in, {output} out\n" +
+		"Response cache: {hits} hits, {misses} misses, {cached} stored\n" +
+		"Errors: {errors}\n" +
+		"Moderation: {moderated} blocked, {flagged} flagged\n" +
+		"Model backend: {provider}\n" +
+		"Uptime: {uptime}",
+	"admin.broadcast.usage":    "Write the broadcast text after the command: /broadcast Message text",
+	"admin.broadcast.progress": "📣 Broadcast: {sent} of {total}",
+	"admin.broadcast.done":     "📣 Broadcast finished: delivered {sent} of {total}, {failed} failed",
+	"admin.ban.usage":          "Specify a user or chat ID, or reply to the user's message with the command, for example: /ban 12345",
+	"admin.ban.admin":          "An administrator cannot be banned.",
+	"admin.ban.done":           "⛔ Blocked: {id}",
+	"admin.unban.done":         "✅ Unblocked: {id}",
+	"admin.reload.done":        "🔄 Configuration reloaded. Administrators: {admins}, access mode: {mode}, model backend: {provider}",
+	"admin.reload.failed":      "❌ Could not reload the configuration: {error}",
+	"admin.provider.current":   "🧠 Model backend: {provider}\nAvailable: {providers}\nSwitch: /provider <name>",
+	"admin.provider.switched":  "🧠 Model backend switched to {provider}",
+	"provider.error.unknown":   "unknown model backend “{name}”",
+	"admin.allow.usage":        "Specify a user or chat ID (group IDs are negative), for example: /allow 12345",
+	"admin.allow.done":         "✅ Added to the access list: {id}",
+	"admin.deny.done":          "🚫 Removed from the access list: {id}",
+	"admin.invite.usage":       "Specify the number of activations, for example: /invite 5",
+	"admin.invite.created":     "🎟️ Invite code: `{code}` (activations: {uses})\nPersonal access: {link}\nFor a group: {group_link}",
+	"admin.invite.mode_hint":   "⚠️ Codes are accepted only with ACCESS_MODE=invite.",
+
+	// Access
+	"access.denied.allowlist":       "🔒 The bot is available only to users on the access list. To be added, send your ID to the administrator: `{id}`",
+	"access.denied.invite":          "🔒 You need an invitation to use the bot. Open the invite link or send /start <code>. Your ID: `{id}`",
+	"access.denied.group":           "🔒 The bot is not enabled in this chat. Chat ID: `{id}`",
+	"access.hint.group_invite":      "To enable the bot, send /start <invite code> to the chat.",
+	"access.granted":                "✅ Invitation accepted, access granted!",
+	"access.granted_group":          "✅ Invitation accepted, the bot is enabled in this chat!",
+	"access.error.invalid_code":     "the invite code is invalid or already used",
+	"access.error.invites_disabled": "invite codes are not accepted right now",
+
+	// Reminders
+	"remind.usage": "⏰ Reminder examples:\n" +
+		"• remind me in 20 minutes to call mom\n" +
+		"• remind me tomorrow at 9:00 about the meeting\n" +
+		"• remind me on December 25 to buy presents\n" +
+		"• remind me every Monday at 10 about the standup\n" +
+		"• remind me on weekdays at 8:30 to exercise\n" +
+		"• remind me every 2 hours to drink water",
+	"remind.created":           "⏰ I'll remind you on {when}: {text}\nYour reminders: /reminders",
+	"remind.created_recurring": "⏰ I'll remind you {schedule}: {text}\nFirst time: {when}\nYour reminders: /reminders",
+	"remind.delivery":          "⏰ Reminder: {text}",
+	"remind.error.no_text":     "I didn't get what to remind you about",
+	"remind.error.no_time":     "I didn't get when to remind you",
+	"remind.error.past":        "that time has already passed",
+	"remind.error.limit":       "you can have at most {max} reminders, cancel some with /reminders",
+	"remind.error.not_found":   "there is no reminder number {number}",
+	"remind.schedule.daily":    "every day at {time}",
+	"remind.schedule.weekdays": "on weekdays at {time}",
+	"remind.schedule.weekly":   "every {day} at {time}",
+	"remind.schedule.interval": "every {interval}",
+	"remind.weekday.0":         "Sunday",
+	"remind.weekday.1":         "Monday",
+	"remind.weekday.2":         "Tuesday",
+	"remind.weekday.3":         "Wednesday",
+	"remind.weekday.4":         "Thursday",
+	"remind.weekday.5":         "Friday",
+	"remind.weekday.6":         "Saturday",
+	"duration.minutes.one":     "{n} minute",
+	"duration.minutes.other":   "{n} minutes",
+	"duration.hours.one":       "{n} hour",
+	"duration.hours.other":     "{n} hours",
+	"duration.days.one":        "{n} day",
+	"duration.days.other":      "{n} days",
+	"reminders.empty":          "⏰ No reminders yet. Try, for example: “remind me in 20 minutes to call mom”.",
+	"reminders.list_title":     "⏰ *Your reminders:*",
+	"reminders.list_item":      "{index}. {text} — {when}",
+	"reminders.list_footer":    "Cancel a reminder: /reminders cancel N",
+	"reminders.cancel_usage":   "Specify the reminder number, for example: /reminders cancel 1",
+	"reminders.cancelled":      "🗑️ Reminder “{text}” cancelled",
+
+	// Notes and to-do list
+	"tool.error.unknown":    "tool “{name}” is not available",
+	"notes.added":           "📝 Note saved:\n{note}",
+	"todo.added":            "📋 Task added:\n{note}",
+	"notes.empty":           "📝 No notes yet. Write “note: door code 1234” or /notes <text>.",
+	"todo.empty":            "📋 Your to-do list is empty. Add a task: /todo buy milk #home due friday",
+	"notes.list_title":      "📝 Notes:",
+	"todo.list_title":       "📋 To-do list:",
+	"notes.search_title":    "🔎 Found for “{query}”:",
+	"notes.search_empty":    "🔎 Nothing found for “{query}”",
+	"todo.completed":        "✅ Task “{text}” completed",
+	"notes.deleted":         "🗑️ Deleted: {text}",
+	"notes.due":             "due {date}",
+	"notes.overdue":         "⚠️ overdue, was due {date}",
+	"notes.error.empty":     "the text cannot be empty",
+	"notes.error.limit":     "you can keep at most {max} entries, delete some first",
+	"notes.error.not_found": "there is no entry number {id}",
+	"notes.error.action":    "unknown action “{action}”",
+	"notes.error.due":       "I didn't understand the due date “{due}”",
+	"notes.usage": "📝 Notes:\n" +
+		"/notes — list\n" +
+		"/notes <text> #tag — save a note\n" +
+		"/notes delete N — delete\n" +
+		"/notes search <query or #tag> — search",
+	"todo.usage": "📋 To-do list:\n" +
+		"/todo — open tasks\n" +
+		"/todo all — all tasks including completed\n" +
+		"/todo <task> #tag due <date> — add\n" +
+		"/todo done N — mark as done\n" +
+		"/todo delete N — delete\n" +
+		"/todo search <query or #tag> — search",
+
+	// Time zones
+	"tool.time.zone_hint":      "🌍 You can set your time zone with /timezone or by sharing your location.",
+	"time.world":               "🕐 {place}: {time}, {weekday} {date} — {zone}, {diff}",
+	"time.convert":             "🕐 {from_place}: {from_time}\n🕐 {to_place}: {to_time}",
+	"time.place.you":           "Your time",
+	"time.shift.future":        "📅 In {shift}: {weekday}, {date}",
+	"time.shift.past":          "📅 {shift} ago: {weekday}, {date}",
+	"time.diff.ahead":          "{diff} ahead of you",
+	"time.diff.behind":         "{diff} behind you",
+	"time.diff.same":           "same as yours",
+	"weekday.0":                "Sunday",
+	"weekday.1":                "Monday",
+	"weekday.2":                "Tuesday",
+	"weekday.3":                "Wednesday",
+	"weekday.4":                "Thursday",
+	"weekday.5":                "Friday",
+	"weekday.6":                "Saturday",
+	"timezone.current":         "🌍 Your time zone: {zone}, it's {time} now.\nChange it: /timezone Europe/London, /timezone Tokyo, /timezone UTC-5 or share your location.",
+	"timezone.default":         "🌍 No time zone set, using {zone} (it's {time} now).\nSet yours: /timezone Europe/London, /timezone Tokyo, /timezone UTC-5 or share your location.",
+	"timezone.set":             "✅ Time zone: {zone}. It's {time} for you now.",
+	"timezone.button.location": "📍 Share location",
+	"timezone.error.unknown":   "unknown time zone “{name}”",
+
+	"convert.usage":          "🔁 Tell me what to convert: “5 miles to km”, “20 °C to F”, “4 GB to MB”, “100 USD to RUB” or /convert 60 mph to km/h",
+	"convert.result":         "🔁 {amount} {from} = {result} {to}",
+	"convert.currency":       "💱 {amount} {from} = {result} {to}\n📅 Bank of Russia rate on {date}: 1 {from} = {rate} {to}",
+	"convert.error.rates":    "exchange rates are unavailable right now, please try later",
+	"convert.error.currency": "the Bank of Russia does not publish a {code} rate",
+	"convert.error.unit":     "unknown unit “{unit}”",
+	"convert.error.mismatch": "cannot convert “{from}” to “{to}”",
+
+	"moderation.blocked.input":  "🚫 This message breaks the chat rules, so I can't answer it.",
+	"moderation.blocked.output": "🚫 I can't show this answer: it didn't pass the content check. Try rephrasing your question.",
+	"moderation.warning.input":  "⚠️ Please follow the chat rules.",
+	"moderation.warning.output": "⚠️ The answer may contain unwanted content.",
+	"moderation.filtered":       "🚫 The model declined to answer this request because of its content filter. Try rephrasing your question.",
+
+	// Conversation export
+	"export.usage":         "📤 Export the conversation history: /export [md|json|html]\n\nMarkdown by default, HTML is a standalone page for the browser.",
+	"export.empty":         "📭 The conversation history is empty so far.",
+	"export.caption.one":   "📤 Conversation history: {n} turn",
+	"export.caption.other": "📤 Conversation history: {n} turns",
+	"export.title":         "Conversation history",
+	"export.exported":      "Exported {time}",
+	"export.summary":       "Summary of the earlier part:",
+	"export.user":          "User",
+	"export.bot":           "Assistant",
+	"export.tool":          "tool: {tool}",
+	"export.model":         "model: {model}",
+
+	// WebSocket protocol errors
+	"ws.error.not_text":      "a JSON text message is expected",
+	"ws.error.json":          "invalid JSON",
+	"ws.error.conversation":  "invalid conversation name",
+	"ws.error.queue_full":    "too many messages in the queue",
+	"ws.error.unknown_event": "unknown event type “{type}”",
+	"ws.error.empty":         "empty message",
+}
@@ -0,0 +1,352 @@
+package main
+
+// messagesRU — строки интерфейса на русском языке
+var messagesRU = map[string]string{
+	// Общие
+	"error.generic":   "Извините, произошла ошибка при обработке вашего запроса.",
+	"error.request":   "❌ Извините, произошла ошибка при обработке вашего запроса.",
+	"format.date":     "02.01.2006",
+	"format.datetime": "02.01.2006 15:04",
+
+	// Команды Telegram
+	"start.text": "🤖 Привет! Я ваш персональный агент-помощник.\n\n" +
+		"Я могу помочь с:\n" +
+		"• Информацией о погоде\n" +
+		"• Текущим временем\n" +
+		"• Математическими вычислениями\n" +
+		"• И многим другим!\n\n" +
+		"Просто напишите мне вопрос или используйте /help для списка команд.",
+	"calculate.usage": "🧮 Для вычислений напишите выражение, например:\n" +
+		"• 2 + 3\n" +
+		"• 10 - 5\n" +
+		"• 4 * 6\n" +
+		"• 15 / 3",
+	"command.unknown": "❓ Неизвестная команда. Используйте /help для списка доступных команд.",
+
+	"keyboard.weather":   "🌤️ Погода",
+	"keyboard.time":      "🕐 Время",
+	"keyboard.calculate": "🧮 Вычисления",
+	"keyboard.help":      "❓ Помощь",
+
+	// Инструменты агента
+	"tool.weather.description":   "Получить информацию о погоде",
+	"tool.time.description":      "Получить текущее время",
+	"tool.calculate.description": "Выполнить математические вычисления",
+	"tool.convert.description":   "Перевести величину в другие единицы (длина, масса, температура, объем, данные, скорость) или сумму в другую валюту по курсу ЦБ РФ",
+	"tool.remind.description":    "Поставить напоминание",
+	"tool.notes.description":     "Заметки пользователя: сохранить, показать, удалить или найти заметку",
+	"tool.todo.description":      "Список дел пользователя: добавить задачу с тегами и сроком, показать, отметить выполненной, удалить или найти",
+	"tool.help.description":      "Показать доступные команды",
+
+	"tool.weather.answer":    "🌤️ К сожалению, я пока не подключен к сервису погоды. Но могу сказать, что сегодня отличный день для прогулки!",
+	"tool.time.answer":       "🕐 Текущее время: {time}\n📅 Дата: {date}, {weekday}\n🌍 Часовой пояс: {zone}",
+	"tool.calculate.usage":   "🧮 Для вычислений используйте команду /calculate или напишите 'вычисли 2+2'",
+	"tool.calculate.error":   "🧮 Не удалось вычислить `{expression}`: {error}",
+	"tool.calculate.result":  "🧮 `{expression}` = {result}",
+	"calc.error.div_by_zero": "деление на ноль",
+	"calc.error.unexpected":  "неожиданный символ «{char}»",
+	"calc.error.end":         "неожиданный конец выражения",
+	"calc.error.paren":       "не хватает закрывающей скобки",
+	"calc.error.number":      "неверное число «{number}»",
+	"calc.error.undefined":   "результат не определён",
+
+	"help.text": "🤖 *Доступные команды:*\n\n" +
+		"/start - Начать работу с ботом\n" +
+		"/help - Показать это сообщение\n" +
+		"/weather - Информация о погоде\n" +
+		"/time - Текущее время\n" +
+		"/calculate - Математические вычисления\n" +
+		"/convert - Перевод единиц и валют\n" +
+		"/docs - Загруженные документы\n" +
+		"/settings - Персональные настройки\n" +
+		"/reminders - Напоминания\n" +
+		"/notes - Заметки\n" +
+		"/todo - Список дел\n" +
+		"/timezone - Часовой пояс\n" +
+		"/export - Выгрузить историю разговора\n\n" +
+		"*Примеры вопросов:*\n" +
+		"• \"Какая погода?\"\n" +
+		"• \"Сколько времени?\"\n" +
+		"• \"Который час в Токио?\"\n" +
+		"• \"Какой день будет через 45 дней?\"\n" +
+		"• \"Вычисли 2+2\"\n" +
+		"• \"Сколько 5 миль в км?\"\n" +
+		"• \"100 USD в рублях\"\n" +
+		"• \"Напомни через час позвонить маме\"\n" +
+		"• \"Помощь\"\n\n" +
+		"📄 Пришлите файл .txt, .md, .pdf или .docx — и задавайте вопросы по нему.\n\n" +
+		"Я готов помочь вам! 😊",
+
+	"general.0": "Интересный вопрос! Можете уточнить, что именно вас интересует?",
+	"general.1": "Я понимаю ваш вопрос. Могу помочь с информацией о погоде, времени, вычислениями или другими задачами.",
+	"general.2": "Хорошо! Для более точного ответа используйте команды: /help - список команд, /weather - погода, /time - время.",
+	"general.3": "Я готов помочь! Попробуйте спросить о погоде, времени или попросите выполнить вычисления.",
+
+	"documents.excerpt": "📄 Из документа «{name}»:\n\n{text}",
+	"knowledge.sources": "📚 Источники:",
+
+	// Инлайн-режим
+	"inline.title.calculate": "🧮 Калькулятор",
+	"inline.title.weather":   "🌤️ Погода",
+	"inline.title.time":      "🕐 Время",
+	"inline.title.help":      "❓ Помощь",
+	"inline.title.llm":       "🤖 Ответ ассистента",
+	"inline.private_only":    "🔒 Напоминания, заметки и задачи доступны только в личном чате с ботом.",
+
+	// Голосовые сообщения
+	"voice.disabled":           "🎤 Распознавание голосовых сообщений не настроено.",
+	"voice.too_long":           "🎤 Сообщение слишком длинное. Я распознаю записи до {seconds} секунд.",
+	"voice.download_failed":    "❌ Не удалось скачать голосовое сообщение.",
+	"voice.recognize_failed":   "❌ Не удалось распознать голосовое сообщение.",
+	"voice.empty":              "🎤 Не удалось разобрать речь. Попробуйте записать сообщение еще раз.",
+	"voice.transcript":         "🎤 «{transcript}»\n\n{answer}",
+	"voice.unsupported_format": "🎤 Формат {format} не поддерживается, отправьте голосовое сообщение.",
+
+	// Документы
+	"docs.unsupported":      "📄 Формат файла «{name}» не поддерживается. Я умею читать .txt, .md, .pdf и .docx.",
+	"docs.too_large":        "📄 Файл «{name}» слишком большой. Максимальный размер — {mb} МБ.",
+	"docs.download_failed":  "❌ Не удалось скачать файл.",
+	"docs.read_failed":      "📄 Не удалось прочитать «{name}»: {error}",
+	"docs.uploaded":         "📄 Документ «{name}» загружен ({chars}). Задавайте вопросы по нему!\nСписок документов: /docs",
+	"docs.chars.one":        "{n} символ",
+	"docs.chars.few":        "{n} символа",
+	"docs.chars.many":       "{n} символов",
+	"docs.cleared.one":      "🗑️ Удален {n} документ",
+	"docs.cleared.few":      "🗑️ Удалено {n} документа",
+	"docs.cleared.many":     "🗑️ Удалено {n} документов",
+	"docs.delete_usage":     "Укажите номер документа, например: /docs delete 1",
+	"docs.delete_not_num":   "Номер документа должен быть числом, например: /docs delete 1",
+	"docs.deleted":          "🗑️ Документ «{name}» удален",
+	"docs.empty":            "📚 Документов пока нет. Пришлите файл .txt, .md, .pdf или .docx, и я отвечу на вопросы по нему.",
+	"docs.list_title":       "📚 *Загруженные документы:*",
+	"docs.list_item":        "{index}. {name} — {chars}, загружен {date}",
+	"docs.list_footer":      "Удалить документ: /docs delete N\nУдалить все: /docs clear",
+	"docs.error.no_text":    "в документе не найден текст",
+	"docs.error.limit":      "можно загрузить не больше {max} документов, удалите лишние через /docs",
+	"docs.error.not_found":  "документа с номером {number} нет",
+	"extract.error.format":  "формат файла {ext} не поддерживается",
+	"extract.error.utf8":    "текстовый файл должен быть в кодировке UTF-8",
+	"extract.error.docx":    "файл .docx поврежден",
+	"extract.error.no_body": "в файле .docx нет основного документа",
+	"extract.error.not_pdf": "файл не похож на PDF",
+	"extract.error.pdf":     "не удалось извлечь текст из PDF (возможно, это скан или нестандартные шрифты)",
+	"extract.error.pdf_cid": "PDF с составными (CID) шрифтами не поддерживается: сохраните документ в .docx или .txt",
+
+	// Настройки
+	"settings.title":               "⚙️ *Настройки ассистента*\n\nНажмите на настройку, чтобы изменить ее.",
+	"settings.custom_prompt":       "*Свой промпт:* {prompt}",
+	"settings.choose_value":        "{title}\n\nВыберите значение:",
+	"settings.custom_prompt_hint":  "Свой промпт задается командой `/settings prompt <текст>`",
+	"settings.prompt_usage":        "Пример: /settings prompt Ты — юрист, отвечай со ссылками на законы.",
+	"settings.prompt_saved":        "✅ Свой системный промпт сохранен и включен.",
+	"settings.foreign_menu":        "Это меню настроек другого пользователя. Откройте свое: /settings",
+	"settings.saved":               "✅ Сохранено",
+	"settings.reset":               "🔄 Настройки сброшены",
+	"settings.button.reset":        "🔄 Сбросить",
+	"settings.button.close":        "✖️ Закрыть",
+	"settings.button.back":         "⬅️ Назад",
+	"settings.error.unknown":       "неизвестная настройка «{key}»",
+	"settings.error.invalid":       "недопустимое значение «{value}» для настройки «{key}»",
+	"settings.error.prompt_empty":  "промпт не может быть пустым",
+	"settings.error.prompt_length": "промпт длиннее {max} символов",
+
+	"settings.field.persona":    "🎭 Персона",
+	"settings.field.length":     "📏 Длина ответа",
+	"settings.field.language":   "🌐 Язык",
+	"settings.field.model":      "🧠 Модель",
+	"settings.field.creativity": "🎨 Креативность",
+
+	"settings.option.persona.default":    "Обычный",
+	"settings.option.persona.assistant":  "Ассистент",
+	"settings.option.persona.teacher":    "Учитель",
+	"settings.option.persona.programmer": "Программист",
+	"settings.option.persona.friend":     "Друг",
+	"settings.option.persona.custom":     "Свой промпт",
+	"settings.option.length.normal":      "Обычная",
+	"settings.option.length.short":       "Кратко",
+	"settings.option.length.long":        "Подробно",
+	"settings.option.language.auto":      "Как у вопроса",
+	"settings.option.language.ru":        "Русский",
+	"settings.option.language.en":        "English",
+	"settings.option.model.auto":         "Автовыбор",
+	"settings.option.model.lite":         "YandexGPT Lite",
+	"settings.option.model.pro":          "YandexGPT Pro",
+	"settings.option.creativity.medium":  "Средняя",
+	"settings.option.creativity.low":     "Низкая",
+	"settings.option.creativity.high":    "Высокая",
+
+	// Команды администратора
+	"admin.stats": "📊 *Статистика*\n\n" +
+		"Пользователи: {users}\n" +
+		"Режим доступа: {mode}, в списке доступа: {allowed}, заблокировано: {blocked}\n" +
+		"Сообщения: {messages}\n" +
+		"Запросы к модели: {requests}\n" +
+		"Токены: {input} на входе, {output} на выходе\n" +
+		"Кэш ответов: {hits} попаданий, {misses} промахов, сохранено {cached}\n" +
+		"Ошибки: {errors}\n" +
+		"Модерация: заблокировано {moderated}, отмечено {flagged}\n" +
+		"Бэкенд модели: {provider}\n" +
+		"Время работы: {uptime}",
+	"admin.broadcast.usage":    "Напишите текст рассылки после команды: /broadcast Текст сообщения",
+	"admin.broadcast.progress": "📣 Рассылка: {sent} из {total}",
+	"admin.broadcast.done":     "📣 Рассылка завершена: доставлено {sent} из {total}, ошибок {failed}",
+	"admin.ban.usage":          "Укажите ID пользователя или чата либо ответьте командой на сообщение пользователя, например: /ban 12345",
+	"admin.ban.admin":          "Нельзя заблокировать администратора.",
+	"admin.ban.done":           "⛔ Заблокирован: {id}",
+	"admin.unban.done":         "✅ Разблокирован: {id}",
+	"admin.reload.done":        "🔄 Конфигурация перечитана. Администраторов: {admins}, режим доступа: {mode}, бэкенд модели: {provider}",
+	"admin.reload.failed":      "❌ Не удалось перечитать конфигурацию: {error}",
+	"admin.provider.current":   "🧠 Бэкенд модели: {provider}\nДоступные: {providers}\nПереключить: /provider <имя>",
+	"admin.provider.switched":  "🧠 Бэкенд модели переключен на {provider}",
+	"provider.error.unknown":   "неизвестный бэкенд модели «{name}»",
+	"admin.allow.usage":        "Укажите ID пользователя или чата (у групп ID отрицательный), например: /allow 12345",
+	"admin.allow.done":         "✅ Добавлен в список доступа: {id}",
+	"admin.deny.done":          "🚫 Удален из списка доступа: {id}",
+	"admin.invite.usage":       "Укажите число активаций, например: /invite 5",
+	"admin.invite.created":     "🎟️ Код приглашения: `{code}` (активаций: {uses})\nЛичный доступ: {link}\nДля группы: {group_link}",
+	"admin.invite.mode_hint":   "⚠️ Коды принимаются только в режиме ACCESS_MODE=invite.",
+
+	// Доступ
+	"access.denied.allowlist":       "🔒 Бот доступен только пользователям из списка доступа. Чтобы вас добавили, сообщите администратору свой ID: `{id}`",
+	"access.denied.invite":          "🔒 Для доступа к боту нужно приглашение. Откройте ссылку-приглашение или отправьте /start <код>. Ваш ID: `{id}`",
+	"access.denied.group":           "🔒 Бот не подключен к этому чату. ID чата: `{id}`",
+	"access.hint.group_invite":      "Чтобы подключить бота, отправьте в чат /start <код приглашения>.",
+	"access.granted":                "✅ Приглашение принято, доступ открыт!",
+	"access.granted_group":          "✅ Приглашение принято, бот подключен к этому чату!",
+	"access.error.invalid_code":     "код приглашения недействителен или уже использован",
+	"access.error.invites_disabled": "коды приглашений сейчас не принимаются",
+
+	// Напоминания
+	"remind.usage": "⏰ Примеры напоминаний:\n" +
+		"• напомни через 20 минут позвонить маме\n" +
+		"• напомни завтра в 9:00 про встречу\n" +
+		"• напомни 25 декабря купить подарки\n" +
+		"• напоминай каждый понедельник в 10 о планерке\n" +
+		"• напоминай по будням в 8:30 сделать зарядку\n" +
+		"• напоминай каждые 2 часа пить воду",
+	"remind.created":           "⏰ Напомню {when}: {text}\nСписок напоминаний: /reminders",
+	"remind.created_recurring": "⏰ Буду напоминать {schedule}: {text}\nПервый раз — {when}\nСписок напоминаний: /reminders",
+	"remind.delivery":          "⏰ Напоминание: {text}",
+	"remind.error.no_text":     "не понял, о чем напомнить",
+	"remind.error.no_time":     "не понял, когда напомнить",
+	"remind.error.past":        "это время уже прошло",
+	"remind.error.limit":       "можно завести не больше {max} напоминаний, отмените лишние через /reminders",
+	"remind.error.not_found":   "напоминания с номером {number} нет",
+	"remind.schedule.daily":    "каждый день в {time}",
+	"remind.schedule.weekdays": "по будням в {time}",
+	"remind.schedule.weekly":   "по {day} в {time}",
+	"remind.schedule.interval": "раз в {interval}",
+	"remind.weekday.0":         "воскресеньям",
+	"remind.weekday.1":         "понедельникам",
+	"remind.weekday.2":         "вторникам",
+	"remind.weekday.3":         "средам",
+	"remind.weekday.4":         "четвергам",
+	"remind.weekday.5":         "пятницам",
+	"remind.weekday.6":         "субботам",
+	"duration.minutes.one":     "{n} минуту",
+	"duration.minutes.few":     "{n} минуты",
+	"duration.minutes.many":    "{n} минут",
+	"duration.hours.one":       "{n} час",
+	"duration.hours.few":       "{n} часа",
+	"duration.hours.many":      "{n} часов",
+	"duration.days.one":        "{n} день",
+	"duration.days.few":        "{n} дня",
+	"duration.days.many":       "{n} дней",
+	"reminders.empty":          "⏰ Напоминаний пока нет. Напишите, например: «напомни через 20 минут позвонить маме».",
+	"reminders.list_title":     "⏰ *Ваши напоминания:*",
+	"reminders.list_item":      "{index}. {text} — {when}",
+	"reminders.list_footer":    "Отменить напоминание: /reminders cancel N",
+	"reminders.cancel_usage":   "Укажите номер напоминания, например: /reminders cancel 1",
+	"reminders.cancelled":      "🗑️ Напоминание «{text}» отменено",
+
+	// Заметки и список дел
+	"tool.error.unknown":    "инструмент «{name}» недоступен",
+	"notes.added":           "📝 Заметка сохранена:\n{note}",
+	"todo.added":            "📋 Задача добавлена:\n{note}",
+	"notes.empty":           "📝 Заметок пока нет. Напишите «запиши: код от домофона 1234» или /notes <текст>.",
+	"todo.empty":            "📋 Список дел пуст. Добавьте задачу: /todo купить молоко #дом до пятницы",
+	"notes.list_title":      "📝 Заметки:",
+	"todo.list_title":       "📋 Список дел:",
+	"notes.search_title":    "🔎 Найдено по запросу «{query}»:",
+	"notes.search_empty":    "🔎 По запросу «{query}» ничего не найдено",
+	"todo.completed":        "✅ Задача «{text}» выполнена",
+	"notes.deleted":         "🗑️ Удалено: {text}",
+	"notes.due":             "до {date}",
+	"notes.overdue":         "⚠️ просрочено, срок был {date}",
+	"notes.error.empty":     "текст записи не может быть пустым",
+	"notes.error.limit":     "можно хранить не больше {max} записей, удалите лишние",
+	"notes.error.not_found": "записи с номером {id} нет",
+	"notes.error.action":    "неизвестное действие «{action}»",
+	"notes.error.due":       "не понял срок «{due}»",
+	"notes.usage": "📝 Заметки:\n" +
+		"/notes — список\n" +
+		"/notes <текст> #тег — сохранить заметку\n" +
+		"/notes delete N — удалить\n" +
+		"/notes search <запрос или #тег> — найти",
+	"todo.usage": "📋 Список дел:\n" +
+		"/todo — открытые задачи\n" +
+		"/todo all — все задачи, включая выполненные\n" +
+		"/todo <задача> #тег до <срок> — добавить\n" +
+		"/todo done N — отметить выполненной\n" +
+		"/todo delete N — удалить\n" +
+		"/todo search <запрос или #тег> — найти",
+
+	// Часовые пояса
+	"tool.time.zone_hint":      "🌍 Свой часовой пояс можно указать командой /timezone или отправив геопозицию.",
+	"time.world":               "🕐 {place}: {time}, {weekday} {date} — {zone}, {diff}",
+	"time.convert":             "🕐 {from_place}: {from_time}\n🕐 {to_place}: {to_time}",
+	"time.place.you":           "У вас",
+	"time.shift.future":        "📅 Через {shift}: {weekday}, {date}",
+	"time.shift.past":          "📅 {shift} назад: {weekday}, {date}",
+	"time.diff.ahead":          "на {diff} впереди вас",
+	"time.diff.behind":         "на {diff} позади вас",
+	"time.diff.same":           "как у вас",
+	"weekday.0":                "воскресенье",
+	"weekday.1":                "понедельник",
+	"weekday.2":                "вторник",
+	"weekday.3":                "среда",
+	"weekday.4":                "четверг",
+	"weekday.5":                "пятница",
+	"weekday.6":                "суббота",
+	"timezone.current":         "🌍 Ваш часовой пояс: {zone}, сейчас {time}.\nИзменить: /timezone Europe/Moscow, /timezone Токио, /timezone UTC+3 или отправьте геопозицию.",
+	"timezone.default":         "🌍 Часовой пояс не указан, используется {zone} (сейчас {time}).\nУкажите свой: /timezone Europe/Moscow, /timezone Токио, /timezone UTC+3 или отправьте геопозицию.",
+	"timezone.set":             "✅ Часовой пояс: {zone}. Сейчас у вас {time}.",
+	"timezone.button.location": "📍 Отправить геопозицию",
+	"timezone.error.unknown":   "неизвестный часовой пояс «{name}»",
+
+	"convert.usage":          "🔁 Напишите, что перевести: «5 миль в км», «20 °C в F», «4 ГБ в МБ», «100 USD в рублях» или /convert 60 миль в час в км/ч",
+	"convert.result":         "🔁 {amount} {from} = {result} {to}",
+	"convert.currency":       "💱 {amount} {from} = {result} {to}\n📅 Курс ЦБ РФ на {date}: 1 {from} = {rate} {to}",
+	"convert.error.rates":    "курсы валют сейчас недоступны, попробуйте позже",
+	"convert.error.currency": "ЦБ РФ не публикует курс {code}",
+	"convert.error.unit":     "неизвестная единица «{unit}»",
+	"convert.error.mismatch": "нельзя перевести «{from}» в «{to}»",
+
+	"moderation.blocked.input":  "🚫 Сообщение нарушает правила общения, поэтому я не могу на него ответить.",
+	"moderation.blocked.output": "🚫 Не могу показать этот ответ: он не прошел проверку содержимого. Попробуйте переформулировать вопрос.",
+	"moderation.warning.input":  "⚠️ Пожалуйста, соблюдайте правила общения.",
+	"moderation.warning.output": "⚠️ Ответ может содержать нежелательное содержимое.",
+	"moderation.filtered":       "🚫 Модель отказалась отвечать на этот запрос: сработал фильтр содержимого. Попробуйте переформулировать вопрос.",
+
+	// Выгрузка разговоров
+	"export.usage":        "📤 Выгрузка истории разговора: /export [md|json|html]\n\nMarkdown по умолчанию, HTML — отдельная страница для браузера.",
+	"export.empty":        "📭 История разговора пока пуста.",
+	"export.caption.one":  "📤 История разговора: {n} реплика",
+	"export.caption.few":  "📤 История разговора: {n} реплики",
+	"export.caption.many": "📤 История разговора: {n} реплик",
+	"export.title":        "История разговора",
+	"export.exported":     "Выгружено {time}",
+	"export.summary":      "Краткое содержание ранней части:",
+	"export.user":         "Пользователь",
+	"export.bot":          "Ассистент",
+	"export.tool":         "инструмент: {tool}",
+	"export.model":        "модель: {model}",
+
+	// Ошибки протокола WebSocket
+	"ws.error.not_text":      "ожидается текстовое сообщение JSON",
+	"ws.error.json":          "неверный JSON",
+	"ws.error.conversation":  "неверное имя разговора",
+	"ws.error.queue_full":    "слишком много сообщений в очереди",
+	"ws.error.unknown_event": "неизвестный тип события «{type}»",
+	"ws.error.empty":         "пустое сообщение",
+}
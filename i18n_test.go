@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestCatalogComplete(t *testing.T) {
+	for locale, keys := range missingTranslations() {
+		t.Errorf("в локали %s не хватает ключей: %v", locale, keys)
+	}
+}
+
+func TestPluralForm(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{LocaleRU, 1, "one"},
+		{LocaleRU, 21, "one"},
+		{LocaleRU, 11, "many"},
+		{LocaleRU, 2, "few"},
+		{LocaleRU, 24, "few"},
+		{LocaleRU, 12, "many"},
+		{LocaleRU, 14, "many"},
+		{LocaleRU, 5, "many"},
+		{LocaleRU, 0, "many"},
+		{LocaleRU, 111, "many"},
+		{LocaleRU, -1, "one"},
+		{LocaleEN, 1, "one"},
+		{LocaleEN, 0, "other"},
+		{LocaleEN, 2, "other"},
+		{LocaleEN, 21, "other"},
+		{"uk", 3, "few"},
+		{"de", 1, "one"},
+	}
+	for _, tt := range tests {
+		if got := pluralForm(tt.locale, tt.n); got != tt.want {
+			t.Errorf("pluralForm(%q, %d) = %q, want %q", tt.locale, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTN(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{LocaleRU, 1, "1 минуту"},
+		{LocaleRU, 3, "3 минуты"},
+		{LocaleRU, 11, "11 минут"},
+		{LocaleRU, 25, "25 минут"},
+		{LocaleEN, 1, "1 minute"},
+		{LocaleEN, 5, "5 minutes"},
+		{"en-US", 0, "0 minutes"},
+	}
+	for _, tt := range tests {
+		if got := TN(tt.locale, "duration.minutes", tt.n); got != tt.want {
+			t.Errorf("TN(%q, duration.minutes, %d) = %q, want %q", tt.locale, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		key    string
+		params []Params
+		want   string
+	}{
+		{"подстановка", LocaleRU, "provider.error.unknown", []Params{{"name": "gpt"}}, "неизвестный бэкенд модели «gpt»"},
+		{"английский", LocaleEN, "provider.error.unknown", []Params{{"name": "gpt"}}, "unknown model backend “gpt”"},
+		{"без параметров остается шаблон", LocaleEN, "provider.error.unknown", nil, "unknown model backend “{name}”"},
+		{"неизвестный параметр не мешает", LocaleEN, "provider.error.unknown", []Params{{"name": "x", "other": 1}}, "unknown model backend “x”"},
+		{"нет ключа — сам ключ", LocaleRU, "no.such.key", nil, "no.such.key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := T(tt.locale, tt.key, tt.params...); got != tt.want {
+				t.Errorf("T(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTNWithParams(t *testing.T) {
+	got := T(LocaleEN, "docs.uploaded", Params{"name": "a.txt", "chars": TN(LocaleEN, "docs.chars", 1)})
+	want := "📄 The document “a.txt” is uploaded (1 character). Ask me questions about it!\nList of documents: /docs"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLookupFallsBackToDefaultLocale(t *testing.T) {
+	catalog[LocaleRU]["test.only.ru"] = "только по-русски"
+	defer delete(catalog[LocaleRU], "test.only.ru")
+
+	if got := T(LocaleEN, "test.only.ru"); got != "только по-русски" {
+		t.Errorf("T(en) = %q, want fallback to ru", got)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// knowledgeTopK — сколько фрагментов базы знаний добавляется в запрос
+	knowledgeTopK = 4
+	// knowledgeMinScore — минимальное сходство, ниже которого фрагмент считается нерелевантным
+	knowledgeMinScore = 0.3
+)
+
+// knowledgeFileTypes — расширения файлов, которые попадают в базу знаний
+var knowledgeFileTypes = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+}
+
+// KnowledgeBase отвечает за индексацию внутренних документов и поиск по ним
+type KnowledgeBase struct {
+	dir      string
+	embedder Embedder
+	index    *VectorIndex
+}
+
+// NewKnowledgeBase загружает индекс и синхронизирует его с каталогом документов
+func NewKnowledgeBase(dir, indexPath string, embedder Embedder) (*KnowledgeBase, error) {
+	index, err := LoadVectorIndex(indexPath, embedder.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	kb := &KnowledgeBase{
+		dir:      dir,
+		embedder: embedder,
+		index:    index,
+	}
+	if err := kb.Sync(); err != nil {
+		return nil, err
+	}
+	return kb, nil
+}
+
+// Sync индексирует новые и измененные файлы и удаляет из индекса пропавшие
+func (kb *KnowledgeBase) Sync() error {
+	seen := make(map[string]bool)
+	changed := 0
+
+	err := filepath.WalkDir(kb.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !knowledgeFileTypes[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		source, err := filepath.Rel(kb.dir, path)
+		if err != nil {
+			return err
+		}
+		source = filepath.ToSlash(source)
+		seen[source] = true
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения %s: %v", source, err)
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if indexed, exists := kb.index.FileHash(source); exists && indexed == hash {
+			return nil
+		}
+
+		text, err := extractPlainText(data)
+		if err != nil {
+			log.Printf("База знаний: пропускаем %s: %v", source, err)
+			return nil
+		}
+		if err := kb.indexFile(source, hash, text); err != nil {
+			return fmt.Errorf("ошибка индексации %s: %v", source, err)
+		}
+		changed++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, source := range kb.index.Sources() {
+		if !seen[source] {
+			kb.index.Remove(source)
+			changed++
+		}
+	}
+
+	if changed > 0 {
+		if err := kb.index.Save(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("База знаний: %d файлов, %d фрагментов (обновлено: %d)",
+		len(seen), kb.index.Len(), changed)
+	return nil
+}
+
+// indexFile разбивает файл на фрагменты и векторизует их
+func (kb *KnowledgeBase) indexFile(source, hash, text string) error {
+	chunks := splitIntoChunks(text, documentChunkSize, documentChunkOverlap)
+	entries := make([]IndexEntry, 0, len(chunks))
+
+	for i, chunk := range chunks {
+		vector, err := kb.embedder.Embed(chunk, EmbeddingDocument)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, IndexEntry{
+			Source: source,
+			Chunk:  i,
+			Text:   chunk,
+			Vector: vector,
+		})
+	}
+
+	kb.index.Replace(source, hash, entries)
+	return nil
+}
+
+// Search находит фрагменты базы знаний, относящиеся к вопросу
+func (kb *KnowledgeBase) Search(query string) ([]SearchResult, error) {
+	if kb.index.Len() == 0 {
+		return nil, nil
+	}
+
+	vector, err := kb.embedder.Embed(query, EmbeddingQuery)
+	if err != nil {
+		return nil, err
+	}
+	return kb.index.Search(vector, knowledgeTopK, knowledgeMinScore), nil
+}
+
+// knowledgeContext формирует инструкцию для модели с пронумерованными источниками
+func knowledgeContext(results []SearchResult) string {
+	var builder strings.Builder
+	builder.WriteString("Ниже приведены фрагменты внутренней базы знаний компании. ")
+	builder.WriteString("Если они относятся к вопросу, отвечай на их основе и ссылайся на источники ")
+	builder.WriteString("номерами в квадратных скобках, например [1]. Не выдумывай факты, которых нет во фрагментах.\n")
+
+	for i, result := range results {
+		fmt.Fprintf(&builder, "\n[%d] %s\n%s\n", i+1, result.Source, result.Text)
+	}
+
+	return builder.String()
+}
+
+// knowledgeSources формирует список источников для ответа
+func knowledgeSources(locale string, results []SearchResult) string {
+	var builder strings.Builder
+	builder.WriteString(T(locale, "knowledge.sources"))
+
+	listed := make(map[string]bool)
+	for i, result := range results {
+		if listed[result.Source] {
+			continue
+		}
+		listed[result.Source] = true
+		fmt.Fprintf(&builder, "\n[%d] %s", i+1, result.Source)
+	}
+	return builder.String()
+}
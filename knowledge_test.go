@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// countingEmbedder считает, какие тексты векторизовались
+type countingEmbedder struct {
+	HashingEmbedder
+	embedded []string
+}
+
+func (e *countingEmbedder) Embed(text string, kind EmbeddingKind) ([]float64, error) {
+	if kind == EmbeddingDocument {
+		e.embedded = append(e.embedded, text)
+	}
+	return e.HashingEmbedder.Embed(text, kind)
+}
+
+func TestKnowledgeBaseSync(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(t.TempDir(), "index.json")
+	write := func(name, text string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("vacation.md", "Отпуск оформляется заявлением за две недели.")
+	write("hr/office.txt", "Офис открыт с девяти утра.")
+	write("logo.png", "не текст")
+
+	embedder := &countingEmbedder{}
+	kb, err := NewKnowledgeBase(dir, indexPath, embedder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kb.index.Sources(); !reflect.DeepEqual(got, []string{"hr/office.txt", "vacation.md"}) {
+		t.Errorf("проиндексированы %v", got)
+	}
+	if len(embedder.embedded) != 2 {
+		t.Errorf("векторизовано фрагментов: %d, want 2", len(embedder.embedded))
+	}
+
+	results, err := kb.Search("как оформить отпуск")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Source != "vacation.md" {
+		t.Errorf("поиск про отпуск: %+v", results)
+	}
+
+	// Неизмененные файлы не векторизуются повторно, в том числе после перезапуска
+	embedder.embedded = nil
+	if _, err := NewKnowledgeBase(dir, indexPath, embedder); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedder.embedded) != 0 {
+		t.Errorf("после перезапуска векторизованы %q", embedder.embedded)
+	}
+
+	write("vacation.md", "Отпуск оформляется заявлением за три недели.")
+	os.Remove(filepath.Join(dir, "hr", "office.txt"))
+	if err := kb.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(embedder.embedded, []string{"Отпуск оформляется заявлением за три недели."}) {
+		t.Errorf("при повторной синхронизации векторизованы %q", embedder.embedded)
+	}
+	if got := kb.index.Sources(); !reflect.DeepEqual(got, []string{"vacation.md"}) {
+		t.Errorf("после удаления файла в индексе %v", got)
+	}
+	if kb.index.Len() != 1 {
+		t.Errorf("старые фрагменты измененного файла остались: %d", kb.index.Len())
+	}
+
+	reloaded, err := LoadVectorIndex(indexPath, embedder.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Len() != 1 || reloaded.Entries[0].Text != "Отпуск оформляется заявлением за три недели." {
+		t.Errorf("изменения не сохранены на диск: %+v", reloaded.Entries)
+	}
+}
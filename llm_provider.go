@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// maxToolRounds — сколько раз подряд модель может вызывать функции, прежде чем ответить текстом
+const maxToolRounds = 4
+
+// builtinProviderName — имя встроенного агента, который отвечает без языковой модели
+const builtinProviderName = "builtin"
+
+// Completion представляет ответ языковой модели
+type Completion struct {
+	Text             string
+	ToolCalls        []ToolCall // Вызовы функций, если модель решила воспользоваться инструментами
+	InputTokens      int
+	CompletionTokens int
+	Model            string // Модель, к которой ушел запрос
+	ModelVersion     string
+	Filtered         bool // Модель отказалась отвечать из-за фильтра содержимого
+}
+
+// FunctionTool описывает инструмент, который модель может вызвать сама.
+// Parameters — JSON Schema аргументов.
+type FunctionTool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToolCall — запрошенный моделью вызов функции с аргументами в JSON
+type ToolCall struct {
+	Name      string
+	Arguments json.RawMessage
+}
+
+// ToolResult — результат вызова функции, который возвращается модели
+type ToolResult struct {
+	Name    string
+	Content string
+}
+
+// LLMProvider — бэкенд языковой модели, к которому агент обращается за ответами
+type LLMProvider interface {
+	// Name возвращает имя бэкенда для /provider и логов
+	Name() string
+	// Generate генерирует ответ на диалог с указанными параметрами генерации
+	Generate(messages []YandexGPTMessage, options GenerationOptions) (Completion, error)
+}
+
+// StreamingProvider — бэкенд, который умеет передавать ответ по мере генерации
+type StreamingProvider interface {
+	LLMProvider
+	// GenerateStream генерирует ответ как Generate и передает в onDelta новые фрагменты текста
+	GenerateStream(messages []YandexGPTMessage, options GenerationOptions, onDelta func(text string)) (Completion, error)
+}
+
+// RegisterProvider добавляет бэкенд модели, заменяя бэкенд с тем же именем
+func (a *Agent) RegisterProvider(provider LLMProvider) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.providers[provider.Name()] = provider
+}
+
+// UnregisterProvider убирает бэкенд модели, например когда из конфигурации удалили его ключи
+func (a *Agent) UnregisterProvider(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.providers, name)
+}
+
+// SetProvider переключает агента на бэкенд с указанным именем; builtin отключает модель
+func (a *Agent) SetProvider(name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.providers[name]; !exists && name != builtinProviderName {
+		return newLocalizedError("provider.error.unknown", Params{"name": name})
+	}
+	a.provider = name
+	return nil
+}
+
+// ProviderName возвращает имя активного бэкенда
+func (a *Agent) ProviderName() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.providers[a.provider]; !exists {
+		return builtinProviderName
+	}
+	return a.provider
+}
+
+// Providers возвращает имена всех доступных бэкендов, включая встроенный
+func (a *Agent) Providers() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	names := []string{builtinProviderName}
+	for name := range a.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// llm возвращает активный бэкенд модели или nil, если используется встроенный агент
+func (a *Agent) llm() LLMProvider {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.providers[a.provider]
+}
+
+// generate обращается к активной модели и учитывает ответы в метриках.
+// Если модель вызывает инструменты агента из options.Tools, они выполняются от имени userID,
+// а результаты передаются модели, пока она не ответит текстом.
+func (a *Agent) generate(provider LLMProvider, messages []YandexGPTMessage, options GenerationOptions, userID int64) (string, error) {
+	completion, _, err := a.complete(provider, messages, options, userID, nil)
+	return completion.Text, err
+}
+
+// cachedGenerate отвечает из кэша ответов, если запрос уже задавался, и иначе обращается к модели.
+// Ответы, для которых модель вызывала инструменты, не кэшируются.
+// Возвращает итоговый ответ вместе с версией модели, которая его сгенерировала.
+// Ответ из кэша приходит целиком, без фрагментов в onDelta.
+func (a *Agent) cachedGenerate(provider LLMProvider, messages []YandexGPTMessage, options GenerationOptions, userID int64, onDelta func(text string)) (Completion, error) {
+	options.Tools = a.functionTools(true)
+	key, ok := a.cache.Key(provider.Name(), messages, options)
+	if !ok {
+		completion, _, err := a.complete(provider, messages, options, userID, onDelta)
+		return completion, err
+	}
+
+	completion, hit, err := a.cache.Do(key, func() (Completion, bool, error) {
+		return a.complete(provider, messages, options, userID, onDelta)
+	})
+	if hit {
+		a.metrics.CacheHits.Add(1)
+		log.Printf("Ответ для пользователя %d взят из кэша", userID)
+	} else {
+		a.metrics.CacheMisses.Add(1)
+	}
+	return completion, err
+}
+
+// complete выполняет запрос к модели вместе с вызовами инструментов и возвращает
+// последний ответ модели с восстановленными персональными данными. Второе значение сообщает, можно ли кэшировать ответ: нельзя, если модель вызывала
+// инструменты или отказалась отвечать из-за фильтра содержимого.
+// Если бэкенд умеет передавать ответ по частям, фрагменты текста передаются в onDelta по мере генерации.
+func (a *Agent) complete(provider LLMProvider, messages []YandexGPTMessage, options GenerationOptions, userID int64, onDelta func(text string)) (Completion, bool, error) {
+	calledTools := false
+
+	// Персональные данные заменяются метками до отправки во внешний API
+	// и возвращаются в ответ и в аргументы инструментов после
+	redaction := a.Redactor().NewRedaction()
+
+	for round := 0; round <= maxToolRounds; round++ {
+		completion, err := generateStream(provider, redaction.RedactMessages(messages), options, redaction.RestoreStream(onDelta))
+		if err != nil {
+			a.metrics.Errors.Add(1)
+			return Completion{}, false, err
+		}
+		a.metrics.RecordCompletion(completion)
+
+		if completion.Filtered {
+			log.Printf("Модель %s отказалась отвечать пользователю %d: сработал фильтр содержимого", provider.Name(), userID)
+			a.metrics.ModerationBlocked.Add(1)
+			completion.Text = T(a.LocaleFor(userID), "moderation.filtered")
+			return completion, false, nil
+		}
+		if len(completion.ToolCalls) == 0 {
+			// Ответы с персональными данными не кэшируются
+			completion.Text = redaction.Restore(completion.Text)
+			return completion, !calledTools && !redaction.Found(), nil
+		}
+		calledTools = true
+
+		calls := make([]ToolCall, len(completion.ToolCalls))
+		for i, call := range completion.ToolCalls {
+			calls[i] = ToolCall{Name: call.Name, Arguments: json.RawMessage(redaction.Reveal(string(call.Arguments)))}
+		}
+		// В историю запроса вызовы попадают с метками, как их прислала модель
+		messages = append(messages,
+			toolCallMessage(completion.ToolCalls),
+			toolResultMessage(a.callTools(calls, options.Tools, userID)))
+	}
+
+	a.metrics.Errors.Add(1)
+	return Completion{}, false, fmt.Errorf("модель не ответила после %d вызовов инструментов", maxToolRounds)
+}
+
+// generateStream запрашивает ответ по частям, если бэкенд это умеет и фрагменты кому-то нужны
+func generateStream(provider LLMProvider, messages []YandexGPTMessage, options GenerationOptions, onDelta func(text string)) (Completion, error) {
+	if streaming, ok := provider.(StreamingProvider); ok && onDelta != nil {
+		return streaming.GenerateStream(messages, options, onDelta)
+	}
+	return provider.Generate(messages, options)
+}
+
+// functionTools возвращает инструменты агента, доступные модели для вызова;
+// mutating включает инструменты, которые меняют данные пользователя
+func (a *Agent) functionTools(mutating bool) []FunctionTool {
+	var tools []FunctionTool
+	for _, tool := range a.tools {
+		if tool.Call == nil || (tool.Mutating && !mutating) {
+			continue
+		}
+		tools = append(tools, FunctionTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		})
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// toolOffered сообщает, предлагался ли модели инструмент name: вызовы других инструментов не выполняются
+func toolOffered(offered []FunctionTool, name string) bool {
+	for _, tool := range offered {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// callTools выполняет вызовы функций из предложенных модели offered;
+// ошибки передаются модели текстом, чтобы она могла их объяснить
+func (a *Agent) callTools(calls []ToolCall, offered []FunctionTool, userID int64) []ToolResult {
+	locale := a.LocaleFor(userID)
+	results := make([]ToolResult, 0, len(calls))
+	for _, call := range calls {
+		log.Printf("Модель вызывает инструмент %s для пользователя %d: %s", call.Name, userID, call.Arguments)
+
+		content := ""
+		tool, exists := a.tools[call.Name]
+		if !exists || tool.Call == nil || !toolOffered(offered, call.Name) {
+			content = T(locale, "tool.error.unknown", Params{"name": call.Name})
+		} else if result, err := tool.Call(call.Arguments, userID); err != nil {
+			log.Printf("Ошибка инструмента %s: %v", call.Name, err)
+			content = "❌ " + localizeError(locale, err)
+		} else {
+			content = result
+		}
+		results = append(results, ToolResult{Name: call.Name, Content: content})
+	}
+	return results
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -13,15 +14,36 @@ func main() {
 		log.Println("Файл .env не найден, используем переменные окружения")
 	}
 
+	// Подкоманды работают без Telegram и HTTP сервера
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "eval":
+			os.Exit(runEval(os.Args[2:]))
+		case "repl":
+			os.Exit(runRepl(os.Args[2:]))
+		case "ws-token":
+			os.Exit(runWebSocketToken(os.Args[2:]))
+		}
+	}
+
 	// Получаем порт для HTTP сервера
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	// Каналы кроме Telegram (вебхук, Mattermost) обслуживает тот же агент
+	channels := NewChannelHub(sharedAgent(), NewStore(dataDir()))
+	for _, channel := range configuredChannels() {
+		channels.Register(channel)
+	}
+	channels.Start()
+	sharedAgent().Reminders().SetDelivery(channels.Deliver(nil))
+
 	// Запускаем HTTP сервер в отдельной горутине
 	go func() {
 		httpServer := NewHTTPServer(port)
+		httpServer.channels = channels
 		if err := httpServer.Start(); err != nil {
 			log.Printf("Ошибка запуска HTTP сервера: %v", err)
 		}
@@ -30,7 +52,13 @@ func main() {
 	// Получаем токен бота
 	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
 	if botToken == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN не установлен")
+		if len(channels.Channels()) == 0 {
+			log.Fatal("TELEGRAM_BOT_TOKEN не установлен")
+		}
+		// Без Telegram бот работает только в подключенных каналах
+		log.Printf("TELEGRAM_BOT_TOKEN не установлен, работают каналы: %s", strings.Join(channels.Channels(), ", "))
+		sharedAgent().Reminders().Start(reminderTickInterval)
+		select {}
 	}
 
 	// Проверяем настройки Yandex GPT
@@ -51,7 +79,11 @@ func main() {
 	}
 
 	// Создаем и запускаем бота
-	bot := NewTelegramBot(botToken)
+	bot, err := NewTelegramBot(botToken)
+	if err != nil {
+		log.Fatal("Ошибка создания бота:", err)
+	}
+	sharedAgent().Reminders().SetDelivery(channels.Deliver(bot.deliverReminder))
 	if err := bot.Start(); err != nil {
 		log.Fatal("Ошибка запуска бота:", err)
 	}
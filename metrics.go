@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics собирает счетчики работы агента с момента запуска
+type Metrics struct {
+	startedAt time.Time
+
+	Messages          atomic.Int64 // Обработанные сообщения
+	Errors            atomic.Int64 // Ошибки модели и обработки
+	LLMRequests       atomic.Int64 // Успешные запросы к модели
+	InputTokens       atomic.Int64 // Токены запросов к модели
+	CompletionTokens  atomic.Int64 // Токены ответов модели
+	CacheHits         atomic.Int64 // Ответы из кэша без обращения к модели
+	CacheMisses       atomic.Int64 // Кэшируемые запросы, которые пришлось отправить модели
+	ModerationBlocked atomic.Int64 // Сообщения и ответы, заблокированные модерацией или фильтром модели
+	ModerationFlagged atomic.Int64 // Нарушения с предупреждением или записью в журнал
+
+	mu     sync.Mutex
+	models map[string]*ModelUsage // Запросы и токены по моделям
+	routes map[routeKey]int64     // Выбор модели маршрутизацией по уровню и причине
+}
+
+// ModelUsage — запросы и токены одной модели
+type ModelUsage struct {
+	Model            string
+	Requests         int64
+	InputTokens      int64
+	CompletionTokens int64
+}
+
+// RouteCount — сколько раз маршрутизация выбрала уровень модели по этой причине
+type RouteCount struct {
+	Tier   string
+	Reason string
+	Count  int64
+}
+
+type routeKey struct {
+	tier, reason string
+}
+
+// NewMetrics создает счетчики, отсчитывая время работы от текущего момента
+func NewMetrics() *Metrics {
+	return &Metrics{startedAt: time.Now(), models: make(map[string]*ModelUsage), routes: make(map[routeKey]int64)}
+}
+
+// RecordCompletion учитывает успешный ответ модели, в том числе по модели, к которой ушел запрос
+func (m *Metrics) RecordCompletion(completion Completion) {
+	m.LLMRequests.Add(1)
+	m.InputTokens.Add(int64(completion.InputTokens))
+	m.CompletionTokens.Add(int64(completion.CompletionTokens))
+	if completion.Model == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	usage := m.models[completion.Model]
+	if usage == nil {
+		usage = &ModelUsage{Model: completion.Model}
+		m.models[completion.Model] = usage
+	}
+	usage.Requests++
+	usage.InputTokens += int64(completion.InputTokens)
+	usage.CompletionTokens += int64(completion.CompletionTokens)
+}
+
+// RecordRoute учитывает выбор модели маршрутизацией
+func (m *Metrics) RecordRoute(route ModelRoute) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.routes[routeKey{route.Tier, route.Reason}]++
+}
+
+// Models возвращает расход по моделям, отсортированный по имени модели
+func (m *Metrics) Models() []ModelUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	models := make([]ModelUsage, 0, len(m.models))
+	for _, usage := range m.models {
+		models = append(models, *usage)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Model < models[j].Model })
+	return models
+}
+
+// Routes возвращает счетчики выбора модели, отсортированные по уровню и причине
+func (m *Metrics) Routes() []RouteCount {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := make([]RouteCount, 0, len(m.routes))
+	for key, count := range m.routes {
+		routes = append(routes, RouteCount{Tier: key.tier, Reason: key.reason, Count: count})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Tier != routes[j].Tier {
+			return routes[i].Tier < routes[j].Tier
+		}
+		return routes[i].Reason < routes[j].Reason
+	})
+	return routes
+}
+
+// Uptime возвращает время работы с момента запуска
+func (m *Metrics) Uptime() time.Duration {
+	return time.Since(m.startedAt)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Уровни моделей, между которыми выбирает маршрутизация
+const (
+	ModelTierLite = "lite"
+	ModelTierPro  = "pro"
+)
+
+// modelSettingAuto — значение настройки model, при котором модель выбирает маршрутизация
+const modelSettingAuto = "auto"
+
+// Причины выбора модели для журнала и метрик
+const (
+	routeReasonSetting = "setting" // Пользователь сам выбрал модель в настройках
+	routeReasonUser    = "user"    // Пользователь с тарифом pro
+	routeReasonContext = "context" // К вопросу добавлены фрагменты документов или базы знаний
+	routeReasonLength  = "length"  // Длинное сообщение
+	routeReasonIntent  = "intent"  // Сообщение похоже на сложную задачу
+	routeReasonDefault = "default" // Короткий простой вопрос
+)
+
+const (
+	defaultLiteModel         = "yandexgpt-lite"
+	defaultProModel          = "yandexgpt"
+	defaultRouteMaxLiteRunes = 300
+)
+
+// defaultProKeywords — начала слов, по которым запрос считается сложным: объяснения,
+// сравнения, анализ, код и планы лучше отдавать старшей модели
+var defaultProKeywords = []string{
+	"объясн", "почему", "сравн", "анализ", "проанализ", "докаж", "обоснуй", "код", "программ",
+	"алгоритм", "оптимиз", "рефактор", "отлад", "стратег", "пошагов", "подробн", "эссе", "резюмир",
+	"explain", "why", "compare", "analy", "prove", "justify", "code", "program", "algorithm",
+	"optimi", "refactor", "debug", "strateg", "step-by-step", "detailed", "essay", "summari",
+}
+
+// ModelRouter выбирает модель Yandex GPT для запроса. Модели задаются именем в каталоге
+// (yandexgpt-lite, yandexgpt/rc, yandexgpt/latest) или полным URI (gpt://…, ds://…).
+type ModelRouter struct {
+	LiteModel    string
+	ProModel     string
+	MaxLiteRunes int            // Сообщения длиннее уходят в pro; 0 — длина не учитывается
+	ProKeywords  []string       // Начала слов, по которым запрос считается сложным
+	ProUsers     map[int64]bool // Пользователи с тарифом pro: в режиме auto всегда получают pro
+}
+
+// ModelRequest — то, что известно о запросе при выборе модели
+type ModelRequest struct {
+	UserID      int64
+	Message     string
+	Setting     string // Значение настройки model: auto, lite или pro
+	WithContext bool   // К запросу добавлены фрагменты документов или базы знаний
+}
+
+// ModelRoute — выбранная модель и причина выбора
+type ModelRoute struct {
+	Tier   string
+	Model  string
+	Reason string
+}
+
+// NewModelRouter создает маршрутизацию с моделями и порогами по умолчанию
+func NewModelRouter() *ModelRouter {
+	return &ModelRouter{
+		LiteModel:    defaultLiteModel,
+		ProModel:     defaultProModel,
+		MaxLiteRunes: defaultRouteMaxLiteRunes,
+		ProKeywords:  defaultProKeywords,
+		ProUsers:     make(map[int64]bool),
+	}
+}
+
+// modelRouterFromEnv читает модели и правила маршрутизации из YANDEX_GPT_MODEL_LITE,
+// YANDEX_GPT_MODEL_PRO, MODEL_ROUTING_MAX_LITE_CHARS, MODEL_ROUTING_KEYWORDS и MODEL_PRO_USERS
+func modelRouterFromEnv() *ModelRouter {
+	router := NewModelRouter()
+	if model := strings.TrimSpace(os.Getenv("YANDEX_GPT_MODEL_LITE")); model != "" {
+		router.LiteModel = model
+	}
+	if model := strings.TrimSpace(os.Getenv("YANDEX_GPT_MODEL_PRO")); model != "" {
+		router.ProModel = model
+	}
+	if value := os.Getenv("MODEL_ROUTING_MAX_LITE_CHARS"); value != "" {
+		if runes, err := strconv.Atoi(value); err == nil && runes >= 0 {
+			router.MaxLiteRunes = runes
+		} else {
+			log.Printf("Неверный порог длины %q, используем %d", value, defaultRouteMaxLiteRunes)
+		}
+	}
+	if value := os.Getenv("MODEL_ROUTING_KEYWORDS"); value != "" {
+		router.ProKeywords = nil
+		for _, keyword := range strings.Split(value, ",") {
+			if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+				router.ProKeywords = append(router.ProKeywords, keyword)
+			}
+		}
+	}
+	router.ProUsers = parseIDList("MODEL_PRO_USERS")
+	return router
+}
+
+// Route выбирает модель: явный выбор пользователя важнее правил, затем тариф пользователя,
+// контекст из документов, длина сообщения и признаки сложной задачи
+func (r *ModelRouter) Route(request ModelRequest) ModelRoute {
+	switch {
+	case request.Setting == ModelTierLite || request.Setting == ModelTierPro:
+		return r.route(request.Setting, routeReasonSetting)
+	case r.ProUsers[request.UserID]:
+		return r.route(ModelTierPro, routeReasonUser)
+	case request.WithContext:
+		return r.route(ModelTierPro, routeReasonContext)
+	case r.MaxLiteRunes > 0 && utf8.RuneCountInString(request.Message) > r.MaxLiteRunes:
+		return r.route(ModelTierPro, routeReasonLength)
+	case r.complex(request.Message):
+		return r.route(ModelTierPro, routeReasonIntent)
+	}
+	return r.route(ModelTierLite, routeReasonDefault)
+}
+
+func (r *ModelRouter) route(tier, reason string) ModelRoute {
+	model := r.LiteModel
+	if tier == ModelTierPro {
+		model = r.ProModel
+	}
+	return ModelRoute{Tier: tier, Model: model, Reason: reason}
+}
+
+// complex проверяет, начинается ли какое-нибудь слово сообщения с ключевого слова сложной задачи
+func (r *ModelRouter) complex(message string) bool {
+	words := strings.FieldsFunc(strings.ToLower(message), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '-'
+	})
+	for _, word := range words {
+		for _, keyword := range r.ProKeywords {
+			if strings.HasPrefix(word, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ModelURI возвращает URI модели для API: полный URI (gpt://, ds://) передается как есть,
+// имя в каталоге вместе с веткой (yandexgpt/rc, yandexgpt-lite/latest) дополняется каталогом folderID
+func ModelURI(folderID, model string) string {
+	if strings.Contains(model, "://") {
+		return model
+	}
+	return fmt.Sprintf("gpt://%s/%s", folderID, model)
+}
+
+// SetModelRouter задает правила выбора модели
+func (a *Agent) SetModelRouter(router *ModelRouter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.router = router
+}
+
+// ModelRouter возвращает правила выбора модели
+func (a *Agent) ModelRouter() *ModelRouter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.router
+}
+
+// routeModel выбирает модель для запроса, подставляет ее в параметры генерации и учитывает выбор в метриках
+func (a *Agent) routeModel(options *GenerationOptions, request ModelRequest) {
+	route := a.ModelRouter().Route(request)
+	options.Model = route.Model
+	a.metrics.RecordRoute(route)
+	log.Printf("Модель для пользователя %d: %s (%s, причина: %s)", request.UserID, route.Model, route.Tier, route.Reason)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModelRoute(t *testing.T) {
+	router := NewModelRouter()
+	router.ProUsers[42] = true
+	long := strings.Repeat("а", defaultRouteMaxLiteRunes+1)
+
+	tests := []struct {
+		name       string
+		request    ModelRequest
+		wantTier   string
+		wantReason string
+	}{
+		{"короткий вопрос", ModelRequest{UserID: 1, Message: "привет"}, ModelTierLite, routeReasonDefault},
+		{"настройка auto", ModelRequest{UserID: 1, Message: "привет", Setting: modelSettingAuto}, ModelTierLite, routeReasonDefault},
+		{"настройка pro", ModelRequest{UserID: 1, Message: "привет", Setting: ModelTierPro}, ModelTierPro, routeReasonSetting},
+		{"настройка lite важнее тарифа", ModelRequest{UserID: 42, Message: "объясни " + long, Setting: ModelTierLite, WithContext: true}, ModelTierLite, routeReasonSetting},
+		{"пользователь pro", ModelRequest{UserID: 42, Message: "привет"}, ModelTierPro, routeReasonUser},
+		{"тариф важнее контекста", ModelRequest{UserID: 42, Message: "привет", WithContext: true}, ModelTierPro, routeReasonUser},
+		{"контекст документов", ModelRequest{UserID: 1, Message: "привет", WithContext: true}, ModelTierPro, routeReasonContext},
+		{"длинное сообщение", ModelRequest{UserID: 1, Message: long}, ModelTierPro, routeReasonLength},
+		{"ровно на пороге", ModelRequest{UserID: 1, Message: long[len("а"):]}, ModelTierLite, routeReasonDefault},
+		{"ключевое слово", ModelRequest{UserID: 1, Message: "Объясни, как работает DNS"}, ModelTierPro, routeReasonIntent},
+		{"ключевое слово по-английски", ModelRequest{UserID: 1, Message: "please REFACTOR this"}, ModelTierPro, routeReasonIntent},
+		{"слово с дефисом", ModelRequest{UserID: 1, Message: "give me a step-by-step guide"}, ModelTierPro, routeReasonIntent},
+		{"ключевое слово только в начале слова", ModelRequest{UserID: 1, Message: "перекодировать видео"}, ModelTierLite, routeReasonDefault},
+	}
+	for _, tt := range tests {
+		route := router.Route(tt.request)
+		wantModel := defaultLiteModel
+		if tt.wantTier == ModelTierPro {
+			wantModel = defaultProModel
+		}
+		if route.Tier != tt.wantTier || route.Reason != tt.wantReason || route.Model != wantModel {
+			t.Errorf("%s: Route = %+v, want %s/%s/%s", tt.name, route, tt.wantTier, wantModel, tt.wantReason)
+		}
+	}
+}
+
+func TestModelRouteWithoutLengthLimit(t *testing.T) {
+	router := NewModelRouter()
+	router.MaxLiteRunes = 0
+	router.LiteModel, router.ProModel = "yandexgpt-lite/rc", "ds://bt120abc"
+
+	if route := router.Route(ModelRequest{Message: strings.Repeat("слово ", 1000)}); route.Tier != ModelTierLite || route.Model != "yandexgpt-lite/rc" {
+		t.Errorf("без порога длины: Route = %+v", route)
+	}
+	if route := router.Route(ModelRequest{Message: "почему небо голубое?"}); route.Model != "ds://bt120abc" {
+		t.Errorf("модель pro из настроек: Route = %+v", route)
+	}
+}
+
+func TestModelRouterFromEnv(t *testing.T) {
+	t.Setenv("YANDEX_GPT_MODEL_LITE", " yandexgpt-lite/rc ")
+	t.Setenv("YANDEX_GPT_MODEL_PRO", "ds://bt120abc")
+	t.Setenv("MODEL_ROUTING_MAX_LITE_CHARS", "10")
+	t.Setenv("MODEL_ROUTING_KEYWORDS", " Перевед , ,translat")
+	t.Setenv("MODEL_PRO_USERS", "7, 8")
+
+	router := modelRouterFromEnv()
+	if router.LiteModel != "yandexgpt-lite/rc" || router.ProModel != "ds://bt120abc" || router.MaxLiteRunes != 10 {
+		t.Errorf("модели и порог: %+v", router)
+	}
+	if strings.Join(router.ProKeywords, ",") != "перевед,translat" {
+		t.Errorf("ключевые слова %q", router.ProKeywords)
+	}
+	if !router.ProUsers[7] || !router.ProUsers[8] || len(router.ProUsers) != 2 {
+		t.Errorf("пользователи pro %v", router.ProUsers)
+	}
+	if route := router.Route(ModelRequest{Message: "объясни"}); route.Tier != ModelTierLite {
+		t.Errorf("ключевые слова по умолчанию должны замениться: Route = %+v", route)
+	}
+
+	t.Setenv("MODEL_ROUTING_MAX_LITE_CHARS", "-1")
+	if router := modelRouterFromEnv(); router.MaxLiteRunes != defaultRouteMaxLiteRunes {
+		t.Errorf("неверный порог должен оставить %d, получилось %d", defaultRouteMaxLiteRunes, router.MaxLiteRunes)
+	}
+}
+
+func TestModelURI(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"yandexgpt-lite", "gpt://b1gfolder/yandexgpt-lite"},
+		{"yandexgpt/rc", "gpt://b1gfolder/yandexgpt/rc"},
+		{"yandexgpt-lite/latest", "gpt://b1gfolder/yandexgpt-lite/latest"},
+		{"gpt://b1gother/yandexgpt/rc", "gpt://b1gother/yandexgpt/rc"},
+		{"ds://bt120abc", "ds://bt120abc"},
+	}
+	for _, tt := range tests {
+		if got := ModelURI("b1gfolder", tt.model); got != tt.want {
+			t.Errorf("ModelURI(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
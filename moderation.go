@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Действия политики модерации в порядке строгости
+const (
+	ModerationAllow = ""      // Нарушений нет
+	ModerationLog   = "log"   // Записать в журнал и ответить как обычно
+	ModerationWarn  = "warn"  // Ответить с предупреждением
+	ModerationBlock = "block" // Отказать в ответе
+)
+
+// Этапы, на которых проверяется текст
+const (
+	ModerationInput  = "input"  // Сообщение пользователя до обращения к модели
+	ModerationOutput = "output" // Ответ модели до отправки пользователю
+)
+
+// moderationSeverity упорядочивает действия, чтобы из нескольких нарушений выбрать самое строгое
+var moderationSeverity = map[string]int{
+	ModerationAllow: 0,
+	ModerationLog:   1,
+	ModerationWarn:  2,
+	ModerationBlock: 3,
+}
+
+// ModerationResult — решение модератора о тексте
+type ModerationResult struct {
+	Action   string
+	Category string // Категория нарушения, например insult или spam
+	Reason   string // Сработавшее правило, для журнала
+}
+
+// stricter возвращает более строгое из двух решений
+func (r ModerationResult) stricter(other ModerationResult) ModerationResult {
+	if moderationSeverity[other.Action] > moderationSeverity[r.Action] {
+		return other
+	}
+	return r
+}
+
+// Moderator проверяет сообщения пользователей и ответы модели
+type Moderator interface {
+	// Moderate проверяет текст на этапе stage (ModerationInput или ModerationOutput)
+	Moderate(text, stage string) (ModerationResult, error)
+}
+
+// ModeratorChain применяет несколько модераторов и возвращает самое строгое решение
+type ModeratorChain []Moderator
+
+// Moderate опрашивает все модераторы; ошибка одного не отменяет проверку остальными
+func (chain ModeratorChain) Moderate(text, stage string) (ModerationResult, error) {
+	var result ModerationResult
+	var errs []string
+	for _, moderator := range chain {
+		verdict, err := moderator.Moderate(text, stage)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		result = result.stricter(verdict)
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("ошибка модерации: %s", strings.Join(errs, "; "))
+	}
+	return result, nil
+}
+
+// moderationRule — правило списка: слово или регулярное выражение с действием
+type moderationRule struct {
+	action   string
+	category string
+	pattern  *regexp.Regexp
+	source   string
+}
+
+// ListModerator проверяет текст по списку слов и регулярных выражений
+type ListModerator struct {
+	rules []moderationRule
+}
+
+// NewListModerator разбирает правила, по одному в строке: "действие категория шаблон".
+// Шаблон с префиксом re: — регулярное выражение, иначе слово или фраза, которые ищутся
+// целиком без учета регистра. Пустые строки и строки с # пропускаются.
+//
+//	block spam re:казино\s+онлайн
+//	warn  insult дурак
+func NewListModerator(rules string) (*ListModerator, error) {
+	m := &ListModerator{}
+	scanner := bufio.NewScanner(strings.NewReader(rules))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("строка %d: ожидается «действие категория шаблон»", line)
+		}
+		action := strings.ToLower(fields[0])
+		if _, ok := moderationSeverity[action]; !ok || action == ModerationAllow {
+			return nil, fmt.Errorf("строка %d: неизвестное действие %q", line, fields[0])
+		}
+		// Шаблон — остаток строки после категории, в нем могут быть пробелы
+		rest := strings.TrimSpace(text[len(fields[0]):])
+		source := strings.TrimSpace(rest[len(fields[1]):])
+
+		var expr string
+		if raw, ok := strings.CutPrefix(source, "re:"); ok {
+			expr = "(?i)" + raw
+		} else {
+			expr = `(?i)(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(source) + `($|[^\p{L}\p{N}])`
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %v", line, err)
+		}
+		m.rules = append(m.rules, moderationRule{action: action, category: fields[1], pattern: pattern, source: source})
+	}
+	return m, scanner.Err()
+}
+
+// LoadListModerator читает правила из файла
+func LoadListModerator(path string) (*ListModerator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения правил модерации: %v", err)
+	}
+	return NewListModerator(string(data))
+}
+
+// Moderate возвращает самое строгое из сработавших правил; список одинаков для обоих этапов
+func (m *ListModerator) Moderate(text, stage string) (ModerationResult, error) {
+	var result ModerationResult
+	for _, rule := range m.rules {
+		if rule.pattern.MatchString(text) {
+			result = result.stricter(ModerationResult{Action: rule.action, Category: rule.category, Reason: rule.source})
+		}
+	}
+	return result, nil
+}
+
+// moderationCategories — категории, которые различает классификатор на основе модели
+var moderationCategories = []string{"insult", "threat", "sexual", "drugs", "extremism", "self_harm", "spam"}
+
+// moderationClassifierPrompt — системный промпт классификатора
+const moderationClassifierPrompt = "Ты модератор чата. Определи, нарушает ли сообщение правила общения. " +
+	"Ответь одним словом: safe, если нарушений нет, иначе категорией нарушения — %s. Не добавляй пояснений."
+
+// LLMModerator классифицирует текст с помощью языковой модели
+type LLMModerator struct {
+	provider func() LLMProvider  // Активный бэкенд модели; nil — проверка пропускается
+	redact   func(string) string // Замена персональных данных перед отправкой модели
+	action   string              // Действие для любой категории нарушений
+}
+
+// NewLLMModerator создает классификатор, который обращается к текущему бэкенду модели.
+// redact может быть nil, если текст отправляется как есть.
+func NewLLMModerator(provider func() LLMProvider, redact func(string) string, action string) *LLMModerator {
+	if redact == nil {
+		redact = func(text string) string { return text }
+	}
+	return &LLMModerator{provider: provider, redact: redact, action: action}
+}
+
+// Moderate просит модель назвать категорию нарушения.
+// Если модель сама отказалась обрабатывать текст, он тоже считается нарушением.
+func (m *LLMModerator) Moderate(text, stage string) (ModerationResult, error) {
+	provider := m.provider()
+	if provider == nil {
+		return ModerationResult{}, nil
+	}
+
+	options := DefaultGenerationOptions()
+	options.Temperature = 0
+	options.MaxTokens = 10
+	completion, err := provider.Generate([]YandexGPTMessage{
+		{Role: "system", Text: fmt.Sprintf(moderationClassifierPrompt, strings.Join(moderationCategories, ", "))},
+		{Role: "user", Text: m.redact(text)},
+	}, options)
+	if err != nil {
+		return ModerationResult{}, err
+	}
+	if completion.Filtered {
+		return ModerationResult{Action: m.action, Category: "filtered", Reason: "фильтр модели"}, nil
+	}
+
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(completion.Text), ".\"'`"))
+	for _, category := range moderationCategories {
+		if strings.HasPrefix(answer, category) {
+			return ModerationResult{Action: m.action, Category: category, Reason: "классификатор"}, nil
+		}
+	}
+	return ModerationResult{}, nil
+}
+
+// createModerator собирает модераторы по конфигурации: список правил из MODERATION_RULES
+// и классификатор на модели при MODERATION_LLM=true. Возвращает nil, если модерация выключена.
+func createModerator(agent *Agent) Moderator {
+	var chain ModeratorChain
+
+	if path := os.Getenv("MODERATION_RULES"); path != "" {
+		list, err := LoadListModerator(path)
+		if err != nil {
+			log.Printf("Правила модерации не загружены: %v", err)
+		} else {
+			log.Printf("Загружено правил модерации: %d", len(list.rules))
+			chain = append(chain, list)
+		}
+	}
+
+	if os.Getenv("MODERATION_LLM") == "true" {
+		action := strings.ToLower(os.Getenv("MODERATION_LLM_ACTION"))
+		if _, ok := moderationSeverity[action]; !ok || action == ModerationAllow {
+			action = ModerationBlock
+		}
+		chain = append(chain, NewLLMModerator(agent.llm, agent.redactText, action))
+	}
+
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain
+}
+
+// Moderator возвращает модератор агента или nil, если модерация выключена
+func (a *Agent) Moderator() Moderator {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.moderator
+}
+
+// SetModerator заменяет модератор; nil отключает модерацию
+func (a *Agent) SetModerator(moderator Moderator) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.moderator = moderator
+}
+
+// moderate проверяет текст и записывает нарушения в журнал и метрики.
+// Ошибка модератора не мешает ответу: текст считается допустимым.
+func (a *Agent) moderate(text, stage string, userID int64) ModerationResult {
+	moderator := a.Moderator()
+	if moderator == nil {
+		return ModerationResult{}
+	}
+
+	result, err := moderator.Moderate(text, stage)
+	if err != nil {
+		log.Printf("Ошибка модерации (%s) для пользователя %d: %v", stage, userID, err)
+	}
+	if result.Action == ModerationAllow {
+		return result
+	}
+
+	log.Printf("Модерация (%s): пользователь %d, действие %s, категория %s, правило %q", stage, userID, result.Action, result.Category, result.Reason)
+	if result.Action == ModerationBlock {
+		a.metrics.ModerationBlocked.Add(1)
+	} else {
+		a.metrics.ModerationFlagged.Add(1)
+	}
+	return result
+}
+
+// moderationNotice возвращает отказ для заблокированного текста или дописывает предупреждение
+func moderationNotice(locale, stage string, result ModerationResult, response string) string {
+	switch result.Action {
+	case ModerationBlock:
+		return T(locale, "moderation.blocked."+stage)
+	case ModerationWarn:
+		return response + "\n\n" + T(locale, "moderation.warning."+stage)
+	}
+	return response
+}
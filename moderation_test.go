@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// funcProvider — бэкенд модели, ответы которого задает тест
+type funcProvider struct {
+	name    string
+	respond func(messages []YandexGPTMessage, options GenerationOptions) (Completion, error)
+}
+
+func (p funcProvider) Name() string { return p.name }
+
+func (p funcProvider) Generate(messages []YandexGPTMessage, options GenerationOptions) (Completion, error) {
+	return p.respond(messages, options)
+}
+
+// answerProvider всегда отвечает одним и тем же
+func answerProvider(completion Completion) funcProvider {
+	return funcProvider{name: "stub", respond: func([]YandexGPTMessage, GenerationOptions) (Completion, error) {
+		return completion, nil
+	}}
+}
+
+// fixedModerator возвращает заданное решение
+type fixedModerator struct {
+	result ModerationResult
+	err    error
+}
+
+func (m fixedModerator) Moderate(text, stage string) (ModerationResult, error) {
+	return m.result, m.err
+}
+
+const testModerationRules = `
+# Комментарии и пустые строки пропускаются
+
+warn  insult дурак
+block spam   re:казино\s+онлайн
+log   spam   промокод
+BLOCK threat убью тебя
+`
+
+func TestListModeratorRules(t *testing.T) {
+	moderator, err := NewListModerator(testModerationRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text         string
+		wantAction   string
+		wantCategory string
+	}{
+		{"Ты ДУРАК!", ModerationWarn, "insult"},
+		{"не будем о дураках", ModerationAllow, ""},
+		{"лучшее Казино   онлайн", ModerationBlock, "spam"},
+		{"держи промокод", ModerationLog, "spam"},
+		{"фраза целиком: убью тебя", ModerationBlock, "threat"},
+		// Сработали три правила — побеждает самое строгое
+		{"дурак, вот промокод в казино онлайн", ModerationBlock, "spam"},
+		{"обычное сообщение", ModerationAllow, ""},
+	}
+	for _, tt := range tests {
+		result, err := moderator.Moderate(tt.text, ModerationInput)
+		if err != nil || result.Action != tt.wantAction || result.Category != tt.wantCategory {
+			t.Errorf("Moderate(%q) = %+v, %v, want %s/%s", tt.text, result, err, tt.wantAction, tt.wantCategory)
+		}
+	}
+}
+
+func TestListModeratorErrors(t *testing.T) {
+	tests := map[string]string{
+		"warn insult":        "строка 1",
+		"allow spam реклама": "неизвестное действие",
+		"ban spam реклама":   "неизвестное действие",
+		"\nblock spam re:(":  "строка 2",
+	}
+	for rules, want := range tests {
+		if _, err := NewListModerator(rules); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("NewListModerator(%q) = %v, want ошибку с %q", rules, err, want)
+		}
+	}
+}
+
+func TestModeratorChainStrictest(t *testing.T) {
+	chain := ModeratorChain{
+		fixedModerator{result: ModerationResult{Action: ModerationLog, Category: "spam"}},
+		fixedModerator{err: errors.New("классификатор недоступен")},
+		fixedModerator{result: ModerationResult{Action: ModerationWarn, Category: "insult"}},
+		fixedModerator{},
+	}
+	result, err := chain.Moderate("текст", ModerationOutput)
+	if result.Action != ModerationWarn || result.Category != "insult" {
+		t.Errorf("решение цепочки %+v, want warn/insult", result)
+	}
+	if err == nil || !strings.Contains(err.Error(), "классификатор недоступен") {
+		t.Errorf("ошибка модератора потеряна: %v", err)
+	}
+}
+
+func TestLLMModerator(t *testing.T) {
+	tests := []struct {
+		name         string
+		completion   Completion
+		wantAction   string
+		wantCategory string
+	}{
+		{"категория нарушения", Completion{Text: "Insult."}, ModerationWarn, "insult"},
+		{"нарушений нет", Completion{Text: "safe"}, ModerationAllow, ""},
+		{"фильтр модели", Completion{Filtered: true}, ModerationWarn, "filtered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent string
+			provider := funcProvider{name: "stub", respond: func(messages []YandexGPTMessage, options GenerationOptions) (Completion, error) {
+				sent = messages[len(messages)-1].Text
+				return tt.completion, nil
+			}}
+			moderator := NewLLMModerator(func() LLMProvider { return provider }, strings.ToUpper, ModerationWarn)
+			result, err := moderator.Moderate("ты дурак", ModerationInput)
+			if err != nil || result.Action != tt.wantAction || result.Category != tt.wantCategory {
+				t.Errorf("Moderate = %+v, %v, want %s/%s", result, err, tt.wantAction, tt.wantCategory)
+			}
+			if sent != "ТЫ ДУРАК" {
+				t.Errorf("модели отправлен текст %q, а не результат redact", sent)
+			}
+		})
+	}
+
+	// Без активного бэкенда проверка пропускается
+	moderator := NewLLMModerator(func() LLMProvider { return nil }, nil, ModerationBlock)
+	if result, err := moderator.Moderate("текст", ModerationInput); err != nil || result.Action != ModerationAllow {
+		t.Errorf("без модели: %+v, %v", result, err)
+	}
+}
+
+func TestContentFilterStatus(t *testing.T) {
+	client := NewYandexGPTClient("key", "folder")
+	client.SetTransport(stubTransport{body: `{"result":{"alternatives":[{"message":{"role":"assistant","text":"Я не могу обсуждать эту тему."},"status":"ALTERNATIVE_STATUS_CONTENT_FILTER"}],"usage":{"inputTextTokens":"14","completionTokens":"7"},"modelVersion":"23.10.2024"}}`})
+
+	completion, err := client.Generate([]YandexGPTMessage{{Role: "user", Text: "вопрос"}}, DefaultGenerationOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !completion.Filtered || completion.Text != "" || completion.InputTokens != 14 {
+		t.Errorf("ответ с фильтром содержимого: %+v", completion)
+	}
+}
+
+func TestFilteredCompletionBecomesRefusal(t *testing.T) {
+	t.Setenv("DATA_DIR", t.TempDir())
+	agent := createAgent()
+	blocked := agent.metrics.ModerationBlocked.Load()
+
+	completion, cacheable, err := agent.complete(answerProvider(Completion{Filtered: true}), []YandexGPTMessage{{Role: "user", Text: "вопрос"}}, DefaultGenerationOptions(), 42, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completion.Text != T(LocaleRU, "moderation.filtered") {
+		t.Errorf("текст отказа %q", completion.Text)
+	}
+	if cacheable {
+		t.Error("отказ фильтра не должен попадать в кэш")
+	}
+	if agent.metrics.ModerationBlocked.Load() != blocked+1 {
+		t.Error("отказ фильтра не учтен в метриках модерации")
+	}
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Виды записей
+const (
+	NoteKindNote = "note" // Заметка
+	NoteKindTodo = "todo" // Задача из списка дел
+)
+
+const (
+	// notesStoreName — имя файла заметок и задач в хранилище
+	notesStoreName = "notes"
+	// maxNotesPerUser — сколько записей может хранить один пользователь
+	maxNotesPerUser = 500
+)
+
+// Note — заметка или задача пользователя
+type Note struct {
+	ID        int64      `json:"id"`
+	Kind      string     `json:"kind"`
+	Text      string     `json:"text"`
+	Tags      []string   `json:"tags,omitempty"`
+	Due       *time.Time `json:"due,omitempty"`
+	Done      bool       `json:"done,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+}
+
+// userNotes — записи одного пользователя; номера не переиспользуются после удаления
+type userNotes struct {
+	NextID int64   `json:"next_id"`
+	Items  []*Note `json:"items"`
+}
+
+// NotesStore хранит заметки и списки дел пользователей
+type NotesStore struct {
+	mu    sync.Mutex
+	store *Store
+	users map[int64]*userNotes
+}
+
+// NewNotesStore загружает записи из хранилища; nil означает хранение только в памяти
+func NewNotesStore(store *Store) *NotesStore {
+	s := &NotesStore{
+		store: store,
+		users: make(map[int64]*userNotes),
+	}
+	if err := store.Load(notesStoreName, &s.users); err != nil {
+		log.Printf("Ошибка загрузки заметок: %v", err)
+	}
+	return s
+}
+
+// Add сохраняет новую запись и возвращает ее с присвоенным номером
+func (s *NotesStore) Add(userID int64, kind, text string, tags []string, due *time.Time) (Note, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Note{}, newLocalizedError("notes.error.empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notes := s.users[userID]
+	if notes == nil {
+		notes = &userNotes{}
+		s.users[userID] = notes
+	}
+	if len(notes.Items) >= maxNotesPerUser {
+		return Note{}, newLocalizedError("notes.error.limit", Params{"max": maxNotesPerUser})
+	}
+
+	notes.NextID++
+	note := &Note{
+		ID:        notes.NextID,
+		Kind:      kind,
+		Text:      text,
+		Tags:      normalizeTags(tags),
+		Due:       due,
+		CreatedAt: time.Now(),
+	}
+	notes.Items = append(notes.Items, note)
+	s.saveLocked()
+
+	return *note, nil
+}
+
+// List возвращает записи указанного вида. Задачи упорядочены по сроку, выполненные — в конце;
+// includeDone включает выполненные задачи.
+func (s *NotesStore) List(userID int64, kind string, includeDone bool) []Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Note
+	for _, note := range s.itemsLocked(userID) {
+		if note.Kind == kind && (includeDone || !note.Done) {
+			result = append(result, *note)
+		}
+	}
+	sortNotes(result)
+	return result
+}
+
+// Search ищет записи всех видов по тексту и тегам; запрос вида #тег ищет только по тегу
+func (s *NotesStore) Search(userID int64, query string) []Note {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	tagOnly := strings.HasPrefix(query, "#")
+	tag := strings.TrimPrefix(query, "#")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Note
+	for _, note := range s.itemsLocked(userID) {
+		if hasTag(note.Tags, tag) || (!tagOnly && strings.Contains(strings.ToLower(note.Text), query)) {
+			result = append(result, *note)
+		}
+	}
+	sortNotes(result)
+	return result
+}
+
+// Complete отмечает задачу выполненной
+func (s *NotesStore) Complete(userID int64, kind string, id int64) (Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note := s.findLocked(userID, kind, id)
+	if note == nil {
+		return Note{}, newLocalizedError("notes.error.not_found", Params{"id": id})
+	}
+	if !note.Done {
+		now := time.Now()
+		note.Done, note.DoneAt = true, &now
+		s.saveLocked()
+	}
+	return *note, nil
+}
+
+// Delete удаляет запись по номеру
+func (s *NotesStore) Delete(userID int64, kind string, id int64) (Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note := s.findLocked(userID, kind, id)
+	if note == nil {
+		return Note{}, newLocalizedError("notes.error.not_found", Params{"id": id})
+	}
+
+	notes := s.users[userID]
+	for i, item := range notes.Items {
+		if item == note {
+			notes.Items = append(notes.Items[:i], notes.Items[i+1:]...)
+			break
+		}
+	}
+	s.saveLocked()
+	return *note, nil
+}
+
+func (s *NotesStore) itemsLocked(userID int64) []*Note {
+	if notes := s.users[userID]; notes != nil {
+		return notes.Items
+	}
+	return nil
+}
+
+func (s *NotesStore) findLocked(userID int64, kind string, id int64) *Note {
+	for _, note := range s.itemsLocked(userID) {
+		if note.ID == id && note.Kind == kind {
+			return note
+		}
+	}
+	return nil
+}
+
+func (s *NotesStore) saveLocked() {
+	if err := s.store.Save(notesStoreName, s.users); err != nil {
+		log.Printf("Ошибка сохранения заметок: %v", err)
+	}
+}
+
+// sortNotes упорядочивает записи: невыполненные раньше, затем по сроку, затем по номеру
+func sortNotes(notes []Note) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		a, b := notes[i], notes[j]
+		if a.Done != b.Done {
+			return !a.Done
+		}
+		if (a.Due == nil) != (b.Due == nil) {
+			return a.Due != nil
+		}
+		if a.Due != nil && !a.Due.Equal(*b.Due) {
+			return a.Due.Before(*b.Due)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// normalizeTags приводит теги к нижнему регистру без решетки и убирает повторы
+func normalizeTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" && !hasTag(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// noteDueMarkers — слова, после которых в тексте задачи указан срок
+var noteDueMarkers = map[string]bool{"до": true, "due": true, "by": true}
+
+// parseNoteText выделяет из текста теги (#дом) и срок в конце ("до пятницы", "due 2026-12-25")
+func parseNoteText(text string, now time.Time, loc *time.Location) (string, []string, *time.Time) {
+	var words, tags []string
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, "#") && len(word) > 1 {
+			tags = append(tags, strings.TrimRight(word, ".,!?;:"))
+			continue
+		}
+		words = append(words, word)
+	}
+
+	// Ищем последний маркер срока, после которого весь остаток — указание даты
+	for i := len(words) - 2; i >= 1; i-- {
+		if !noteDueMarkers[strings.ToLower(words[i])] {
+			continue
+		}
+		if due, ok := parseDueDate(strings.Join(words[i+1:], " "), now, loc); ok {
+			return strings.Join(words[:i], " "), normalizeTags(tags), &due
+		}
+	}
+	return strings.Join(words, " "), normalizeTags(tags), nil
+}
+
+// parseDue разбирает срок из аргумента функции: дату 2026-12-25 или фразу "завтра"
+func parseDue(value string, now time.Time, loc *time.Location) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return &t, nil
+	}
+	if t, ok := parseDueDate(value, now, loc); ok {
+		return &t, nil
+	}
+	return nil, newLocalizedError("notes.error.due", Params{"due": value})
+}
+
+// formatNoteList выводит записи списком с заголовком
+func formatNoteList(locale, title string, notes []Note, now time.Time, loc *time.Location) string {
+	var builder strings.Builder
+	builder.WriteString(title + "\n\n")
+	for _, note := range notes {
+		builder.WriteString(formatNote(locale, note, now, loc) + "\n")
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+// formatNote выводит запись одной строкой: номер, отметка, текст, теги и срок
+func formatNote(locale string, note Note, now time.Time, loc *time.Location) string {
+	line := strconv.FormatInt(note.ID, 10) + ". "
+	switch {
+	case note.Kind == NoteKindTodo && note.Done:
+		line += "✅ "
+	case note.Kind == NoteKindTodo:
+		line += "▫️ "
+	default:
+		line += "📝 "
+	}
+	line += note.Text
+
+	for _, tag := range note.Tags {
+		line += " #" + tag
+	}
+
+	if note.Due != nil {
+		due := note.Due.In(loc)
+		date := due.Format(T(locale, "format.date"))
+		today := time.Date(now.In(loc).Year(), now.In(loc).Month(), now.In(loc).Day(), 0, 0, 0, 0, loc)
+		if !note.Done && due.Before(today) {
+			line += " — " + T(locale, "notes.overdue", Params{"date": date})
+		} else {
+			line += " — " + T(locale, "notes.due", Params{"date": date})
+		}
+	}
+	return line
+}
+
+// noteTriggers сопоставляет начало сообщения с инструментом заметок или задач
+var noteTriggers = map[string]string{
+	"запиши": "notes", "запишите": "notes", "заметка": "notes",
+	"note": "notes", "note down": "notes", "make a note": "notes",
+	"remember": "notes", "remember this": "notes", "remember that": "notes",
+	"todo": "todo", "задача": "todo", "task": "todo",
+	"add to my list": "todo", "add to my todo list": "todo", "add to my to-do list": "todo",
+}
+
+// noteTrigger находит самую длинную фразу-обращение в начале сообщения
+// и возвращает инструмент и текст записи после нее
+func noteTrigger(message string) (string, string) {
+	best := ""
+	for phrase := range noteTriggers {
+		if len(phrase) <= len(best) || len(message) < len(phrase) || !strings.EqualFold(message[:len(phrase)], phrase) {
+			continue
+		}
+		// Фраза должна заканчиваться на границе слова: "notebook" — не "note"
+		if len(message) == len(phrase) || strings.ContainsRune(" \t\n:,.", rune(message[len(phrase)])) {
+			best = phrase
+		}
+	}
+	if best == "" {
+		return "", message
+	}
+	return noteTriggers[best], strings.TrimSpace(strings.TrimLeft(message[len(best):], ":,. \t\n"))
+}
+
+// noteListPhrases — просьбы показать записи
+var noteListPhrases = map[string]string{
+	"мои заметки": "notes", "покажи заметки": "notes", "my notes": "notes", "show notes": "notes",
+	"список дел": "todo", "мои задачи": "todo", "покажи задачи": "todo", "my todos": "todo", "todo list": "todo",
+}
+
+// notesToolFor возвращает инструмент заметок или задач, если сообщение к нему обращается
+func notesToolFor(message string) string {
+	message = strings.TrimSpace(strings.ToLower(message))
+	if tool, ok := noteListPhrases[strings.TrimRight(message, ".!?")]; ok {
+		return tool
+	}
+	tool, _ := noteTrigger(message)
+	return tool
+}
+
+// notesToolParameters описывает аргументы инструментов notes и todo для модели
+func notesToolParameters(kind string) map[string]any {
+	actions := []string{"add", "list", "delete", "search"}
+	if kind == NoteKindTodo {
+		actions = []string{"add", "list", "complete", "delete", "search"}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{"type": "string", "enum": actions, "description": "Действие"},
+			"text":   map[string]any{"type": "string", "description": "Текст записи для add"},
+			"tags": map[string]any{
+				"type": "array", "items": map[string]any{"type": "string"},
+				"description": "Теги записи для add без символа #",
+			},
+			"due":   map[string]any{"type": "string", "description": "Срок для add в формате YYYY-MM-DD"},
+			"id":    map[string]any{"type": "integer", "description": "Номер записи для complete и delete"},
+			"query": map[string]any{"type": "string", "description": "Строка или #тег для search"},
+			"include_done": map[string]any{
+				"type": "boolean", "description": "Показывать выполненные задачи в list",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// notesToolArgs — аргументы вызова инструментов notes и todo моделью
+type notesToolArgs struct {
+	Action      string          `json:"action"`
+	Text        string          `json:"text"`
+	Tags        []string        `json:"tags"`
+	Due         string          `json:"due"`
+	ID          json.RawMessage `json:"id"` // Модель может прислать номер и числом, и строкой
+	Query       string          `json:"query"`
+	IncludeDone bool            `json:"include_done"`
+}
+
+func (args notesToolArgs) id() (int64, bool) {
+	id, err := strconv.ParseInt(strings.Trim(string(args.ID), `" `), 10, 64)
+	return id, err == nil
+}
+
+// callNotesTool выполняет действие с заметками или задачами по вызову модели
+func (a *Agent) callNotesTool(kind string, raw json.RawMessage, userID int64) (string, error) {
+	var args notesToolArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", err
+	}
+	locale := a.LocaleFor(userID)
+	loc := a.LocationFor(userID)
+	now := time.Now()
+
+	switch args.Action {
+	case "add":
+		due, err := parseDue(args.Due, now, loc)
+		if err != nil {
+			return "", err
+		}
+		text, tags, textDue := parseNoteText(args.Text, now, loc)
+		if due == nil {
+			due = textDue
+		}
+		note, err := a.notes.Add(userID, kind, text, append(tags, args.Tags...), due)
+		if err != nil {
+			return "", err
+		}
+		return noteAddedText(locale, note, now, loc), nil
+
+	case "list":
+		return a.notesListText(locale, userID, kind, args.IncludeDone, now, loc), nil
+
+	case "search":
+		return a.notesSearchText(locale, userID, args.Query, now, loc), nil
+
+	case "complete", "delete":
+		id, ok := args.id()
+		if !ok {
+			return "", newLocalizedError("notes.error.not_found", Params{"id": string(args.ID)})
+		}
+		if args.Action == "complete" {
+			note, err := a.notes.Complete(userID, kind, id)
+			if err != nil {
+				return "", err
+			}
+			return T(locale, "todo.completed", Params{"text": note.Text}), nil
+		}
+		note, err := a.notes.Delete(userID, kind, id)
+		if err != nil {
+			return "", err
+		}
+		return T(locale, "notes.deleted", Params{"text": note.Text}), nil
+	}
+	return "", newLocalizedError("notes.error.action", Params{"action": args.Action})
+}
+
+// handleNotesText обрабатывает обращение к заметкам или задачам обычным текстом:
+// "запиши: код от домофона 1234", "todo купить молоко #дом до пятницы", "мои заметки"
+func (a *Agent) handleNotesText(kind, message string, userID int64) (string, error) {
+	locale := a.LocaleFor(userID)
+	loc := a.LocationFor(userID)
+	now := time.Now()
+
+	trimmed := strings.TrimSpace(message)
+	if _, isList := noteListPhrases[strings.TrimRight(strings.ToLower(trimmed), ".!?")]; isList {
+		return a.notesListText(locale, userID, kind, false, now, loc), nil
+	}
+
+	// Убираем обращение: "запиши", "todo:", "note down" и т.п.
+	if tool, rest := noteTrigger(trimmed); tool != "" {
+		trimmed = rest
+	}
+	if trimmed == "" {
+		return a.notesListText(locale, userID, kind, false, now, loc), nil
+	}
+
+	text, tags, due := parseNoteText(trimmed, now, loc)
+	note, err := a.notes.Add(userID, kind, text, tags, due)
+	if err != nil {
+		return "❌ " + localizeError(locale, err), nil
+	}
+	return noteAddedText(locale, note, now, loc), nil
+}
+
+// notesListText выводит записи пользователя или подсказку, если их нет
+func (a *Agent) notesListText(locale string, userID int64, kind string, includeDone bool, now time.Time, loc *time.Location) string {
+	notes := a.notes.List(userID, kind, includeDone)
+	if len(notes) == 0 {
+		return T(locale, noteKeyPrefix(kind)+".empty")
+	}
+	return formatNoteList(locale, T(locale, noteKeyPrefix(kind)+".list_title"), notes, now, loc)
+}
+
+// notesSearchText выводит результаты поиска по заметкам и задачам
+func (a *Agent) notesSearchText(locale string, userID int64, query string, now time.Time, loc *time.Location) string {
+	notes := a.notes.Search(userID, query)
+	if len(notes) == 0 {
+		return T(locale, "notes.search_empty", Params{"query": query})
+	}
+	return formatNoteList(locale, T(locale, "notes.search_title", Params{"query": query}), notes, now, loc)
+}
+
+// noteAddedText подтверждает сохранение записи
+func noteAddedText(locale string, note Note, now time.Time, loc *time.Location) string {
+	return T(locale, noteKeyPrefix(note.Kind)+".added", Params{"note": formatNote(locale, note, now, loc)})
+}
+
+// noteKeyPrefix возвращает префикс ключей каталога строк для вида записей
+func noteKeyPrefix(kind string) string {
+	if kind == NoteKindTodo {
+		return "todo"
+	}
+	return "notes"
+}
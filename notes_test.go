@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestNotesToolRouting(t *testing.T) {
+	tests := []struct {
+		message  string
+		wantTool string
+		wantText string
+	}{
+		{"запиши: код от домофона 1234", "notes", "код от домофона 1234"},
+		{"Запиши купить молоко", "notes", "купить молоко"},
+		{"заметка пароль от wifi на роутере", "notes", "пароль от wifi на роутере"},
+		{"remember this list: milk, eggs", "notes", "list: milk, eggs"},
+		{"Remember that the door code is 1234", "notes", "the door code is 1234"},
+		{"note down: call the bank", "notes", "call the bank"},
+		{"make a note, parking on level 3", "notes", "parking on level 3"},
+		{"todo: купить молоко до пятницы", "todo", "купить молоко до пятницы"},
+		{"задача отправить отчет", "todo", "отправить отчет"},
+		{"Add to my list: buy milk", "todo", "buy milk"},
+		{"add to my to-do list renew passport by 2026-12-25", "todo", "renew passport by 2026-12-25"},
+		{"notebook with 16 GB of RAM", "", "notebook with 16 GB of RAM"},
+		{"rememberable quotes", "", "rememberable quotes"},
+		{"какая погода в Москве", "", "какая погода в Москве"},
+	}
+	for _, tt := range tests {
+		tool, text := noteTrigger(tt.message)
+		if tool != tt.wantTool || text != tt.wantText {
+			t.Errorf("noteTrigger(%q) = %q, %q, want %q, %q", tt.message, tool, text, tt.wantTool, tt.wantText)
+		}
+	}
+}
+
+func TestNotesListPhrases(t *testing.T) {
+	tests := map[string]string{
+		"мои заметки":    "notes",
+		"Show notes!":    "notes",
+		"список дел":     "todo",
+		"my todos?":      "todo",
+		"покажи погоду":  "",
+		"notes are here": "",
+	}
+	for message, want := range tests {
+		if got := notesToolFor(message); got != want {
+			t.Errorf("notesToolFor(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
+
+func TestDetermineToolRoutesNotes(t *testing.T) {
+	t.Setenv("DATA_DIR", t.TempDir())
+	agent := createAgent()
+	tests := map[string]string{
+		"remember this list: milk, eggs": "notes",
+		"запиши купить молоко":           "notes",
+		"add to my list: buy milk":       "todo",
+		"напомни завтра записать отчет":  "remind",
+		"note down the weather forecast": "notes",
+	}
+	for message, want := range tests {
+		if got := agent.determineTool(message); got != want {
+			t.Errorf("determineTool(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
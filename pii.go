@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Категории персональных данных, которые распознает редактор
+const (
+	PIIEmail    = "email"
+	PIIPhone    = "phone"
+	PIICard     = "card"
+	PIIPassport = "passport"
+	PIISNILS    = "snils"
+	PIIINN      = "inn"
+	PIIIBAN     = "iban"
+)
+
+// Режимы обработки категории
+const (
+	PIIModeOff     = "off"     // Данные отправляются модели как есть
+	PIIModeRestore = "restore" // Заменяются меткой, в ответе возвращается исходное значение
+	PIIModeMask    = "mask"    // Заменяются меткой, в ответе показывается маска: •••• 1234
+)
+
+// piiPattern описывает, как найти данные одной категории.
+// Group — номер группы регулярного выражения со значением (0 — все совпадение),
+// чтобы ключевые слова вроде «паспорт» оставались в тексте. Valid отсекает случайные
+// последовательности цифр по контрольной сумме.
+type piiPattern struct {
+	Category string
+	Regexp   *regexp.Regexp
+	Group    int
+	Valid    func(value string) bool
+}
+
+// piiPatterns проверяются по порядку: сначала форматы с буквами и контрольными суммами,
+// затем телефоны, чтобы номер карты или СНИЛС не приняли за телефон
+var piiPatterns = []piiPattern{
+	{Category: PIIEmail, Regexp: regexp.MustCompile(`[\p{L}0-9._%+\-]+@[\p{L}0-9\-]+(?:\.[\p{L}0-9\-]+)*\.\p{L}{2,}`)},
+	{Category: PIIIBAN, Regexp: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`), Valid: validIBAN},
+	{Category: PIICard, Regexp: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), Valid: validCard},
+	{Category: PIISNILS, Regexp: regexp.MustCompile(`\b\d{3}-\d{3}-\d{3}[ \-]\d{2}\b`), Valid: validSNILS},
+	{Category: PIIPassport, Regexp: regexp.MustCompile(`(?i)(?:паспорт\p{L}*|серия)\D{0,20}?(\d{2} ?\d{2} ?(?:(?:№|номер|n)\s*)?\d{6})\b`), Group: 1},
+	{Category: PIIINN, Regexp: regexp.MustCompile(`(?i)инн\D{0,5}?(\d{12}|\d{10})\b`), Group: 1, Valid: validINN},
+	{Category: PIIPhone, Regexp: regexp.MustCompile(`(?:\+7|\b8)[ \-]?\(?\d{3}\)?[ \-]?\d{3}[ \-]?\d{2}[ \-]?\d{2}\b`)},
+	{Category: PIIPhone, Regexp: regexp.MustCompile(`\+\d{1,3}[ \-]?\(?\d{1,4}\)?(?:[ \-]?\d{2,4}){2,4}\b`), Valid: validInternationalPhone},
+}
+
+// DefaultPIIModes — режимы по умолчанию: контакты пользователя возвращаются в ответе,
+// а номера документов и карт показываются только маской
+func DefaultPIIModes() map[string]string {
+	return map[string]string{
+		PIIEmail:    PIIModeRestore,
+		PIIPhone:    PIIModeRestore,
+		PIICard:     PIIModeMask,
+		PIIPassport: PIIModeMask,
+		PIISNILS:    PIIModeMask,
+		PIIINN:      PIIModeMask,
+		PIIIBAN:     PIIModeMask,
+	}
+}
+
+// piiModesFromEnv читает режимы из PII_REDACTION: "off" отключает редактирование,
+// а список вида "email:off,card:restore" меняет режимы отдельных категорий
+func piiModesFromEnv() map[string]string {
+	modes := DefaultPIIModes()
+	value := strings.TrimSpace(strings.ToLower(os.Getenv("PII_REDACTION")))
+	if value == PIIModeOff {
+		return nil
+	}
+
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		category, mode, _ := strings.Cut(item, ":")
+		_, known := modes[category]
+		if !known || (mode != PIIModeOff && mode != PIIModeRestore && mode != PIIModeMask) {
+			log.Printf("Неверная настройка PII_REDACTION %q", item)
+			continue
+		}
+		modes[category] = mode
+	}
+	return modes
+}
+
+// Redactor заменяет персональные данные метками, прежде чем текст уйдет во внешний API
+type Redactor struct {
+	modes map[string]string
+}
+
+// NewRedactor создает редактор с режимами по категориям; nil или пустые режимы отключают его
+func NewRedactor(modes map[string]string) *Redactor {
+	if len(modes) == 0 {
+		return nil
+	}
+	return &Redactor{modes: modes}
+}
+
+// mode возвращает режим категории; неизвестные категории не редактируются
+func (r *Redactor) mode(category string) string {
+	if mode, ok := r.modes[category]; ok {
+		return mode
+	}
+	return PIIModeOff
+}
+
+// Redaction — замены в одном запросе к модели. Одинаковые значения получают одну метку,
+// поэтому модель может ссылаться на них, а ответ — восстановить их.
+type Redaction struct {
+	redactor *Redactor
+	values   map[string]piiValue // Метка → исходное значение
+	labels   map[string]string   // Исходное значение → метка
+	counts   map[string]int
+}
+
+// piiValue — замененное значение и его категория
+type piiValue struct {
+	Category string
+	Value    string
+}
+
+// NewRedaction начинает замены для нового запроса; у выключенного редактора возвращает nil
+func (r *Redactor) NewRedaction() *Redaction {
+	if r == nil {
+		return nil
+	}
+	return &Redaction{
+		redactor: r,
+		values:   make(map[string]piiValue),
+		labels:   make(map[string]string),
+		counts:   make(map[string]int),
+	}
+}
+
+// Redact заменяет найденные персональные данные метками вида [PHONE_1]
+func (rd *Redaction) Redact(text string) string {
+	if rd == nil || text == "" {
+		return text
+	}
+	for _, pattern := range piiPatterns {
+		if rd.redactor.mode(pattern.Category) == PIIModeOff {
+			continue
+		}
+		text = rd.replace(text, pattern)
+	}
+	return text
+}
+
+func (rd *Redaction) replace(text string, pattern piiPattern) string {
+	var out strings.Builder
+	last := 0
+	for _, match := range pattern.Regexp.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2*pattern.Group], match[2*pattern.Group+1]
+		if start < 0 {
+			continue
+		}
+		value := text[start:end]
+		if pattern.Valid != nil && !pattern.Valid(value) {
+			continue
+		}
+		out.WriteString(text[last:start])
+		out.WriteString(rd.label(pattern.Category, value))
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// label возвращает метку значения, заводя новую при первой встрече
+func (rd *Redaction) label(category, value string) string {
+	if label, ok := rd.labels[value]; ok {
+		return label
+	}
+	rd.counts[category]++
+	label := fmt.Sprintf("[%s_%d]", strings.ToUpper(category), rd.counts[category])
+	rd.labels[value] = label
+	rd.values[label] = piiValue{Category: category, Value: value}
+	return label
+}
+
+// Found сообщает, были ли в запросе персональные данные
+func (rd *Redaction) Found() bool {
+	return rd != nil && len(rd.values) > 0
+}
+
+// Restore подставляет в ответ модели исходные значения категорий restore
+// и маски категорий mask
+func (rd *Redaction) Restore(text string) string {
+	if !rd.Found() {
+		return text
+	}
+	for label, original := range rd.values {
+		value := original.Value
+		if rd.redactor.mode(original.Category) == PIIModeMask {
+			value = maskPII(original.Category, value)
+		}
+		text = strings.ReplaceAll(text, label, value)
+	}
+	return text
+}
+
+// RestoreStream оборачивает onDelta: фрагменты ответа модели передаются с восстановленными
+// данными, как в Restore. Хвост, похожий на начало метки, придерживается, пока метка не придет целиком.
+func (rd *Redaction) RestoreStream(onDelta func(text string)) func(text string) {
+	if onDelta == nil {
+		return nil
+	}
+	var raw strings.Builder
+	sent := 0
+	return func(delta string) {
+		raw.WriteString(delta)
+		text := raw.String()
+		if open := strings.LastIndexByte(text, '['); open >= 0 && !strings.Contains(text[open:], "]") {
+			text = text[:open]
+		}
+		if restored := rd.Restore(text); len(restored) > sent {
+			onDelta(restored[sent:])
+			sent = len(restored)
+		}
+	}
+}
+
+// Reveal подставляет все исходные значения. Используется для аргументов инструментов,
+// которые выполняются у нас: заметка с номером телефона должна сохранить сам номер.
+func (rd *Redaction) Reveal(text string) string {
+	if !rd.Found() {
+		return text
+	}
+	for label, original := range rd.values {
+		text = strings.ReplaceAll(text, label, original.Value)
+	}
+	return text
+}
+
+// piiInstruction объясняет модели, что метки нужно сохранять как есть
+const piiInstruction = "Персональные данные в сообщениях заменены метками вида [PHONE_1] или [EMAIL_1]. " +
+	"Если нужно упомянуть эти данные, пиши метку без изменений."
+
+// RedactMessages возвращает копию диалога с замененными персональными данными.
+// Если что-то заменено, в системный промпт добавляется просьба сохранять метки.
+func (rd *Redaction) RedactMessages(messages []YandexGPTMessage) []YandexGPTMessage {
+	if rd == nil {
+		return messages
+	}
+	redacted := make([]YandexGPTMessage, len(messages))
+	for i, message := range messages {
+		message.Text = rd.Redact(message.Text)
+		if message.ToolResultList != nil {
+			list := &YandexGPTToolResultList{ToolResults: append([]YandexGPTToolResult(nil), message.ToolResultList.ToolResults...)}
+			for j := range list.ToolResults {
+				list.ToolResults[j].FunctionResult.Content = rd.Redact(list.ToolResults[j].FunctionResult.Content)
+			}
+			message.ToolResultList = list
+		}
+		redacted[i] = message
+	}
+	if !rd.Found() {
+		return redacted
+	}
+	if len(redacted) > 0 && redacted[0].Role == "system" {
+		redacted[0].Text += "\n\n" + piiInstruction
+		return redacted
+	}
+	return append([]YandexGPTMessage{{Role: "system", Text: piiInstruction}}, redacted...)
+}
+
+// maskPII скрывает значение, оставляя его узнаваемым: i•••@mail.ru, •••• 1234
+func maskPII(category, value string) string {
+	if category == PIIEmail {
+		name, domain, _ := strings.Cut(value, "@")
+		runes := []rune(name)
+		return string(runes[:1]) + "•••@" + domain
+	}
+
+	var digits []rune
+	for _, r := range value {
+		if unicode.IsDigit(r) || unicode.IsLetter(r) {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) <= 4 {
+		return "••••"
+	}
+	return "•••• " + string(digits[len(digits)-4:])
+}
+
+// onlyDigits возвращает цифры значения
+func onlyDigits(value string) []int {
+	var digits []int
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	return digits
+}
+
+// validCard проверяет номер карты по алгоритму Луна
+func validCard(value string) bool {
+	digits := onlyDigits(value)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validSNILS проверяет контрольное число СНИЛС
+func validSNILS(value string) bool {
+	digits := onlyDigits(value)
+	if len(digits) != 11 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += digits[i] * (9 - i)
+	}
+	check := sum % 101
+	if check == 100 {
+		check = 0
+	}
+	return check == digits[9]*10+digits[10]
+}
+
+// validINN проверяет контрольные цифры ИНН организации (10 цифр) или физического лица (12 цифр)
+func validINN(value string) bool {
+	digits := onlyDigits(value)
+	checksum := func(weights []int) int {
+		sum := 0
+		for i, w := range weights {
+			sum += digits[i] * w
+		}
+		return sum % 11 % 10
+	}
+	switch len(digits) {
+	case 10:
+		return checksum([]int{2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[9]
+	case 12:
+		return checksum([]int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[10] &&
+			checksum([]int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[11]
+	}
+	return false
+}
+
+// validIBAN проверяет IBAN по модулю 97
+func validIBAN(value string) bool {
+	value = strings.ReplaceAll(value, " ", "")
+	if len(value) < 15 || len(value) > 34 {
+		return false
+	}
+	var numeric strings.Builder
+	for _, r := range value[4:] + value[:4] {
+		if r >= 'A' && r <= 'Z' {
+			numeric.WriteString(fmt.Sprint(r - 'A' + 10))
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+	number, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
+
+// validInternationalPhone отсекает короткие последовательности: в номере E.164 от 10 до 15 цифр
+func validInternationalPhone(value string) bool {
+	n := len(onlyDigits(value))
+	return n >= 10 && n <= 15
+}
+
+// Redactor возвращает редактор персональных данных или nil, если он выключен
+func (a *Agent) Redactor() *Redactor {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.redactor
+}
+
+// SetRedactor заменяет редактор персональных данных; nil отключает редактирование
+func (a *Agent) SetRedactor(redactor *Redactor) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.redactor = redactor
+}
+
+// redactText заменяет персональные данные в отдельном тексте, например для классификатора модерации
+func (a *Agent) redactText(text string) string {
+	return a.Redactor().NewRedaction().Redact(text)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactEmails(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"пишите на ivan.petrov@mail.ru", "пишите на [EMAIL_1]"},
+		{"user+tag@example.co.uk и admin@example.com", "[EMAIL_1] и [EMAIL_2]"},
+		{"почта иван@почта.рф", "почта [EMAIL_1]"},
+		{"a@b.c и @username — не адреса", "a@b.c и @username — не адреса"},
+	}
+	for _, tt := range tests {
+		rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+		if got := rd.Redact(tt.text); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRedactPhones(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"мой номер +7 (912) 345-67-89", "мой номер [PHONE_1]"},
+		{"звоните 8 912 345 67 89", "звоните [PHONE_1]"},
+		{"+79123456789", "[PHONE_1]"},
+		{"London +44 20 7946 0958", "London [PHONE_1]"},
+		{"код +12 34", "код +12 34"},
+		{"заказ 912345678", "заказ 912345678"},
+	}
+	for _, tt := range tests {
+		rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+		if got := rd.Redact(tt.text); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRedactCards(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"карта 4111 1111 1111 1111", "карта [CARD_1]"},
+		{"карта 4111-1111-1111-1111", "карта [CARD_1]"},
+		{"5500000000000004", "[CARD_1]"},
+		// Не проходит проверку по алгоритму Луна
+		{"карта 4111 1111 1111 1112", "карта 4111 1111 1111 1112"},
+		{"трек 1234567890123", "трек 1234567890123"},
+	}
+	for _, tt := range tests {
+		rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+		if got := rd.Redact(tt.text); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRedactDocuments(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"СНИЛС 112-233-445 95", "СНИЛС [SNILS_1]"},
+		{"СНИЛС 112-233-445 96", "СНИЛС 112-233-445 96"},
+		{"ИНН 7707083893", "ИНН [INN_1]"},
+		{"ИНН 7707083894", "ИНН 7707083894"},
+		{"паспорт 45 08 123456", "паспорт [PASSPORT_1]"},
+		{"IBAN GB82 WEST 1234 5698 7654 32", "IBAN [IBAN_1]"},
+	}
+	for _, tt := range tests {
+		rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+		if got := rd.Redact(tt.text); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRedactSameValueSameLabel(t *testing.T) {
+	rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+	first := rd.Redact("пишите на a.b@mail.ru")
+	second := rd.Redact("повторю: a.b@mail.ru, копия c.d@mail.ru")
+	if first != "пишите на [EMAIL_1]" || second != "повторю: [EMAIL_1], копия [EMAIL_2]" {
+		t.Errorf("Redact = %q, %q", first, second)
+	}
+}
+
+func TestRestoreInResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		modes    map[string]string
+		text     string
+		response func(redacted string) string
+		want     string
+	}{
+		{
+			name:     "restore возвращает исходное значение",
+			modes:    DefaultPIIModes(),
+			text:     "мой email ivan@mail.ru",
+			response: func(string) string { return "Записал адрес [EMAIL_1]" },
+			want:     "Записал адрес ivan@mail.ru",
+		},
+		{
+			name:     "mask показывает последние цифры карты",
+			modes:    DefaultPIIModes(),
+			text:     "карта 4111 1111 1111 1111",
+			response: func(string) string { return "Карта [CARD_1] сохранена" },
+			want:     "Карта •••• 1111 сохранена",
+		},
+		{
+			name:     "mask у email оставляет первую букву и домен",
+			modes:    map[string]string{PIIEmail: PIIModeMask},
+			text:     "ivan@mail.ru",
+			response: func(string) string { return "[EMAIL_1]" },
+			want:     "i•••@mail.ru",
+		},
+		{
+			name:     "несколько меток в одном ответе",
+			modes:    DefaultPIIModes(),
+			text:     "+7 912 345 67 89, ivan@mail.ru",
+			response: func(redacted string) string { return "Контакты: " + redacted },
+			want:     "Контакты: +7 912 345 67 89, ivan@mail.ru",
+		},
+		{
+			name:     "ответ без меток не меняется",
+			modes:    DefaultPIIModes(),
+			text:     "ivan@mail.ru",
+			response: func(string) string { return "Готово" },
+			want:     "Готово",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := NewRedactor(tt.modes).NewRedaction()
+			redacted := rd.Redact(tt.text)
+			if got := rd.Restore(tt.response(redacted)); got != tt.want {
+				t.Errorf("Restore = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevealRestoresMaskedValues(t *testing.T) {
+	rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+	rd.Redact("карта 4111 1111 1111 1111")
+	if got := rd.Reveal(`{"text":"карта [CARD_1]"}`); got != `{"text":"карта 4111 1111 1111 1111"}` {
+		t.Errorf("Reveal = %q", got)
+	}
+}
+
+func TestRestoreStream(t *testing.T) {
+	rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+	rd.Redact("мой email ivan@mail.ru")
+
+	var got []string
+	write := rd.RestoreStream(func(text string) { got = append(got, text) })
+	for _, delta := range []string{"Записал ", "адрес [EM", "AIL_1", "]", " и ссылку [", "сайт](x)"} {
+		write(delta)
+	}
+	// Фрагменты с началом метки придерживаются, пока метка не закроется
+	want := []string{"Записал ", "адрес ", "ivan@mail.ru", " и ссылку ", "[сайт](x)"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("фрагменты %q, want %q", got, want)
+	}
+	if rd.RestoreStream(nil) != nil {
+		t.Error("RestoreStream(nil) должен возвращать nil")
+	}
+}
+
+func TestRedactorDisabled(t *testing.T) {
+	text := "ivan@mail.ru, +7 912 345 67 89, 4111 1111 1111 1111"
+
+	if NewRedactor(nil) != nil || NewRedactor(map[string]string{}) != nil {
+		t.Fatal("NewRedactor без режимов должен возвращать nil")
+	}
+	var disabled *Redactor
+	rd := disabled.NewRedaction()
+	if got := rd.Redact(text); got != text {
+		t.Errorf("выключенный редактор изменил текст: %q", got)
+	}
+	if rd.Found() {
+		t.Error("Found у выключенного редактора")
+	}
+	if got := rd.Restore("[EMAIL_1]"); got != "[EMAIL_1]" {
+		t.Errorf("Restore у выключенного редактора = %q", got)
+	}
+	messages := []YandexGPTMessage{{Role: "user", Text: text}}
+	if got := rd.RedactMessages(messages); got[0].Text != text {
+		t.Errorf("RedactMessages у выключенного редактора = %q", got[0].Text)
+	}
+}
+
+func TestRedactCategoryOff(t *testing.T) {
+	modes := DefaultPIIModes()
+	modes[PIIEmail] = PIIModeOff
+	rd := NewRedactor(modes).NewRedaction()
+	got := rd.Redact("ivan@mail.ru, +7 912 345 67 89")
+	if got != "ivan@mail.ru, [PHONE_1]" {
+		t.Errorf("Redact = %q", got)
+	}
+}
+
+func TestRedactMessagesAddsInstruction(t *testing.T) {
+	rd := NewRedactor(DefaultPIIModes()).NewRedaction()
+	got := rd.RedactMessages([]YandexGPTMessage{
+		{Role: "system", Text: "Ты помощник."},
+		{Role: "user", Text: "мой email ivan@mail.ru"},
+	})
+	if !strings.HasSuffix(got[0].Text, piiInstruction) {
+		t.Errorf("в системный промпт не добавлена инструкция: %q", got[0].Text)
+	}
+	if got[1].Text != "мой email [EMAIL_1]" {
+		t.Errorf("сообщение пользователя = %q", got[1].Text)
+	}
+
+	clean := NewRedactor(DefaultPIIModes()).NewRedaction().RedactMessages([]YandexGPTMessage{{Role: "user", Text: "привет"}})
+	if len(clean) != 1 {
+		t.Errorf("без персональных данных инструкция не нужна: %+v", clean)
+	}
+}
+
+func TestPIIModesFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+	}{
+		{"", DefaultPIIModes()},
+		{"off", nil},
+		{"email:off,card:restore", func() map[string]string {
+			modes := DefaultPIIModes()
+			modes[PIIEmail] = PIIModeOff
+			modes[PIICard] = PIIModeRestore
+			return modes
+		}()},
+		// Неизвестные категории и режимы пропускаются
+		{"fax:off phone:hide", DefaultPIIModes()},
+	}
+	for _, tt := range tests {
+		t.Setenv("PII_REDACTION", tt.value)
+		got := piiModesFromEnv()
+		if len(got) != len(tt.want) {
+			t.Errorf("PII_REDACTION=%q: %v, want %v", tt.value, got, tt.want)
+			continue
+		}
+		for category, mode := range tt.want {
+			if got[category] != mode {
+				t.Errorf("PII_REDACTION=%q: %s = %q, want %q", tt.value, category, got[category], mode)
+			}
+		}
+	}
+}
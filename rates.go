@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultCBRRatesURL — ежедневные курсы валют Центрального банка России в XML
+const defaultCBRRatesURL = "https://www.cbr.ru/scripts/XML_daily.asp"
+
+// ratesCacheTTL — как долго курсы берутся из кэша; ЦБ обновляет их раз в день
+const ratesCacheTTL = time.Hour
+
+// baseCurrency — валюта, к которой ЦБ приводит все курсы
+const baseCurrency = "RUB"
+
+// RateTable — курсы валют на дату: сколько рублей стоит одна единица валюты
+type RateTable struct {
+	Date  time.Time
+	Rates map[string]float64
+}
+
+// Rate возвращает курс валюты в рублях; курс рубля всегда равен 1
+func (t RateTable) Rate(code string) (float64, bool) {
+	if code == baseCurrency {
+		return 1, true
+	}
+	rate, ok := t.Rates[code]
+	return rate, ok
+}
+
+// RatesProvider — источник курсов валют
+type RatesProvider interface {
+	// Rates возвращает актуальную таблицу курсов
+	Rates() (RateTable, error)
+}
+
+// CBRRates получает курсы из XML Центрального банка России и кэширует их
+type CBRRates struct {
+	url        string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	cached    *RateTable
+	fetchedAt time.Time
+}
+
+// NewCBRRates создает источник курсов ЦБ; пустой url означает адрес по умолчанию
+func NewCBRRates(url string) *CBRRates {
+	if url == "" {
+		url = defaultCBRRatesURL
+	}
+	return &CBRRates{
+		url: url,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Rates возвращает курсы из кэша или загружает их заново.
+// Если ЦБ недоступен, используются последние загруженные курсы.
+func (c *CBRRates) Rates() (RateTable, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.fetchedAt) < ratesCacheTTL {
+		return *c.cached, nil
+	}
+
+	table, err := c.fetch()
+	if err != nil {
+		if c.cached != nil {
+			return *c.cached, nil
+		}
+		return RateTable{}, err
+	}
+	c.cached = &table
+	c.fetchedAt = time.Now()
+	return table, nil
+}
+
+// cbrValCurs — ответ ЦБ: дата курсов и список валют
+type cbrValCurs struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+func (c *CBRRates) fetch() (RateTable, error) {
+	resp, err := c.httpClient.Get(c.url)
+	if err != nil {
+		return RateTable{}, fmt.Errorf("ошибка запроса курсов ЦБ: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return RateTable{}, fmt.Errorf("ошибка чтения курсов ЦБ: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RateTable{}, fmt.Errorf("ЦБ вернул статус %d", resp.StatusCode)
+	}
+	return parseCBRRates(body)
+}
+
+// parseCBRRates разбирает XML ЦБ. Числа в нем записаны с запятой ("81,5000"),
+// а курс указан за Nominal единиц валюты (например, за 100 иен).
+func parseCBRRates(data []byte) (RateTable, error) {
+	var curs cbrValCurs
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&curs); err != nil {
+		return RateTable{}, fmt.Errorf("ошибка разбора курсов ЦБ: %v", err)
+	}
+
+	date, err := time.Parse("02.01.2006", curs.Date)
+	if err != nil {
+		return RateTable{}, fmt.Errorf("неверная дата курсов ЦБ %q", curs.Date)
+	}
+
+	table := RateTable{Date: date, Rates: make(map[string]float64, len(curs.Valutes))}
+	for _, valute := range curs.Valutes {
+		value, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(valute.Value), ",", ".", 1), 64)
+		if err != nil {
+			continue
+		}
+		nominal, err := strconv.ParseFloat(strings.TrimSpace(valute.Nominal), 64)
+		if err != nil || nominal <= 0 {
+			nominal = 1
+		}
+		table.Rates[strings.ToUpper(strings.TrimSpace(valute.CharCode))] = value / nominal
+	}
+	if len(table.Rates) == 0 {
+		return RateTable{}, fmt.Errorf("в ответе ЦБ нет курсов валют")
+	}
+	return table, nil
+}
+
+// charsetReader перекодирует windows-1251, в которой ЦБ отдает XML, в UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return input, nil
+	case "windows-1251", "cp1251":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(decodeWindows1251(data)), nil
+	}
+	return nil, fmt.Errorf("неподдерживаемая кодировка %s", charset)
+}
+
+// windows1251High — символы windows-1251 с кодами 0x80–0xBF; буквы А–я идут подряд с 0xC0
+var windows1251High = []rune("ЂЃ‚ѓ„…†‡€‰Љ‹ЊЌЋЏђ‘’“”•–—�™љ›њќћџ ЎўЈ¤Ґ¦§Ё©Є«¬­®Ї°±Ііґµ¶·ё№є»јЅѕї")
+
+func decodeWindows1251(data []byte) []byte {
+	out := make([]byte, 0, len(data)*2)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			out = append(out, b)
+		case b < 0xC0:
+			out = utf8.AppendRune(out, windows1251High[b-0x80])
+		default:
+			out = utf8.AppendRune(out, rune(b)-0xC0+'А')
+		}
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// cbrDailyXML — фрагмент ответа XML_daily.asp; отдается в windows-1251, как у ЦБ
+const cbrDailyXML = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs Date="17.10.2026" name="Foreign Currency Market">
+<Valute ID="R01235"><NumCode>840</NumCode><CharCode>USD</CharCode><Nominal>1</Nominal><Name>Доллар США</Name><Value>81,5000</Value><VunitRate>81,5</VunitRate></Valute>
+<Valute ID="R01239"><NumCode>978</NumCode><CharCode>EUR</CharCode><Nominal>1</Nominal><Name>Евро</Name><Value>94,1234</Value><VunitRate>94,1234</VunitRate></Valute>
+<Valute ID="R01820"><NumCode>392</NumCode><CharCode>JPY</CharCode><Nominal>100</Nominal><Name>Японских иен</Name><Value>54,3200</Value><VunitRate>0,5432</VunitRate></Valute>
+<Valute ID="R01720"><NumCode>980</NumCode><CharCode>UAH</CharCode><Nominal>10</Nominal><Name>Украинских гривен</Name><Value>19,8765</Value><VunitRate>1,98765</VunitRate></Valute>
+</ValCurs>`
+
+// encodeWindows1251 — обратное преобразование к decodeWindows1251 для подготовки ответа
+func encodeWindows1251(t *testing.T, text string) []byte {
+	t.Helper()
+	codes := make(map[rune]byte)
+	for i, r := range windows1251High {
+		codes[r] = byte(0x80 + i)
+	}
+	var out []byte
+	for _, r := range text {
+		switch {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case r >= 'А' && r <= 'я':
+			out = append(out, byte(r-'А'+0xC0))
+		default:
+			code, ok := codes[r]
+			if !ok {
+				t.Fatalf("символа %q нет в windows-1251", r)
+			}
+			out = append(out, code)
+		}
+	}
+	return out
+}
+
+func TestDecodeWindows1251(t *testing.T) {
+	if len(windows1251High) != 0xC0-0x80 {
+		t.Fatalf("в таблице %d символов, want %d", len(windows1251High), 0xC0-0x80)
+	}
+	text := "Курс: 100 иен — «ЁЖИК» ёжик №5, Ђђ ЎўЈ‰€"
+	if got := string(decodeWindows1251(encodeWindows1251(t, text))); got != text {
+		t.Errorf("decodeWindows1251 = %q, want %q", got, text)
+	}
+	for b := 0; b < 256; b++ {
+		if decoded := decodeWindows1251([]byte{byte(b)}); !utf8.Valid(decoded) {
+			t.Errorf("байт %#x декодирован в неверный UTF-8: %q", b, decoded)
+		}
+	}
+}
+
+func TestCBRRates(t *testing.T) {
+	var requests atomic.Int32
+	var fail atomic.Bool
+	body := encodeWindows1251(t, cbrDailyXML)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=windows-1251")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	rates := NewCBRRates(server.URL)
+	table, err := rates.Rates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !table.Date.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("дата курсов %v", table.Date)
+	}
+	tests := map[string]float64{
+		"USD": 81.5,
+		"EUR": 94.1234,
+		"JPY": 0.5432,  // Курс указан за 100 иен
+		"UAH": 1.98765, // и за 10 гривен
+		"RUB": 1,
+	}
+	for code, want := range tests {
+		got, ok := table.Rate(code)
+		if !ok || math.Abs(got-want) > 1e-9 {
+			t.Errorf("курс %s = %v, %v, want %v", code, got, ok, want)
+		}
+	}
+	if _, ok := table.Rate("XYZ"); ok {
+		t.Error("курс неизвестной валюты")
+	}
+
+	// В пределах ratesCacheTTL ЦБ повторно не запрашивается
+	rates.Rates()
+	if n := requests.Load(); n != 1 {
+		t.Errorf("запросов к ЦБ: %d, want 1", n)
+	}
+
+	// Кэш устарел, а ЦБ недоступен: остаются последние загруженные курсы
+	fail.Store(true)
+	rates.fetchedAt = time.Now().Add(-2 * ratesCacheTTL)
+	stale, err := rates.Rates()
+	if err != nil || stale.Rates["USD"] != 81.5 || requests.Load() != 2 {
+		t.Errorf("при недоступном ЦБ: %v, %v, запросов %d", stale.Rates, err, requests.Load())
+	}
+
+	if _, err := NewCBRRates(server.URL).Rates(); err == nil {
+		t.Error("без кэша ошибка ЦБ должна возвращаться")
+	}
+}
+
+func TestParseCBRRatesErrors(t *testing.T) {
+	tests := map[string]string{
+		"не XML":          "<html",
+		"неверная дата":   `<ValCurs Date="2026-10-17"><Valute><CharCode>USD</CharCode><Nominal>1</Nominal><Value>81,5</Value></Valute></ValCurs>`,
+		"нет курсов":      `<ValCurs Date="17.10.2026"><Valute><CharCode>USD</CharCode><Nominal>1</Nominal><Value>н/д</Value></Valute></ValCurs>`,
+		"чужая кодировка": `<?xml version="1.0" encoding="koi8-r"?><ValCurs Date="17.10.2026"></ValCurs>`,
+	}
+	for name, data := range tests {
+		if _, err := parseCBRRates([]byte(data)); err == nil {
+			t.Errorf("%s: ошибки нет", name)
+		}
+	}
+
+	// Ошибочный номинал не обнуляет курс
+	table, err := parseCBRRates([]byte(`<ValCurs Date="17.10.2026"><Valute><CharCode> usd </CharCode><Nominal>0</Nominal><Value> 81,5 </Value></Valute></ValCurs>`))
+	if err != nil || table.Rates["USD"] != 81.5 {
+		t.Errorf("номинал 0: %v, %v", table.Rates, err)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxReadlineHistory — сколько последних строк хранится в файле истории
+const maxReadlineHistory = 500
+
+// LineReader читает строки из терминала с редактированием и историей ввода.
+// В терминале поддерживаются стрелки, Home/End, Backspace/Delete и сочетания
+// Ctrl-A/E/B/F/K/U/P/N; Ctrl-C отменяет строку, Ctrl-D на пустой строке завершает ввод.
+// Если вход не терминал (например, команды переданы через конвейер), строки читаются как есть.
+type LineReader struct {
+	in          *os.File
+	out         io.Writer
+	reader      *bufio.Reader
+	history     []string
+	historyPath string
+}
+
+// NewLineReader создает читатель стандартного ввода; история загружается из historyPath, если он задан
+func NewLineReader(historyPath string) *LineReader {
+	r := &LineReader{in: os.Stdin, out: os.Stdout, reader: bufio.NewReader(os.Stdin), historyPath: historyPath}
+	if historyPath != "" {
+		if data, err := os.ReadFile(historyPath); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if line != "" {
+					r.history = append(r.history, line)
+				}
+			}
+		}
+	}
+	return r
+}
+
+// ReadLine выводит приглашение и читает строку. В конце ввода возвращает io.EOF.
+func (r *LineReader) ReadLine(prompt string) (string, error) {
+	var line string
+	restore, err := makeRaw(int(r.in.Fd()))
+	if err == nil {
+		line, err = r.edit(prompt)
+		restore()
+	} else {
+		line, err = r.readPlain(prompt)
+	}
+	if err == nil {
+		r.addHistory(line)
+	}
+	return line, err
+}
+
+// Close сохраняет историю ввода
+func (r *LineReader) Close() error {
+	if r.historyPath == "" {
+		return nil
+	}
+	history := r.history
+	if len(history) > maxReadlineHistory {
+		history = history[len(history)-maxReadlineHistory:]
+	}
+	if err := os.MkdirAll(filepath.Dir(r.historyPath), 0o755); err != nil {
+		return err
+	}
+	// В истории могут быть личные данные, поэтому файл доступен только владельцу
+	return os.WriteFile(r.historyPath, []byte(strings.Join(history, "\n")+"\n"), 0o600)
+}
+
+func (r *LineReader) readPlain(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	line, err := r.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (r *LineReader) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(r.history) > 0 && r.history[len(r.history)-1] == line) {
+		return
+	}
+	r.history = append(r.history, line)
+}
+
+// edit читает строку посимвольно в режиме raw
+func (r *LineReader) edit(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	index := len(r.history) // Позиция в истории; len(history) — новая строка
+	draft := ""             // Новая строка, сохраненная на время просмотра истории
+
+	redraw := func() {
+		fmt.Fprintf(r.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(r.out, "\x1b[%dD", back)
+		}
+	}
+	browse := func(to int) {
+		if to < 0 || to > len(r.history) || to == index {
+			return
+		}
+		if index == len(r.history) {
+			draft = string(buf)
+		}
+		index = to
+		if index == len(r.history) {
+			buf = []rune(draft)
+		} else {
+			buf = []rune(r.history[index])
+		}
+		pos = len(buf)
+		redraw()
+	}
+
+	fmt.Fprint(r.out, prompt)
+	for {
+		ch, _, err := r.reader.ReadRune()
+		if err != nil {
+			fmt.Fprint(r.out, "\r\n")
+			return "", err
+		}
+
+		switch ch {
+		case '\r', '\n':
+			fmt.Fprint(r.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(r.out, "^C\r\n")
+			return "", nil
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(r.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(buf)
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+			}
+		case 6: // Ctrl-F
+			if pos < len(buf) {
+				pos++
+			}
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+		case 21: // Ctrl-U
+			buf = buf[pos:]
+			pos = 0
+		case 16: // Ctrl-P
+			browse(index - 1)
+			continue
+		case 14: // Ctrl-N
+			browse(index + 1)
+			continue
+		case 27: // Escape-последовательность клавиш
+			switch r.escape() {
+			case "A":
+				browse(index - 1)
+				continue
+			case "B":
+				browse(index + 1)
+				continue
+			case "C":
+				if pos < len(buf) {
+					pos++
+				}
+			case "D":
+				if pos > 0 {
+					pos--
+				}
+			case "H", "1~", "7~":
+				pos = 0
+			case "F", "4~", "8~":
+				pos = len(buf)
+			case "3~":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if ch < 32 {
+				continue
+			}
+			buf = append(buf[:pos], append([]rune{ch}, buf[pos:]...)...)
+			pos++
+		}
+		redraw()
+	}
+}
+
+// escape читает продолжение последовательности после ESC: "A" для ESC [ A, "3~" для ESC [ 3 ~
+func (r *LineReader) escape() string {
+	kind, _, err := r.reader.ReadRune()
+	if err != nil || (kind != '[' && kind != 'O') {
+		return ""
+	}
+	var seq []rune
+	for {
+		ch, _, err := r.reader.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, ch)
+		if ch < '0' || ch > '9' {
+			return string(seq)
+		}
+	}
+}
@@ -0,0 +1,672 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Виды повторяющихся напоминаний
+const (
+	RecurDaily    = "daily"    // Каждый день в указанное время
+	RecurWeekdays = "weekdays" // По будням в указанное время
+	RecurWeekly   = "weekly"   // Раз в неделю в указанный день
+	RecurInterval = "interval" // Через равные промежутки времени
+)
+
+const (
+	// minReminderInterval — самый частый разрешенный повтор, чтобы напоминания не превращались в спам
+	minReminderInterval = time.Minute
+	// defaultReminderHour — время по умолчанию, если указан только день ("завтра", "every monday")
+	defaultReminderHour = 9
+)
+
+// Recurrence описывает правило повтора напоминания
+type Recurrence struct {
+	Kind     string        `json:"kind"`
+	Weekday  time.Weekday  `json:"weekday,omitempty"`
+	Hour     int           `json:"hour,omitempty"`
+	Minute   int           `json:"minute,omitempty"`
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+// Next возвращает первое срабатывание после now; prev — предыдущее срабатывание
+func (r Recurrence) Next(prev, now time.Time, loc *time.Location) time.Time {
+	if r.Kind == RecurInterval {
+		if r.Interval < minReminderInterval {
+			return now.Add(minReminderInterval)
+		}
+		// Пропущенные за время простоя срабатывания не повторяем, сохраняя фазу расписания
+		steps := now.Sub(prev)/r.Interval + 1
+		return prev.Add(steps * r.Interval)
+	}
+
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), r.Hour, r.Minute, 0, 0, loc)
+	for i := 0; i < 8; i++ {
+		if next.After(now) && r.matches(next.Weekday()) {
+			return next
+		}
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func (r Recurrence) matches(day time.Weekday) bool {
+	switch r.Kind {
+	case RecurWeekdays:
+		return day != time.Saturday && day != time.Sunday
+	case RecurWeekly:
+		return day == r.Weekday
+	}
+	return true
+}
+
+// ReminderSpec — результат разбора фразы вида "напомни завтра в 9 позвонить маме"
+type ReminderSpec struct {
+	Text       string
+	At         time.Time
+	Recurrence *Recurrence
+}
+
+// reminderTrigger — слова, с которых начинается просьба о напоминании
+var reminderTrigger = map[string]bool{
+	"напомни": true, "напомните": true, "напомнить": true, "напоминай": true, "напоминайте": true,
+	"remind": true,
+}
+
+// reminderFiller — служебные слова между временем и текстом напоминания
+var reminderFiller = map[string]bool{
+	"мне": true, "нам": true, "о": true, "об": true, "про": true, "что": true, "чтобы": true,
+	"me": true, "us": true, "to": true, "about": true, "that": true, "of": true,
+}
+
+// weekdayWords сопоставляет формы дней недели
+var weekdayWords = map[string]time.Weekday{
+	"понедельник": time.Monday, "понедельника": time.Monday, "понедельникам": time.Monday,
+	"вторник": time.Tuesday, "вторника": time.Tuesday, "вторникам": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "среды": time.Wednesday, "средам": time.Wednesday,
+	"четверг": time.Thursday, "четверга": time.Thursday, "четвергам": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пятницы": time.Friday, "пятницам": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "субботы": time.Saturday, "субботам": time.Saturday,
+	"воскресенье": time.Sunday, "воскресенья": time.Sunday, "воскресеньям": time.Sunday,
+	"monday": time.Monday, "mondays": time.Monday,
+	"tuesday": time.Tuesday, "tuesdays": time.Tuesday,
+	"wednesday": time.Wednesday, "wednesdays": time.Wednesday,
+	"thursday": time.Thursday, "thursdays": time.Thursday,
+	"friday": time.Friday, "fridays": time.Friday,
+	"saturday": time.Saturday, "saturdays": time.Saturday,
+	"sunday": time.Sunday, "sundays": time.Sunday,
+}
+
+// monthWords сопоставляет названия месяцев
+var monthWords = map[string]time.Month{
+	"января": time.January, "февраля": time.February, "марта": time.March, "апреля": time.April,
+	"мая": time.May, "июня": time.June, "июля": time.July, "августа": time.August,
+	"сентября": time.September, "октября": time.October, "ноября": time.November, "декабря": time.December,
+	"january": time.January, "february": time.February, "march": time.March, "april": time.April,
+	"may": time.May, "june": time.June, "july": time.July, "august": time.August,
+	"september": time.September, "october": time.October, "november": time.November, "december": time.December,
+}
+
+// numberWords — числа, которые часто пишут словами
+var numberWords = map[string]int{
+	"один": 1, "одну": 1, "одна": 1, "a": 1, "an": 1, "one": 1,
+	"два": 2, "две": 2, "two": 2,
+	"три": 3, "three": 3,
+	"пять": 5, "five": 5,
+	"десять": 10, "ten": 10,
+	"пятнадцать": 15, "fifteen": 15,
+	"двадцать": 20, "twenty": 20,
+	"тридцать": 30, "thirty": 30,
+}
+
+// durationUnit возвращает единицу времени по слову ("минут", "часа", "days")
+func durationUnit(word string) (time.Duration, bool) {
+	switch word {
+	case "second", "seconds", "sec", "secs":
+		return time.Second, true
+	case "minute", "minutes", "min", "mins", "мин":
+		return time.Minute, true
+	case "hour", "hours", "hr", "hrs", "час", "часа", "часов":
+		return time.Hour, true
+	case "day", "days", "день", "дня", "дней", "сутки", "суток":
+		return 24 * time.Hour, true
+	case "week", "weeks", "неделю", "недели", "недель", "неделя":
+		return 7 * 24 * time.Hour, true
+	}
+	switch {
+	case strings.HasPrefix(word, "секунд"):
+		return time.Second, true
+	case strings.HasPrefix(word, "минут"):
+		return time.Minute, true
+	}
+	return 0, false
+}
+
+// reminderToken — слово фразы и признак того, что оно разобрано как часть времени
+type reminderToken struct {
+	word     string // Исходное слово
+	lower    string // Слово в нижнем регистре без знаков препинания по краям
+	consumed bool
+}
+
+// reminderParser разбирает фразу по словам
+type reminderParser struct {
+	tokens []reminderToken
+
+	relative   time.Duration
+	recurrence *Recurrence
+	hasTime    bool
+	hour, min  int
+	day        *time.Time    // Явно указанная дата
+	dayYear    bool          // Год даты указан явно
+	dayOffset  int           // "сегодня", "завтра", "послезавтра"
+	weekday    *time.Weekday // "в понедельник"
+	hasDay     bool
+	now        time.Time // Текущее время в часовом поясе пользователя
+}
+
+// parseReminder разбирает просьбу о напоминании. Поддерживаются относительное время ("через 20 минут",
+// "in 2 hours"), абсолютное ("завтра в 9:00", "25.12 в 10", "on friday at 6pm") и повторы
+// ("каждый день в 8:00", "every Monday at 9:00", "по будням в 9", "каждые 2 часа").
+func parseReminder(text string, now time.Time, loc *time.Location) (ReminderSpec, error) {
+	p := newReminderParser(text, now, loc)
+	p.consumeTrigger()
+	p.parseAll()
+
+	spec := ReminderSpec{Text: p.remainingText()}
+	if spec.Text == "" {
+		return spec, newLocalizedError("remind.error.no_text")
+	}
+
+	at, recurrence, ok := p.resolve(now, loc)
+	if !ok {
+		return spec, newLocalizedError("remind.error.no_time")
+	}
+	if !at.After(now) {
+		return spec, newLocalizedError("remind.error.past")
+	}
+	spec.At, spec.Recurrence = at, recurrence
+	return spec, nil
+}
+
+// parseDueDate разбирает срок без повторов: "завтра", "в пятницу", "25.12", "2026-12-25".
+// Фраза должна целиком состоять из указания времени; прошедший срок допустим.
+func parseDueDate(text string, now time.Time, loc *time.Location) (time.Time, bool) {
+	p := newReminderParser(text, now, loc)
+	p.parseAll()
+	if p.remainingText() != "" || p.recurrence != nil {
+		return time.Time{}, false
+	}
+
+	at, _, ok := p.resolve(now, loc)
+	return at, ok
+}
+
+func newReminderParser(text string, now time.Time, loc *time.Location) *reminderParser {
+	p := &reminderParser{now: now.In(loc)}
+	for _, word := range strings.Fields(text) {
+		p.tokens = append(p.tokens, reminderToken{
+			word:  word,
+			lower: strings.ToLower(strings.TrimFunc(word, isReminderPunct)),
+		})
+	}
+	return p
+}
+
+// parseAll отмечает слова, которые относятся к указанию времени
+func (p *reminderParser) parseAll() {
+	for i := 0; i < len(p.tokens); {
+		if n := p.parseAt(i); n > 0 {
+			for j := i; j < i+n; j++ {
+				p.tokens[j].consumed = true
+			}
+			i += n
+			continue
+		}
+		i++
+	}
+}
+
+// resolve вычисляет первое срабатывание по разобранным словам; false — время не указано
+func (p *reminderParser) resolve(now time.Time, loc *time.Location) (time.Time, *Recurrence, bool) {
+	local := now.In(loc)
+	hour, minute := defaultReminderHour, 0
+	if p.hasTime {
+		hour, minute = p.hour, p.min
+	}
+
+	switch {
+	case p.recurrence != nil:
+		rec := *p.recurrence
+		if rec.Kind == RecurInterval {
+			return now.Add(rec.Interval), &rec, true
+		}
+		if rec.Kind == RecurWeekly && p.weekday == nil && p.day == nil {
+			rec.Weekday = local.Weekday()
+		}
+		rec.Hour, rec.Minute = hour, minute
+		if !p.hasTime && rec.Kind == RecurDaily {
+			rec.Hour, rec.Minute = local.Hour(), local.Minute()
+		}
+		return rec.Next(now, now, loc), &rec, true
+
+	case p.relative > 0:
+		at := now.Add(p.relative)
+		// "через 2 дня в 10:00" — день считаем от сегодня, время берем указанное
+		if p.hasTime && p.relative >= 24*time.Hour {
+			day := at.In(loc)
+			at = time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+		}
+		return at, nil, true
+
+	case p.day != nil:
+		at := time.Date(p.day.Year(), p.day.Month(), p.day.Day(), hour, minute, 0, 0, loc)
+		// Дата без года, которая в этом году уже прошла, относится к следующему году
+		if !p.dayYear && !at.After(now) {
+			at = at.AddDate(1, 0, 0)
+		}
+		return at, nil, true
+
+	case p.weekday != nil:
+		days := (int(*p.weekday) - int(local.Weekday()) + 7) % 7
+		at := time.Date(local.Year(), local.Month(), local.Day()+days, hour, minute, 0, 0, loc)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 7)
+		}
+		return at, nil, true
+
+	case p.hasDay:
+		return time.Date(local.Year(), local.Month(), local.Day()+p.dayOffset, hour, minute, 0, 0, loc), nil, true
+
+	case p.hasTime:
+		// Только время: сегодня, а если оно уже прошло — завтра
+		at := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil, true
+	}
+	return time.Time{}, nil, false
+}
+
+// isReminderPunct проверяет знаки препинания, которые не входят в слово
+func isReminderPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// consumeTrigger убирает "напомни мне" / "remind me" в начале фразы
+func (p *reminderParser) consumeTrigger() {
+	for i := range p.tokens {
+		token := &p.tokens[i]
+		if reminderTrigger[token.lower] || (i > 0 && reminderFiller[token.lower] && p.tokens[i-1].consumed) {
+			token.consumed = true
+			continue
+		}
+		return
+	}
+}
+
+// word возвращает слово в нижнем регистре или пустую строку за пределами фразы
+func (p *reminderParser) word(i int) string {
+	if i < 0 || i >= len(p.tokens) || p.tokens[i].consumed {
+		return ""
+	}
+	return p.tokens[i].lower
+}
+
+// parseAt пытается разобрать конструкцию времени с позиции i и возвращает число занятых слов
+func (p *reminderParser) parseAt(i int) int {
+	word := p.word(i)
+	next := p.word(i + 1)
+
+	switch word {
+	case "через", "in":
+		if duration, n := p.parseDuration(i + 1); n > 0 && p.relative == 0 {
+			p.relative = duration
+			return n + 1
+		}
+		// "in december", "in 10 days" разбираются ниже
+	case "every", "каждый", "каждую", "каждое", "каждые":
+		return p.parseRecurring(i)
+	case "ежедневно", "daily":
+		p.setRecurrence(&Recurrence{Kind: RecurDaily})
+		return 1
+	case "weekly", "еженедельно":
+		p.setRecurrence(&Recurrence{Kind: RecurWeekly})
+		return 1
+	case "weekdays":
+		p.setRecurrence(&Recurrence{Kind: RecurWeekdays})
+		return 1
+	case "по":
+		if next == "будням" {
+			p.setRecurrence(&Recurrence{Kind: RecurWeekdays})
+			return 2
+		}
+		// "по понедельникам" — повтор, "по понедельник" в этом смысле не пишут
+		if weekday, ok := weekdayWords[next]; ok && (strings.HasSuffix(next, "ам") || strings.HasSuffix(next, "ям")) {
+			p.setRecurrence(&Recurrence{Kind: RecurWeekly, Weekday: weekday})
+			p.weekday = &weekday
+			return 2
+		}
+	case "on":
+		if weekday, ok := weekdayWords[next]; ok {
+			p.setWeekday(weekday)
+			return 2
+		}
+		if n := p.parseDate(i + 1); n > 0 {
+			return n + 1
+		}
+	case "today", "сегодня":
+		p.hasDay, p.dayOffset = true, 0
+		return 1
+	case "tomorrow", "завтра":
+		p.hasDay, p.dayOffset = true, 1
+		return 1
+	case "послезавтра":
+		p.hasDay, p.dayOffset = true, 2
+		return 1
+	case "tonight":
+		p.hasDay, p.dayOffset = true, 0
+		if !p.hasTime {
+			p.hasTime, p.hour, p.min = true, 20, 0
+		}
+		return 1
+	case "в", "во", "at", "к":
+		if n := p.parseTime(i + 1); n > 0 {
+			return n + 1
+		}
+		if n := p.parseDate(i + 1); n > 0 {
+			return n + 1
+		}
+		if weekday, ok := weekdayWords[next]; ok && word != "at" {
+			p.setWeekday(weekday)
+			return 2
+		}
+	}
+
+	if weekday, ok := weekdayWords[word]; ok {
+		p.setWeekday(weekday)
+		return 1
+	}
+	if n := p.parseDate(i); n > 0 {
+		return n
+	}
+	// Время без предлога, но с явным форматом: "9:30", "6pm"
+	if strings.Contains(word, ":") || strings.HasSuffix(word, "am") || strings.HasSuffix(word, "pm") {
+		if n := p.parseTime(i); n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// parseDuration разбирает "20 минут", "час", "1 час 30 минут", "полчаса", "half an hour"
+func (p *reminderParser) parseDuration(i int) (time.Duration, int) {
+	var total time.Duration
+	used := 0
+
+	for {
+		start := i + used
+		word := p.word(start)
+
+		if word == "полчаса" {
+			total += 30 * time.Minute
+			used++
+			continue
+		}
+		if word == "half" && (p.word(start+1) == "an" || p.word(start+1) == "a") && p.word(start+2) == "hour" {
+			total += 30 * time.Minute
+			used += 3
+			continue
+		}
+
+		amount, n := p.parseNumber(start)
+		unit, ok := durationUnit(p.word(start + n))
+		if !ok {
+			break
+		}
+		if n == 0 {
+			amount = 1
+		}
+		total += time.Duration(amount) * unit
+		used += n + 1
+
+		if p.word(i+used) == "и" || p.word(i+used) == "and" {
+			if _, ok := durationUnit(p.word(i + used + 2)); ok {
+				used++
+			}
+		}
+	}
+
+	if total == 0 {
+		return 0, 0
+	}
+	return total, used
+}
+
+// parseNumber разбирает число цифрами или словом; возвращает 0 занятых слов, если числа нет
+func (p *reminderParser) parseNumber(i int) (int, int) {
+	word := p.word(i)
+	if n, err := strconv.Atoi(word); err == nil && n > 0 {
+		return n, 1
+	}
+	if n, ok := numberWords[word]; ok {
+		return n, 1
+	}
+	return 0, 0
+}
+
+// parseRecurring разбирает "каждый день", "every monday", "каждые 2 часа", "every weekday"
+func (p *reminderParser) parseRecurring(i int) int {
+	next := p.word(i + 1)
+
+	switch next {
+	case "день", "day", "утро", "morning", "вечер", "evening":
+		rec := &Recurrence{Kind: RecurDaily}
+		p.setRecurrence(rec)
+		switch next {
+		case "утро", "morning":
+			p.setDefaultTime(9)
+		case "вечер", "evening":
+			p.setDefaultTime(20)
+		}
+		return 2
+	case "weekday", "будний":
+		if p.word(i+2) == "день" {
+			p.setRecurrence(&Recurrence{Kind: RecurWeekdays})
+			return 3
+		}
+		p.setRecurrence(&Recurrence{Kind: RecurWeekdays})
+		return 2
+	case "неделю", "week":
+		p.setRecurrence(&Recurrence{Kind: RecurWeekly})
+		return 2
+	}
+
+	if weekday, ok := weekdayWords[next]; ok {
+		p.setRecurrence(&Recurrence{Kind: RecurWeekly, Weekday: weekday})
+		p.weekday = &weekday
+		return 2
+	}
+
+	if interval, n := p.parseDuration(i + 1); n > 0 {
+		if interval < minReminderInterval {
+			interval = minReminderInterval
+		}
+		p.setRecurrence(&Recurrence{Kind: RecurInterval, Interval: interval})
+		return n + 1
+	}
+	return 0
+}
+
+// parseTime разбирает "9", "9:30", "21.30", "6pm", "7 вечера", "6 pm"
+func (p *reminderParser) parseTime(i int) int {
+	word := p.word(i)
+	if word == "" {
+		return 0
+	}
+
+	suffix := ""
+	for _, s := range []string{"am", "pm"} {
+		if strings.HasSuffix(word, s) {
+			suffix, word = s, strings.TrimSuffix(word, s)
+		}
+	}
+
+	hour, minute := 0, 0
+	var err error
+	if sep := strings.IndexAny(word, ":."); sep != -1 {
+		hour, err = strconv.Atoi(word[:sep])
+		if err != nil {
+			return 0
+		}
+		minute, err = strconv.Atoi(word[sep+1:])
+		if err != nil || len(word[sep+1:]) != 2 {
+			return 0
+		}
+	} else if hour, err = strconv.Atoi(word); err != nil {
+		return 0
+	}
+
+	used := 1
+	if suffix == "" {
+		switch p.word(i + 1) {
+		case "am", "pm":
+			suffix = p.word(i + 1)
+			used++
+		case "утра", "ночи":
+			suffix = "am"
+			used++
+		case "вечера", "дня":
+			suffix = "pm"
+			used++
+		case "часов", "часа", "час", "o'clock":
+			used++
+		}
+	}
+
+	switch suffix {
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0
+	}
+	p.hasTime, p.hour, p.min = true, hour, minute
+	return used
+}
+
+// parseDate разбирает "25.12", "25.12.2026", "2026-12-25", "25 декабря", "december 25"
+func (p *reminderParser) parseDate(i int) int {
+	word := p.word(i)
+	if word == "" {
+		return 0
+	}
+
+	year := p.now.Year()
+
+	if t, err := time.Parse("2006-01-02", word); err == nil {
+		p.setDay(t.Year(), t.Month(), t.Day())
+		p.dayYear = true
+		return 1
+	}
+
+	if parts := strings.Split(word, "."); len(parts) == 2 || len(parts) == 3 {
+		day, errDay := strconv.Atoi(parts[0])
+		month, errMonth := strconv.Atoi(parts[1])
+		if errDay == nil && errMonth == nil && day >= 1 && day <= 31 && month >= 1 && month <= 12 {
+			if len(parts) == 3 {
+				y, err := strconv.Atoi(parts[2])
+				if err != nil {
+					return 0
+				}
+				if y < 100 {
+					y += 2000
+				}
+				year = y
+				p.dayYear = true
+			}
+			p.setDay(year, time.Month(month), day)
+			return 1
+		}
+		return 0
+	}
+
+	if day, err := strconv.Atoi(word); err == nil && day >= 1 && day <= 31 {
+		if month, ok := monthWords[p.word(i+1)]; ok {
+			p.setDay(year, month, day)
+			return 2
+		}
+		return 0
+	}
+
+	if month, ok := monthWords[word]; ok {
+		if day, err := strconv.Atoi(p.word(i + 1)); err == nil && day >= 1 && day <= 31 {
+			p.setDay(year, month, day)
+			return 2
+		}
+	}
+	return 0
+}
+
+func (p *reminderParser) setRecurrence(rec *Recurrence) {
+	if p.recurrence == nil {
+		p.recurrence = rec
+	}
+}
+
+func (p *reminderParser) setWeekday(weekday time.Weekday) {
+	p.weekday = &weekday
+	if p.recurrence != nil && p.recurrence.Kind == RecurWeekly {
+		p.recurrence.Weekday = weekday
+	}
+}
+
+func (p *reminderParser) setDay(year int, month time.Month, day int) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	p.day = &t
+}
+
+func (p *reminderParser) setDefaultTime(hour int) {
+	if !p.hasTime {
+		p.hasTime, p.hour, p.min = true, hour, 0
+	}
+}
+
+// remainingText собирает текст напоминания из неразобранных слов
+func (p *reminderParser) remainingText() string {
+	var words []string
+	for _, token := range p.tokens {
+		if token.consumed {
+			continue
+		}
+		// Служебные слова в начале текста ("о", "about", "что") отбрасываем
+		if len(words) == 0 && reminderFiller[token.lower] {
+			continue
+		}
+		words = append(words, token.word)
+	}
+	return strings.TrimRight(strings.Join(words, " "), ".,!;: ")
+}
+
+// isReminderRequest проверяет, что в сообщении есть просьба напомнить
+func isReminderRequest(message string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return unicode.IsSpace(r) || isReminderPunct(r)
+	}) {
+		if reminderTrigger[word] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// remindersStoreName — имя файла напоминаний в хранилище
+	remindersStoreName = "reminders"
+	// maxRemindersPerUser — сколько активных напоминаний может быть у одного пользователя
+	maxRemindersPerUser = 50
+	// reminderTickInterval — как часто планировщик проверяет наступившие напоминания
+	reminderTickInterval = 15 * time.Second
+	// maxReminderAttempts — сколько раз подряд планировщик пытается доставить напоминание
+	maxReminderAttempts = 6
+	// reminderRetryDelay — пауза перед первой повторной попыткой; дальше она удваивается
+	reminderRetryDelay = 30 * time.Second
+)
+
+// Clock возвращает текущее время; в тестах подменяется часами с ручным управлением
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock — часы операционной системы
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Reminder — запланированное напоминание
+type Reminder struct {
+	ID         int64       `json:"id"`
+	UserID     int64       `json:"user_id"`
+	ChatID     int64       `json:"chat_id"`
+	Text       string      `json:"text"`
+	At         time.Time   `json:"at"` // Следующее срабатывание
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	Location   string      `json:"location"` // Часовой пояс, в котором считаются повторы
+	Locale     string      `json:"locale"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Attempts   int         `json:"attempts,omitempty"` // Неудачные попытки доставки текущего срабатывания
+	RetryAt    time.Time   `json:"retry_at,omitempty"` // Когда повторить доставку после ошибки
+}
+
+// location возвращает часовой пояс напоминания
+func (r Reminder) location() *time.Location {
+	if loc, err := loadTimezone(r.Location); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// remindersState — сохраняемое состояние планировщика
+type remindersState struct {
+	NextID    int64       `json:"next_id"`
+	Reminders []*Reminder `json:"reminders"`
+}
+
+// ReminderScheduler хранит напоминания и отправляет их, когда наступает время.
+// Состояние сохраняется на диск, поэтому напоминания переживают перезапуск;
+// пропущенные за время простоя разовые напоминания отправляются при первой проверке.
+type ReminderScheduler struct {
+	mu      sync.Mutex
+	tick    sync.Mutex // Не дает проверкам пересекаться, чтобы напоминание не ушло дважды
+	store   *Store
+	clock   Clock
+	state   remindersState
+	deliver func(Reminder) error
+}
+
+// NewReminderScheduler загружает напоминания из хранилища; nil означает хранение только в памяти
+func NewReminderScheduler(store *Store, clock Clock) *ReminderScheduler {
+	s := &ReminderScheduler{store: store, clock: clock}
+	if err := store.Load(remindersStoreName, &s.state); err != nil {
+		log.Printf("Ошибка загрузки напоминаний: %v", err)
+	}
+	return s
+}
+
+// Now возвращает текущее время по часам планировщика
+func (s *ReminderScheduler) Now() time.Time {
+	return s.clock.Now()
+}
+
+// SetDelivery задает способ доставки наступивших напоминаний.
+// Если доставка вернула ошибку, напоминание остается в списке и отправляется повторно.
+func (s *ReminderScheduler) SetDelivery(deliver func(Reminder) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliver = deliver
+}
+
+// Add планирует напоминание и возвращает его с присвоенным ID
+func (s *ReminderScheduler) Add(reminder Reminder) (Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.listLocked(reminder.UserID)) >= maxRemindersPerUser {
+		return Reminder{}, newLocalizedError("remind.error.limit", Params{"max": maxRemindersPerUser})
+	}
+
+	s.state.NextID++
+	reminder.ID = s.state.NextID
+	reminder.CreatedAt = s.clock.Now()
+	s.state.Reminders = append(s.state.Reminders, &reminder)
+	s.saveLocked()
+
+	return reminder, nil
+}
+
+// List возвращает напоминания пользователя в порядке срабатывания
+func (s *ReminderScheduler) List(userID int64) []Reminder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listLocked(userID)
+}
+
+func (s *ReminderScheduler) listLocked(userID int64) []Reminder {
+	var reminders []Reminder
+	for _, reminder := range s.state.Reminders {
+		if reminder.UserID == userID {
+			reminders = append(reminders, *reminder)
+		}
+	}
+	sort.Slice(reminders, func(i, j int) bool { return reminders[i].At.Before(reminders[j].At) })
+	return reminders
+}
+
+// Cancel отменяет напоминание по номеру в списке пользователя (начиная с 1)
+func (s *ReminderScheduler) Cancel(userID int64, number int) (Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reminders := s.listLocked(userID)
+	if number < 1 || number > len(reminders) {
+		return Reminder{}, newLocalizedError("remind.error.not_found", Params{"number": number})
+	}
+
+	cancelled := reminders[number-1]
+	for i, reminder := range s.state.Reminders {
+		if reminder.ID == cancelled.ID {
+			s.state.Reminders = append(s.state.Reminders[:i], s.state.Reminders[i+1:]...)
+			break
+		}
+	}
+	s.saveLocked()
+
+	return cancelled, nil
+}
+
+// Tick отправляет наступившие напоминания и планирует следующие срабатывания повторяющихся.
+// Напоминание убирается или переносится только после успешной доставки; при ошибке
+// доставка повторяется с растущей паузой, а после maxReminderAttempts попыток пропускается.
+func (s *ReminderScheduler) Tick() {
+	s.tick.Lock()
+	defer s.tick.Unlock()
+
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	deliver := s.deliver
+	var due []Reminder
+	for _, reminder := range s.state.Reminders {
+		if !reminder.At.After(now) && !reminder.RetryAt.After(now) {
+			due = append(due, *reminder)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	delivered := make(map[int64]error, len(due))
+	for _, reminder := range due {
+		if deliver == nil {
+			log.Printf("Напоминание %d некуда отправить: доставка не настроена", reminder.ID)
+			delivered[reminder.ID] = nil
+			continue
+		}
+		delivered[reminder.ID] = deliver(reminder)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.state.Reminders[:0]
+	for _, reminder := range s.state.Reminders {
+		err, handled := delivered[reminder.ID]
+		if !handled {
+			// Напоминание еще не наступило или появилось, пока шла доставка
+			kept = append(kept, reminder)
+			continue
+		}
+		if err != nil {
+			reminder.Attempts++
+			if reminder.Attempts < maxReminderAttempts {
+				log.Printf("Не удалось доставить напоминание %d (попытка %d из %d): %v", reminder.ID, reminder.Attempts, maxReminderAttempts, err)
+				reminder.RetryAt = now.Add(reminderRetryDelay << (reminder.Attempts - 1))
+				kept = append(kept, reminder)
+				continue
+			}
+			log.Printf("Напоминание %d не доставлено после %d попыток, пропускаем: %v", reminder.ID, reminder.Attempts, err)
+		}
+
+		if reminder.Recurrence != nil {
+			reminder.At = reminder.Recurrence.Next(reminder.At, now, reminder.location())
+			reminder.Attempts = 0
+			reminder.RetryAt = time.Time{}
+			kept = append(kept, reminder)
+		}
+	}
+	s.state.Reminders = kept
+	s.saveLocked()
+}
+
+// Start запускает периодическую проверку напоминаний в отдельной горутине
+func (s *ReminderScheduler) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		s.Tick()
+		for range ticker.C {
+			s.Tick()
+		}
+	}()
+}
+
+func (s *ReminderScheduler) saveLocked() {
+	if err := s.store.Save(remindersStoreName, s.state); err != nil {
+		log.Printf("Ошибка сохранения напоминаний: %v", err)
+	}
+}
+
+// formatReminderTime форматирует время срабатывания для пользователя
+func formatReminderTime(locale string, at time.Time) string {
+	return at.Format(T(locale, "format.datetime"))
+}
+
+// describeRecurrence описывает правило повтора словами: "по будням в 09:00", "every 2 hours"
+func describeRecurrence(locale string, rec Recurrence) string {
+	clock := fmt.Sprintf("%02d:%02d", rec.Hour, rec.Minute)
+	switch rec.Kind {
+	case RecurWeekdays:
+		return T(locale, "remind.schedule.weekdays", Params{"time": clock})
+	case RecurWeekly:
+		return T(locale, "remind.schedule.weekly", Params{
+			"day":  T(locale, fmt.Sprintf("remind.weekday.%d", rec.Weekday)),
+			"time": clock,
+		})
+	case RecurInterval:
+		return T(locale, "remind.schedule.interval", Params{"interval": formatInterval(locale, rec.Interval)})
+	}
+	return T(locale, "remind.schedule.daily", Params{"time": clock})
+}
+
+// formatInterval записывает промежуток в самых крупных целых единицах: "2 часа", "90 минут"
+func formatInterval(locale string, d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d >= day && d%day == 0:
+		return TN(locale, "duration.days", int(d/day))
+	case d >= time.Hour && d%time.Hour == 0:
+		return TN(locale, "duration.hours", int(d/time.Hour))
+	}
+	return TN(locale, "duration.minutes", int(d/time.Minute))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock — часы, которые двигаются только из теста
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// deliveryLog запоминает доставленные напоминания и может имитировать сбой доставки
+type deliveryLog struct {
+	delivered []Reminder
+	fail      bool
+}
+
+func (d *deliveryLog) deliver(reminder Reminder) error {
+	if d.fail {
+		return errors.New("сеть недоступна")
+	}
+	d.delivered = append(d.delivered, reminder)
+	return nil
+}
+
+func newTestScheduler(t *testing.T, store *Store, start time.Time) (*ReminderScheduler, *manualClock, *deliveryLog) {
+	t.Helper()
+	clock := &manualClock{now: start}
+	scheduler := NewReminderScheduler(store, clock)
+	log := &deliveryLog{}
+	scheduler.SetDelivery(log.deliver)
+	return scheduler, clock, log
+}
+
+func TestReminderFiresOnce(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	scheduler, clock, log := newTestScheduler(t, nil, start)
+
+	if _, err := scheduler.Add(Reminder{UserID: 1, ChatID: 1, Text: "позвонить маме", At: start.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(59 * time.Minute)
+	scheduler.Tick()
+	if len(log.delivered) != 0 {
+		t.Fatalf("напоминание сработало раньше времени: %+v", log.delivered)
+	}
+
+	clock.Advance(time.Minute)
+	scheduler.Tick()
+	scheduler.Tick()
+	if len(log.delivered) != 1 || log.delivered[0].Text != "позвонить маме" {
+		t.Fatalf("доставлено %+v, want одно напоминание", log.delivered)
+	}
+	if left := scheduler.List(1); len(left) != 0 {
+		t.Errorf("разовое напоминание осталось в списке: %+v", left)
+	}
+}
+
+func TestReminderRecurrence(t *testing.T) {
+	moscow, err := loadTimezone("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 6, 8, 0, 0, 0, moscow) // Пятница
+
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		first      time.Time
+		elapsed    time.Duration // Сколько прошло до проверки, включая простой
+		want       time.Time
+	}{
+		{
+			name:       "каждый день",
+			recurrence: Recurrence{Kind: RecurDaily, Hour: 9},
+			first:      time.Date(2026, 3, 6, 9, 0, 0, 0, moscow),
+			elapsed:    time.Hour,
+			want:       time.Date(2026, 3, 7, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:       "по будням пропускают выходные",
+			recurrence: Recurrence{Kind: RecurWeekdays, Hour: 9, Minute: 30},
+			first:      time.Date(2026, 3, 6, 9, 30, 0, 0, moscow),
+			elapsed:    2 * time.Hour,
+			want:       time.Date(2026, 3, 9, 9, 30, 0, 0, moscow),
+		},
+		{
+			name:       "интервал сохраняет фазу после простоя",
+			recurrence: Recurrence{Kind: RecurInterval, Interval: 2 * time.Hour},
+			first:      start.Add(10 * time.Minute),
+			elapsed:    5 * time.Hour,
+			want:       start.Add(6*time.Hour + 10*time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler, clock, log := newTestScheduler(t, nil, start)
+			recurrence := tt.recurrence
+			if _, err := scheduler.Add(Reminder{UserID: 1, Text: "зарядка", At: tt.first, Recurrence: &recurrence, Location: "Europe/Moscow"}); err != nil {
+				t.Fatal(err)
+			}
+
+			clock.Advance(tt.elapsed)
+			scheduler.Tick()
+			if len(log.delivered) != 1 {
+				t.Fatalf("доставлено %d напоминаний, want 1", len(log.delivered))
+			}
+			left := scheduler.List(1)
+			if len(left) != 1 || !left[0].At.Equal(tt.want) {
+				t.Fatalf("следующее срабатывание %+v, want %v", left, tt.want)
+			}
+		})
+	}
+}
+
+func TestReminderRetryBackoff(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	scheduler, clock, log := newTestScheduler(t, nil, start)
+	scheduler.Add(Reminder{UserID: 1, Text: "разовое", At: start})
+	scheduler.Add(Reminder{UserID: 2, Text: "ежедневное", At: start, Recurrence: &Recurrence{Kind: RecurDaily, Hour: 10}, Location: "UTC"})
+
+	log.fail = true
+	scheduler.Tick()
+	for _, userID := range []int64{1, 2} {
+		left := scheduler.List(userID)
+		if len(left) != 1 || left[0].Attempts != 1 || !left[0].RetryAt.Equal(start.Add(reminderRetryDelay)) {
+			t.Fatalf("после первой ошибки у пользователя %d: %+v", userID, left)
+		}
+	}
+
+	// До RetryAt повторной попытки нет, затем пауза удваивается
+	clock.Advance(reminderRetryDelay - time.Second)
+	scheduler.Tick()
+	if left := scheduler.List(1); left[0].Attempts != 1 {
+		t.Fatalf("повтор раньше паузы: %+v", left)
+	}
+	clock.Advance(time.Second)
+	scheduler.Tick()
+	if left := scheduler.List(1); left[0].Attempts != 2 || !left[0].RetryAt.Equal(clock.Now().Add(2*reminderRetryDelay)) {
+		t.Fatalf("после второй ошибки: %+v", left)
+	}
+
+	// После успешной доставки разовое удаляется, а повторяющееся переносится со сбросом попыток
+	log.fail = false
+	clock.Advance(2 * reminderRetryDelay)
+	scheduler.Tick()
+	if len(log.delivered) != 2 {
+		t.Fatalf("доставлено %d напоминаний, want 2", len(log.delivered))
+	}
+	if left := scheduler.List(1); len(left) != 0 {
+		t.Errorf("разовое напоминание осталось: %+v", left)
+	}
+	daily := scheduler.List(2)
+	if len(daily) != 1 || daily[0].Attempts != 0 || !daily[0].RetryAt.IsZero() || !daily[0].At.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("ежедневное после доставки: %+v", daily)
+	}
+}
+
+func TestReminderGivesUpAfterMaxAttempts(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	scheduler, clock, log := newTestScheduler(t, nil, start)
+	scheduler.Add(Reminder{UserID: 1, Text: "разовое", At: start})
+	scheduler.Add(Reminder{UserID: 2, Text: "ежедневное", At: start, Recurrence: &Recurrence{Kind: RecurDaily, Hour: 10}, Location: "UTC"})
+
+	log.fail = true
+	for attempt := 1; attempt <= maxReminderAttempts; attempt++ {
+		scheduler.Tick()
+		clock.Advance(reminderRetryDelay << (attempt - 1))
+	}
+	if left := scheduler.List(1); len(left) != 0 {
+		t.Errorf("разовое не пропущено после %d попыток: %+v", maxReminderAttempts, left)
+	}
+	daily := scheduler.List(2)
+	if len(daily) != 1 || daily[0].Attempts != 0 || !daily[0].At.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("ежедневное должно перейти к следующему срабатыванию: %+v", daily)
+	}
+}
+
+func TestReminderPersistence(t *testing.T) {
+	store := NewStore(t.TempDir())
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	scheduler, _, _ := newTestScheduler(t, store, start)
+	first, _ := scheduler.Add(Reminder{UserID: 1, Text: "первое", At: start.Add(time.Hour)})
+	scheduler.Add(Reminder{UserID: 1, Text: "второе", At: start.Add(2 * time.Hour)})
+	if _, err := scheduler.Cancel(1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// После перезапуска пропущенное за время простоя напоминание уходит при первой проверке
+	restarted, _, log := newTestScheduler(t, store, start.Add(3*time.Hour))
+	restarted.Tick()
+	if len(log.delivered) != 1 || log.delivered[0].ID != first.ID {
+		t.Fatalf("после перезапуска доставлено %+v", log.delivered)
+	}
+	if next, _ := restarted.Add(Reminder{UserID: 1, Text: "третье", At: start.Add(5 * time.Hour)}); next.ID != 3 {
+		t.Errorf("ID после перезапуска = %d, want 3", next.ID)
+	}
+	if _, err := restarted.Cancel(1, 5); err == nil {
+		t.Error("отмена несуществующего номера должна возвращать ошибку")
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// replHelp — справка по командам REPL
+const replHelp = `Сообщения без двоеточия отправляются агенту. Команды:
+  :reset            очистить историю разговора
+  :user [id]        показать или сменить пользователя
+  :provider [имя]   показать бэкенды или переключить активный
+  :history          показать историю разговора
+  :help             эта справка
+  :quit             выход (или Ctrl-D)`
+
+// Repl — интерактивный разговор с агентом в терминале без Telegram и HTTP сервера
+type Repl struct {
+	agent  *Agent
+	userID int64
+	locale string
+	out    io.Writer
+}
+
+// runRepl запускает подкоманду repl и возвращает код завершения
+func runRepl(args []string) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	userID := flags.Int64("user", 1, "идентификатор пользователя, от имени которого идет разговор")
+	provider := flags.String("provider", "", "бэкенд модели: yandexgpt или builtin (по умолчанию из конфигурации)")
+	locale := flags.String("locale", defaultLocale, "язык ответов: ru или en")
+	verbose := flags.Bool("v", false, "показывать журнал агента")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Использование: chatagent repl [-user id] [-provider имя] [-locale ru|en] [-v]")
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	agent := createLocalAgent()
+	if *provider != "" {
+		if err := agent.SetProvider(*provider); err != nil {
+			fmt.Fprintf(os.Stderr, "неизвестный бэкенд %s\n", *provider)
+			return 2
+		}
+	}
+
+	repl := &Repl{agent: agent, userID: *userID, locale: *locale, out: os.Stdout}
+	reader := NewLineReader(filepath.Join(dataDir(), "repl_history"))
+	defer func() {
+		if err := reader.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "История ввода не сохранена: %v\n", err)
+		}
+	}()
+
+	fmt.Fprintf(repl.out, "Бэкенд: %s, пользователь: %d. :help — список команд\n", agent.ProviderName(), repl.userID)
+	for {
+		line, err := reader.ReadLine(fmt.Sprintf("[%d]> ", repl.userID))
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, ":"):
+			if !repl.command(line) {
+				return 0
+			}
+		default:
+			repl.send(line)
+		}
+	}
+}
+
+func (r *Repl) conversationID() string {
+	return userConversationID(r.userID)
+}
+
+// send отправляет сообщение агенту и печатает ответ с выбранным инструментом, временем и токенами
+func (r *Repl) send(message string) {
+	metrics := r.agent.Metrics()
+	inputBefore, completionBefore := metrics.InputTokens.Load(), metrics.CompletionTokens.Load()
+	started := time.Now()
+
+	reply, err := r.agent.ProcessMessageReply(MessageContext{
+		UserID:         r.userID,
+		ConversationID: r.conversationID(),
+		Locale:         r.locale,
+	}, message)
+	elapsed := time.Since(started)
+	if err != nil {
+		fmt.Fprintf(r.out, "Ошибка: %s\n", localizeError(r.locale, err))
+		return
+	}
+	fmt.Fprintln(r.out, reply.Text)
+
+	info := []string{fmt.Sprintf("%d мс", elapsed.Milliseconds())}
+	if reply.Tool != "" {
+		info = append([]string{"инструмент: " + reply.Tool}, info...)
+	}
+	if model := modelLabel(reply.Model, reply.ModelVersion); model != "" {
+		info = append(info, "модель: "+model)
+	}
+	input := metrics.InputTokens.Load() - inputBefore
+	completion := metrics.CompletionTokens.Load() - completionBefore
+	if input > 0 || completion > 0 {
+		info = append(info, fmt.Sprintf("токены: %d → %d", input, completion))
+	}
+	fmt.Fprintf(r.out, "  [%s]\n", strings.Join(info, " · "))
+}
+
+// command выполняет команду REPL; false означает выход
+func (r *Repl) command(line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":quit", ":exit", ":q":
+		return false
+	case ":help":
+		fmt.Fprintln(r.out, replHelp)
+	case ":reset":
+		r.agent.ResetConversation(r.conversationID())
+		fmt.Fprintln(r.out, "История разговора очищена")
+	case ":user":
+		if len(fields) < 2 {
+			fmt.Fprintf(r.out, "Пользователь: %d\n", r.userID)
+			break
+		}
+		userID, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			fmt.Fprintf(r.out, "Некорректный идентификатор: %s\n", fields[1])
+			break
+		}
+		r.userID = userID
+		fmt.Fprintf(r.out, "Пользователь: %d\n", r.userID)
+	case ":provider":
+		if len(fields) < 2 {
+			active := r.agent.ProviderName()
+			for _, name := range r.agent.Providers() {
+				marker := " "
+				if name == active {
+					marker = "*"
+				}
+				fmt.Fprintf(r.out, "%s %s\n", marker, name)
+			}
+			break
+		}
+		if err := r.agent.SetProvider(fields[1]); err != nil {
+			fmt.Fprintln(r.out, localizeError(r.locale, err))
+			break
+		}
+		fmt.Fprintf(r.out, "Бэкенд: %s\n", r.agent.ProviderName())
+	case ":history":
+		r.printHistory()
+	default:
+		fmt.Fprintf(r.out, "Неизвестная команда %s, :help — список команд\n", fields[0])
+	}
+	return true
+}
+
+func (r *Repl) printHistory() {
+	conversation, exists := r.agent.ExportConversation(r.conversationID())
+	if !exists || (len(conversation.Entries) == 0 && conversation.Summary == "") {
+		fmt.Fprintln(r.out, "История пуста")
+		return
+	}
+	loc := r.agent.LocationFor(r.userID)
+	if conversation.Summary != "" {
+		fmt.Fprintf(r.out, "Краткое содержание: %s\n", conversation.Summary)
+	}
+	for _, entry := range conversation.Entries {
+		fmt.Fprintf(r.out, "%s  > %s\n", entry.Timestamp.In(loc).Format("15:04:05"), entry.Message)
+		fmt.Fprintf(r.out, "          %s\n", strings.ReplaceAll(entry.Response, "\n", "\n          "))
+		if entry.Tool != "" {
+			fmt.Fprintf(r.out, "          [инструмент: %s]\n", entry.Tool)
+		}
+	}
+}
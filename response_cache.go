@@ -0,0 +1,302 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// responseCacheStoreName — документ хранилища с кэшем ответов модели
+const responseCacheStoreName = "response_cache"
+
+// Ограничения кэша ответов по умолчанию
+const (
+	defaultResponseCacheTTL  = 6 * time.Hour
+	defaultResponseCacheSize = 1000
+)
+
+// responseCacheSaveDelay — через сколько после изменения кэш записывается на диск.
+// Изменения за это время сохраняются одной записью файла.
+const responseCacheSaveDelay = 5 * time.Second
+
+// ResponseCacheConfig задает ограничения кэша ответов
+type ResponseCacheConfig struct {
+	TTL        time.Duration // Время жизни ответа; 0 отключает кэш
+	MaxEntries int           // Сколько ответов хранить; самые давние вытесняются
+	// ContextAware включает в ключ историю разговора. Без этого кэшируются
+	// только первые вопросы диалога, ответ на которые не зависит от истории.
+	ContextAware bool
+}
+
+// cachedResponse — ответ модели в кэше
+type cachedResponse struct {
+	Key          string    `json:"key"`
+	Text         string    `json:"text"`
+	Model        string    `json:"model,omitempty"`
+	ModelVersion string    `json:"model_version,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// cacheCall — запрос к модели, который уже выполняется; одинаковые запросы ждут его результата
+type cacheCall struct {
+	done       chan struct{}
+	completion Completion
+	cacheable  bool
+	err        error
+}
+
+// ResponseCache кэширует ответы модели на одинаковые запросы.
+// Ответы хранятся в памяти с вытеснением самых давних и, если задано хранилище, на диске.
+// Файл записывается в фоне не чаще раза в responseCacheSaveDelay и без блокировки читателей.
+type ResponseCache struct {
+	mu        sync.Mutex
+	saveMu    sync.Mutex // Упорядочивает фоновые записи, чтобы старый снимок не затер новый
+	store     *Store
+	config    ResponseCacheConfig
+	entries   map[string]*list.Element
+	order     *list.List // Недавно использованные ответы в начале
+	inflight  map[string]*cacheCall
+	saveTimer *time.Timer // Запланированная запись на диск; nil, если изменений нет
+}
+
+// NewResponseCache создает кэш ответов; с nil хранилищем кэш живет только в памяти
+func NewResponseCache(store *Store, config ResponseCacheConfig) *ResponseCache {
+	c := &ResponseCache{
+		store:    store,
+		config:   config,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		inflight: make(map[string]*cacheCall),
+	}
+
+	var saved []cachedResponse
+	if err := store.Load(responseCacheStoreName, &saved); err != nil {
+		log.Printf("Ошибка загрузки кэша ответов: %v", err)
+	}
+	// Файл хранится от новых ответов к старым; восстанавливаем порядок, пропуская устаревшие
+	for i := len(saved) - 1; i >= 0; i-- {
+		if c.expired(saved[i]) {
+			continue
+		}
+		entry := saved[i]
+		c.entries[entry.Key] = c.order.PushFront(&entry)
+	}
+	c.evict()
+	return c
+}
+
+// responseCacheConfigFromEnv читает ограничения кэша из RESPONSE_CACHE_TTL,
+// RESPONSE_CACHE_SIZE и RESPONSE_CACHE_CONTEXT
+func responseCacheConfigFromEnv() ResponseCacheConfig {
+	config := ResponseCacheConfig{
+		TTL:          defaultResponseCacheTTL,
+		MaxEntries:   defaultResponseCacheSize,
+		ContextAware: os.Getenv("RESPONSE_CACHE_CONTEXT") == "true",
+	}
+	if value := os.Getenv("RESPONSE_CACHE_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Неверное время жизни кэша %q, используем %s", value, config.TTL)
+		} else {
+			config.TTL = ttl
+		}
+	}
+	if value := os.Getenv("RESPONSE_CACHE_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			log.Printf("Неверный размер кэша %q, используем %d", value, config.MaxEntries)
+		} else {
+			config.MaxEntries = size
+		}
+	}
+	return config
+}
+
+// Enabled сообщает, включен ли кэш
+func (c *ResponseCache) Enabled() bool {
+	return c != nil && c.config.TTL > 0 && c.config.MaxEntries > 0
+}
+
+// Len возвращает число ответов в кэше
+func (c *ResponseCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Clear удаляет все сохраненные ответы
+func (c *ResponseCache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.scheduleSaveLocked()
+}
+
+// Key строит ключ кэша из бэкенда, параметров генерации и нормализованного диалога.
+// Второе значение равно false, если запрос нельзя кэшировать: ответ зависит от истории,
+// а кэш не учитывает контекст.
+func (c *ResponseCache) Key(provider string, messages []YandexGPTMessage, options GenerationOptions) (string, bool) {
+	if !c.Enabled() {
+		return "", false
+	}
+
+	turns := 0
+	for _, message := range messages {
+		if message.Role != "system" {
+			turns++
+		}
+	}
+	if turns > 1 && !c.config.ContextAware {
+		return "", false
+	}
+
+	tools := make([]string, 0, len(options.Tools))
+	for _, tool := range options.Tools {
+		tools = append(tools, tool.Name)
+	}
+	normalized := make([][2]string, 0, len(messages))
+	for _, message := range messages {
+		normalized = append(normalized, [2]string{message.Role, normalizePrompt(message.Text)})
+	}
+
+	data, err := json.Marshal(struct {
+		Provider    string
+		Model       string
+		Temperature float64
+		MaxTokens   int
+		Tools       []string
+		Messages    [][2]string
+	}{provider, options.Model, options.Temperature, options.MaxTokens, tools, normalized})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// normalizePrompt приводит текст к виду, в котором одинаковые по смыслу вопросы совпадают:
+// "Что такое  Go?" и "что такое go" дают один ключ
+func normalizePrompt(text string) string {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	return strings.TrimRight(text, "?!.… ")
+}
+
+// Do возвращает ответ из кэша или вызывает generate; второе значение сообщает о попадании в кэш.
+// Одинаковые запросы, пришедшие, пока первый еще выполняется, ждут его результата
+// вместо повторного обращения к модели. generate сообщает, можно ли сохранить ответ:
+// ответы, полученные с вызовом инструментов, зависят от данных пользователя,
+// поэтому не кэшируются и не передаются другим запросам.
+func (c *ResponseCache) Do(key string, generate func() (Completion, bool, error)) (Completion, bool, error) {
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cachedResponse)
+		if !c.expired(*entry) {
+			c.order.MoveToFront(element)
+			c.mu.Unlock()
+			return Completion{Text: entry.Text, Model: entry.Model, ModelVersion: entry.ModelVersion}, true, nil
+		}
+		c.removeLocked(element)
+	}
+
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		if call.err != nil {
+			return Completion{}, false, call.err
+		}
+		if call.cacheable {
+			return Completion{Text: call.completion.Text, Model: call.completion.Model, ModelVersion: call.completion.ModelVersion}, true, nil
+		}
+		// Ответ первого запроса личный — получаем свой
+		completion, _, err := generate()
+		return completion, false, err
+	}
+
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.completion, call.cacheable, call.err = generate()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil && call.cacheable && call.completion.Text != "" {
+		c.putLocked(cachedResponse{Key: key, Text: call.completion.Text, Model: call.completion.Model, ModelVersion: call.completion.ModelVersion, CreatedAt: time.Now()})
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.completion, false, call.err
+}
+
+func (c *ResponseCache) expired(entry cachedResponse) bool {
+	return time.Since(entry.CreatedAt) >= c.config.TTL
+}
+
+func (c *ResponseCache) putLocked(entry cachedResponse) {
+	if element, ok := c.entries[entry.Key]; ok {
+		c.removeLocked(element)
+	}
+	c.entries[entry.Key] = c.order.PushFront(&entry)
+	c.evict()
+	c.scheduleSaveLocked()
+}
+
+func (c *ResponseCache) removeLocked(element *list.Element) {
+	delete(c.entries, element.Value.(*cachedResponse).Key)
+	c.order.Remove(element)
+}
+
+// evict вытесняет самые давно использованные ответы сверх MaxEntries
+func (c *ResponseCache) evict() {
+	for c.order.Len() > max(c.config.MaxEntries, 0) {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// scheduleSaveLocked планирует запись кэша на диск, если она еще не запланирована
+func (c *ResponseCache) scheduleSaveLocked() {
+	if c.store == nil || c.saveTimer != nil {
+		return
+	}
+	c.saveTimer = time.AfterFunc(responseCacheSaveDelay, c.flush)
+}
+
+// flush записывает снимок кэша на диск. Под c.mu снимается только копия записей,
+// сериализация и запись файла идут без блокировки кэша.
+func (c *ResponseCache) flush() {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.mu.Lock()
+	if c.saveTimer == nil {
+		c.mu.Unlock()
+		return
+	}
+	c.saveTimer.Stop()
+	c.saveTimer = nil
+	saved := make([]cachedResponse, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		saved = append(saved, *element.Value.(*cachedResponse))
+	}
+	c.mu.Unlock()
+
+	if err := c.store.Save(responseCacheStoreName, saved); err != nil {
+		log.Printf("Ошибка сохранения кэша ответов: %v", err)
+	}
+}
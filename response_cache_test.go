@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheDo(t *testing.T) {
+	cache := NewResponseCache(nil, ResponseCacheConfig{TTL: time.Hour, MaxEntries: 2})
+	calls := 0
+	generate := func(text string, cacheable bool) func() (Completion, bool, error) {
+		return func() (Completion, bool, error) {
+			calls++
+			return Completion{Text: text, Model: "yandexgpt-lite"}, cacheable, nil
+		}
+	}
+
+	if got, hit, _ := cache.Do("a", generate("ответ A", true)); hit || got.Text != "ответ A" {
+		t.Fatalf("первый запрос: %+v, hit=%v", got, hit)
+	}
+	if got, hit, _ := cache.Do("a", generate("другой", true)); !hit || got.Text != "ответ A" || got.Model != "yandexgpt-lite" {
+		t.Fatalf("повторный запрос: %+v, hit=%v", got, hit)
+	}
+	// Ответы с вызовом инструментов не кэшируются
+	cache.Do("tools", generate("личный", false))
+	if _, hit, _ := cache.Do("tools", generate("личный", false)); hit {
+		t.Error("некэшируемый ответ попал в кэш")
+	}
+	// Вытесняется давно не использованный ответ
+	cache.Do("b", generate("ответ B", true))
+	cache.Do("a", generate("", true))
+	cache.Do("c", generate("ответ C", true))
+	if _, hit, _ := cache.Do("b", generate("ответ B2", true)); hit {
+		t.Error("давно не использованный ответ не вытеснен")
+	}
+	if calls != 6 {
+		t.Errorf("вызовов модели: %d, want 6", calls)
+	}
+}
+
+func TestResponseCacheInflight(t *testing.T) {
+	cache := NewResponseCache(nil, ResponseCacheConfig{TTL: time.Hour, MaxEntries: 10})
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	var wg sync.WaitGroup
+	hits := make([]bool, 5)
+	for i := range hits {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, hits[i], _ = cache.Do("key", func() (Completion, bool, error) {
+				calls.Add(1)
+				<-release
+				return Completion{Text: "ответ"}, true, nil
+			})
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("одинаковые запросы вызвали модель %d раз", n)
+	}
+}
+
+func TestResponseCacheSavesInBackground(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	config := ResponseCacheConfig{TTL: time.Hour, MaxEntries: 10}
+	cache := NewResponseCache(store, config)
+
+	cache.Do("a", func() (Completion, bool, error) { return Completion{Text: "ответ A"}, true, nil })
+	cache.Do("b", func() (Completion, bool, error) { return Completion{Text: "ответ B"}, true, nil })
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 0 {
+		t.Fatalf("кэш записан на диск при промахе, а не в фоне: %v", files)
+	}
+
+	cache.mu.Lock()
+	scheduled := cache.saveTimer != nil
+	cache.mu.Unlock()
+	if !scheduled {
+		t.Fatal("запись на диск не запланирована")
+	}
+	cache.flush()
+	if _, err := os.Stat(filepath.Join(dir, responseCacheStoreName+".json")); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewResponseCache(store, config)
+	if got, hit, _ := restored.Do("a", func() (Completion, bool, error) { return Completion{}, false, nil }); !hit || got.Text != "ответ A" {
+		t.Errorf("после перезапуска: %+v, hit=%v", got, hit)
+	}
+	if restored.Len() != 2 {
+		t.Errorf("восстановлено ответов: %d, want 2", restored.Len())
+	}
+}
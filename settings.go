@@ -0,0 +1,289 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserSettings представляет персональные настройки пользователя
+type UserSettings struct {
+	Persona      string `json:"persona"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	Length       string `json:"length"`
+	Language     string `json:"language"`
+	Model        string `json:"model"`
+	Creativity   string `json:"creativity"`
+	Timezone     string `json:"timezone,omitempty"` // Имя IANA или смещение вида UTC+3
+}
+
+// settingField описывает настройку, которую можно выбрать в /settings.
+// Названия настроек и значений берутся из каталога строк:
+// settings.field.<key> и settings.option.<key>.<value>.
+type settingField struct {
+	Key     string
+	Options []string
+}
+
+// settingFields перечисляет настройки в порядке показа; первое значение — по умолчанию
+var settingFields = []settingField{
+	{Key: "persona", Options: []string{"default", "assistant", "teacher", "programmer", "friend", "custom"}},
+	{Key: "length", Options: []string{"normal", "short", "long"}},
+	{Key: "language", Options: []string{"auto", "ru", "en"}},
+	{Key: "model", Options: []string{modelSettingAuto, ModelTierLite, ModelTierPro}},
+	{Key: "creativity", Options: []string{"medium", "low", "high"}},
+}
+
+// personaPrompts содержит системные промпты персон
+var personaPrompts = map[string]string{
+	"assistant":  "Ты — вежливый и внимательный ассистент. Отвечай по делу и структурированно.",
+	"teacher":    "Ты — терпеливый учитель. Объясняй шаг за шагом, приводи примеры и проверяй понимание.",
+	"programmer": "Ты — опытный программист. Отвечай технически точно, приводи код, указывай на подводные камни.",
+	"friend":     "Ты — дружелюбный собеседник. Общайся неформально и с юмором, но оставайся полезным.",
+}
+
+// maxSystemPromptLength — ограничение на длину собственного системного промпта
+const maxSystemPromptLength = 2000
+
+// DefaultUserSettings возвращает настройки по умолчанию
+func DefaultUserSettings() UserSettings {
+	var settings UserSettings
+	for _, field := range settingFields {
+		settings.set(field.Key, field.Options[0])
+	}
+	return settings
+}
+
+// Get возвращает значение настройки по ключу
+func (s UserSettings) Get(key string) string {
+	switch key {
+	case "persona":
+		return s.Persona
+	case "length":
+		return s.Length
+	case "language":
+		return s.Language
+	case "model":
+		return s.Model
+	case "creativity":
+		return s.Creativity
+	}
+	return ""
+}
+
+// Set проверяет и устанавливает значение настройки
+func (s *UserSettings) Set(key, value string) error {
+	field, ok := findSettingField(key)
+	if !ok {
+		return newLocalizedError("settings.error.unknown", Params{"key": key})
+	}
+	for _, option := range field.Options {
+		if option == value {
+			s.set(key, value)
+			return nil
+		}
+	}
+	return newLocalizedError("settings.error.invalid", Params{"key": key, "value": value})
+}
+
+func (s *UserSettings) set(key, value string) {
+	switch key {
+	case "persona":
+		s.Persona = value
+	case "length":
+		s.Length = value
+	case "language":
+		s.Language = value
+	case "model":
+		s.Model = value
+	case "creativity":
+		s.Creativity = value
+	}
+}
+
+// SetSystemPrompt задает собственный системный промпт и включает персону "custom"
+func (s *UserSettings) SetSystemPrompt(prompt string) error {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return newLocalizedError("settings.error.prompt_empty")
+	}
+	if len([]rune(prompt)) > maxSystemPromptLength {
+		return newLocalizedError("settings.error.prompt_length", Params{"max": maxSystemPromptLength})
+	}
+	s.SystemPrompt = prompt
+	s.Persona = "custom"
+	return nil
+}
+
+// ClearSystemPrompt удаляет собственный системный промпт; персона "custom" без него становится обычной
+func (s *UserSettings) ClearSystemPrompt() {
+	s.SystemPrompt = ""
+	if s.Persona == "custom" {
+		s.Persona = DefaultUserSettings().Persona
+	}
+}
+
+// SetTimezone задает часовой пояс по имени IANA, городу или смещению от UTC
+func (s *UserSettings) SetTimezone(name string) error {
+	loc, err := loadTimezone(name)
+	if err != nil {
+		return err
+	}
+	s.Timezone = loc.String()
+	return nil
+}
+
+// Location возвращает часовой пояс, если пользователь его указал
+func (s UserSettings) Location() (*time.Location, bool) {
+	if s.Timezone == "" {
+		return nil, false
+	}
+	loc, err := loadTimezone(s.Timezone)
+	return loc, err == nil
+}
+
+// Instructions возвращает системные инструкции для модели
+func (s UserSettings) Instructions() string {
+	var parts []string
+
+	if s.Persona == "custom" {
+		if s.SystemPrompt != "" {
+			parts = append(parts, s.SystemPrompt)
+		}
+	} else if prompt, ok := personaPrompts[s.Persona]; ok {
+		parts = append(parts, prompt)
+	}
+
+	switch s.Length {
+	case "short":
+		parts = append(parts, "Отвечай кратко: не больше 2–3 предложений.")
+	case "long":
+		parts = append(parts, "Отвечай подробно и развернуто.")
+	}
+
+	switch s.Language {
+	case "ru":
+		parts = append(parts, "Всегда отвечай на русском языке.")
+	case "en":
+		parts = append(parts, "Always answer in English.")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// GenerationOptions возвращает параметры генерации для этих настроек.
+// Модель по настройке model выбирает ModelRouter.
+func (s UserSettings) GenerationOptions() GenerationOptions {
+	options := DefaultGenerationOptions()
+
+	switch s.Creativity {
+	case "low":
+		options.Temperature = 0.2
+	case "high":
+		options.Temperature = 0.9
+	}
+
+	switch s.Length {
+	case "short":
+		options.MaxTokens = 300
+	case "long":
+		options.MaxTokens = 4000
+	}
+
+	return options
+}
+
+// Label возвращает подпись текущего значения настройки в указанной локали
+func (s UserSettings) Label(locale, key string) string {
+	return T(locale, "settings.option."+key+"."+s.Get(key))
+}
+
+// Locale возвращает локаль интерфейса, если пользователь явно выбрал язык
+func (s UserSettings) Locale() (string, bool) {
+	switch s.Language {
+	case LocaleRU, LocaleEN:
+		return s.Language, true
+	}
+	return "", false
+}
+
+// findSettingField ищет описание настройки по ключу
+func findSettingField(key string) (settingField, bool) {
+	for _, field := range settingFields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return settingField{}, false
+}
+
+// settingsStoreName — имя файла настроек в хранилище
+const settingsStoreName = "settings"
+
+// SettingsStore хранит настройки пользователей
+type SettingsStore struct {
+	mu       sync.Mutex
+	store    *Store
+	settings map[int64]UserSettings
+}
+
+// NewSettingsStore загружает настройки из хранилища; nil означает хранение только в памяти
+func NewSettingsStore(store *Store) *SettingsStore {
+	s := &SettingsStore{
+		store:    store,
+		settings: make(map[int64]UserSettings),
+	}
+	if err := store.Load(settingsStoreName, &s.settings); err != nil {
+		log.Printf("Ошибка загрузки настроек пользователей: %v", err)
+	}
+	return s
+}
+
+// Get возвращает настройки пользователя, дополняя отсутствующие значения умолчаниями
+func (s *SettingsStore) Get(userID int64) UserSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getLocked(userID)
+}
+
+func (s *SettingsStore) getLocked(userID int64) UserSettings {
+	defaults := DefaultUserSettings()
+	settings, exists := s.settings[userID]
+	if !exists {
+		return defaults
+	}
+	for _, field := range settingFields {
+		if settings.Get(field.Key) == "" {
+			settings.set(field.Key, defaults.Get(field.Key))
+		}
+	}
+	return settings
+}
+
+// Update изменяет настройки пользователя и сохраняет их
+func (s *SettingsStore) Update(userID int64, update func(*UserSettings) error) (UserSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.getLocked(userID)
+	if err := update(&settings); err != nil {
+		return settings, err
+	}
+	s.settings[userID] = settings
+
+	if err := s.store.Save(settingsStoreName, s.settings); err != nil {
+		log.Printf("Ошибка сохранения настроек пользователей: %v", err)
+	}
+	return settings, nil
+}
+
+// Reset возвращает настройки пользователя к значениям по умолчанию
+func (s *SettingsStore) Reset(userID int64) UserSettings {
+	settings, _ := s.Update(userID, func(settings *UserSettings) error {
+		*settings = DefaultUserSettings()
+		return nil
+	})
+	return settings
+}
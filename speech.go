@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Форматы аудио, которые понимает распознаватель
+const (
+	AudioFormatOggOpus = "oggopus"
+	AudioFormatLPCM    = "lpcm"
+)
+
+// SpeechRecognizer распознает речь в аудиозаписи
+type SpeechRecognizer interface {
+	// Recognize возвращает текст, распознанный в аудио указанного формата;
+	// locale — язык пользователя из каталога сообщений (ru, en)
+	Recognize(audio []byte, format, locale string) (string, error)
+}
+
+// defaultSpeechKitURL — адрес синхронного распознавания Yandex SpeechKit
+const defaultSpeechKitURL = "https://stt.api.cloud.yandex.net/speech/v1/stt:recognize"
+
+// speechKitMaxAudioSize — ограничение SpeechKit на размер аудио при синхронном распознавании
+const speechKitMaxAudioSize = 1 << 20
+
+// speechKitMaxDuration — ограничение SpeechKit на длительность аудио
+const speechKitMaxDuration = 30 * time.Second
+
+// speechKitLanguages сопоставляет языки интерфейса с языками распознавания SpeechKit
+var speechKitLanguages = map[string]string{
+	LocaleRU: "ru-RU",
+	LocaleEN: "en-US",
+}
+
+// speechKitLanguage возвращает язык распознавания для локали пользователя
+func speechKitLanguage(locale string) string {
+	if language, ok := speechKitLanguages[normalizeLocale(locale)]; ok {
+		return language
+	}
+	return speechKitLanguages[defaultLocale]
+}
+
+// YandexSpeechKit распознает речь через Yandex SpeechKit
+type YandexSpeechKit struct {
+	apiKey     string
+	folderID   string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// SpeechKitResponse представляет ответ SpeechKit
+type SpeechKitResponse struct {
+	Result       string `json:"result"`
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// NewYandexSpeechKit создает клиент SpeechKit; пустой baseURL означает адрес по умолчанию
+func NewYandexSpeechKit(apiKey, folderID, baseURL string) *YandexSpeechKit {
+	if baseURL == "" {
+		baseURL = defaultSpeechKitURL
+	}
+	return &YandexSpeechKit{
+		apiKey:   apiKey,
+		folderID: folderID,
+		baseURL:  baseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetTransport заменяет транспорт HTTP-клиента, например на кассету с записанными ответами
+func (s *YandexSpeechKit) SetTransport(transport http.RoundTripper) {
+	s.httpClient.Transport = transport
+}
+
+// Recognize отправляет аудио в SpeechKit и возвращает распознанный текст
+func (s *YandexSpeechKit) Recognize(audio []byte, format, locale string) (string, error) {
+	if len(audio) > speechKitMaxAudioSize {
+		return "", fmt.Errorf("аудио слишком большое: %d байт", len(audio))
+	}
+
+	params := url.Values{}
+	params.Set("lang", speechKitLanguage(locale))
+	params.Set("format", format)
+	if s.folderID != "" {
+		params.Set("folderId", s.folderID)
+	}
+	if format == AudioFormatLPCM {
+		params.Set("sampleRateHertz", "48000")
+	}
+
+	req, err := http.NewRequest("POST", s.baseURL+"?"+params.Encode(), bytes.NewReader(audio))
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Authorization", "Api-Key "+s.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ошибка HTTP запроса: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения ответа: %v", err)
+	}
+
+	var response SpeechKitResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("ошибка парсинга JSON ответа: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || response.ErrorCode != "" {
+		return "", fmt.Errorf("SpeechKit вернул ошибку %d: %s %s", resp.StatusCode, response.ErrorCode, response.ErrorMessage)
+	}
+
+	return response.Result, nil
+}
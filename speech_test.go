@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSpeechKitRequest(t *testing.T) {
+	var got *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, body = r, string(data)
+		w.Write([]byte(`{"result":"привет"}`))
+	}))
+	defer server.Close()
+
+	speech := NewYandexSpeechKit("test-key", "b1gfolder", server.URL)
+	tests := []struct {
+		format, locale string
+		wantLang       string
+		wantRate       string
+	}{
+		{AudioFormatOggOpus, LocaleRU, "ru-RU", ""},
+		{AudioFormatOggOpus, "en-GB", "en-US", ""},
+		{AudioFormatOggOpus, "", "ru-RU", ""},
+		{AudioFormatLPCM, LocaleEN, "en-US", "48000"},
+	}
+	for _, tt := range tests {
+		text, err := speech.Recognize([]byte("audio-data"), tt.format, tt.locale)
+		if err != nil || text != "привет" {
+			t.Fatalf("Recognize(%s, %s) = %q, %v", tt.format, tt.locale, text, err)
+		}
+		query := got.URL.Query()
+		if got.Method != http.MethodPost || body != "audio-data" {
+			t.Errorf("запрос %s с телом %q", got.Method, body)
+		}
+		if auth := got.Header.Get("Authorization"); auth != "Api-Key test-key" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if query.Get("lang") != tt.wantLang || query.Get("format") != tt.format ||
+			query.Get("folderId") != "b1gfolder" || query.Get("sampleRateHertz") != tt.wantRate {
+			t.Errorf("Recognize(%s, %s): параметры %v", tt.format, tt.locale, query)
+		}
+	}
+}
+
+func TestSpeechKitErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"ошибка HTTP", http.StatusUnauthorized, `{"error_code":"UNAUTHORIZED","error_message":"bad key"}`, "ошибку 401: UNAUTHORIZED bad key"},
+		{"ошибка в ответе 200", http.StatusOK, `{"error_code":"BAD_REQUEST","error_message":"audio is too long"}`, "BAD_REQUEST audio is too long"},
+		{"не JSON", http.StatusBadGateway, `<html>bad gateway</html>`, "ошибка парсинга JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewYandexSpeechKit("key", "", server.URL).Recognize([]byte("audio"), AudioFormatOggOpus, LocaleRU)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ошибка = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requested = true }))
+	defer server.Close()
+	big := make([]byte, speechKitMaxAudioSize+1)
+	if _, err := NewYandexSpeechKit("key", "", server.URL).Recognize(big, AudioFormatOggOpus, LocaleRU); err == nil || requested {
+		t.Errorf("слишком большое аудио: ошибка %v, запрос отправлен: %v", err, requested)
+	}
+
+	server.Close()
+	if _, err := NewYandexSpeechKit("key", "", server.URL).Recognize([]byte("audio"), AudioFormatOggOpus, LocaleRU); err == nil {
+		t.Error("недоступный сервер должен возвращать ошибку")
+	}
+}
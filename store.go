@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store сохраняет данные бота в JSON-файлах внутри каталога данных
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore создает хранилище в указанном каталоге
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// dataDir возвращает каталог данных из DATA_DIR
+func dataDir() string {
+	if dir := os.Getenv("DATA_DIR"); dir != "" {
+		return dir
+	}
+	return "data"
+}
+
+// Load читает документ name в v. Отсутствие файла не считается ошибкой.
+func (s *Store) Load(name string, v interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения %s: %v", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("ошибка парсинга %s: %v", name, err)
+	}
+	return nil
+}
+
+// Save атомарно записывает v в документ name
+func (s *Store) Save(name string, v interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга %s: %v", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога данных: %v", err)
+	}
+	tmp := s.path(name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("ошибка записи %s: %v", name, err)
+	}
+	return os.Rename(tmp, s.path(name))
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
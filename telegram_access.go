@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleStart приветствует пользователя и активирует код приглашения из ссылки вида t.me/bot?start=<код>
+func (tb *TelegramBot) handleStart(message *tgbotapi.Message) {
+	locale := tb.locale(message.From)
+	access := tb.agent.Access()
+
+	code := strings.TrimSpace(message.CommandArguments())
+	if code != "" && access.Check(message.From.ID, message.Chat.ID) == AccessDenied {
+		// В группе код открывает доступ всему чату, в личном чате — пользователю
+		if err := access.Redeem(code, message.Chat.ID); err != nil {
+			tb.reply(message, "❌ "+localizeError(locale, err))
+			return
+		}
+
+		log.Printf("Пользователь %d активировал код приглашения в чате %d", message.From.ID, message.Chat.ID)
+		if isGroupChat(message.Chat) {
+			tb.reply(message, T(locale, "access.granted_group"))
+			return
+		}
+		tb.reply(message, T(locale, "access.granted"))
+	}
+
+	if !tb.checkAccess(message) {
+		return
+	}
+	tb.agent.Users().Touch(message.From.ID, displayName(message.From))
+
+	tb.reply(message, T(locale, "start.text"))
+}
+
+// checkAccess проверяет политику доступа и при отказе объясняет, как получить доступ.
+// Заблокированным пользователям бот не отвечает.
+func (tb *TelegramBot) checkAccess(message *tgbotapi.Message) bool {
+	switch tb.agent.Access().Check(message.From.ID, message.Chat.ID) {
+	case AccessAllowed:
+		return true
+	case AccessBlocked:
+		log.Printf("Сообщение от %d в чате %d проигнорировано: блокировка", message.From.ID, message.Chat.ID)
+		return false
+	}
+
+	log.Printf("Доступ запрещен пользователю %d в чате %d", message.From.ID, message.Chat.ID)
+
+	// В группах объясняем отказ, только если обращаются к боту, чтобы не засорять переписку
+	if isGroupChat(message.Chat) {
+		_, addressed := tb.addressedText(message, message.Text)
+		if !addressed && !(message.IsCommand() && tb.isCommandForMe(message)) {
+			return false
+		}
+	}
+
+	tb.reply(message, tb.accessDeniedText(message))
+	return false
+}
+
+// accessDeniedText возвращает объяснение отказа для текущего режима доступа
+func (tb *TelegramBot) accessDeniedText(message *tgbotapi.Message) string {
+	locale := tb.locale(message.From)
+	invite := tb.agent.Access().Mode() == AccessModeInvite
+
+	if isGroupChat(message.Chat) {
+		text := T(locale, "access.denied.group", Params{"id": message.Chat.ID})
+		if invite {
+			text += "\n" + T(locale, "access.hint.group_invite")
+		}
+		return text
+	}
+
+	if invite {
+		return T(locale, "access.denied.invite", Params{"id": message.From.ID})
+	}
+	return T(locale, "access.denied.allowlist", Params{"id": message.From.ID})
+}
+
+// callbackChatID возвращает чат, в котором нажата кнопка; для инлайн-сообщений — личный чат
+func callbackChatID(callback *tgbotapi.CallbackQuery) int64 {
+	if callback.Message != nil && callback.Message.Chat != nil {
+		return callback.Message.Chat.ID
+	}
+	return callback.From.ID
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/joho/godotenv"
+)
+
+const (
+	// broadcastInterval — пауза между сообщениями рассылки (Telegram допускает около 30 сообщений в секунду)
+	broadcastInterval = 50 * time.Millisecond
+	// broadcastProgressEvery — как часто обновлять сообщение с ходом рассылки
+	broadcastProgressEvery = 25
+)
+
+// isAdmin проверяет, что пользователь указан в ADMIN_IDS
+func (tb *TelegramBot) isAdmin(userID int64) bool {
+	return tb.config.Load().admins[userID]
+}
+
+// handleAdminCommand обрабатывает команды администратора; права проверяет вызывающий
+func (tb *TelegramBot) handleAdminCommand(message *tgbotapi.Message) {
+	locale := tb.locale(message.From)
+	log.Printf("Команда администратора от %d: %s", message.From.ID, message.Text)
+
+	switch message.Command() {
+	case "stats":
+		tb.reply(message, tb.statsText(locale))
+	case "broadcast":
+		tb.handleBroadcast(message, locale)
+	case "ban":
+		tb.handleBan(message, locale, true)
+	case "unban":
+		tb.handleBan(message, locale, false)
+	case "reload":
+		tb.handleReload(message, locale)
+	case "provider":
+		tb.handleProvider(message, locale)
+	case "invite":
+		tb.handleInvite(message, locale)
+	case "allow":
+		tb.handleAllow(message, locale, true)
+	case "deny":
+		tb.handleAllow(message, locale, false)
+	}
+}
+
+// statsText собирает статистику работы бота
+func (tb *TelegramBot) statsText(locale string) string {
+	access := tb.agent.Access()
+	allowed, blocked := access.Counts()
+
+	metrics := tb.agent.Metrics()
+	return T(locale, "admin.stats", Params{
+		"users":     len(tb.agent.Users().List()),
+		"mode":      access.Mode(),
+		"allowed":   allowed,
+		"blocked":   blocked,
+		"messages":  metrics.Messages.Load(),
+		"requests":  metrics.LLMRequests.Load(),
+		"input":     metrics.InputTokens.Load(),
+		"output":    metrics.CompletionTokens.Load(),
+		"errors":    metrics.Errors.Load(),
+		"hits":      metrics.CacheHits.Load(),
+		"misses":    metrics.CacheMisses.Load(),
+		"cached":    tb.agent.ResponseCache().Len(),
+		"moderated": metrics.ModerationBlocked.Load(),
+		"flagged":   metrics.ModerationFlagged.Load(),
+		"provider":  tb.agent.ProviderName(),
+		"uptime":    metrics.Uptime().Truncate(time.Second),
+	})
+}
+
+// handleBroadcast рассылает сообщение всем известным пользователям Telegram, у которых есть доступ
+func (tb *TelegramBot) handleBroadcast(message *tgbotapi.Message, locale string) {
+	text := strings.TrimSpace(message.CommandArguments())
+	if text == "" {
+		tb.reply(message, T(locale, "admin.broadcast.usage"))
+		return
+	}
+
+	var recipients []int64
+	for _, user := range tb.agent.Users().List() {
+		// Пользователи других каналов не адресуемы через Bot API
+		if isChannelUserID(user.ID) {
+			continue
+		}
+		if tb.agent.Access().Check(user.ID, user.ID) == AccessAllowed {
+			recipients = append(recipients, user.ID)
+		}
+	}
+
+	total := len(recipients)
+	progress, err := tb.bot.Send(tgbotapi.NewMessage(message.Chat.ID,
+		T(locale, "admin.broadcast.progress", Params{"sent": 0, "total": total})))
+	if err != nil {
+		log.Printf("Ошибка отправки хода рассылки: %v", err)
+	}
+
+	sent, failed := 0, 0
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	for i, userID := range recipients {
+		<-ticker.C
+		// Текст рассылки отправляем без разметки, чтобы он дошел в точности как написан
+		if _, err := tb.bot.Send(tgbotapi.NewMessage(userID, text)); err != nil {
+			log.Printf("Ошибка рассылки пользователю %d: %v", userID, err)
+			failed++
+		} else {
+			sent++
+		}
+
+		if (i+1)%broadcastProgressEvery == 0 && i+1 < total && progress.MessageID != 0 {
+			tb.editText(progress, T(locale, "admin.broadcast.progress", Params{"sent": i + 1, "total": total}))
+		}
+	}
+
+	log.Printf("Рассылка завершена: доставлено %d из %d, ошибок %d", sent, total, failed)
+	done := T(locale, "admin.broadcast.done", Params{"sent": sent, "total": total, "failed": failed})
+	if progress.MessageID != 0 {
+		tb.editText(progress, done)
+		return
+	}
+	tb.reply(message, done)
+}
+
+// editText заменяет текст ранее отправленного сообщения
+func (tb *TelegramBot) editText(message tgbotapi.Message, text string) {
+	if _, err := tb.bot.Request(tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, text)); err != nil {
+		log.Printf("Ошибка обновления сообщения: %v", err)
+	}
+}
+
+// targetID возвращает ID из аргумента команды или автора сообщения, на которое ответили командой
+func targetID(message *tgbotapi.Message) (int64, bool) {
+	if args := strings.Fields(message.CommandArguments()); len(args) > 0 {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		return id, err == nil
+	}
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil {
+		return reply.From.ID, true
+	}
+	return 0, false
+}
+
+// handleBan блокирует или разблокирует пользователя или групповой чат
+func (tb *TelegramBot) handleBan(message *tgbotapi.Message, locale string, banned bool) {
+	id, ok := targetID(message)
+	if !ok {
+		tb.reply(message, T(locale, "admin.ban.usage"))
+		return
+	}
+
+	if banned && tb.isAdmin(id) {
+		tb.reply(message, T(locale, "admin.ban.admin"))
+		return
+	}
+
+	log.Printf("Администратор %d изменил блокировку %d: %v", message.From.ID, id, banned)
+	if banned {
+		tb.agent.Access().Block(id, message.From.ID)
+		tb.reply(message, T(locale, "admin.ban.done", Params{"id": id}))
+		return
+	}
+	tb.agent.Access().Unblock(id)
+	tb.reply(message, T(locale, "admin.unban.done", Params{"id": id}))
+}
+
+// handleAllow добавляет пользователя или групповой чат в список доступа или удаляет из него
+func (tb *TelegramBot) handleAllow(message *tgbotapi.Message, locale string, allowed bool) {
+	id, ok := targetID(message)
+	if !ok {
+		tb.reply(message, T(locale, "admin.allow.usage"))
+		return
+	}
+
+	log.Printf("Администратор %d изменил список доступа для %d: %v", message.From.ID, id, allowed)
+	if allowed {
+		tb.agent.Access().Allow(id, message.From.ID)
+		tb.reply(message, T(locale, "admin.allow.done", Params{"id": id}))
+		return
+	}
+	tb.agent.Access().Revoke(id)
+	tb.reply(message, T(locale, "admin.deny.done", Params{"id": id}))
+}
+
+// handleInvite создает код приглашения: /invite [число активаций]
+func (tb *TelegramBot) handleInvite(message *tgbotapi.Message, locale string) {
+	uses := 1
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			tb.reply(message, T(locale, "admin.invite.usage"))
+			return
+		}
+		uses = n
+	}
+
+	invite, err := tb.agent.Access().CreateInvite(message.From.ID, uses)
+	if err != nil {
+		log.Printf("Ошибка создания кода приглашения: %v", err)
+		tb.reply(message, T(locale, "error.request"))
+		return
+	}
+
+	text := T(locale, "admin.invite.created", Params{
+		"code":       invite.Code,
+		"uses":       invite.MaxUses,
+		"link":       "https://t.me/" + tb.bot.Self.UserName + "?start=" + invite.Code,
+		"group_link": "https://t.me/" + tb.bot.Self.UserName + "?startgroup=" + invite.Code,
+	})
+	if tb.agent.Access().Mode() != AccessModeInvite {
+		text += "\n\n" + T(locale, "admin.invite.mode_hint")
+	}
+	tb.reply(message, text)
+}
+
+// handleReload перечитывает .env и применяет настройки без перезапуска
+func (tb *TelegramBot) handleReload(message *tgbotapi.Message, locale string) {
+	if err := godotenv.Overload(); err != nil && !os.IsNotExist(err) {
+		tb.reply(message, T(locale, "admin.reload.failed", Params{"error": err}))
+		return
+	}
+
+	config := loadTelegramConfig()
+	tb.config.Store(config)
+	configureProviders(tb.agent)
+	// Бэкенд, выбранный через /provider, сохраняем, пока он остается настроенным
+	if provider := tb.provider.Load(); provider != nil {
+		if err := tb.agent.SetProvider(*provider); err != nil {
+			log.Printf("Бэкенд %s больше не настроен, используем %s", *provider, tb.agent.ProviderName())
+			tb.provider.Store(nil)
+		}
+	}
+	tb.agent.Access().Configure(accessModeFromEnv(), accessAllowlistFromEnv())
+	tb.agent.SetDefaultTimezone(defaultTimezone())
+	tb.agent.SetContextWindow(createTokenCounter(), contextWindowFromEnv())
+	tb.agent.SetRedactor(NewRedactor(piiModesFromEnv()))
+	tb.agent.SetModerator(createModerator(tb.agent))
+
+	log.Printf("Конфигурация перечитана администратором %d", message.From.ID)
+	tb.reply(message, T(locale, "admin.reload.done", Params{
+		"admins":   len(config.admins),
+		"mode":     tb.agent.Access().Mode(),
+		"provider": tb.agent.ProviderName(),
+	}))
+}
+
+// handleProvider показывает или переключает бэкенд модели: /provider [имя]
+func (tb *TelegramBot) handleProvider(message *tgbotapi.Message, locale string) {
+	name := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if name == "" {
+		tb.reply(message, T(locale, "admin.provider.current", Params{
+			"provider":  tb.agent.ProviderName(),
+			"providers": strings.Join(tb.agent.Providers(), ", "),
+		}))
+		return
+	}
+
+	if err := tb.agent.SetProvider(name); err != nil {
+		tb.reply(message, "❌ "+localizeError(locale, err))
+		return
+	}
+	tb.provider.Store(&name)
+
+	log.Printf("Администратор %d переключил бэкенд модели на %s", message.From.ID, name)
+	tb.reply(message, T(locale, "admin.provider.switched", Params{"provider": name}))
+}
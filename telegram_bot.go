@@ -2,7 +2,12 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -10,148 +15,301 @@ import (
 // TelegramBot представляет Telegram бота
 type TelegramBot struct {
 	bot        *tgbotapi.BotAPI
+	channel    *TelegramChannel // Ответы, статус «печатает» и прием обновлений
+	agent      *Agent
 	httpClient *HTTPClient
+	files      *http.Client // Скачивание файлов через Bot API
+	inline     *InlineHandler
+	config     atomic.Pointer[telegramConfig]
+	provider   atomic.Pointer[string] // Бэкенд, выбранный администратором через /provider
 }
 
-// NewTelegramBot создает новый экземпляр Telegram бота
-func NewTelegramBot(token string) *TelegramBot {
-	bot, err := tgbotapi.NewBotAPI(token)
+// telegramConfig — настройки бота из окружения, которые перечитываются командой /reload
+type telegramConfig struct {
+	admins       map[int64]bool
+	speech       SpeechRecognizer
+	groupContext string
+}
+
+// loadTelegramConfig читает настройки бота из переменных окружения
+func loadTelegramConfig() *telegramConfig {
+	return &telegramConfig{
+		admins:       parseIDList("ADMIN_IDS"),
+		speech:       createSpeechRecognizer(),
+		groupContext: groupContextMode(),
+	}
+}
+
+// NewTelegramBot создает новый экземпляр Telegram бота.
+// С HTTP_CASSETTE_MODE запросы к Bot API идут через кассету.
+func NewTelegramBot(token string) (*TelegramBot, error) {
+	client := &http.Client{}
+	if err := useCassette("telegram", func(transport http.RoundTripper) { client.Transport = transport }); err != nil {
+		return nil, err
+	}
+	return NewTelegramBotWithClient(token, client, sharedAgent())
+}
+
+// NewTelegramBotWithClient создает бота, который обращается к Bot API через client
+// и отвечает с помощью agent. Через client подключается кассета с записанными ответами.
+func NewTelegramBotWithClient(token string, client *http.Client, agent *Agent) (*TelegramBot, error) {
+	bot, err := tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, client)
 	if err != nil {
-		log.Fatal("Ошибка создания бота:", err)
+		return nil, err
 	}
 
 	// Получаем URL для внешнего API
 	apiURL := os.Getenv("EXTERNAL_API_URL")
 	httpClient := NewHTTPClient(apiURL)
+	httpClient.agent = agent
 
-	return &TelegramBot{
+	tb := &TelegramBot{
 		bot:        bot,
+		channel:    NewTelegramChannel(bot),
+		agent:      agent,
 		httpClient: httpClient,
+		files:      &http.Client{Timeout: 60 * time.Second, Transport: client.Transport},
+		inline:     NewInlineHandler(bot, agent),
 	}
+	tb.config.Store(loadTelegramConfig())
+	agent.Reminders().SetDelivery(tb.deliverReminder)
+
+	return tb, nil
 }
 
 // Start запускает бота
 func (tb *TelegramBot) Start() error {
 	log.Printf("Бот @%s запущен", tb.bot.Self.UserName)
 
-	// Настраиваем обновления
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	// Напоминания отправляет бот, поэтому планировщик запускается вместе с ним
+	tb.agent.Reminders().Start(reminderTickInterval)
 
-	updates := tb.bot.GetUpdatesChan(u)
+	// Обновления принимает канал Telegram, а разбирает их бот: у Telegram свои команды, кнопки и инлайн-режим
+	return tb.channel.Start(func(in InboundMessage) {
+		tb.HandleUpdate(in.Raw.(tgbotapi.Update))
+	})
+}
 
-	// Обрабатываем обновления
-	for update := range updates {
-		if update.Message != nil {
-			go tb.handleMessage(update.Message)
-		}
+// HandleUpdate обрабатывает одно обновление до конца: сообщение, инлайн-запрос или нажатие кнопки
+func (tb *TelegramBot) HandleUpdate(update tgbotapi.Update) {
+	if update.Message != nil {
+		tb.handleMessage(update.Message)
+	}
+	if update.InlineQuery != nil {
+		tb.inline.Handle(update.InlineQuery)
+	}
+	if update.CallbackQuery != nil {
+		tb.handleCallback(update.CallbackQuery)
 	}
-
-	return nil
 }
 
 // handleMessage обрабатывает входящие сообщения
 func (tb *TelegramBot) handleMessage(message *tgbotapi.Message) {
-	log.Printf("Получено сообщение от %s (%d): %s", 
-		message.From.UserName, message.From.ID, message.Text)
+	if message.From == nil {
+		return
+	}
+
+	log.Printf("Получено сообщение от %s (%d) в чате %d: %s", 
+		message.From.UserName, message.From.ID, message.Chat.ID, message.Text)
+
+	// /start доступна всем: через нее активируются коды приглашений
+	if message.IsCommand() && message.Command() == "start" {
+		if tb.isCommandForMe(message) {
+			tb.handleStart(message)
+		}
+		return
+	}
+
+	// Политика доступа проверяется до любой обработки, чтобы не тратить квоту модели
+	if !tb.checkAccess(message) {
+		return
+	}
+	tb.agent.Users().Touch(message.From.ID, displayName(message.From))
 
-	// Обрабатываем команды
+	// Обрабатываем команды, адресованные этому боту
 	if message.IsCommand() {
-		tb.handleCommand(message)
+		if tb.isCommandForMe(message) {
+			tb.handleCommand(message)
+		}
+		return
+	}
+
+	// Голосовые и аудиосообщения распознаем и обрабатываем как текст
+	if message.Voice != nil || message.Audio != nil {
+		tb.handleVoiceMessage(message)
+		return
+	}
+
+	// Геопозиция в личном чате задает часовой пояс
+	if message.Location != nil && !isGroupChat(message.Chat) {
+		tb.handleLocationMessage(message)
+		return
+	}
+
+	// Документы сохраняем для ответов на вопросы по ним
+	if message.Document != nil {
+		tb.handleDocumentMessage(message)
+		return
+	}
+
+	// В группах отвечаем только на упоминания и ответы на сообщения бота
+	text, addressed := tb.addressedText(message, message.Text)
+	if !addressed || text == "" {
 		return
 	}
 
 	// Обрабатываем обычные сообщения
-	tb.handleTextMessage(message)
+	tb.handleTextMessage(message, text)
 }
 
 // handleCommand обрабатывает команды
 func (tb *TelegramBot) handleCommand(message *tgbotapi.Message) {
 	switch message.Command() {
-	case "start":
-		tb.sendMessage(message.Chat.ID, 
-			"🤖 Привет! Я ваш персональный агент-помощник.\n\n"+
-			"Я могу помочь с:\n"+
-			"• Информацией о погоде\n"+
-			"• Текущим временем\n"+
-			"• Математическими вычислениями\n"+
-			"• И многим другим!\n\n"+
-			"Просто напишите мне вопрос или используйте /help для списка команд.")
-		
 	case "help":
-		response, _ := tb.httpClient.SendRequest("/help", message.From.ID)
-		tb.sendMessage(message.Chat.ID, response.Answer)
+		tb.reply(message, tb.ask(message, "/help"))
 		
 	case "weather":
-		response, _ := tb.httpClient.SendRequest("погода", message.From.ID)
-		tb.sendMessage(message.Chat.ID, response.Answer)
+		tb.reply(message, tb.ask(message, "погода"))
 		
 	case "time":
-		response, _ := tb.httpClient.SendRequest("время", message.From.ID)
-		tb.sendMessage(message.Chat.ID, response.Answer)
+		tb.reply(message, tb.ask(message, "время"))
 		
+	case "docs":
+		tb.handleDocsCommand(message)
+
+	case "settings":
+		tb.handleSettingsCommand(message)
+
+	case "reminders":
+		tb.handleRemindersCommand(message)
+
+	case "notes":
+		tb.handleNotesCommand(message, NoteKindNote)
+
+	case "todo":
+		tb.handleNotesCommand(message, NoteKindTodo)
+
+	case "timezone":
+		tb.handleTimezoneCommand(message)
+
+	case "export":
+		tb.handleExportCommand(message)
+
+	case "stats", "broadcast", "ban", "unban", "reload", "provider", "invite", "allow", "deny":
+		// Для остальных пользователей команд администратора не существует
+		if !tb.isAdmin(message.From.ID) {
+			tb.reply(message, T(tb.locale(message.From), "command.unknown"))
+			return
+		}
+		tb.handleAdminCommand(message)
+
 	case "calculate":
-		tb.sendMessage(message.Chat.ID, 
-			"🧮 Для вычислений напишите выражение, например:\n"+
-			"• 2 + 3\n"+
-			"• 10 - 5\n"+
-			"• 4 * 6\n"+
-			"• 15 / 3")
-		
+		if expression := strings.TrimSpace(message.CommandArguments()); expression != "" {
+			tb.reply(message, tb.ask(message, "вычисли "+expression))
+			return
+		}
+		tb.reply(message, T(tb.locale(message.From), "calculate.usage"))
+
+	case "convert":
+		if query := strings.TrimSpace(message.CommandArguments()); query != "" {
+			tb.reply(message, tb.ask(message, query))
+			return
+		}
+		tb.reply(message, T(tb.locale(message.From), "convert.usage"))
+
 	default:
-		tb.sendMessage(message.Chat.ID, 
-			"❓ Неизвестная команда. Используйте /help для списка доступных команд.")
+		tb.reply(message, T(tb.locale(message.From), "command.unknown"))
+	}
+}
+
+// handleCallback обрабатывает нажатия кнопок инлайн-клавиатур
+func (tb *TelegramBot) handleCallback(callback *tgbotapi.CallbackQuery) {
+	switch {
+	case tb.agent.Access().Check(callback.From.ID, callbackChatID(callback)) != AccessAllowed:
+		tb.answerCallback(callback.ID, "")
+	case strings.HasPrefix(callback.Data, settingsCallbackPrefix):
+		tb.handleSettingsCallback(callback)
+	default:
+		tb.answerCallback(callback.ID, "")
 	}
 }
 
 // handleTextMessage обрабатывает текстовые сообщения
-func (tb *TelegramBot) handleTextMessage(message *tgbotapi.Message) {
+func (tb *TelegramBot) handleTextMessage(message *tgbotapi.Message, text string) {
 	// Показываем, что бот печатает
 	tb.sendTypingAction(message.Chat.ID)
 
+	// Отправляем ответ пользователю
+	tb.reply(message, tb.ask(message, text))
+}
+
+// ask отправляет текст агенту в контексте чата, из которого пришло сообщение
+func (tb *TelegramBot) ask(message *tgbotapi.Message, text string) string {
+	mc := tb.conversationFor(message)
+
 	// Отправляем запрос через HTTP клиент
-	response, err := tb.httpClient.SendRequest(message.Text, message.From.ID)
+	response, err := tb.httpClient.Send(Request{
+		Message:        text,
+		UserID:         mc.UserID,
+		ConversationID: mc.ConversationID,
+		Author:         mc.Author,
+		Locale:         mc.Locale,
+	})
 	if err != nil {
 		log.Printf("Ошибка HTTP запроса: %v", err)
-		tb.sendMessage(message.Chat.ID, 
-			"❌ Извините, произошла ошибка при обработке вашего запроса.")
-		return
+		return T(mc.Locale, "error.request")
 	}
 
-	// Отправляем ответ пользователю
-	tb.sendMessage(message.Chat.ID, response.Answer)
+	return response.Answer
+}
+
+// locale возвращает язык интерфейса пользователя Telegram и запоминает его для агента
+func (tb *TelegramBot) locale(user *tgbotapi.User) string {
+	if user == nil {
+		return defaultLocale
+	}
+	tb.agent.RememberLocale(user.ID, user.LanguageCode)
+	return tb.agent.LocaleFor(user.ID)
+}
+
+// reply отвечает на сообщение; в группах ответ привязывается к исходному сообщению
+func (tb *TelegramBot) reply(message *tgbotapi.Message, text string) {
+	reply := OutboundMessage{Text: text, Markdown: true}
+	if isGroupChat(message.Chat) {
+		reply.ReplyTo = strconv.Itoa(message.MessageID)
+	}
+
+	if err := tb.channel.Send(strconv.FormatInt(message.Chat.ID, 10), reply); err != nil {
+		log.Printf("Ошибка отправки сообщения: %v", err)
+	}
 }
 
 // sendMessage отправляет сообщение пользователю
 func (tb *TelegramBot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-	
-	if _, err := tb.bot.Send(msg); err != nil {
+	if err := tb.channel.Send(strconv.FormatInt(chatID, 10), OutboundMessage{Text: text, Markdown: true}); err != nil {
 		log.Printf("Ошибка отправки сообщения: %v", err)
 	}
 }
 
 // sendTypingAction показывает индикатор "печатает"
 func (tb *TelegramBot) sendTypingAction(chatID int64) {
-	action := tgbotapi.NewChatAction(chatID, "typing")
-	tb.bot.Send(action)
+	tb.channel.SendTyping(strconv.FormatInt(chatID, 10))
 }
 
 // sendMessageWithKeyboard отправляет сообщение с клавиатурой
-func (tb *TelegramBot) sendMessageWithKeyboard(chatID int64, text string) {
+func (tb *TelegramBot) sendMessageWithKeyboard(chatID int64, text, locale string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
 	
 	// Создаем клавиатуру с быстрыми командами
 	keyboard := tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🌤️ Погода"),
-			tgbotapi.NewKeyboardButton("🕐 Время"),
+			tgbotapi.NewKeyboardButton(T(locale, "keyboard.weather")),
+			tgbotapi.NewKeyboardButton(T(locale, "keyboard.time")),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("🧮 Вычисления"),
-			tgbotapi.NewKeyboardButton("❓ Помощь"),
+			tgbotapi.NewKeyboardButton(T(locale, "keyboard.calculate")),
+			tgbotapi.NewKeyboardButton(T(locale, "keyboard.help")),
 		),
 	)
 	keyboard.OneTimeKeyboard = true
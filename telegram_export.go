@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleExportCommand отправляет историю разговора файлом: /export [json|md|html].
+// Администратор может выгрузить разговоры другого пользователя: /export html 123456.
+func (tb *TelegramBot) handleExportCommand(message *tgbotapi.Message) {
+	locale := tb.locale(message.From)
+	args := strings.Fields(message.CommandArguments())
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	format, ok := parseExportFormat(name)
+	if !ok || len(args) > 2 {
+		tb.reply(message, T(locale, "export.usage"))
+		return
+	}
+
+	var conversations []ConversationExport
+	fileName := ""
+	if len(args) == 2 {
+		userID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || !tb.isAdmin(message.From.ID) {
+			tb.reply(message, T(locale, "export.usage"))
+			return
+		}
+		conversations = tb.agent.ExportUserConversations(userID)
+		fileName = args[1]
+	} else {
+		mc := tb.conversationFor(message)
+		if conversation, exists := tb.agent.ExportConversation(mc.ConversationID); exists {
+			conversations = []ConversationExport{conversation}
+		}
+		fileName = mc.ConversationID
+	}
+
+	entries := 0
+	for _, conversation := range conversations {
+		entries += len(conversation.Entries)
+	}
+	if entries == 0 {
+		tb.reply(message, T(locale, "export.empty"))
+		return
+	}
+
+	now := time.Now()
+	data, err := RenderExport(format, locale, tb.agent.LocationFor(message.From.ID), conversations)
+	if err != nil {
+		log.Printf("Ошибка выгрузки разговора: %v", err)
+		tb.reply(message, T(locale, "error.generic"))
+		return
+	}
+
+	document := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{
+		Name:  exportFileName(fileName, format, now),
+		Bytes: data,
+	})
+	document.Caption = TN(locale, "export.caption", entries)
+	if isGroupChat(message.Chat) {
+		document.ReplyToMessageID = message.MessageID
+	}
+	if _, err := tb.bot.Send(document); err != nil {
+		log.Printf("Ошибка отправки выгрузки: %v", err)
+		tb.reply(message, T(locale, "error.generic"))
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Режимы контекста в групповых чатах (переменная GROUP_CONTEXT)
+const (
+	groupContextShared  = "shared"   // Одна история на весь чат
+	groupContextPerUser = "per_user" // Отдельная ветка для каждого участника
+)
+
+// groupContextMode читает режим контекста групповых чатов из окружения
+func groupContextMode() string {
+	if os.Getenv("GROUP_CONTEXT") == groupContextPerUser {
+		return groupContextPerUser
+	}
+	return groupContextShared
+}
+
+// isGroupChat проверяет, что сообщение пришло из группы или супергруппы
+func isGroupChat(chat *tgbotapi.Chat) bool {
+	return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+}
+
+// conversationFor определяет, в какую историю записывать сообщение
+func (tb *TelegramBot) conversationFor(message *tgbotapi.Message) MessageContext {
+	mc := MessageContext{
+		UserID:         message.From.ID,
+		ConversationID: userConversationID(message.From.ID),
+		Locale:         tb.locale(message.From),
+	}
+
+	if isGroupChat(message.Chat) {
+		mc.Author = displayName(message.From)
+		mc.ConversationID = fmt.Sprintf("chat:%d", message.Chat.ID)
+		if tb.config.Load().groupContext == groupContextPerUser {
+			mc.ConversationID = fmt.Sprintf("chat:%d:user:%d", message.Chat.ID, message.From.ID)
+		}
+	}
+
+	return mc
+}
+
+// isCommandForMe проверяет, что команда вида /cmd@botname адресована этому боту
+func (tb *TelegramBot) isCommandForMe(message *tgbotapi.Message) bool {
+	command := message.CommandWithAt()
+	at := strings.Index(command, "@")
+	if at == -1 {
+		return true
+	}
+	return strings.EqualFold(command[at+1:], tb.bot.Self.UserName)
+}
+
+// addressedText возвращает текст (сообщения или подписи), обращенный к боту, и признак того,
+// что боту нужно ответить. В личных чатах бот отвечает всегда, в группах — только на упоминание
+// или ответ на его сообщение.
+func (tb *TelegramBot) addressedText(message *tgbotapi.Message, text string) (string, bool) {
+	if !isGroupChat(message.Chat) {
+		return text, true
+	}
+
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil && reply.From.ID == tb.bot.Self.ID {
+		return text, true
+	}
+
+	entities := message.Entities
+	if message.Text == "" {
+		entities = message.CaptionEntities
+	}
+	for _, entity := range entities {
+		if entity.Type == "text_mention" && entity.User != nil && entity.User.ID == tb.bot.Self.ID {
+			return text, true
+		}
+	}
+
+	if start, end, ok := tb.mentionRange(text, entities); ok {
+		stripped := strings.TrimSpace(text[:start] + text[end:])
+		stripped = strings.TrimLeft(stripped, ",: ")
+		return stripped, true
+	}
+
+	return "", false
+}
+
+// mentionRange ищет в text упоминание @username бота и возвращает его границы в байтах.
+// Telegram размечает упоминания сущностями mention со смещениями в UTF-16; если сущностей
+// нет, ищем имя целиком, чтобы @mybot не срабатывал на @mybot_helper.
+func (tb *TelegramBot) mentionRange(text string, entities []tgbotapi.MessageEntity) (int, int, bool) {
+	mention := "@" + tb.bot.Self.UserName
+
+	if len(entities) > 0 {
+		for _, entity := range entities {
+			if entity.Type != "mention" {
+				continue
+			}
+			start, end := utf16Range(text, entity.Offset, entity.Length)
+			if start < end && strings.EqualFold(text[start:end], mention) {
+				return start, end, true
+			}
+		}
+		return 0, 0, false
+	}
+
+	for start := 0; start+len(mention) <= len(text); start++ {
+		end := start + len(mention)
+		if !strings.EqualFold(text[start:end], mention) {
+			continue
+		}
+		if (start == 0 || !isUsernameByte(text[start-1])) && (end == len(text) || !isUsernameByte(text[end])) {
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// utf16Range переводит смещение и длину в единицах UTF-16 (так их считает Telegram)
+// в границы в байтах строки
+func utf16Range(text string, offset, length int) (int, int) {
+	start, end := -1, -1
+	units := 0
+	for i, r := range text {
+		if units == offset {
+			start = i
+		}
+		if units == offset+length {
+			end = i
+			break
+		}
+		units++
+		if r >= 0x10000 {
+			units++
+		}
+	}
+	if units == offset+length && end == -1 {
+		end = len(text)
+	}
+	if start == -1 || end == -1 {
+		return 0, 0
+	}
+	return start, end
+}
+
+// isUsernameByte проверяет, что байт может входить в имя пользователя Telegram
+func isUsernameByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// displayName возвращает имя пользователя для подписи сообщений в группе
+func displayName(user *tgbotapi.User) string {
+	if user == nil {
+		return ""
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.UserName
+	}
+	return name
+}
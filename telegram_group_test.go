@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestAddressedText(t *testing.T) {
+	tb := &TelegramBot{bot: &tgbotapi.BotAPI{Self: tgbotapi.User{ID: 100, UserName: "mybot"}}}
+	group := &tgbotapi.Chat{ID: -1, Type: "group"}
+	mention := func(offset, length int) []tgbotapi.MessageEntity {
+		return []tgbotapi.MessageEntity{{Type: "mention", Offset: offset, Length: length}}
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		entities []tgbotapi.MessageEntity
+		want     string
+		wantOK   bool
+	}{
+		{"упоминание в начале", "@mybot, сколько времени?", mention(0, 6), "сколько времени?", true},
+		{"регистр имени", "@MyBot привет", mention(0, 6), "привет", true},
+		{"упоминание после кириллицы", "Привет, 😀 @mybot как дела", mention(11, 6), "Привет, 😀  как дела", true},
+		{"упоминание другого бота", "@mybot_helper привет", mention(0, 13), "", false},
+		{"без сущностей: имя целиком", "скажи @mybot: 2+2", nil, "скажи : 2+2", true},
+		{"без сущностей: длинное имя", "@mybot_helper привет", nil, "", false},
+		{"без сущностей: почта", "пишите на info@mybot", nil, "", false},
+		{"без упоминания", "просто сообщение", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := &tgbotapi.Message{Chat: group, Text: tt.text, Entities: tt.entities}
+			got, ok := tb.addressedText(message, tt.text)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("addressedText(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	reply := &tgbotapi.Message{
+		Chat:           group,
+		Text:           "а еще?",
+		ReplyToMessage: &tgbotapi.Message{From: &tgbotapi.User{ID: 100}},
+	}
+	if got, ok := tb.addressedText(reply, reply.Text); !ok || got != "а еще?" {
+		t.Errorf("ответ на сообщение бота: %q, %v", got, ok)
+	}
+	private := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 5, Type: "private"}, Text: "привет"}
+	if got, ok := tb.addressedText(private, private.Text); !ok || got != "привет" {
+		t.Errorf("личный чат: %q, %v", got, ok)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// inlineDebounce — пауза после последнего нажатия, прежде чем идти в LLM
+	inlineDebounce = 800 * time.Millisecond
+	// inlineCacheTTL — время жизни ответа LLM в кэше инлайн-запросов
+	inlineCacheTTL = 10 * time.Minute
+	// inlineCacheSize — максимальное число закэшированных ответов
+	inlineCacheSize = 500
+	// inlineTelegramCacheTime — сколько секунд Telegram может кэшировать результат у себя
+	inlineTelegramCacheTime = 60
+)
+
+// inlineCacheEntry представляет закэшированный ответ LLM
+type inlineCacheEntry struct {
+	answer    string
+	expiresAt time.Time
+}
+
+// InlineHandler обрабатывает инлайн-запросы вида "@bot 2+2*3"
+type InlineHandler struct {
+	bot   *tgbotapi.BotAPI
+	agent *Agent
+
+	mu      sync.Mutex
+	pending map[int64]*time.Timer
+	cache   map[string]inlineCacheEntry
+}
+
+// NewInlineHandler создает обработчик инлайн-запросов
+func NewInlineHandler(bot *tgbotapi.BotAPI, agent *Agent) *InlineHandler {
+	return &InlineHandler{
+		bot:     bot,
+		agent:   agent,
+		pending: make(map[int64]*time.Timer),
+		cache:   make(map[string]inlineCacheEntry),
+	}
+}
+
+// Handle отвечает на инлайн-запрос: встроенные инструменты — сразу,
+// LLM — после паузы в наборе и с кэшированием
+func (h *InlineHandler) Handle(query *tgbotapi.InlineQuery) {
+	text := strings.TrimSpace(query.Query)
+	log.Printf("Инлайн-запрос от %s (%d): %s", query.From.UserName, query.From.ID, text)
+
+	h.agent.RememberLocale(query.From.ID, query.From.LanguageCode)
+	locale := h.agent.LocaleFor(query.From.ID)
+
+	// Инлайн-запросы приходят без чата, поэтому доступ проверяется как в личном чате
+	if text == "" || h.agent.Access().Check(query.From.ID, query.From.ID) != AccessAllowed {
+		h.answer(query.ID, nil)
+		return
+	}
+
+	// Встроенные инструменты отвечают мгновенно
+	if toolName, answer, ok := h.agent.AnswerWithTool(text, query.From.ID); ok {
+		h.answer(query.ID, []interface{}{
+			newInlineArticle(toolName, text, inlineToolTitle(locale, toolName), answer),
+		})
+		return
+	}
+
+	if answer, ok := h.cached(query.From.ID, text); ok {
+		h.answer(query.ID, []interface{}{
+			newInlineArticle("llm", text, inlineToolTitle(locale, "llm"), answer),
+		})
+		return
+	}
+
+	// Откладываем обращение к LLM, пока пользователь продолжает печатать
+	h.mu.Lock()
+	if timer, exists := h.pending[query.From.ID]; exists {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(inlineDebounce, func() {
+		h.mu.Lock()
+		if h.pending[query.From.ID] != timer {
+			h.mu.Unlock()
+			return
+		}
+		delete(h.pending, query.From.ID)
+		h.mu.Unlock()
+
+		h.answerWithLLM(query, text, locale)
+	})
+	h.pending[query.From.ID] = timer
+	h.mu.Unlock()
+}
+
+// answerWithLLM запрашивает ответ у LLM и отправляет его как результат инлайн-запроса
+func (h *InlineHandler) answerWithLLM(query *tgbotapi.InlineQuery, text, locale string) {
+	answer, err := h.agent.AnswerWithLLM(text, query.From.ID)
+	if err != nil {
+		log.Printf("Ошибка LLM для инлайн-запроса: %v", err)
+		answer = h.agent.generateGeneralResponse(text, locale)
+	} else {
+		h.store(query.From.ID, text, answer)
+	}
+
+	h.answer(query.ID, []interface{}{
+		newInlineArticle("llm", text, inlineToolTitle(locale, "llm"), answer),
+	})
+}
+
+// cached возвращает ответ пользователю из кэша, если он еще не устарел
+func (h *InlineHandler) cached(userID int64, text string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := inlineCacheKey(userID, text)
+	entry, exists := h.cache[key]
+	if !exists {
+		return "", false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(h.cache, key)
+		return "", false
+	}
+	return entry.answer, true
+}
+
+// store сохраняет ответ пользователю в кэш, вытесняя устаревшие записи при переполнении
+func (h *InlineHandler) store(userID int64, text, answer string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if len(h.cache) >= inlineCacheSize {
+		for key, entry := range h.cache {
+			if now.After(entry.expiresAt) {
+				delete(h.cache, key)
+			}
+		}
+	}
+	// Если место так и не освободилось, удаляем произвольную запись
+	for key := range h.cache {
+		if len(h.cache) < inlineCacheSize {
+			break
+		}
+		delete(h.cache, key)
+	}
+
+	h.cache[inlineCacheKey(userID, text)] = inlineCacheEntry{
+		answer:    answer,
+		expiresAt: now.Add(inlineCacheTTL),
+	}
+}
+
+// answer отправляет результаты инлайн-запроса
+func (h *InlineHandler) answer(queryID string, results []interface{}) {
+	if results == nil {
+		results = []interface{}{}
+	}
+
+	config := tgbotapi.InlineConfig{
+		InlineQueryID: queryID,
+		Results:       results,
+		CacheTime:     inlineTelegramCacheTime,
+		IsPersonal:    true, // Ответы зависят от настроек, заметок и напоминаний пользователя
+	}
+
+	if _, err := h.bot.Request(config); err != nil {
+		log.Printf("Ошибка ответа на инлайн-запрос: %v", err)
+	}
+}
+
+// newInlineArticle создает статью с ответом; ID стабилен для одинаковых запросов
+func newInlineArticle(kind, query, title, answer string) tgbotapi.InlineQueryResultArticle {
+	hash := sha1.Sum([]byte(kind + "\x00" + query))
+	article := tgbotapi.NewInlineQueryResultArticleMarkdown(hex.EncodeToString(hash[:]), title, answer)
+	article.Description = inlineDescription(answer)
+	return article
+}
+
+// inlineToolTitle возвращает заголовок результата для встроенного инструмента
+func inlineToolTitle(locale, toolName string) string {
+	switch toolName {
+	case "calculate", "weather", "time", "help":
+		return T(locale, "inline.title."+toolName)
+	default:
+		return T(locale, "inline.title.llm")
+	}
+}
+
+// inlineDescription укорачивает ответ для превью в списке результатов
+func inlineDescription(answer string) string {
+	answer = strings.Join(strings.Fields(answer), " ")
+	runes := []rune(answer)
+	if len(runes) > 100 {
+		return string(runes[:100]) + "…"
+	}
+	return answer
+}
+
+// inlineCacheKey нормализует запрос для кэша. Ответ зависит от персоны, языка, пояса и модели
+// пользователя, поэтому кэш у каждого пользователя свой.
+func inlineCacheKey(userID int64, text string) string {
+	return strconv.FormatInt(userID, 10) + "\x00" + strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
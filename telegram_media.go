@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleVoiceMessage распознает голосовое или аудиосообщение и обрабатывает его как текст
+func (tb *TelegramBot) handleVoiceMessage(message *tgbotapi.Message) {
+	// В группах реагируем только на голосовые, адресованные боту
+	if _, addressed := tb.addressedText(message, message.Caption); !addressed {
+		return
+	}
+
+	locale := tb.locale(message.From)
+	speech := tb.config.Load().speech
+	if speech == nil {
+		tb.reply(message, T(locale, "voice.disabled"))
+		return
+	}
+
+	fileID, format, duration, err := voiceSource(message)
+	if err != nil {
+		tb.reply(message, localizeError(locale, err))
+		return
+	}
+	if duration > speechKitMaxDuration {
+		tb.reply(message, T(locale, "voice.too_long", Params{"seconds": int(speechKitMaxDuration.Seconds())}))
+		return
+	}
+
+	tb.sendTypingAction(message.Chat.ID)
+
+	audio, err := tb.downloadFile(fileID, speechKitMaxAudioSize)
+	if err != nil {
+		log.Printf("Ошибка скачивания голосового сообщения: %v", err)
+		tb.reply(message, T(locale, "voice.download_failed"))
+		return
+	}
+
+	transcript, err := speech.Recognize(audio, format, locale)
+	if err != nil {
+		log.Printf("Ошибка распознавания речи: %v", err)
+		tb.reply(message, T(locale, "voice.recognize_failed"))
+		return
+	}
+	transcript = strings.TrimSpace(transcript)
+	if transcript == "" {
+		tb.reply(message, T(locale, "voice.empty"))
+		return
+	}
+
+	log.Printf("Распознано голосовое сообщение от %d: %s", message.From.ID, transcript)
+
+	answer := tb.ask(message, transcript)
+	tb.reply(message, T(locale, "voice.transcript", Params{"transcript": escapeMarkdown(transcript), "answer": answer}))
+}
+
+// voiceSource возвращает файл, формат и длительность голосового или аудиосообщения
+func voiceSource(message *tgbotapi.Message) (string, string, time.Duration, error) {
+	if message.Voice != nil {
+		// Голосовые сообщения Telegram всегда записаны в OGG/Opus
+		return message.Voice.FileID, AudioFormatOggOpus,
+			time.Duration(message.Voice.Duration) * time.Second, nil
+	}
+
+	audio := message.Audio
+	if audio.MimeType != "audio/ogg" && audio.MimeType != "audio/opus" {
+		return "", "", 0, newLocalizedError("voice.unsupported_format", Params{"format": audio.MimeType})
+	}
+	return audio.FileID, AudioFormatOggOpus, time.Duration(audio.Duration) * time.Second, nil
+}
+
+// downloadFile скачивает файл через Bot API, ограничивая его размер
+func (tb *TelegramBot) downloadFile(fileID string, maxSize int64) ([]byte, error) {
+	fileURL, err := tb.bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ссылки на файл: %v", err)
+	}
+
+	resp, err := tb.files.Get(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка HTTP запроса: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Bot API вернул ошибку %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("файл больше %d байт", maxSize)
+	}
+
+	return data, nil
+}
+
+// handleDocumentMessage извлекает текст из присланного файла и сохраняет его для вопросов
+func (tb *TelegramBot) handleDocumentMessage(message *tgbotapi.Message) {
+	// В группах принимаем только файлы, адресованные боту
+	question, addressed := tb.addressedText(message, message.Caption)
+	if !addressed {
+		return
+	}
+
+	locale := tb.locale(message.From)
+	document := message.Document
+	name := escapeMarkdown(document.FileName)
+	if !isSupportedDocument(document.FileName) {
+		tb.reply(message, T(locale, "docs.unsupported", Params{"name": name}))
+		return
+	}
+	if int64(document.FileSize) > maxDocumentFileSize {
+		tb.reply(message, T(locale, "docs.too_large", Params{"name": name, "mb": maxDocumentFileSize >> 20}))
+		return
+	}
+
+	tb.sendTypingAction(message.Chat.ID)
+
+	data, err := tb.downloadFile(document.FileID, maxDocumentFileSize)
+	if err != nil {
+		log.Printf("Ошибка скачивания документа: %v", err)
+		tb.reply(message, T(locale, "docs.download_failed"))
+		return
+	}
+
+	text, err := extractDocumentText(document.FileName, data)
+	if err != nil {
+		tb.reply(message, T(locale, "docs.read_failed", Params{"name": name, "error": localizeError(locale, err)}))
+		return
+	}
+
+	conversationID := tb.conversationFor(message).ConversationID
+	stored, err := tb.agent.Documents().Add(conversationID, document.FileName, text)
+	if err != nil {
+		tb.reply(message, "📄 "+localizeError(locale, err))
+		return
+	}
+
+	log.Printf("Документ %s загружен в разговор %s: %d символов, %d фрагментов",
+		document.FileName, conversationID, stored.Size, len(stored.Chunks))
+
+	tb.reply(message, T(locale, "docs.uploaded", Params{"name": name, "chars": TN(locale, "docs.chars", stored.Size)}))
+
+	// Если в подписи к файлу был вопрос, сразу отвечаем на него
+	if question = strings.TrimSpace(question); question != "" {
+		tb.reply(message, tb.ask(message, question))
+	}
+}
+
+// handleDocsCommand показывает и удаляет загруженные документы: /docs, /docs delete N, /docs clear
+func (tb *TelegramBot) handleDocsCommand(message *tgbotapi.Message) {
+	store := tb.agent.Documents()
+	conversationID := tb.conversationFor(message).ConversationID
+	args := strings.Fields(message.CommandArguments())
+	locale := tb.locale(message.From)
+
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "clear":
+			count := store.Clear(conversationID)
+			tb.reply(message, TN(locale, "docs.cleared", count))
+			return
+		case "delete", "remove", "del":
+			if len(args) < 2 {
+				tb.reply(message, T(locale, "docs.delete_usage"))
+				return
+			}
+			number, err := strconv.Atoi(args[1])
+			if err != nil {
+				tb.reply(message, T(locale, "docs.delete_not_num"))
+				return
+			}
+			name, err := store.Remove(conversationID, number)
+			if err != nil {
+				tb.reply(message, "❌ "+localizeError(locale, err))
+				return
+			}
+			tb.reply(message, T(locale, "docs.deleted", Params{"name": escapeMarkdown(name)}))
+			return
+		}
+	}
+
+	documents := store.List(conversationID)
+	if len(documents) == 0 {
+		tb.reply(message, T(locale, "docs.empty"))
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString(T(locale, "docs.list_title") + "\n\n")
+	for i, document := range documents {
+		builder.WriteString(T(locale, "docs.list_item", Params{
+			"index": i + 1,
+			"name":  escapeMarkdown(document.Name),
+			"chars": TN(locale, "docs.chars", document.Size),
+			"date":  document.UploadedAt.Format(T(locale, "format.datetime")),
+		}) + "\n")
+	}
+	builder.WriteString("\n" + T(locale, "docs.list_footer"))
+
+	tb.reply(message, builder.String())
+}
+
+// escapeMarkdown экранирует пользовательский текст для отправки в режиме Markdown
+func escapeMarkdown(text string) string {
+	return tgbotapi.EscapeText(tgbotapi.ModeMarkdown, text)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleNotesCommand обрабатывает /notes и /todo:
+// без аргументов — список, add <текст>, done N (только /todo), delete N, search <запрос>.
+// Текст без подкоманды сохраняется как новая запись.
+func (tb *TelegramBot) handleNotesCommand(message *tgbotapi.Message, kind string) {
+	agent := tb.agent
+	notes := agent.Notes()
+	userID := message.From.ID
+	locale := tb.locale(message.From)
+	loc := agent.LocationFor(userID)
+	now := time.Now()
+	prefix := noteKeyPrefix(kind)
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		tb.reply(message, agent.notesListText(locale, userID, kind, false, now, loc))
+		return
+	}
+
+	command, rest, _ := strings.Cut(args, " ")
+	command = strings.ToLower(command)
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case "all":
+		tb.reply(message, agent.notesListText(locale, userID, kind, true, now, loc))
+		return
+
+	case "help":
+		tb.reply(message, T(locale, prefix+".usage"))
+		return
+
+	case "search", "find":
+		if rest == "" {
+			tb.reply(message, T(locale, prefix+".usage"))
+			return
+		}
+		tb.reply(message, agent.notesSearchText(locale, userID, rest, now, loc))
+		return
+
+	case "done", "complete", "delete", "remove", "del":
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			tb.reply(message, T(locale, prefix+".usage"))
+			return
+		}
+
+		var note Note
+		var text string
+		if command == "delete" || command == "remove" || command == "del" {
+			note, err = notes.Delete(userID, kind, id)
+			text = T(locale, "notes.deleted", Params{"text": note.Text})
+		} else if kind == NoteKindTodo {
+			note, err = notes.Complete(userID, kind, id)
+			text = T(locale, "todo.completed", Params{"text": note.Text})
+		} else {
+			tb.reply(message, T(locale, prefix+".usage"))
+			return
+		}
+		if err != nil {
+			tb.reply(message, "❌ "+localizeError(locale, err))
+			return
+		}
+		tb.reply(message, text)
+		return
+
+	case "add":
+		args = rest
+	}
+
+	text, tags, due := parseNoteText(args, now, loc)
+	note, err := notes.Add(userID, kind, text, tags, due)
+	if err != nil {
+		tb.reply(message, "❌ "+localizeError(locale, err))
+		return
+	}
+	tb.reply(message, noteAddedText(locale, note, now, loc))
+}
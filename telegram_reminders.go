@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// deliverReminder отправляет наступившее напоминание в чат, где оно создано
+func (tb *TelegramBot) deliverReminder(reminder Reminder) error {
+	log.Printf("Отправляем напоминание %d пользователю %d", reminder.ID, reminder.UserID)
+	return tb.channel.Send(strconv.FormatInt(reminder.ChatID, 10), OutboundMessage{
+		Text:     T(reminder.Locale, "remind.delivery", Params{"text": escapeMarkdown(reminder.Text)}),
+		Markdown: true,
+	})
+}
+
+// handleRemindersCommand показывает и отменяет напоминания: /reminders, /reminders cancel N
+func (tb *TelegramBot) handleRemindersCommand(message *tgbotapi.Message) {
+	scheduler := tb.agent.Reminders()
+	userID := message.From.ID
+	args := strings.Fields(message.CommandArguments())
+	locale := tb.locale(message.From)
+
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "cancel", "delete", "remove", "del":
+			if len(args) < 2 {
+				tb.reply(message, T(locale, "reminders.cancel_usage"))
+				return
+			}
+			number, err := strconv.Atoi(args[1])
+			if err != nil {
+				tb.reply(message, T(locale, "reminders.cancel_usage"))
+				return
+			}
+			reminder, err := scheduler.Cancel(userID, number)
+			if err != nil {
+				tb.reply(message, "❌ "+localizeError(locale, err))
+				return
+			}
+			tb.reply(message, T(locale, "reminders.cancelled", Params{"text": escapeMarkdown(reminder.Text)}))
+			return
+		}
+	}
+
+	reminders := scheduler.List(userID)
+	if len(reminders) == 0 {
+		tb.reply(message, T(locale, "reminders.empty"))
+		return
+	}
+
+	loc := tb.agent.LocationFor(userID)
+	var builder strings.Builder
+	builder.WriteString(T(locale, "reminders.list_title") + "\n\n")
+	for i, reminder := range reminders {
+		when := formatReminderTime(locale, reminder.At.In(loc))
+		if reminder.Recurrence != nil {
+			when += ", " + describeRecurrence(locale, *reminder.Recurrence)
+		}
+		builder.WriteString(T(locale, "reminders.list_item", Params{
+			"index": i + 1,
+			"text":  escapeMarkdown(reminder.Text),
+			"when":  when,
+		}) + "\n")
+	}
+	builder.WriteString("\n" + T(locale, "reminders.list_footer"))
+
+	tb.reply(message, builder.String())
+}
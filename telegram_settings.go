@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// settingsCallbackPrefix — префикс данных кнопок меню настроек.
+// Формат: settings:<владелец>:<действие>[:<настройка>[:<значение>]]
+const settingsCallbackPrefix = "settings:"
+
+// handleSettingsCommand показывает меню настроек или задает свой промпт: /settings prompt <текст>
+func (tb *TelegramBot) handleSettingsCommand(message *tgbotapi.Message) {
+	args := strings.TrimSpace(message.CommandArguments())
+	userID := message.From.ID
+	locale := tb.locale(message.From)
+
+	if strings.HasPrefix(strings.ToLower(args), "prompt") {
+		prompt := strings.TrimSpace(args[len("prompt"):])
+		_, err := tb.agent.Settings().Update(userID, func(settings *UserSettings) error {
+			return settings.SetSystemPrompt(prompt)
+		})
+		if err != nil {
+			tb.reply(message, "❌ "+localizeError(locale, err)+"\n"+T(locale, "settings.prompt_usage"))
+			return
+		}
+		tb.reply(message, T(locale, "settings.prompt_saved"))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, tb.settingsMenuText(locale, userID))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = settingsMenuKeyboard(locale, userID, tb.agent.Settings().Get(userID))
+	if isGroupChat(message.Chat) {
+		msg.ReplyToMessageID = message.MessageID
+	}
+
+	if _, err := tb.bot.Send(msg); err != nil {
+		log.Printf("Ошибка отправки меню настроек: %v", err)
+	}
+}
+
+// handleSettingsCallback обрабатывает нажатия кнопок меню настроек
+func (tb *TelegramBot) handleSettingsCallback(callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(strings.TrimPrefix(callback.Data, settingsCallbackPrefix), ":")
+	if len(parts) < 2 || callback.Message == nil {
+		tb.answerCallback(callback.ID, "")
+		return
+	}
+
+	locale := tb.locale(callback.From)
+	ownerID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || ownerID != callback.From.ID {
+		tb.answerCallback(callback.ID, T(locale, "settings.foreign_menu"))
+		return
+	}
+
+	store := tb.agent.Settings()
+	text := tb.settingsMenuText(locale, ownerID)
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	notice := ""
+
+	switch parts[1] {
+	case "menu":
+		keyboard = settingsMenuKeyboard(locale, ownerID, store.Get(ownerID))
+	case "field":
+		field, ok := findSettingField(argAt(parts, 2))
+		if !ok {
+			tb.answerCallback(callback.ID, "")
+			return
+		}
+		text = T(locale, "settings.choose_value", Params{"title": T(locale, "settings.field."+field.Key)})
+		if field.Key == "persona" {
+			text += "\n\n" + T(locale, "settings.custom_prompt_hint")
+		}
+		keyboard = settingsFieldKeyboard(locale, ownerID, field, store.Get(ownerID))
+	case "set":
+		settings, err := store.Update(ownerID, func(settings *UserSettings) error {
+			return settings.Set(argAt(parts, 2), argAt(parts, 3))
+		})
+		if err != nil {
+			tb.answerCallback(callback.ID, localizeError(locale, err))
+			return
+		}
+		// Язык интерфейса мог измениться вместе с настройкой
+		locale = tb.agent.LocaleFor(ownerID)
+		text = tb.settingsMenuText(locale, ownerID)
+		notice = T(locale, "settings.saved")
+		keyboard = settingsMenuKeyboard(locale, ownerID, settings)
+	case "reset":
+		settings := store.Reset(ownerID)
+		locale = tb.agent.LocaleFor(ownerID)
+		text = tb.settingsMenuText(locale, ownerID)
+		notice = T(locale, "settings.reset")
+		keyboard = settingsMenuKeyboard(locale, ownerID, settings)
+	case "close":
+		tb.answerCallback(callback.ID, "")
+		if _, err := tb.bot.Request(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)); err != nil {
+			log.Printf("Ошибка удаления меню настроек: %v", err)
+		}
+		return
+	default:
+		tb.answerCallback(callback.ID, "")
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard)
+	edit.ParseMode = "Markdown"
+	if _, err := tb.bot.Request(edit); err != nil {
+		log.Printf("Ошибка обновления меню настроек: %v", err)
+	}
+	tb.answerCallback(callback.ID, notice)
+}
+
+// settingsMenuText возвращает текст главного меню настроек
+func (tb *TelegramBot) settingsMenuText(locale string, userID int64) string {
+	settings := tb.agent.Settings().Get(userID)
+	text := T(locale, "settings.title")
+	if settings.Persona == "custom" && settings.SystemPrompt != "" {
+		text += "\n\n" + T(locale, "settings.custom_prompt", Params{"prompt": escapeMarkdown(settings.SystemPrompt)})
+	}
+	return text
+}
+
+// settingsMenuKeyboard строит главное меню: по кнопке на каждую настройку
+func settingsMenuKeyboard(locale string, ownerID int64, settings UserSettings) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, field := range settingFields {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%s: %s", T(locale, "settings.field."+field.Key), settings.Label(locale, field.Key)),
+				settingsCallback(ownerID, "field", field.Key),
+			),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(T(locale, "settings.button.reset"), settingsCallback(ownerID, "reset")),
+		tgbotapi.NewInlineKeyboardButtonData(T(locale, "settings.button.close"), settingsCallback(ownerID, "close")),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// settingsFieldKeyboard строит список значений одной настройки
+func settingsFieldKeyboard(locale string, ownerID int64, field settingField, settings UserSettings) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	current := settings.Get(field.Key)
+	for _, option := range field.Options {
+		label := T(locale, "settings.option."+field.Key+"."+option)
+		if option == current {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, settingsCallback(ownerID, "set", field.Key, option)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(T(locale, "settings.button.back"), settingsCallback(ownerID, "menu")),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// settingsCallback формирует данные кнопки меню настроек
+func settingsCallback(ownerID int64, parts ...string) string {
+	return settingsCallbackPrefix + strconv.FormatInt(ownerID, 10) + ":" + strings.Join(parts, ":")
+}
+
+// answerCallback подтверждает нажатие кнопки, при необходимости показывая уведомление
+func (tb *TelegramBot) answerCallback(callbackID, text string) {
+	if _, err := tb.bot.Request(tgbotapi.NewCallback(callbackID, text)); err != nil {
+		log.Printf("Ошибка ответа на нажатие кнопки: %v", err)
+	}
+}
+
+// argAt безопасно возвращает элемент среза или пустую строку
+func argAt(parts []string, index int) string {
+	if index < len(parts) {
+		return parts[index]
+	}
+	return ""
+}
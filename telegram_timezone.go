@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleTimezoneCommand показывает или задает часовой пояс: /timezone [Europe/Moscow | Токио | UTC+3].
+// Без аргументов в личном чате предлагает отправить геопозицию.
+func (tb *TelegramBot) handleTimezoneCommand(message *tgbotapi.Message) {
+	locale := tb.locale(message.From)
+	userID := message.From.ID
+
+	if name := strings.TrimSpace(message.CommandArguments()); name != "" {
+		tb.setTimezone(message, func(settings *UserSettings) error {
+			return settings.SetTimezone(name)
+		})
+		return
+	}
+
+	loc := tb.agent.LocationFor(userID)
+	key := "timezone.current"
+	if _, ok := tb.agent.Settings().Get(userID).Location(); !ok {
+		key = "timezone.default"
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, T(locale, key, Params{
+		"zone": zoneLabel(time.Now().In(loc)),
+		"time": time.Now().In(loc).Format("15:04"),
+	}))
+
+	// Кнопка запроса геопозиции работает только в личном чате
+	if !isGroupChat(message.Chat) {
+		keyboard := tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButtonLocation(T(locale, "timezone.button.location")),
+		))
+		keyboard.OneTimeKeyboard = true
+		keyboard.ResizeKeyboard = true
+		msg.ReplyMarkup = keyboard
+	}
+	if _, err := tb.bot.Send(msg); err != nil {
+		log.Printf("Ошибка отправки сообщения: %v", err)
+	}
+}
+
+// handleLocationMessage определяет часовой пояс по отправленной геопозиции.
+// Координаты не сохраняются — только пояс.
+func (tb *TelegramBot) handleLocationMessage(message *tgbotapi.Message) {
+	zone := zoneForLocation(message.Location.Latitude, message.Location.Longitude)
+	tb.setTimezone(message, func(settings *UserSettings) error {
+		return settings.SetTimezone(zone.String())
+	})
+}
+
+// setTimezone сохраняет часовой пояс пользователя и убирает клавиатуру с кнопкой геопозиции
+func (tb *TelegramBot) setTimezone(message *tgbotapi.Message, update func(*UserSettings) error) {
+	locale := tb.locale(message.From)
+
+	settings, err := tb.agent.Settings().Update(message.From.ID, update)
+	if err != nil {
+		tb.reply(message, "❌ "+localizeError(locale, err))
+		return
+	}
+
+	loc, _ := settings.Location()
+	now := time.Now().In(loc)
+	log.Printf("Пользователь %d выбрал часовой пояс %s", message.From.ID, settings.Timezone)
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, T(locale, "timezone.set", Params{
+		"zone": zoneLabel(now),
+		"time": now.Format("15:04"),
+	}))
+	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(false)
+	if _, err := tb.bot.Send(msg); err != nil {
+		log.Printf("Ошибка отправки сообщения: %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// makeRaw на других системах не поддерживается: строки читаются без редактирования
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("посимвольный режим терминала не поддерживается")
+}
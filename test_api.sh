@@ -4,6 +4,10 @@
 
 API_URL="http://localhost:8080"
 
+# Токен пользователя 12345: по умолчанию выдаем подкомандой ws-token (нужен WS_AUTH_SECRET),
+# либо передайте ADMIN_API_TOKEN в переменной TOKEN
+TOKEN="${TOKEN:-$(go run . ws-token 12345)}"
+
 echo "🧪 Тестирование Chat Agent API..."
 
 # Тест 1: Проверка здоровья сервиса
@@ -16,6 +20,7 @@ echo -e "\n"
 echo "2. Отправка сообщения агенту..."
 curl -s -X POST "$API_URL/chat" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
   -d '{
     "message": "Привет! Как дела?",
     "user_id": 12345
@@ -27,6 +32,7 @@ echo -e "\n"
 echo "3. Запрос времени..."
 curl -s -X POST "$API_URL/chat" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
   -d '{
     "message": "Сколько времени?",
     "user_id": 12345
@@ -38,6 +44,7 @@ echo -e "\n"
 echo "4. Запрос погоды..."
 curl -s -X POST "$API_URL/chat" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
   -d '{
     "message": "Какая погода?",
     "user_id": 12345
@@ -49,6 +56,7 @@ echo -e "\n"
 echo "5. Запрос помощи..."
 curl -s -X POST "$API_URL/chat" \
   -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
   -d '{
     "message": "Помощь",
     "user_id": 12345
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	// Встроенная база часовых поясов: время считается одинаково на любом сервере,
+	// даже если в системе нет /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+// cityZone — город с часовым поясом и координатами для определения пояса по геопозиции
+type cityZone struct {
+	Zone     string
+	Lat, Lon float64
+	RU, EN   string   // Названия для ответа
+	Forms    []string // Формы в запросах; "*" в конце допускает окончание до двух букв
+}
+
+// worldCities — города, которые можно назвать в запросе времени
+var worldCities = []cityZone{
+	{"Europe/Kaliningrad", 54.71, 20.51, "Калининград", "Kaliningrad", []string{"калининград*", "kaliningrad"}},
+	{"Europe/Moscow", 55.75, 37.62, "Москва", "Moscow", []string{"москв*", "мск", "moscow"}},
+	{"Europe/Moscow", 59.94, 30.31, "Санкт-Петербург", "Saint Petersburg", []string{"санкт-петербург*", "петербург*", "питер*", "спб", "saint petersburg", "st petersburg"}},
+	{"Europe/Moscow", 55.79, 49.12, "Казань", "Kazan", []string{"казан*", "kazan"}},
+	{"Europe/Moscow", 43.60, 39.73, "Сочи", "Sochi", []string{"сочи", "sochi"}},
+	{"Europe/Samara", 53.20, 50.15, "Самара", "Samara", []string{"самар*", "samara"}},
+	{"Asia/Yekaterinburg", 56.84, 60.61, "Екатеринбург", "Yekaterinburg", []string{"екатеринбург*", "екб", "yekaterinburg"}},
+	{"Asia/Omsk", 54.99, 73.37, "Омск", "Omsk", []string{"омск*", "omsk"}},
+	{"Asia/Novosibirsk", 55.03, 82.92, "Новосибирск", "Novosibirsk", []string{"новосибирск*", "novosibirsk"}},
+	{"Asia/Krasnoyarsk", 56.01, 92.87, "Красноярск", "Krasnoyarsk", []string{"красноярск*", "krasnoyarsk"}},
+	{"Asia/Irkutsk", 52.29, 104.28, "Иркутск", "Irkutsk", []string{"иркутск*", "irkutsk"}},
+	{"Asia/Yakutsk", 62.03, 129.73, "Якутск", "Yakutsk", []string{"якутск*", "yakutsk"}},
+	{"Asia/Vladivostok", 43.12, 131.89, "Владивосток", "Vladivostok", []string{"владивосток*", "vladivostok"}},
+	{"Asia/Magadan", 59.56, 150.81, "Магадан", "Magadan", []string{"магадан*", "magadan"}},
+	{"Asia/Kamchatka", 53.02, 158.65, "Петропавловск-Камчатский", "Petropavlovsk-Kamchatsky", []string{"камчатк*", "петропавловск-камчатский", "kamchatka"}},
+	{"Europe/Minsk", 53.90, 27.56, "Минск", "Minsk", []string{"минск*", "minsk"}},
+	{"Europe/Kyiv", 50.45, 30.52, "Киев", "Kyiv", []string{"киев*", "kyiv", "kiev"}},
+	{"Asia/Almaty", 43.24, 76.89, "Алматы", "Almaty", []string{"алматы", "алма-ата", "almaty"}},
+	{"Asia/Tashkent", 41.30, 69.24, "Ташкент", "Tashkent", []string{"ташкент*", "tashkent"}},
+	{"Asia/Tbilisi", 41.72, 44.79, "Тбилиси", "Tbilisi", []string{"тбилиси", "tbilisi"}},
+	{"Asia/Yerevan", 40.18, 44.51, "Ереван", "Yerevan", []string{"ереван*", "yerevan"}},
+	{"Asia/Baku", 40.41, 49.87, "Баку", "Baku", []string{"баку", "baku"}},
+	{"Europe/London", 51.51, -0.13, "Лондон", "London", []string{"лондон*", "london"}},
+	{"Europe/Paris", 48.86, 2.35, "Париж", "Paris", []string{"париж*", "paris"}},
+	{"Europe/Berlin", 52.52, 13.40, "Берлин", "Berlin", []string{"берлин*", "berlin"}},
+	{"Europe/Rome", 41.90, 12.50, "Рим", "Rome", []string{"рим", "риме", "rome"}},
+	{"Europe/Madrid", 40.42, -3.70, "Мадрид", "Madrid", []string{"мадрид*", "madrid"}},
+	{"Europe/Istanbul", 41.01, 28.98, "Стамбул", "Istanbul", []string{"стамбул*", "istanbul"}},
+	{"Asia/Jerusalem", 32.08, 34.78, "Тель-Авив", "Tel Aviv", []string{"тель-авив*", "израил*", "tel aviv", "israel"}},
+	{"Africa/Cairo", 30.04, 31.24, "Каир", "Cairo", []string{"каир*", "cairo"}},
+	{"Asia/Dubai", 25.20, 55.27, "Дубай", "Dubai", []string{"дубай*", "dubai"}},
+	{"Asia/Kolkata", 28.61, 77.21, "Дели", "Delhi", []string{"дели", "нью-дели", "delhi", "new delhi", "индии", "india"}},
+	{"Asia/Bangkok", 13.76, 100.50, "Бангкок", "Bangkok", []string{"бангкок*", "bangkok"}},
+	{"Asia/Shanghai", 39.90, 116.41, "Пекин", "Beijing", []string{"пекин*", "шанха*", "китае", "beijing", "shanghai", "china"}},
+	{"Asia/Hong_Kong", 22.32, 114.17, "Гонконг", "Hong Kong", []string{"гонконг*", "hong kong"}},
+	{"Asia/Singapore", 1.35, 103.82, "Сингапур", "Singapore", []string{"сингапур*", "singapore"}},
+	{"Asia/Seoul", 37.57, 126.98, "Сеул", "Seoul", []string{"сеул*", "seoul"}},
+	{"Asia/Tokyo", 35.68, 139.69, "Токио", "Tokyo", []string{"токио", "японии", "tokyo", "japan"}},
+	{"Australia/Sydney", -33.87, 151.21, "Сидней", "Sydney", []string{"сидне*", "sydney"}},
+	{"America/New_York", 40.71, -74.01, "Нью-Йорк", "New York", []string{"нью-йорк*", "нью йорк*", "new york", "nyc"}},
+	{"America/Chicago", 41.88, -87.63, "Чикаго", "Chicago", []string{"чикаго", "chicago"}},
+	{"America/Denver", 39.74, -104.99, "Денвер", "Denver", []string{"денвер*", "denver"}},
+	{"America/Los_Angeles", 34.05, -118.24, "Лос-Анджелес", "Los Angeles", []string{"лос-анджелес*", "los angeles"}},
+	{"America/Los_Angeles", 37.77, -122.42, "Сан-Франциско", "San Francisco", []string{"сан-франциско", "san francisco"}},
+	{"America/Toronto", 43.65, -79.38, "Торонто", "Toronto", []string{"торонто", "toronto"}},
+	{"America/Mexico_City", 19.43, -99.13, "Мехико", "Mexico City", []string{"мехико", "mexico city"}},
+	{"America/Sao_Paulo", -23.55, -46.63, "Сан-Паулу", "São Paulo", []string{"сан-паулу", "sao paulo", "são paulo"}},
+	{"America/Argentina/Buenos_Aires", -34.60, -58.38, "Буэнос-Айрес", "Buenos Aires", []string{"буэнос-айрес*", "buenos aires"}},
+}
+
+// maxCityDistanceKm — дальше этого расстояния от известных городов пояс по геопозиции
+// определяется по долготе
+const maxCityDistanceKm = 600
+
+// Place — место, названное в запросе: город, пояс IANA или смещение от UTC
+type Place struct {
+	Name     string
+	Location *time.Location
+}
+
+// placeName возвращает название города на языке пользователя
+func (c cityZone) placeName(locale string) string {
+	if normalizeLocale(locale) == LocaleEN {
+		return c.EN
+	}
+	return c.RU
+}
+
+// matches проверяет, что слово — одна из форм названия города
+func (c cityZone) matches(word string) bool {
+	for _, form := range c.Forms {
+		if stem, ok := strings.CutSuffix(form, "*"); ok {
+			if strings.HasPrefix(word, stem) && len([]rune(word))-len([]rune(stem)) <= 2 {
+				return true
+			}
+			continue
+		}
+		if word == form {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTimezone находит часовой пояс по имени IANA ("Asia/Tokyo"), городу ("Токио")
+// или смещению ("UTC+3", "GMT-5", "+05:30")
+func loadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, newLocalizedError("timezone.error.unknown", Params{"name": name})
+	}
+
+	if loc, ok := parseUTCOffset(name); ok {
+		return loc, nil
+	}
+	if strings.Contains(name, "/") || strings.EqualFold(name, "UTC") {
+		// Имена IANA чувствительны к регистру: europe/moscow → Europe/Moscow
+		for _, candidate := range []string{name, canonicalZoneName(name)} {
+			if loc, err := time.LoadLocation(candidate); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	if city, ok := findCity(strings.ToLower(name)); ok {
+		return time.LoadLocation(city.Zone)
+	}
+	return nil, newLocalizedError("timezone.error.unknown", Params{"name": name})
+}
+
+// canonicalZoneName восстанавливает регистр имени IANA: america/new_york → America/New_York
+func canonicalZoneName(name string) string {
+	if strings.EqualFold(name, "UTC") {
+		return "UTC"
+	}
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '_' })
+	separators := strings.FieldsFunc(name, func(r rune) bool { return r != '/' && r != '_' })
+	var builder strings.Builder
+	for i, part := range parts {
+		builder.WriteString(strings.ToUpper(part[:1]) + strings.ToLower(part[1:]))
+		if i < len(separators) {
+			builder.WriteString(separators[i])
+		}
+	}
+	return builder.String()
+}
+
+// findCity ищет город по слову или сочетанию слов в нижнем регистре
+func findCity(word string) (cityZone, bool) {
+	for _, city := range worldCities {
+		if city.matches(word) {
+			return city, true
+		}
+	}
+	return cityZone{}, false
+}
+
+// parseUTCOffset разбирает смещение "UTC+3", "GMT-05:00", "+5:30"
+func parseUTCOffset(text string) (*time.Location, bool) {
+	upper := strings.ToUpper(strings.ReplaceAll(text, " ", ""))
+	for _, prefix := range []string{"UTC", "GMT"} {
+		upper = strings.TrimPrefix(upper, prefix)
+	}
+	if len(upper) < 2 || (upper[0] != '+' && upper[0] != '-') {
+		return nil, false
+	}
+
+	sign := 1
+	if upper[0] == '-' {
+		sign = -1
+	}
+	hoursText, minutesText, _ := strings.Cut(upper[1:], ":")
+	hours, err := strconv.Atoi(hoursText)
+	if err != nil || hours > 14 {
+		return nil, false
+	}
+	minutes := 0
+	if minutesText != "" {
+		if minutes, err = strconv.Atoi(minutesText); err != nil || minutes > 59 {
+			return nil, false
+		}
+	}
+
+	offset := sign * (hours*3600 + minutes*60)
+	return time.FixedZone(formatUTCOffset(offset), offset), true
+}
+
+// formatUTCOffset записывает смещение в виде "UTC+3" или "UTC+5:30"
+func formatUTCOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	if minutes := offset % 3600 / 60; minutes != 0 {
+		return fmt.Sprintf("UTC%s%d:%02d", sign, offset/3600, minutes)
+	}
+	return fmt.Sprintf("UTC%s%d", sign, offset/3600)
+}
+
+// zoneLabel описывает пояс для ответа: "Asia/Tokyo (UTC+9)"; для пояса сервера — только смещение
+func zoneLabel(t time.Time) string {
+	_, offset := t.Zone()
+	name := t.Location().String()
+	utc := formatUTCOffset(offset)
+	if name == utc || name == "Local" {
+		return utc
+	}
+	return name + " (" + utc + ")"
+}
+
+// zoneForLocation определяет часовой пояс по координатам: по ближайшему известному городу,
+// а вдали от них — по долготе
+func zoneForLocation(lat, lon float64) *time.Location {
+	best, bestDistance := -1, math.MaxFloat64
+	for i, city := range worldCities {
+		if distance := distanceKm(lat, lon, city.Lat, city.Lon); distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	if best >= 0 && bestDistance <= maxCityDistanceKm {
+		if loc, err := time.LoadLocation(worldCities[best].Zone); err == nil {
+			return loc
+		}
+	}
+
+	// В зонах Etc/GMT знак обратный: Etc/GMT-3 — это UTC+3
+	hours := int(math.Round(lon / 15))
+	name := "Etc/GMT"
+	if hours > 0 {
+		name += "-" + strconv.Itoa(hours)
+	} else if hours < 0 {
+		name += "+" + strconv.Itoa(-hours)
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// distanceKm — расстояние между точками по поверхности Земли
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// defaultTimezone возвращает пояс по умолчанию из DEFAULT_TIMEZONE или пояс сервера
+func defaultTimezone() *time.Location {
+	if name := os.Getenv("DEFAULT_TIMEZONE"); name != "" {
+		if loc, err := loadTimezone(name); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
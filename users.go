@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// usersStoreName — имя файла реестра пользователей в хранилище
+const usersStoreName = "users"
+
+// KnownUser описывает пользователя, который писал боту
+type KnownUser struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// UserRegistry хранит известных боту пользователей, например для рассылок
+type UserRegistry struct {
+	mu    sync.Mutex
+	store *Store
+	users map[int64]*KnownUser
+}
+
+// NewUserRegistry загружает реестр из хранилища; nil означает хранение только в памяти
+func NewUserRegistry(store *Store) *UserRegistry {
+	r := &UserRegistry{
+		store: store,
+		users: make(map[int64]*KnownUser),
+	}
+	if err := store.Load(usersStoreName, &r.users); err != nil {
+		log.Printf("Ошибка загрузки реестра пользователей: %v", err)
+	}
+	return r
+}
+
+// Touch отмечает активность пользователя. На диск реестр записывается
+// только при появлении нового пользователя, чтобы не писать файл на каждое сообщение.
+func (r *UserRegistry) Touch(userID int64, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if user, exists := r.users[userID]; exists {
+		user.LastSeen = now
+		if name != "" {
+			user.Name = name
+		}
+		return
+	}
+
+	r.users[userID] = &KnownUser{ID: userID, Name: name, FirstSeen: now, LastSeen: now}
+	r.saveLocked()
+}
+
+// List возвращает копию списка пользователей, отсортированную по ID
+func (r *UserRegistry) List() []KnownUser {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := make([]KnownUser, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (r *UserRegistry) saveLocked() {
+	if err := r.store.Save(usersStoreName, r.users); err != nil {
+		log.Printf("Ошибка сохранения реестра пользователей: %v", err)
+	}
+}
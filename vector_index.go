@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// IndexEntry представляет фрагмент документа с его вектором
+type IndexEntry struct {
+	Source string    `json:"source"`
+	Chunk  int       `json:"chunk"`
+	Text   string    `json:"text"`
+	Vector []float64 `json:"vector"`
+}
+
+// IndexedFile хранит отпечаток проиндексированного файла, чтобы не векторизовать его повторно
+type IndexedFile struct {
+	Hash   string `json:"hash"`
+	Chunks int    `json:"chunks"`
+}
+
+// SearchResult представляет найденный фрагмент и его сходство с запросом
+type SearchResult struct {
+	IndexEntry
+	Score float64
+}
+
+// VectorIndex — простой векторный индекс с полным перебором, хранящийся в JSON-файле
+type VectorIndex struct {
+	mu       sync.RWMutex
+	path     string
+	Embedder string                 `json:"embedder"`
+	Files    map[string]IndexedFile `json:"files"`
+	Entries  []IndexEntry           `json:"entries"`
+}
+
+// LoadVectorIndex читает индекс с диска. Если файла нет или он построен
+// другим эмбеддером, возвращается пустой индекс.
+func LoadVectorIndex(path, embedder string) (*VectorIndex, error) {
+	index := &VectorIndex{
+		path:     path,
+		Embedder: embedder,
+		Files:    make(map[string]IndexedFile),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения индекса: %v", err)
+	}
+
+	var stored VectorIndex
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга индекса: %v", err)
+	}
+	if stored.Embedder != embedder {
+		return index, nil
+	}
+
+	index.Entries = stored.Entries
+	if stored.Files != nil {
+		index.Files = stored.Files
+	}
+	return index, nil
+}
+
+// Save атомарно записывает индекс на диск
+func (idx *VectorIndex) Save() error {
+	idx.mu.RLock()
+	data, err := json.Marshal(idx)
+	idx.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга индекса: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога индекса: %v", err)
+	}
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("ошибка записи индекса: %v", err)
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// FileHash возвращает отпечаток проиндексированного файла
+func (idx *VectorIndex) FileHash(source string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	file, exists := idx.Files[source]
+	return file.Hash, exists
+}
+
+// Sources возвращает список проиндексированных файлов
+func (idx *VectorIndex) Sources() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	sources := make([]string, 0, len(idx.Files))
+	for source := range idx.Files {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Replace заменяет все фрагменты файла новыми
+func (idx *VectorIndex) Replace(source, hash string, entries []IndexEntry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(source)
+	idx.Entries = append(idx.Entries, entries...)
+	idx.Files[source] = IndexedFile{Hash: hash, Chunks: len(entries)}
+}
+
+// Remove удаляет файл из индекса
+func (idx *VectorIndex) Remove(source string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(source)
+}
+
+func (idx *VectorIndex) removeLocked(source string) {
+	kept := idx.Entries[:0]
+	for _, entry := range idx.Entries {
+		if entry.Source != source {
+			kept = append(kept, entry)
+		}
+	}
+	idx.Entries = kept
+	delete(idx.Files, source)
+}
+
+// Len возвращает количество фрагментов в индексе
+func (idx *VectorIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.Entries)
+}
+
+// Search возвращает topK фрагментов, наиболее похожих на вектор запроса
+func (idx *VectorIndex) Search(query []float64, topK int, minScore float64) []SearchResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []SearchResult
+	for _, entry := range idx.Entries {
+		score := cosineSimilarity(query, entry.Vector)
+		if score >= minScore {
+			results = append(results, SearchResult{IndexEntry: entry, Score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
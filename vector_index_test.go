@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVectorIndexSearch(t *testing.T) {
+	index, err := LoadVectorIndex(filepath.Join(t.TempDir(), "index.json"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Replace("a.md", "hash-a", []IndexEntry{
+		{Source: "a.md", Chunk: 0, Text: "восток", Vector: []float64{1, 0}},
+		{Source: "a.md", Chunk: 1, Text: "северо-восток", Vector: []float64{0.8, 0.6}},
+	})
+	index.Replace("b.md", "hash-b", []IndexEntry{
+		{Source: "b.md", Chunk: 0, Text: "север", Vector: []float64{0, 1}},
+		{Source: "b.md", Chunk: 1, Text: "запад", Vector: []float64{-1, 0}},
+	})
+
+	texts := func(results []SearchResult) []string {
+		var out []string
+		for _, result := range results {
+			out = append(out, result.Text)
+		}
+		return out
+	}
+	query := []float64{0.6, 0.8}
+	tests := []struct {
+		topK     int
+		minScore float64
+		want     []string
+	}{
+		{10, -1, []string{"северо-восток", "север", "восток", "запад"}},
+		{2, -1, []string{"северо-восток", "север"}},
+		{10, 0.7, []string{"северо-восток", "север"}},
+		{10, 0.99, nil},
+	}
+	for _, tt := range tests {
+		if got := texts(index.Search(query, tt.topK, tt.minScore)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(topK=%d, minScore=%v) = %v, want %v", tt.topK, tt.minScore, got, tt.want)
+		}
+	}
+
+	index.Replace("a.md", "hash-a2", []IndexEntry{{Source: "a.md", Text: "юг", Vector: []float64{0, -1}}})
+	index.Remove("b.md")
+	if got := texts(index.Search(query, 10, -1)); !reflect.DeepEqual(got, []string{"юг"}) {
+		t.Errorf("после Replace и Remove: %v", got)
+	}
+	if hash, ok := index.FileHash("a.md"); !ok || hash != "hash-a2" {
+		t.Errorf("FileHash(a.md) = %q, %v", hash, ok)
+	}
+	if _, ok := index.FileHash("b.md"); ok {
+		t.Error("удаленный файл остался в индексе")
+	}
+}
+
+func TestVectorIndexSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knowledge", "index.json")
+	index, err := LoadVectorIndex(path, "hashing/512")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []IndexEntry{
+		{Source: "guide.md", Chunk: 0, Text: "первый", Vector: []float64{0.6, 0.8}},
+		{Source: "guide.md", Chunk: 1, Text: "второй", Vector: []float64{1, 0}},
+	}
+	index.Replace("guide.md", "abc", entries)
+	if err := index.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("временный файл не удален: %v", err)
+	}
+
+	loaded, err := LoadVectorIndex(path, "hashing/512")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Entries, entries) || loaded.Files["guide.md"] != (IndexedFile{Hash: "abc", Chunks: 2}) {
+		t.Errorf("загружен другой индекс: %+v, %+v", loaded.Entries, loaded.Files)
+	}
+
+	// Индекс другого эмбеддера не подходит: векторы несравнимы
+	other, err := LoadVectorIndex(path, "yandex/text-search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Len() != 0 || len(other.Sources()) != 0 {
+		t.Errorf("индекс другого эмбеддера не сброшен: %d фрагментов", other.Len())
+	}
+
+	if err := os.WriteFile(path, []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadVectorIndex(path, "hashing/512"); err == nil {
+		t.Error("поврежденный индекс должен возвращать ошибку")
+	}
+}
@@ -0,0 +1,621 @@
+// Веб-чат: работает поверх /ws (диалог) и /settings (настройки).
+// Идентификатор пользователя и токен WebSocket хранятся в localStorage.
+(function () {
+  "use strict";
+
+  var dictionaries = {
+    ru: {
+      new_chat: "Новый чат",
+      settings: "Настройки",
+      logout: "Выйти",
+      reset: "Очистить",
+      send: "Отправить",
+      placeholder: "Сообщение… (Enter — отправить, Shift+Enter — перенос)",
+      login_title: "Вход",
+      login_hint: "Укажите ID пользователя и токен WebSocket, выданный администратором.",
+      user_id: "ID пользователя",
+      token: "Токен",
+      login: "Войти",
+      system_prompt: "Системный промпт",
+      timezone: "Часовой пояс",
+      cancel: "Отмена",
+      save: "Сохранить",
+      main_chat: "Основной разговор",
+      untitled: "Новый разговор",
+      empty: "Напишите что-нибудь, чтобы начать разговор",
+      online: "в сети",
+      offline: "нет соединения, переподключение…",
+      unauthorized: "Неверный ID пользователя или токен",
+      reset_confirm: "Очистить историю этого разговора?",
+      settings_reset: "Сбросить",
+      settings_reset_confirm: "Сбросить все настройки, включая персону и системный промпт?",
+      forbidden: "Доступ запрещен",
+      messages: "сообщ."
+    },
+    en: {
+      new_chat: "New chat",
+      settings: "Settings",
+      logout: "Log out",
+      reset: "Clear",
+      send: "Send",
+      placeholder: "Message… (Enter to send, Shift+Enter for a new line)",
+      login_title: "Sign in",
+      login_hint: "Enter your user ID and the WebSocket token issued by the administrator.",
+      user_id: "User ID",
+      token: "Token",
+      login: "Sign in",
+      system_prompt: "System prompt",
+      timezone: "Time zone",
+      cancel: "Cancel",
+      save: "Save",
+      main_chat: "Main conversation",
+      untitled: "New conversation",
+      empty: "Type something to start the conversation",
+      online: "online",
+      offline: "disconnected, reconnecting…",
+      unauthorized: "Wrong user ID or token",
+      reset_confirm: "Clear the history of this conversation?",
+      settings_reset: "Reset",
+      settings_reset_confirm: "Reset all settings, including the persona and system prompt?",
+      forbidden: "Access denied",
+      messages: "msgs"
+    }
+  };
+
+  var locale = (navigator.language || "ru").slice(0, 2) === "en" ? "en" : "ru";
+  var dict = dictionaries[locale];
+
+  function t(key) {
+    return dict[key] || key;
+  }
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  var state = {
+    userID: localStorage.getItem("chat.user_id") || "",
+    token: localStorage.getItem("chat.token") || "",
+    conversation: localStorage.getItem("chat.conversation") || "",
+    conversations: [],
+    socket: null,
+    connected: false,
+    everConnected: false,
+    retry: 0,
+    pingTimer: null,
+    reconnectTimer: null,
+    pending: {}, // id сообщения → элемент ответа, который собирается из delta до события done
+    busy: false,
+    seq: 0,
+    systemPrompt: "" // промпт, загруженный в панель настроек
+  };
+
+  // --- Markdown ---------------------------------------------------------
+
+  function escapeHTML(text) {
+    return text.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;")
+      .replace(/"/g, "&quot;").replace(/'/g, "&#39;");
+  }
+
+  // inline обрабатывает уже экранированный текст: код, ссылки, жирный и курсив
+  function inline(text) {
+    var codes = [];
+    text = text.replace(/`([^`]+)`/g, function (_, code) {
+      codes.push("<code>" + code + "</code>");
+      return "\u0000" + (codes.length - 1) + "\u0000";
+    });
+    text = text.replace(/\[([^\]]+)\]\((https?:\/\/[^\s)]+)\)/g, '<a href="$2" target="_blank" rel="noopener noreferrer">$1</a>');
+    text = text.replace(/(^|[\s(])(https?:\/\/[^\s<)]+)/g, '$1<a href="$2" target="_blank" rel="noopener noreferrer">$2</a>');
+    text = text.replace(/\*\*([^*]+)\*\*/g, "<strong>$1</strong>");
+    text = text.replace(/(^|[^*\w])\*([^*\s][^*]*)\*(?![*\w])/g, "$1<strong>$2</strong>");
+    text = text.replace(/(^|[^_\w])_([^_\s][^_]*)_(?![_\w])/g, "$1<em>$2</em>");
+    return text.replace(/\u0000(\d+)\u0000/g, function (_, i) { return codes[+i]; });
+  }
+
+  // renderMarkdown поддерживает подмножество Markdown, которое используют ответы модели.
+  // HTML экранируется до разбора, поэтому ответ не может внедрить разметку.
+  function renderMarkdown(source) {
+    var lines = escapeHTML(source).split("\n");
+    var html = [];
+    var paragraph = [];
+    var list = null;
+
+    function flushParagraph() {
+      if (paragraph.length) {
+        html.push("<p>" + paragraph.map(inline).join("<br>") + "</p>");
+        paragraph = [];
+      }
+    }
+    function flushList() {
+      if (list) {
+        html.push("<" + list.tag + ">" + list.items.map(function (item) {
+          return "<li>" + inline(item) + "</li>";
+        }).join("") + "</" + list.tag + ">");
+        list = null;
+      }
+    }
+
+    for (var i = 0; i < lines.length; i++) {
+      var line = lines[i];
+      var match;
+      if (/^```/.test(line)) {
+        flushParagraph();
+        flushList();
+        var code = [];
+        for (i++; i < lines.length && !/^```/.test(lines[i]); i++) {
+          code.push(lines[i]);
+        }
+        html.push("<pre><code>" + code.join("\n") + "</code></pre>");
+      } else if ((match = /^(#{1,6})\s+(.*)$/.exec(line))) {
+        flushParagraph();
+        flushList();
+        var level = Math.min(match[1].length + 2, 6);
+        html.push("<h" + level + ">" + inline(match[2]) + "</h" + level + ">");
+      } else if ((match = /^&gt;\s?(.*)$/.exec(line))) {
+        flushParagraph();
+        flushList();
+        html.push("<blockquote>" + inline(match[1]) + "</blockquote>");
+      } else if ((match = /^\s*(?:[-*•]|(\d+)[.)])\s+(.*)$/.exec(line))) {
+        flushParagraph();
+        var tag = match[1] ? "ol" : "ul";
+        if (list && list.tag !== tag) {
+          flushList();
+        }
+        list = list || { tag: tag, items: [] };
+        list.items.push(match[2]);
+      } else if (line.trim() === "") {
+        flushParagraph();
+        flushList();
+      } else {
+        flushList();
+        paragraph.push(line);
+      }
+    }
+    flushParagraph();
+    flushList();
+    return html.join("");
+  }
+
+  // --- Сообщения --------------------------------------------------------
+
+  function clearMessages() {
+    $("messages").innerHTML = "";
+    state.pending = {};
+  }
+
+  function showEmpty() {
+    if (!$("messages").children.length) {
+      var empty = document.createElement("p");
+      empty.className = "empty";
+      empty.textContent = t("empty");
+      $("messages").appendChild(empty);
+    }
+  }
+
+  function addMessage(kind, text) {
+    var empty = $("messages").querySelector(".empty");
+    if (empty) {
+      empty.remove();
+    }
+    var element = document.createElement("div");
+    element.className = "message " + kind;
+    element.dataset.text = text || "";
+    if (kind === "user") {
+      element.textContent = text;
+    } else {
+      element.innerHTML = renderMarkdown(text || "");
+    }
+    $("messages").appendChild(element);
+    scrollDown();
+    return element;
+  }
+
+  function setMeta(element, tool, model, version) {
+    var parts = [tool, model && version ? model + " (" + version + ")" : model || version].filter(Boolean);
+    if (!parts.length) {
+      return;
+    }
+    var meta = document.createElement("span");
+    meta.className = "meta";
+    meta.textContent = parts.join(" · ");
+    element.appendChild(meta);
+  }
+
+  function scrollDown() {
+    var messages = $("messages");
+    messages.scrollTop = messages.scrollHeight;
+  }
+
+  function renderHistory(entries) {
+    clearMessages();
+    (entries || []).forEach(function (entry) {
+      addMessage("user", entry.message);
+      setMeta(addMessage("bot", entry.response), entry.tool, entry.model, entry.model_version);
+    });
+    showEmpty();
+  }
+
+  // --- Разговоры --------------------------------------------------------
+
+  function conversationTitle(conversation) {
+    if (!conversation) {
+      return t("main_chat");
+    }
+    for (var i = 0; i < state.conversations.length; i++) {
+      if (state.conversations[i].conversation === conversation) {
+        return state.conversations[i].title || t("untitled");
+      }
+    }
+    return t("untitled");
+  }
+
+  function renderConversations() {
+    var nav = $("conversations");
+    nav.innerHTML = "";
+    // Основной разговор и только что созданный показываются, даже пока в них нет реплик
+    var list = state.conversations.slice();
+    [state.conversation, ""].forEach(function (name) {
+      var known = list.some(function (c) { return c.conversation === name; });
+      if (!known) {
+        list.unshift({ conversation: name, title: "", messages: 0 });
+      }
+    });
+    list.forEach(function (c) {
+      var link = document.createElement("a");
+      link.href = "#";
+      link.className = c.conversation === state.conversation ? "active" : "";
+      link.textContent = c.conversation ? (c.title || t("untitled")) : t("main_chat");
+      if (c.messages) {
+        var small = document.createElement("small");
+        small.textContent = c.messages + " " + t("messages") +
+          (c.updated ? " · " + new Date(c.updated).toLocaleString(locale) : "");
+        link.appendChild(small);
+      }
+      link.addEventListener("click", function (event) {
+        event.preventDefault();
+        selectConversation(c.conversation);
+      });
+      nav.appendChild(link);
+    });
+    $("chat-title").textContent = conversationTitle(state.conversation);
+  }
+
+  function selectConversation(conversation) {
+    state.conversation = conversation;
+    localStorage.setItem("chat.conversation", conversation);
+    clearMessages();
+    renderConversations();
+    send({ type: "history", conversation: conversation });
+  }
+
+  // --- WebSocket --------------------------------------------------------
+
+  function nextID() {
+    state.seq += 1;
+    return "m" + state.seq;
+  }
+
+  function send(event) {
+    if (!state.socket || state.socket.readyState !== WebSocket.OPEN) {
+      return false;
+    }
+    state.socket.send(JSON.stringify(event));
+    return true;
+  }
+
+  function setConnection(online) {
+    state.connected = online;
+    var element = $("connection");
+    element.className = "connection " + (online ? "online" : "offline");
+    element.textContent = t(online ? "online" : "offline");
+  }
+
+  function connect() {
+    clearTimeout(state.reconnectTimer);
+    if (!state.userID || !state.token) {
+      showLogin();
+      return;
+    }
+    var scheme = location.protocol === "https:" ? "wss://" : "ws://";
+    var url = scheme + location.host + "/ws?user_id=" + encodeURIComponent(state.userID) +
+      "&token=" + encodeURIComponent(state.token) + "&locale=" + locale;
+    var socket = new WebSocket(url);
+    state.socket = socket;
+
+    socket.onopen = function () {
+      state.retry = 0;
+      state.everConnected = true;
+      setConnection(true);
+      clearInterval(state.pingTimer);
+      state.pingTimer = setInterval(function () { send({ type: "ping" }); }, 25000);
+      send({ type: "conversations" });
+      send({ type: "history", conversation: state.conversation });
+    };
+    socket.onmessage = function (message) {
+      var event;
+      try {
+        event = JSON.parse(message.data);
+      } catch (e) {
+        return;
+      }
+      handleEvent(event);
+    };
+    socket.onclose = function () {
+      clearInterval(state.pingTimer);
+      if (state.socket !== socket) {
+        return;
+      }
+      setConnection(false);
+      setBusy(false);
+      // Рукопожатие отклонено до первого подключения: скорее всего, неверный токен
+      if (!state.everConnected) {
+        showLogin(t("unauthorized"));
+        return;
+      }
+      var delay = Math.min(30000, 1000 * Math.pow(2, state.retry++));
+      state.reconnectTimer = setTimeout(connect, delay);
+    };
+  }
+
+  function handleEvent(event) {
+    var element;
+    switch (event.type) {
+      case "status":
+        if (event.status === "idle") {
+          setBusy(false);
+          send({ type: "conversations" });
+        } else if (event.status === "reset" && event.conversation === state.conversation) {
+          clearMessages();
+          showEmpty();
+          send({ type: "conversations" });
+        }
+        break;
+      case "typing":
+        element = state.pending[event.id];
+        if (element) {
+          element.classList.add("typing");
+        }
+        break;
+      case "delta":
+        element = state.pending[event.id];
+        if (element) {
+          element.classList.remove("typing");
+          element.dataset.text += event.text || "";
+          element.innerHTML = renderMarkdown(element.dataset.text);
+          scrollDown();
+        }
+        break;
+      case "done":
+        // Итоговый текст заменяет склеенные фрагменты: модерация могла изменить ответ
+        element = state.pending[event.id];
+        if (element) {
+          element.classList.remove("typing");
+          element.dataset.text = event.text || "";
+          element.innerHTML = renderMarkdown(element.dataset.text);
+          setMeta(element, event.tool, event.model, event.model_version);
+          delete state.pending[event.id];
+          scrollDown();
+        }
+        break;
+      case "error":
+        element = state.pending[event.id];
+        if (element) {
+          delete state.pending[event.id];
+        } else {
+          element = addMessage("bot", "");
+        }
+        element.classList.remove("typing");
+        element.classList.add("error");
+        element.textContent = event.error;
+        break;
+      case "conversations":
+        state.conversations = event.conversations || [];
+        renderConversations();
+        break;
+      case "history":
+        if ((event.conversation || "") === state.conversation) {
+          renderHistory(event.entries);
+        }
+        break;
+    }
+  }
+
+  function setBusy(busy) {
+    state.busy = busy;
+    document.querySelector("#composer button").disabled = busy;
+  }
+
+  // --- Обработчики интерфейса -------------------------------------------
+
+  function submitMessage() {
+    var input = $("input");
+    var text = input.value.trim();
+    if (!text || state.busy) {
+      return;
+    }
+    var id = nextID();
+    if (!send({ type: "message", id: id, conversation: state.conversation, text: text })) {
+      return;
+    }
+    addMessage("user", text);
+    state.pending[id] = addMessage("bot", "");
+    state.pending[id].classList.add("typing");
+    input.value = "";
+    autoGrow();
+    setBusy(true);
+  }
+
+  function autoGrow() {
+    var input = $("input");
+    input.style.height = "auto";
+    input.style.height = input.scrollHeight + "px";
+  }
+
+  function showLogin(error) {
+    $("login-user").value = state.userID;
+    $("login-token").value = "";
+    $("login-error").hidden = !error;
+    $("login-error").textContent = error || "";
+    $("login").hidden = false;
+  }
+
+  // settingsFetch обращается к /settings с токеном пользователя. Ошибки авторизации
+  // приходят текстом, а не JSON, поэтому показываются отдельно.
+  function settingsFetch(url, options) {
+    options = options || {};
+    options.headers = Object.assign({
+      "Accept-Language": locale,
+      "Authorization": "Bearer " + state.token
+    }, options.headers);
+    return fetch(url, options).then(function (response) {
+      if (response.status === 401 || response.status === 403) {
+        throw new Error(t(response.status === 401 ? "unauthorized" : "forbidden"));
+      }
+      return response.json();
+    });
+  }
+
+  function showSettingsError(error) {
+    $("settings-error").textContent = error && error.message ? error.message : String(error || "");
+    $("settings-error").hidden = false;
+  }
+
+  function openSettings() {
+    $("settings-error").hidden = true;
+    settingsFetch("/settings?user_id=" + encodeURIComponent(state.userID))
+      .then(function (data) {
+        var container = $("settings-fields");
+        container.innerHTML = "";
+        (data.fields || []).forEach(function (field) {
+          var label = document.createElement("label");
+          var caption = document.createElement("span");
+          caption.textContent = field.label;
+          var select = document.createElement("select");
+          select.name = field.key;
+          field.options.forEach(function (option) {
+            var element = document.createElement("option");
+            element.value = option.value;
+            element.textContent = option.label;
+            select.appendChild(element);
+          });
+          select.value = data.settings[field.key] || "";
+          label.appendChild(caption);
+          label.appendChild(select);
+          container.appendChild(label);
+        });
+        state.systemPrompt = data.settings.system_prompt || "";
+        $("settings-prompt").value = state.systemPrompt;
+        $("settings-timezone").value = data.settings.timezone || "";
+        $("settings").hidden = false;
+      })
+      .catch(function (error) {
+        showSettingsError(error);
+        $("settings").hidden = false;
+      });
+  }
+
+  function postSettings(request) {
+    return settingsFetch("/settings", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(request)
+    }).then(function (data) {
+      if (data.status !== "success") {
+        throw new Error(data.error || "");
+      }
+      return data;
+    });
+  }
+
+  function saveSettings(event) {
+    event.preventDefault();
+    var request = { user_id: Number(state.userID) };
+    $("settings-fields").querySelectorAll("select").forEach(function (select) {
+      request[select.name] = select.value;
+    });
+    // Пустой system_prompt сервер понимает как «не менять», поэтому очистку просим явно
+    var prompt = $("settings-prompt").value.trim();
+    if (prompt === "" && state.systemPrompt !== "") {
+      request.clear_system_prompt = true;
+    } else if (prompt !== state.systemPrompt) {
+      request.system_prompt = prompt;
+    }
+    request.timezone = $("settings-timezone").value.trim();
+    postSettings(request)
+      .then(function () { $("settings").hidden = true; })
+      .catch(showSettingsError);
+  }
+
+  function resetSettings() {
+    if (!confirm(t("settings_reset_confirm"))) {
+      return;
+    }
+    postSettings({ user_id: Number(state.userID), reset: true })
+      .then(openSettings)
+      .catch(showSettingsError);
+  }
+
+  function init() {
+    document.documentElement.lang = locale;
+    document.querySelectorAll("[data-i18n]").forEach(function (element) {
+      element.textContent = t(element.dataset.i18n);
+    });
+    document.querySelectorAll("[data-i18n-placeholder]").forEach(function (element) {
+      element.placeholder = t(element.dataset.i18nPlaceholder);
+    });
+
+    $("composer").addEventListener("submit", function (event) {
+      event.preventDefault();
+      submitMessage();
+    });
+    $("input").addEventListener("keydown", function (event) {
+      if (event.key === "Enter" && !event.shiftKey && !event.isComposing) {
+        event.preventDefault();
+        submitMessage();
+      }
+    });
+    $("input").addEventListener("input", autoGrow);
+
+    $("new-chat").addEventListener("click", function () {
+      selectConversation("c" + Date.now().toString(36));
+    });
+    $("reset").addEventListener("click", function () {
+      if (confirm(t("reset_confirm"))) {
+        send({ type: "reset", conversation: state.conversation });
+      }
+    });
+    $("logout").addEventListener("click", function () {
+      localStorage.removeItem("chat.token");
+      state.token = "";
+      var socket = state.socket;
+      state.socket = null;
+      if (socket) {
+        socket.close();
+      }
+      clearMessages();
+      showLogin();
+    });
+
+    $("login-form").addEventListener("submit", function (event) {
+      event.preventDefault();
+      state.userID = $("login-user").value.trim();
+      state.token = $("login-token").value.trim();
+      state.everConnected = false;
+      localStorage.setItem("chat.user_id", state.userID);
+      localStorage.setItem("chat.token", state.token);
+      $("login").hidden = true;
+      connect();
+    });
+
+    $("open-settings").addEventListener("click", openSettings);
+    $("close-settings").addEventListener("click", function () { $("settings").hidden = true; });
+    $("settings-form").addEventListener("submit", saveSettings);
+    $("reset-settings").addEventListener("click", resetSettings);
+
+    renderConversations();
+    setConnection(false);
+    connect();
+  }
+
+  init();
+})();
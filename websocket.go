@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Коды операций кадров WebSocket (RFC 6455, раздел 5.2)
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// Коды закрытия соединения (RFC 6455, раздел 7.4.1)
+const (
+	WSCloseNormal        = 1000
+	WSCloseGoingAway     = 1001
+	WSCloseProtocol      = 1002
+	WSCloseInvalidData   = 1007
+	WSClosePolicy        = 1008
+	WSCloseTooLarge      = 1009
+	WSCloseInternalError = 1011
+)
+
+// websocketGUID — строка из RFC 6455 для вычисления Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsWriteTimeout — сколько ждать отправки кадра медленному клиенту
+const wsWriteTimeout = 10 * time.Second
+
+// ErrWebSocketClosed возвращается, когда клиент закрыл соединение
+var ErrWebSocketClosed = errors.New("соединение WebSocket закрыто")
+
+// WebSocketConn — серверная сторона соединения WebSocket (RFC 6455) без расширений.
+// Читать сообщения можно из одной горутины, писать — из любых.
+type WebSocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	// MaxMessageSize ограничивает размер сообщения после сборки из фрагментов
+	MaxMessageSize int
+	// ReadTimeout — сколько ждать следующего кадра от клиента, включая ответы на ping; 0 — без ограничения
+	ReadTimeout time.Duration
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// UpgradeWebSocket проверяет рукопожатие и переключает HTTP-соединение на WebSocket.
+// При ошибке клиенту уже отправлен ответ HTTP.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return nil, errors.New("рукопожатие WebSocket не методом GET")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "Ожидается Upgrade: websocket", http.StatusBadRequest)
+		return nil, errors.New("нет заголовков Upgrade")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Поддерживается только WebSocket версии 13", http.StatusUpgradeRequired)
+		return nil, errors.New("неподдерживаемая версия WebSocket")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Неверный Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("неверный Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Сервер не поддерживает WebSocket", http.StatusInternalServerError)
+		return nil, errors.New("соединение нельзя перехватить")
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка перехвата соединения: %v", err)
+	}
+
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка отправки рукопожатия: %v", err)
+	}
+
+	return &WebSocketConn{conn: conn, reader: buffered.Reader, MaxMessageSize: 64 << 10}, nil
+}
+
+// headerHasToken проверяет, что заголовок содержит значение из списка через запятую без учета регистра
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage возвращает следующее текстовое или двоичное сообщение, собирая его из фрагментов.
+// На ping отвечает pong, на закрытие — закрытием и ErrWebSocketClosed.
+func (c *WebSocketConn) ReadMessage() (int, []byte, error) {
+	opcode := 0
+	var message []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			code := WSCloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return 0, nil, ErrWebSocketClosed
+		case wsOpContinuation:
+			if opcode == 0 {
+				return 0, nil, c.fail(WSCloseProtocol, "фрагмент без начала сообщения")
+			}
+		case wsOpText, wsOpBinary:
+			if opcode != 0 {
+				return 0, nil, c.fail(WSCloseProtocol, "новое сообщение до окончания предыдущего")
+			}
+			opcode = op
+		default:
+			return 0, nil, c.fail(WSCloseProtocol, fmt.Sprintf("неизвестный код операции %d", op))
+		}
+
+		message = append(message, payload...)
+		if len(message) > c.MaxMessageSize {
+			return 0, nil, c.fail(WSCloseTooLarge, "сообщение слишком большое")
+		}
+		if fin {
+			if opcode == wsOpText && !utf8.Valid(message) {
+				return 0, nil, c.fail(WSCloseInvalidData, "текст не в UTF-8")
+			}
+			return opcode, message, nil
+		}
+	}
+}
+
+// readFrame читает один кадр. Кадры клиента обязаны быть замаскированы.
+func (c *WebSocketConn) readFrame() (bool, int, []byte, error) {
+	if c.ReadTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	op := int(header[0] & 0x0F)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(WSCloseProtocol, "расширения не поддерживаются")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(WSCloseProtocol, "кадр клиента без маски")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if op >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(WSCloseProtocol, "неверный управляющий кадр")
+	}
+	if length > uint64(c.MaxMessageSize) {
+		return false, 0, nil, c.fail(WSCloseTooLarge, "сообщение слишком большое")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteText отправляет текстовое сообщение одним кадром
+func (c *WebSocketConn) WriteText(data []byte) error {
+	return c.writeFrame(wsOpText, data)
+}
+
+// Ping отправляет ping; клиент должен ответить pong до истечения ReadTimeout
+func (c *WebSocketConn) Ping() error {
+	return c.writeFrame(wsOpPing, nil)
+}
+
+// writeFrame отправляет кадр без маски, как положено серверу
+func (c *WebSocketConn) writeFrame(op int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return ErrWebSocketClosed
+	}
+	return c.writeFrameLocked(op, payload)
+}
+
+func (c *WebSocketConn) writeFrameLocked(op int, payload []byte) error {
+	frame := []byte{0x80 | byte(op)}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 126, byte(length>>8), byte(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close отправляет кадр закрытия с кодом и причиной и закрывает соединение
+func (c *WebSocketConn) Close(code int, reason string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	// Причина ограничена 123 байтами; обрезаем по границе символа
+	for len(reason) > 123 {
+		_, size := utf8.DecodeLastRuneInString(reason)
+		reason = reason[:len(reason)-size]
+	}
+	c.writeFrameLocked(wsOpClose, append(payload, reason...))
+	return c.conn.Close()
+}
+
+// fail закрывает соединение из-за нарушения протокола и возвращает ошибку
+func (c *WebSocketConn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return fmt.Errorf("ошибка протокола WebSocket: %s", reason)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры соединений /ws
+const (
+	wsPingInterval  = 30 * time.Second // Как часто сервер проверяет клиента ping-ом
+	wsReadTimeout   = 75 * time.Second // Сколько ждать любого кадра, включая pong, прежде чем закрыть соединение
+	wsQueueSize     = 8                // Сообщения клиента, ожидающие обработки
+	wsTitleMaxRunes = 60               // Длина заголовка разговора в списке
+)
+
+// Типы событий протокола /ws
+const (
+	WSEventMessage = "message" // Клиент → сервер: сообщение агенту
+	WSEventReset   = "reset"   // Клиент → сервер: очистить историю разговора
+	WSEventPing    = "ping"    // Клиент → сервер: проверка связи, сервер отвечает pong
+	WSEventPong    = "pong"
+	WSEventList    = "conversations" // Клиент ↔ сервер: список разговоров пользователя
+	WSEventHistory = "history"       // Клиент ↔ сервер: реплики разговора
+	WSEventStatus  = "status"        // Сервер: connected, processing, idle, reset
+	WSEventTyping  = "typing"        // Сервер: агент готовит ответ
+	WSEventTool    = "tool"          // Сервер: инструмент, выбранный для сообщения
+	WSEventDelta   = "delta"         // Сервер: очередной фрагмент ответа модели
+	WSEventDone    = "done"          // Сервер: ответ целиком
+	WSEventError   = "error"
+)
+
+// wsConversationPattern ограничивает имена дополнительных разговоров, которые создает клиент
+var wsConversationPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// WSEvent — сообщение протокола /ws в обе стороны. ID связывает события ответа с сообщением клиента.
+// Conversation — имя дополнительного разговора; пустое — основной разговор пользователя.
+type WSEvent struct {
+	Type         string `json:"type"`
+	ID           string `json:"id,omitempty"`
+	Conversation string `json:"conversation,omitempty"`
+	Text         string `json:"text,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Tool         string `json:"tool,omitempty"`
+	Model        string `json:"model,omitempty"`
+	ModelVersion string `json:"model_version,omitempty"`
+	Error        string `json:"error,omitempty"`
+	UserID       int64  `json:"user_id,omitempty"`
+	Provider     string `json:"provider,omitempty"`
+
+	Conversations []WSConversation `json:"conversations,omitempty"`
+	Entries       []ExportEntry    `json:"entries,omitempty"`
+}
+
+// WSConversation — разговор в списке: заголовок по первому сообщению, время и число реплик
+type WSConversation struct {
+	Conversation string    `json:"conversation"`
+	Title        string    `json:"title"`
+	Updated      time.Time `json:"updated"`
+	Messages     int       `json:"messages"`
+}
+
+// WebSocketToken возвращает токен пользователя для подключения к /ws: HMAC-SHA256 его ID
+// на секрете WS_AUTH_SECRET. Токены выдает бэкенд веб-приложения, которое знает пользователя.
+func WebSocketToken(secret string, userID int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(userID, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// runWebSocketToken печатает токен пользователя для /ws и веб-чата: chatagent ws-token <user_id>
+func runWebSocketToken(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Использование: chatagent ws-token <user_id>")
+		return 2
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "неверный user_id %s\n", args[0])
+		return 2
+	}
+	secret := os.Getenv("WS_AUTH_SECRET")
+	if secret == "" {
+		fmt.Fprintln(os.Stderr, "Не задан WS_AUTH_SECRET")
+		return 1
+	}
+	fmt.Println(WebSocketToken(secret, userID))
+	return 0
+}
+
+// wsSession — разговор одного подключения; сообщения обрабатываются по очереди
+type wsSession struct {
+	conn   *WebSocketConn
+	agent  *Agent
+	userID int64
+	locale string
+	queue  chan WSEvent
+}
+
+// handleWebSocket принимает подключения к /ws?user_id=N&token=...
+// Токен проверяется при рукопожатии, до переключения протокола; его можно передать
+// и заголовком Authorization: Bearer. История общая с Telegram и /chat для того же user_id.
+func (s *HTTPServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Неверный user_id", http.StatusBadRequest)
+		return
+	}
+	if !s.authorizeUser(w, r, userID, true) {
+		return
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = requestLocale(r)
+	}
+	// Язык клиента нужен уже для ошибок протокола, до первого сообщения
+	s.agent.RememberLocale(userID, locale)
+
+	conn, err := UpgradeWebSocket(w, r)
+	if err != nil {
+		log.Printf("Ошибка подключения WebSocket: %v", err)
+		return
+	}
+	conn.ReadTimeout = wsReadTimeout
+
+	session := &wsSession{conn: conn, agent: s.agent, userID: userID, locale: locale, queue: make(chan WSEvent, wsQueueSize)}
+	s.agent.Users().Touch(userID, "")
+	log.Printf("WebSocket: пользователь %d подключен", userID)
+	session.run()
+	log.Printf("WebSocket: пользователь %d отключен", userID)
+}
+
+// run обслуживает соединение до его закрытия: чтение, обработка сообщений и ping
+func (ws *wsSession) run() {
+	done := make(chan struct{})
+	defer close(done)
+
+	go ws.process()
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := ws.conn.Ping(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	ws.send(WSEvent{Type: WSEventStatus, Status: "connected", UserID: ws.userID, Provider: ws.agent.ProviderName()})
+	defer close(ws.queue)
+	for {
+		opcode, data, err := ws.conn.ReadMessage()
+		if err != nil {
+			ws.conn.Close(WSCloseGoingAway, "")
+			return
+		}
+		if opcode != wsOpText {
+			ws.sendError("", "ws.error.not_text", nil)
+			continue
+		}
+
+		var event WSEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			ws.sendError("", "ws.error.json", nil)
+			continue
+		}
+		switch event.Type {
+		case WSEventPing:
+			ws.send(WSEvent{Type: WSEventPong, ID: event.ID})
+		case WSEventMessage, WSEventReset, WSEventList, WSEventHistory:
+			if event.Conversation != "" && !wsConversationPattern.MatchString(event.Conversation) {
+				ws.sendError(event.ID, "ws.error.conversation", nil)
+				continue
+			}
+			select {
+			case ws.queue <- event:
+			default:
+				ws.sendError(event.ID, "ws.error.queue_full", nil)
+			}
+		default:
+			ws.sendError(event.ID, "ws.error.unknown_event", Params{"type": event.Type})
+		}
+	}
+}
+
+// process обрабатывает сообщения клиента по очереди, чтобы ответы не перемешивались
+func (ws *wsSession) process() {
+	for event := range ws.queue {
+		conversationID := ws.conversationID(event.Conversation)
+		switch event.Type {
+		case WSEventReset:
+			ws.agent.ResetConversation(conversationID)
+			ws.send(WSEvent{Type: WSEventStatus, ID: event.ID, Conversation: event.Conversation, Status: "reset"})
+			continue
+		case WSEventList:
+			ws.send(WSEvent{Type: WSEventList, ID: event.ID, Conversations: ws.conversations()})
+			continue
+		case WSEventHistory:
+			reply := WSEvent{Type: WSEventHistory, ID: event.ID, Conversation: event.Conversation}
+			if conversation, exists := ws.agent.ExportConversation(conversationID); exists {
+				reply.Entries = conversation.Entries
+			}
+			ws.send(reply)
+			continue
+		}
+
+		text := strings.TrimSpace(event.Text)
+		if text == "" {
+			ws.sendError(event.ID, "ws.error.empty", nil)
+			continue
+		}
+		ws.send(WSEvent{Type: WSEventStatus, ID: event.ID, Conversation: event.Conversation, Status: "processing"})
+		ws.send(WSEvent{Type: WSEventTyping, ID: event.ID, Conversation: event.Conversation})
+		ws.send(WSEvent{Type: WSEventTool, ID: event.ID, Conversation: event.Conversation, Tool: ws.agent.determineTool(text)})
+
+		mc := MessageContext{UserID: ws.userID, ConversationID: conversationID, Locale: ws.locale}
+		mc.OnDelta = func(delta string) {
+			ws.send(WSEvent{Type: WSEventDelta, ID: event.ID, Conversation: event.Conversation, Text: delta})
+		}
+		reply, err := ws.agent.ProcessMessageReply(mc, text)
+		if err != nil {
+			log.Printf("WebSocket: ошибка обработки сообщения: %v", err)
+			ws.send(WSEvent{Type: WSEventError, ID: event.ID, Error: localizeError(ws.agent.LocaleFor(ws.userID), err)})
+			ws.send(WSEvent{Type: WSEventStatus, ID: event.ID, Status: "idle"})
+			continue
+		}
+
+		// done приходит последним с итоговым текстом: модерация готового ответа может его заменить,
+		// а ответы из кэша и встроенных инструментов приходят без фрагментов
+		ws.send(WSEvent{Type: WSEventDone, ID: event.ID, Conversation: event.Conversation, Text: reply.Text,
+			Tool: reply.Tool, Model: reply.Model, ModelVersion: reply.ModelVersion})
+		ws.send(WSEvent{Type: WSEventStatus, ID: event.ID, Status: "idle"})
+	}
+}
+
+// conversationID возвращает ключ истории: основной разговор общий с Telegram и /chat,
+// дополнительные называются web:<имя>:user:<id> и попадают в выгрузку разговоров пользователя
+func (ws *wsSession) conversationID(name string) string {
+	if name == "" {
+		return userConversationID(ws.userID)
+	}
+	return fmt.Sprintf("web:%s:user:%d", name, ws.userID)
+}
+
+// conversations возвращает основной и дополнительные разговоры пользователя, последние — первыми
+func (ws *wsSession) conversations() []WSConversation {
+	prefix, suffix := "web:", fmt.Sprintf(":user:%d", ws.userID)
+	list := make([]WSConversation, 0)
+	for _, conversation := range ws.agent.ExportUserConversations(ws.userID) {
+		item := WSConversation{Messages: len(conversation.Entries)}
+		switch id := conversation.ConversationID; {
+		case id == userConversationID(ws.userID):
+		case strings.HasPrefix(id, prefix) && strings.HasSuffix(id, suffix):
+			item.Conversation = strings.TrimSuffix(strings.TrimPrefix(id, prefix), suffix)
+		default:
+			continue // Ветки групповых чатов в веб-клиенте не показываются
+		}
+		if len(conversation.Entries) == 0 {
+			continue
+		}
+		item.Title = truncateRunes(conversation.Entries[0].Message, wsTitleMaxRunes)
+		item.Updated = conversation.Entries[len(conversation.Entries)-1].Timestamp
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Updated.After(list[j].Updated) })
+	return list
+}
+
+// truncateRunes обрезает текст до max символов с многоточием
+func truncateRunes(text string, max int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= max {
+		return string(runes)
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+func (ws *wsSession) send(event WSEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	ws.conn.WriteText(data)
+}
+
+// sendError отправляет ошибку протокола на языке пользователя
+func (ws *wsSession) sendError(id, key string, params Params) {
+	ws.send(WSEvent{Type: WSEventError, ID: id, Error: T(ws.agent.LocaleFor(ws.userID), key, params)})
+}
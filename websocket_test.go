@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsTestFrame — кадр, который сервер отправил клиенту
+type wsTestFrame struct {
+	fin     bool
+	op      int
+	payload []byte
+}
+
+// closeCode возвращает код из кадра закрытия
+func (f wsTestFrame) closeCode() int {
+	if f.op != wsOpClose || len(f.payload) < 2 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(f.payload))
+}
+
+// maskedFrame собирает кадр клиента с маской, как требует RFC 6455
+func maskedFrame(fin bool, op int, payload []byte) []byte {
+	first := byte(op)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 0x80|126, byte(length>>8), byte(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame читает кадр сервера: он не замаскирован
+func readServerFrame(r io.Reader) (wsTestFrame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return wsTestFrame{}, err
+	}
+	if header[1]&0x80 != 0 {
+		return wsTestFrame{}, errors.New("кадр сервера с маской")
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return wsTestFrame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return wsTestFrame{}, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return wsTestFrame{}, err
+	}
+	return wsTestFrame{fin: header[0]&0x80 != 0, op: int(header[0] & 0x0F), payload: payload}, nil
+}
+
+// newWSPipe соединяет серверную сторону WebSocketConn с клиентом в памяти.
+// Кадры сервера собираются в канал, пока соединение не закроется.
+func newWSPipe(t *testing.T, maxSize int) (*WebSocketConn, net.Conn, <-chan wsTestFrame) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	frames := make(chan wsTestFrame, 16)
+	go func() {
+		defer close(frames)
+		for {
+			frame, err := readServerFrame(client)
+			if err != nil {
+				return
+			}
+			frames <- frame
+		}
+	}()
+
+	conn := &WebSocketConn{conn: server, reader: bufio.NewReader(server), MaxMessageSize: maxSize, ReadTimeout: 5 * time.Second}
+	return conn, client, frames
+}
+
+func TestWebSocketReadMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxSize  int
+		frames   [][]byte
+		wantOp   int
+		wantData string
+		wantErr  bool
+		// Кадры, которые сервер должен отправить в ответ, по кодам операций, и код закрытия
+		wantOps   []int
+		wantClose int
+		wantPong  string
+	}{
+		{
+			name:     "замаскированный текст",
+			frames:   [][]byte{maskedFrame(true, wsOpText, []byte("Hello"))},
+			wantOp:   wsOpText,
+			wantData: "Hello",
+		},
+		{
+			name:     "двоичное сообщение",
+			frames:   [][]byte{maskedFrame(true, wsOpBinary, []byte{0, 1, 2, 0xFF})},
+			wantOp:   wsOpBinary,
+			wantData: "\x00\x01\x02\xFF",
+		},
+		{
+			name:     "длина 126 в двух байтах",
+			frames:   [][]byte{maskedFrame(true, wsOpText, bytes.Repeat([]byte("a"), 300))},
+			wantOp:   wsOpText,
+			wantData: strings.Repeat("a", 300),
+		},
+		{
+			name: "сообщение из фрагментов",
+			frames: [][]byte{
+				maskedFrame(false, wsOpText, []byte("При")),
+				maskedFrame(false, wsOpContinuation, []byte("ве")),
+				maskedFrame(true, wsOpContinuation, []byte("т")),
+			},
+			wantOp:   wsOpText,
+			wantData: "Привет",
+		},
+		{
+			name: "ping между фрагментами",
+			frames: [][]byte{
+				maskedFrame(false, wsOpText, []byte("Hel")),
+				maskedFrame(true, wsOpPing, []byte("check")),
+				maskedFrame(true, wsOpContinuation, []byte("lo")),
+			},
+			wantOp:   wsOpText,
+			wantData: "Hello",
+			wantOps:  []int{wsOpPong},
+			wantPong: "check",
+		},
+		{
+			name: "pong клиента пропускается",
+			frames: [][]byte{
+				maskedFrame(true, wsOpPong, nil),
+				maskedFrame(true, wsOpText, []byte("{}")),
+			},
+			wantOp:   wsOpText,
+			wantData: "{}",
+		},
+		{
+			name:      "закрытие клиентом",
+			frames:    [][]byte{maskedFrame(true, wsOpClose, []byte{0x03, 0xE8})},
+			wantErr:   true,
+			wantClose: WSCloseNormal,
+		},
+		{
+			name:      "кадр без маски",
+			frames:    [][]byte{{0x81, 0x05, 'H', 'e', 'l', 'l', 'o'}},
+			wantErr:   true,
+			wantClose: WSCloseProtocol,
+		},
+		{
+			name:      "зарезервированные биты",
+			frames:    [][]byte{append([]byte{0xC1}, maskedFrame(true, wsOpText, []byte("x"))[1:]...)},
+			wantErr:   true,
+			wantClose: WSCloseProtocol,
+		},
+		{
+			name:      "неизвестный код операции",
+			frames:    [][]byte{maskedFrame(true, 0x3, []byte("x"))},
+			wantErr:   true,
+			wantClose: WSCloseProtocol,
+		},
+		{
+			name:      "продолжение без начала",
+			frames:    [][]byte{maskedFrame(true, wsOpContinuation, []byte("lo"))},
+			wantErr:   true,
+			wantClose: WSCloseProtocol,
+		},
+		{
+			name: "новое сообщение внутри фрагментированного",
+			frames: [][]byte{
+				maskedFrame(false, wsOpText, []byte("Hel")),
+				maskedFrame(true, wsOpText, []byte("lo")),
+			},
+			wantErr:   true,
+			wantClose: WSCloseProtocol,
+		},
+		{
+			name:      "управляющий кадр длиннее 125 байт",
+			frames:    [][]byte{maskedFrame(true, wsOpPing, bytes.Repeat([]byte("p"), 126))},
+			wantErr:   true,
+			wantClose: WSCloseProtocol,
+		},
+		{
+			name:      "фрагментированный управляющий кадр",
+			frames:    [][]byte{maskedFrame(false, wsOpPing, []byte("p"))},
+			wantErr:   true,
+			wantClose: WSCloseProtocol,
+		},
+		{
+			name:      "текст не в UTF-8",
+			frames:    [][]byte{maskedFrame(true, wsOpText, []byte{0xFF, 0xFE})},
+			wantErr:   true,
+			wantClose: WSCloseInvalidData,
+		},
+		{
+			name:      "кадр больше лимита",
+			maxSize:   16,
+			frames:    [][]byte{maskedFrame(true, wsOpText, bytes.Repeat([]byte("a"), 17))},
+			wantErr:   true,
+			wantClose: WSCloseTooLarge,
+		},
+		{
+			name:    "фрагменты вместе больше лимита",
+			maxSize: 16,
+			frames: [][]byte{
+				maskedFrame(false, wsOpText, bytes.Repeat([]byte("a"), 10)),
+				maskedFrame(true, wsOpContinuation, bytes.Repeat([]byte("a"), 10)),
+			},
+			wantErr:   true,
+			wantClose: WSCloseTooLarge,
+		},
+		{
+			// Заголовок обещает 2^40 байт: сервер должен отказать, не читая и не выделяя память под тело
+			name:      "64-битная длина",
+			frames:    [][]byte{{0x81, 0x80 | 127, 0, 0, 0x01, 0, 0, 0, 0, 0, 1, 2, 3, 4}},
+			wantErr:   true,
+			wantClose: WSCloseTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxSize := tt.maxSize
+			if maxSize == 0 {
+				maxSize = 64 << 10
+			}
+			conn, client, frames := newWSPipe(t, maxSize)
+			go func() {
+				for _, frame := range tt.frames {
+					if _, err := client.Write(frame); err != nil {
+						return
+					}
+				}
+			}()
+
+			op, data, err := conn.ReadMessage()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ReadMessage = %d %q, want ошибку", op, data)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if op != tt.wantOp || string(data) != tt.wantData {
+					t.Errorf("ReadMessage = %d %q, want %d %q", op, data, tt.wantOp, tt.wantData)
+				}
+				conn.conn.Close()
+			}
+
+			var ops []int
+			closeCode := 0
+			for frame := range frames {
+				if !frame.fin {
+					t.Errorf("сервер отправил фрагментированный кадр %d", frame.op)
+				}
+				if frame.op == wsOpClose {
+					closeCode = frame.closeCode()
+					continue
+				}
+				ops = append(ops, frame.op)
+				if frame.op == wsOpPong && string(frame.payload) != tt.wantPong {
+					t.Errorf("pong = %q, want %q", frame.payload, tt.wantPong)
+				}
+			}
+			if len(ops) != len(tt.wantOps) || (len(ops) > 0 && ops[0] != tt.wantOps[0]) {
+				t.Errorf("кадры сервера %v, want %v", ops, tt.wantOps)
+			}
+			if closeCode != tt.wantClose {
+				t.Errorf("код закрытия %d, want %d", closeCode, tt.wantClose)
+			}
+		})
+	}
+}
+
+func TestWebSocketWriteFrames(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		conn, _, frames := newWSPipe(t, 64<<10)
+		payload := bytes.Repeat([]byte("x"), size)
+		go conn.WriteText(payload)
+
+		frame := <-frames
+		if !frame.fin || frame.op != wsOpText || len(frame.payload) != size {
+			t.Errorf("WriteText(%d байт): fin=%v op=%d длина %d", size, frame.fin, frame.op, len(frame.payload))
+		}
+	}
+}
+
+func TestWebSocketCloseReason(t *testing.T) {
+	conn, _, frames := newWSPipe(t, 64<<10)
+	// 70 символов по 2 байта: причина обрезается до 123 байт по границе символа
+	go conn.Close(WSClosePolicy, strings.Repeat("я", 70))
+
+	frame := <-frames
+	if frame.closeCode() != WSClosePolicy {
+		t.Errorf("код закрытия %d", frame.closeCode())
+	}
+	if reason := string(frame.payload[2:]); reason != strings.Repeat("я", 61) {
+		t.Errorf("причина %d байт: %q", len(reason), reason)
+	}
+	if err := conn.WriteText([]byte("после закрытия")); !errors.Is(err, ErrWebSocketClosed) {
+		t.Errorf("WriteText после Close = %v", err)
+	}
+}
+
+func TestUpgradeWebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		// Эхо-сервер: возвращает первое сообщение и закрывает соединение
+		if _, data, err := conn.ReadMessage(); err == nil {
+			conn.WriteText(data)
+		}
+		conn.Close(WSCloseNormal, "")
+	}))
+	defer server.Close()
+
+	headers := func(mutate func(http.Header)) http.Header {
+		header := http.Header{
+			"Connection":            {"keep-alive, Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+		}
+		if mutate != nil {
+			mutate(header)
+		}
+		return header
+	}
+	rejected := []struct {
+		name   string
+		method string
+		header http.Header
+		want   int
+	}{
+		{"не GET", http.MethodPost, headers(nil), http.StatusMethodNotAllowed},
+		{"без Upgrade", http.MethodGet, headers(func(h http.Header) { h.Del("Upgrade") }), http.StatusBadRequest},
+		{"версия 8", http.MethodGet, headers(func(h http.Header) { h.Set("Sec-Websocket-Version", "8") }), http.StatusUpgradeRequired},
+		{"неверный ключ", http.MethodGet, headers(func(h http.Header) { h.Set("Sec-Websocket-Key", "short") }), http.StatusBadRequest},
+	}
+	for _, tt := range rejected {
+		req, _ := http.NewRequest(tt.method, server.URL, nil)
+		req.Header = tt.header
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: статус %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	req.Header = headers(nil)
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Пример ключа и ответа из RFC 6455, раздел 1.3
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("рукопожатие: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	conn.Write(maskedFrame(true, wsOpText, []byte("эхо")))
+	echo, err := readServerFrame(reader)
+	if err != nil || echo.op != wsOpText || string(echo.payload) != "эхо" {
+		t.Errorf("эхо: %+v, %v", echo, err)
+	}
+	if closing, err := readServerFrame(reader); err != nil || closing.closeCode() != WSCloseNormal {
+		t.Errorf("закрытие: %+v, %v", closing, err)
+	}
+}
+
+func TestWebSocketSessionErrorsLocalized(t *testing.T) {
+	tests := []struct {
+		locale string
+		frame  []byte
+		want   string
+	}{
+		{LocaleEN, maskedFrame(true, wsOpText, []byte(`{"type":"bogus"}`)), "unknown event type “bogus”"},
+		{LocaleRU, maskedFrame(true, wsOpText, []byte(`{"type":"bogus"}`)), "неизвестный тип события «bogus»"},
+		{LocaleEN, maskedFrame(true, wsOpText, []byte(`not json`)), "invalid JSON"},
+		{LocaleEN, maskedFrame(true, wsOpBinary, []byte(`{}`)), "a JSON text message is expected"},
+		{LocaleRU, maskedFrame(true, wsOpText, []byte(`{"type":"message","conversation":"../etc"}`)), "неверное имя разговора"},
+	}
+	for _, tt := range tests {
+		agent := NewAgent()
+		agent.RememberLocale(1, tt.locale)
+		conn, client, frames := newWSPipe(t, 64<<10)
+		session := &wsSession{conn: conn, agent: agent, userID: 1, queue: make(chan WSEvent, wsQueueSize)}
+		go session.run()
+		go client.Write(tt.frame)
+
+		var got []string
+		for frame := range frames {
+			var event WSEvent
+			if frame.op == wsOpText && json.Unmarshal(frame.payload, &event) == nil && event.Type == WSEventError {
+				got = append(got, event.Error)
+				client.Write(maskedFrame(true, wsOpClose, []byte{0x03, 0xE8}))
+			}
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s %q: ошибки %q, want %q", tt.locale, tt.frame, got, tt.want)
+		}
+	}
+}
+
+// wsMessageEvents отправляет сообщение в сессию пользователя 7 и возвращает события ответа
+// до статуса idle
+func wsMessageEvents(t *testing.T, agent *Agent, text string) []WSEvent {
+	t.Helper()
+	conn, client, frames := newWSPipe(t, 64<<10)
+	session := &wsSession{conn: conn, agent: agent, userID: 7, queue: make(chan WSEvent, wsQueueSize)}
+	go session.run()
+	message, _ := json.Marshal(WSEvent{Type: WSEventMessage, ID: "m1", Text: text})
+	go client.Write(maskedFrame(true, wsOpText, message))
+
+	var events []WSEvent
+	for frame := range frames {
+		var event WSEvent
+		if frame.op != wsOpText || json.Unmarshal(frame.payload, &event) != nil || event.ID != "m1" {
+			continue
+		}
+		events = append(events, event)
+		if event.Type == WSEventStatus && event.Status == "idle" {
+			client.Write(maskedFrame(true, wsOpClose, []byte{0x03, 0xE8}))
+		}
+	}
+	return events
+}
+
+func TestWebSocketDoneReplyMetadata(t *testing.T) {
+	t.Setenv("DATA_DIR", t.TempDir())
+	agent := createAgent()
+	agent.RegisterProvider(funcProvider{name: "stub", respond: func([]YandexGPTMessage, GenerationOptions) (Completion, error) {
+		// Пока модель отвечает, в тот же разговор пишут из Telegram: его реплика становится последней
+		agent.ProcessMessage("помощь", 7)
+		return Completion{Text: "Ответ модели", Model: "yandexgpt-lite", ModelVersion: "23.10.2024"}, nil
+	}})
+	if err := agent.SetProvider("stub"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := wsMessageEvents(t, agent, "расскажи о космосе")
+	var done *WSEvent
+	for i := range events {
+		if events[i].Type == WSEventDone {
+			done = &events[i]
+		}
+	}
+	if done == nil {
+		t.Fatalf("нет события done среди %+v", events)
+	}
+	if done.Text != "Ответ модели" || done.Tool != "general" || done.Model != "yandexgpt-lite" || done.ModelVersion != "23.10.2024" {
+		t.Errorf("done = %+v, ожидались ответ модели, general и yandexgpt-lite 23.10.2024", *done)
+	}
+	if history := agent.history(userConversationID(7)); len(history) == 0 || history[len(history)-1].Tool != "help" {
+		t.Error("последней в истории должна быть реплика из другого канала")
+	}
+}
+
+// streamProvider передает ответ модели заданными фрагментами
+type streamProvider struct {
+	chunks []string
+}
+
+func (p streamProvider) Name() string { return "stream" }
+
+func (p streamProvider) Generate(messages []YandexGPTMessage, options GenerationOptions) (Completion, error) {
+	return p.GenerateStream(messages, options, func(string) {})
+}
+
+func (p streamProvider) GenerateStream(messages []YandexGPTMessage, options GenerationOptions, onDelta func(text string)) (Completion, error) {
+	for _, chunk := range p.chunks {
+		onDelta(chunk)
+	}
+	return Completion{Text: strings.Join(p.chunks, ""), Model: "yandexgpt-lite"}, nil
+}
+
+func TestWebSocketStreamsDeltas(t *testing.T) {
+	t.Setenv("DATA_DIR", t.TempDir())
+	agent := createAgent()
+	agent.RegisterProvider(streamProvider{chunks: []string{"Солнце — ", "звезда ", "класса G."}})
+	if err := agent.SetProvider("stream"); err != nil {
+		t.Fatal(err)
+	}
+
+	var types, deltas []string
+	var done WSEvent
+	for _, event := range wsMessageEvents(t, agent, "что такое солнце?") {
+		types = append(types, event.Type)
+		switch event.Type {
+		case WSEventDelta:
+			deltas = append(deltas, event.Text)
+		case WSEventDone:
+			done = event
+		}
+	}
+	want := []string{WSEventStatus, WSEventTyping, WSEventTool, WSEventDelta, WSEventDelta, WSEventDelta, WSEventDone, WSEventStatus}
+	if strings.Join(types, " ") != strings.Join(want, " ") {
+		t.Errorf("события %v, want %v", types, want)
+	}
+	if strings.Join(deltas, "|") != "Солнце — |звезда |класса G." {
+		t.Errorf("фрагменты %q", deltas)
+	}
+	if done.Text != "Солнце — звезда класса G." || done.Model != "yandexgpt-lite" {
+		t.Errorf("done = %+v", done)
+	}
+}
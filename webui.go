@@ -0,0 +1,28 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFiles — веб-чат: одна страница, скрипт и стили, встроенные в бинарный файл
+//
+//go:embed web
+var webFiles embed.FS
+
+// webUIHandler отдает веб-чат: index.html на / и остальные файлы каталога web.
+// Чат работает через /ws, настройки — через /settings, поэтому отдельный деплой не нужен.
+func webUIHandler() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.FileServer(http.FS(files))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Страница и скрипт меняются вместе с бинарным файлом — браузер должен перепроверять их
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeShift — сдвиг от текущего момента: "через 45 дней", "3 недели назад", "in 2 months"
+type timeShift struct {
+	Phrase   string // Сдвиг словами, как в запросе
+	Years    int
+	Months   int
+	Duration time.Duration
+}
+
+// calendarUnit возвращает число месяцев в единице календаря ("месяц" — 1, "год" — 12)
+func calendarUnit(word string) (int, bool) {
+	switch {
+	case strings.HasPrefix(word, "месяц"), word == "month", word == "months":
+		return 1, true
+	case word == "год", word == "года", word == "лет", word == "year", word == "years":
+		return 12, true
+	}
+	return 0, false
+}
+
+// timeQuery — разобранный запрос о времени
+type timeQuery struct {
+	places []Place
+	clock  *[2]int // Явно указанное время "15:00" для перевода между поясами
+	shift  *timeShift
+	past   bool // Сдвиг в прошлое ("назад", "ago")
+}
+
+// parseTimeQuery находит в запросе города и пояса, время и сдвиг по дням
+func parseTimeQuery(message, locale string, now time.Time, loc *time.Location) timeQuery {
+	var query timeQuery
+	p := newReminderParser(message, now, loc)
+
+	for i := 0; i < len(p.tokens); i++ {
+		word := p.word(i)
+
+		// Сдвиг: "через 45 дней", "in 3 weeks"
+		if (word == "через" || word == "in") && query.shift == nil {
+			if shift, n := parseShift(p, i+1); n > 0 {
+				query.shift = &shift
+				i += n
+				continue
+			}
+		}
+		// Сдвиг в прошлое: "10 дней назад", "2 weeks ago"
+		if shift, n := parseShift(p, i); n > 0 && query.shift == nil {
+			if after := p.word(i + n); after == "назад" || after == "ago" {
+				query.shift = &shift
+				query.past = true
+				i += n
+				continue
+			}
+		}
+
+		// Время с минутами: "15:00", "9:30pm"
+		if strings.Contains(word, ":") && query.clock == nil {
+			if n := p.parseTime(i); n > 0 {
+				query.clock = &[2]int{p.hour, p.min}
+				i += n - 1
+				continue
+			}
+		}
+
+		// Город из двух слов ("нью йорк", "new york") или одного, пояс IANA или смещение
+		if city, ok := findCity(word + " " + p.word(i+1)); ok && p.word(i+1) != "" {
+			query.places = appendPlace(query.places, cityPlace(city, locale))
+			i++
+			continue
+		}
+		if city, ok := findCity(word); ok {
+			query.places = appendPlace(query.places, cityPlace(city, locale))
+			continue
+		}
+		original := strings.Trim(p.tokens[i].word, ",.!?")
+		if strings.Contains(original, "/") || strings.HasPrefix(word, "utc") || strings.HasPrefix(word, "gmt") {
+			if zone, err := loadTimezone(original); err == nil {
+				query.places = appendPlace(query.places, Place{Name: zone.String(), Location: zone})
+			}
+		}
+	}
+	return query
+}
+
+// parseShift разбирает "45 дней", "3 недели", "2 месяца", "год" с позиции i
+func parseShift(p *reminderParser, i int) (timeShift, int) {
+	amount, n := p.parseNumber(i)
+	if months, ok := calendarUnit(p.word(i + n)); ok {
+		if n == 0 {
+			amount = 1
+		}
+		phrase := joinTokens(p, i, i+n+1)
+		return timeShift{Phrase: phrase, Years: amount * months / 12, Months: amount * months % 12}, n + 1
+	}
+
+	duration, used := p.parseDuration(i)
+	if used == 0 {
+		return timeShift{}, 0
+	}
+	return timeShift{Phrase: joinTokens(p, i, i+used), Duration: duration}, used
+}
+
+// joinTokens возвращает исходные слова фразы с позиции from до to
+func joinTokens(p *reminderParser, from, to int) string {
+	var words []string
+	for i := from; i < to && i < len(p.tokens); i++ {
+		words = append(words, strings.Trim(p.tokens[i].word, ",.!?"))
+	}
+	return strings.Join(words, " ")
+}
+
+func cityPlace(city cityZone, locale string) Place {
+	loc, err := time.LoadLocation(city.Zone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return Place{Name: city.placeName(locale), Location: loc}
+}
+
+// appendPlace добавляет место, пропуская повторы
+func appendPlace(places []Place, place Place) []Place {
+	for _, existing := range places {
+		if existing.Name == place.Name {
+			return places
+		}
+	}
+	return append(places, place)
+}
+
+// isTimeQuestion проверяет вопросы о времени, дате и часовых поясах
+func isTimeQuestion(message string) bool {
+	for _, phrase := range []string{
+		"время", "time", "сколько времени", "который час", "какой день", "какое число",
+		"какая дата", "what day", "what date", "часовой пояс", "timezone", "time zone",
+	} {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+
+	// Перевод времени без слова "время": "15:00 по Москве в Нью-Йорке"
+	query := parseTimeQuery(message, defaultLocale, time.Now(), time.UTC)
+	return query.clock != nil && len(query.places) > 0
+}
+
+// handleTimeRequest отвечает на вопросы о времени в поясе пользователя и других городах,
+// переводит время между поясами и считает даты: "который час в Токио",
+// "15:00 по Москве в Нью-Йорке", "какой день будет через 45 дней"
+func (a *Agent) handleTimeRequest(message string, userID int64) (string, error) {
+	locale := a.LocaleFor(userID)
+	loc := a.LocationFor(userID)
+	now := time.Now().In(loc)
+	query := parseTimeQuery(message, locale, now, loc)
+
+	switch {
+	case query.shift != nil:
+		return shiftAnswer(locale, now, *query.shift, query.past), nil
+
+	case query.clock != nil && len(query.places) > 0:
+		// Одно место — переводим из пояса пользователя, два — из первого во второе
+		from := Place{Name: T(locale, "time.place.you"), Location: loc}
+		to := query.places[0]
+		if len(query.places) > 1 {
+			from, to = query.places[0], query.places[1]
+		}
+		base := now.In(from.Location)
+		source := time.Date(base.Year(), base.Month(), base.Day(), query.clock[0], query.clock[1], 0, 0, from.Location)
+		return T(locale, "time.convert", Params{
+			"from_place": from.Name,
+			"from_time":  formatClock(locale, source),
+			"to_place":   to.Name,
+			"to_time":    formatClock(locale, source.In(to.Location)),
+		}), nil
+
+	case len(query.places) > 0:
+		lines := make([]string, 0, len(query.places))
+		for _, place := range query.places {
+			local := now.In(place.Location)
+			lines = append(lines, T(locale, "time.world", Params{
+				"place":   place.Name,
+				"time":    local.Format("15:04"),
+				"weekday": weekdayName(locale, local.Weekday()),
+				"date":    local.Format(T(locale, "format.date")),
+				"zone":    zoneLabel(local),
+				"diff":    offsetDifference(locale, now, local),
+			}))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	answer := T(locale, "tool.time.answer", Params{
+		"time":    now.Format("15:04:05"),
+		"date":    now.Format(T(locale, "format.date")),
+		"weekday": weekdayName(locale, now.Weekday()),
+		"zone":    zoneLabel(now),
+	})
+	if _, ok := a.settings.Get(userID).Location(); !ok {
+		answer += "\n\n" + T(locale, "tool.time.zone_hint")
+	}
+	return answer, nil
+}
+
+// shiftAnswer называет дату через указанный срок или срок назад
+func shiftAnswer(locale string, now time.Time, shift timeShift, past bool) string {
+	sign, key := 1, "time.shift.future"
+	if past {
+		sign, key = -1, "time.shift.past"
+	}
+	target := now.AddDate(sign*shift.Years, sign*shift.Months, 0).Add(time.Duration(sign) * shift.Duration)
+
+	when := target.Format(T(locale, "format.date"))
+	// Для сдвига меньше суток важнее время, чем дата
+	if shift.Years == 0 && shift.Months == 0 && shift.Duration < 24*time.Hour {
+		when = target.Format(T(locale, "format.datetime"))
+	}
+	return T(locale, key, Params{
+		"shift":   shift.Phrase,
+		"weekday": weekdayName(locale, target.Weekday()),
+		"date":    when,
+	})
+}
+
+// formatClock записывает время с датой, чтобы был виден переход через полночь
+func formatClock(locale string, t time.Time) string {
+	return t.Format("15:04") + ", " + weekdayName(locale, t.Weekday()) + " " + t.Format(T(locale, "format.date"))
+}
+
+// weekdayName возвращает название дня недели на языке пользователя
+func weekdayName(locale string, day time.Weekday) string {
+	return T(locale, fmt.Sprintf("weekday.%d", day))
+}
+
+// offsetDifference описывает разницу во времени между местом и пользователем
+func offsetDifference(locale string, user, place time.Time) string {
+	_, userOffset := user.Zone()
+	_, placeOffset := place.Zone()
+	diff := time.Duration(placeOffset-userOffset) * time.Second
+	if diff == 0 {
+		return T(locale, "time.diff.same")
+	}
+
+	key := "time.diff.ahead"
+	if diff < 0 {
+		key, diff = "time.diff.behind", -diff
+	}
+	amount := TN(locale, "duration.hours", int(diff/time.Hour))
+	if minutes := int(diff % time.Hour / time.Minute); minutes != 0 {
+		if diff >= time.Hour {
+			amount += " " + TN(locale, "duration.minutes", minutes)
+		} else {
+			amount = TN(locale, "duration.minutes", minutes)
+		}
+	}
+	return T(locale, key, Params{"diff": amount})
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,22 +28,86 @@ type YandexGPTRequest struct {
 		MaxTokens  int    `json:"maxTokens"`
 	} `json:"completionOptions"`
 	Messages []YandexGPTMessage `json:"messages"`
+	Tools    []YandexGPTTool    `json:"tools,omitempty"`
 }
 
-// YandexGPTMessage представляет сообщение в Yandex GPT
+// YandexGPTMessage представляет сообщение в Yandex GPT.
+// Вместо текста сообщение может содержать вызовы функций или их результаты.
 type YandexGPTMessage struct {
-	Role string `json:"role"`
-	Text string `json:"text"`
+	Role           string                   `json:"role"`
+	Text           string                   `json:"text,omitempty"`
+	ToolCallList   *YandexGPTToolCallList   `json:"toolCallList,omitempty"`
+	ToolResultList *YandexGPTToolResultList `json:"toolResultList,omitempty"`
 }
 
+// YandexGPTTool описывает функцию, которую модель может вызвать
+type YandexGPTTool struct {
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+// YandexGPTToolCallList содержит вызовы функций, которые запросила модель
+type YandexGPTToolCallList struct {
+	ToolCalls []YandexGPTToolCall `json:"toolCalls"`
+}
+
+// YandexGPTToolCall — вызов одной функции
+type YandexGPTToolCall struct {
+	FunctionCall struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"functionCall"`
+}
+
+// YandexGPTToolResultList содержит результаты вызовов функций для модели
+type YandexGPTToolResultList struct {
+	ToolResults []YandexGPTToolResult `json:"toolResults"`
+}
+
+// YandexGPTToolResult — результат вызова одной функции
+type YandexGPTToolResult struct {
+	FunctionResult struct {
+		Name    string `json:"name"`
+		Content string `json:"content"`
+	} `json:"functionResult"`
+}
+
+// toolCallMessage записывает вызовы функций в историю запроса от имени модели
+func toolCallMessage(calls []ToolCall) YandexGPTMessage {
+	list := &YandexGPTToolCallList{}
+	for _, call := range calls {
+		var item YandexGPTToolCall
+		item.FunctionCall.Name = call.Name
+		item.FunctionCall.Arguments = call.Arguments
+		list.ToolCalls = append(list.ToolCalls, item)
+	}
+	return YandexGPTMessage{Role: "assistant", ToolCallList: list}
+}
+
+// toolResultMessage передает модели результаты вызовов функций
+func toolResultMessage(results []ToolResult) YandexGPTMessage {
+	list := &YandexGPTToolResultList{}
+	for _, result := range results {
+		var item YandexGPTToolResult
+		item.FunctionResult.Name = result.Name
+		item.FunctionResult.Content = result.Content
+		list.ToolResults = append(list.ToolResults, item)
+	}
+	return YandexGPTMessage{Role: "user", ToolResultList: list}
+}
+
+// alternativeStatusContentFilter — статус альтернативы, которую модель не сгенерировала
+// из-за фильтра содержимого; текста в ней может не быть
+const alternativeStatusContentFilter = "ALTERNATIVE_STATUS_CONTENT_FILTER"
+
 // YandexGPTResponse представляет ответ от Yandex GPT API
 type YandexGPTResponse struct {
 	Result struct {
 		Alternatives []struct {
-			Message struct {
-				Role string `json:"role"`
-				Text string `json:"text"`
-			} `json:"message"`
+			Message YandexGPTMessage `json:"message"`
 			Status string `json:"status"`
 		} `json:"alternatives"`
 		Usage struct {
@@ -53,6 +119,23 @@ type YandexGPTResponse struct {
 	} `json:"result"`
 }
 
+// GenerationOptions задает параметры генерации ответа
+type GenerationOptions struct {
+	Model       string  // Имя модели в каталоге (yandexgpt-lite, yandexgpt/rc) или полный URI gpt:// или ds://
+	Temperature float64
+	MaxTokens   int
+	Tools       []FunctionTool // Функции, которые модель может вызвать
+}
+
+// DefaultGenerationOptions возвращает параметры генерации по умолчанию
+func DefaultGenerationOptions() GenerationOptions {
+	return GenerationOptions{
+		Model:       "yandexgpt-lite",
+		Temperature: 0.6,
+		MaxTokens:   2000,
+	}
+}
+
 // NewYandexGPTClient создает новый клиент Yandex GPT
 func NewYandexGPTClient(apiKey, folderID string) *YandexGPTClient {
 	return &YandexGPTClient{
@@ -65,38 +148,77 @@ func NewYandexGPTClient(apiKey, folderID string) *YandexGPTClient {
 	}
 }
 
+// SetTransport заменяет транспорт HTTP-клиента, например на кассету с записанными ответами
+func (c *YandexGPTClient) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
 // GenerateResponse генерирует ответ с помощью Yandex GPT
 func (c *YandexGPTClient) GenerateResponse(message string, userID int64) (string, error) {
+	return c.GenerateChatResponse([]YandexGPTMessage{
+		{
+			Role: "user",
+			Text: message,
+		},
+	})
+}
+
+// GenerateChatResponse генерирует ответ на диалог из нескольких сообщений
+func (c *YandexGPTClient) GenerateChatResponse(messages []YandexGPTMessage) (string, error) {
+	return c.Complete(messages, DefaultGenerationOptions())
+}
+
+// Complete генерирует ответ на диалог с указанными параметрами генерации
+func (c *YandexGPTClient) Complete(messages []YandexGPTMessage, options GenerationOptions) (string, error) {
+	completion, err := c.Generate(messages, options)
+	return completion.Text, err
+}
+
+// Name возвращает имя бэкенда модели
+func (c *YandexGPTClient) Name() string {
+	return "yandexgpt"
+}
+
+// Generate генерирует ответ и возвращает его вместе с расходом токенов
+func (c *YandexGPTClient) Generate(messages []YandexGPTMessage, options GenerationOptions) (Completion, error) {
+	return c.GenerateStream(messages, options, nil)
+}
+
+// GenerateStream генерирует ответ с потоковой передачей: API присылает текст по мере генерации,
+// а новые фрагменты передаются в onDelta. Без onDelta ответ запрашивается целиком.
+func (c *YandexGPTClient) GenerateStream(messages []YandexGPTMessage, options GenerationOptions, onDelta func(text string)) (Completion, error) {
 	// Формируем запрос
 	request := YandexGPTRequest{
-		ModelURI: fmt.Sprintf("gpt://%s/yandexgpt-lite", c.folderID),
+		ModelURI: ModelURI(c.folderID, options.Model),
 		CompletionOptions: struct {
 			Stream    bool    `json:"stream"`
 			Temperature float64 `json:"temperature"`
 			MaxTokens  int    `json:"maxTokens"`
 		}{
-			Stream:     false,
-			Temperature: 0.6,
-			MaxTokens:  2000,
-		},
-		Messages: []YandexGPTMessage{
-			{
-				Role: "user",
-				Text: message,
-			},
+			Stream:     onDelta != nil,
+			Temperature: options.Temperature,
+			MaxTokens:  options.MaxTokens,
 		},
+		Messages: messages,
+	}
+	for _, tool := range options.Tools {
+		var item YandexGPTTool
+		item.Function.Name = tool.Name
+		item.Function.Description = tool.Description
+		item.Function.Parameters = tool.Parameters
+		request.Tools = append(request.Tools, item)
 	}
 
 	// Конвертируем в JSON
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("ошибка маршалинга JSON: %v", err)
+		return Completion{}, fmt.Errorf("ошибка маршалинга JSON: %v", err)
 	}
 
 	// Создаем HTTP запрос
 	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("ошибка создания запроса: %v", err)
+		return Completion{}, fmt.Errorf("ошибка создания запроса: %v", err)
 	}
 
 	// Устанавливаем заголовки
@@ -106,39 +228,86 @@ func (c *YandexGPTClient) GenerateResponse(message string, userID int64) (string
 	// Отправляем запрос
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("ошибка HTTP запроса: %v", err)
+		return Completion{}, fmt.Errorf("ошибка HTTP запроса: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Читаем ответ
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("ошибка чтения ответа: %v", err)
-	}
-
 	// Проверяем статус код
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API вернул ошибку %d: %s", resp.StatusCode, string(body))
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return Completion{}, fmt.Errorf("ошибка чтения ответа: %v", err)
+		}
+		return Completion{}, fmt.Errorf("API вернул ошибку %d: %s", resp.StatusCode, string(body))
 	}
 
-	// Парсим ответ
+	// Парсим ответ. При потоковой передаче приходит последовательность JSON-объектов,
+	// в каждом — весь текст, сгенерированный к этому моменту; последний объект — итоговый ответ.
 	var response YandexGPTResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("ошибка парсинга JSON ответа: %v", err)
+	decoder := json.NewDecoder(resp.Body)
+	streamed := ""
+	for {
+		var chunk YandexGPTResponse
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return Completion{}, fmt.Errorf("ошибка парсинга JSON ответа: %v", err)
+		}
+		response = chunk
+
+		if onDelta == nil || len(chunk.Result.Alternatives) == 0 {
+			continue
+		}
+		if text := chunk.Result.Alternatives[0].Message.Text; len(text) > len(streamed) && strings.HasPrefix(text, streamed) {
+			onDelta(text[len(streamed):])
+			streamed = text
+		}
 	}
 
 	// Проверяем, что есть ответ
 	if len(response.Result.Alternatives) == 0 {
-		return "", fmt.Errorf("пустой ответ от API")
+		return Completion{}, fmt.Errorf("пустой ответ от API")
+	}
+
+	usage := response.Result.Usage
+	inputTokens, _ := strconv.Atoi(usage.InputTextTokens)
+	completionTokens, _ := strconv.Atoi(usage.CompletionTokens)
+
+	// Фильтр содержимого — не ошибка API, а отказ, о котором нужно сказать пользователю
+	alternative := response.Result.Alternatives[0]
+	if alternative.Status == alternativeStatusContentFilter {
+		return Completion{
+			Filtered:         true,
+			InputTokens:      inputTokens,
+			CompletionTokens: completionTokens,
+			Model:            options.Model,
+			ModelVersion:     response.Result.ModelVersion,
+		}, nil
+	}
+
+	// Вместо текста модель может запросить вызов функций
+	message := alternative.Message
+	var toolCalls []ToolCall
+	if message.ToolCallList != nil {
+		for _, call := range message.ToolCallList.ToolCalls {
+			toolCalls = append(toolCalls, ToolCall{Name: call.FunctionCall.Name, Arguments: call.FunctionCall.Arguments})
+		}
 	}
 
 	// Возвращаем текст ответа
-	generatedText := response.Result.Alternatives[0].Message.Text
-	if generatedText == "" {
-		return "", fmt.Errorf("пустой текст ответа")
+	generatedText := message.Text
+	if generatedText == "" && len(toolCalls) == 0 {
+		return Completion{}, fmt.Errorf("пустой текст ответа")
 	}
 
-	return generatedText, nil
+	return Completion{
+		Text:             generatedText,
+		ToolCalls:        toolCalls,
+		InputTokens:      inputTokens,
+		CompletionTokens: completionTokens,
+		Model:            options.Model,
+		ModelVersion:     response.Result.ModelVersion,
+	}, nil
 }
 
 // IsAvailable проверяет доступность Yandex GPT API
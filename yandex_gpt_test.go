@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// yandexGPTChunk — объект потокового ответа: весь текст, сгенерированный к этому моменту
+func yandexGPTChunk(text, status string) string {
+	return fmt.Sprintf(`{"result":{"alternatives":[{"message":{"role":"assistant","text":%q},"status":%q}],`+
+		`"usage":{"inputTextTokens":"12","completionTokens":"%d"},"modelVersion":"23.10.2024"}}`, text, status, len([]rune(text)))
+}
+
+// newYandexGPTStub возвращает клиент, запросы которого обслуживает handler
+func newYandexGPTStub(t *testing.T, handler http.HandlerFunc) *YandexGPTClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := NewYandexGPTClient("key", "folder")
+	client.baseURL = server.URL
+	return client
+}
+
+func TestGenerateStream(t *testing.T) {
+	var stream bool
+	client := newYandexGPTStub(t, func(w http.ResponseWriter, r *http.Request) {
+		var request YandexGPTRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		stream = request.CompletionOptions.Stream
+		for _, chunk := range []string{
+			yandexGPTChunk("Привет", "ALTERNATIVE_STATUS_PARTIAL"),
+			yandexGPTChunk("Привет, мир", "ALTERNATIVE_STATUS_PARTIAL"),
+			yandexGPTChunk("Привет, мир!", "ALTERNATIVE_STATUS_FINAL"),
+		} {
+			fmt.Fprintln(w, chunk)
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	var deltas []string
+	completion, err := client.GenerateStream([]YandexGPTMessage{{Role: "user", Text: "привет"}}, DefaultGenerationOptions(), func(text string) {
+		deltas = append(deltas, text)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stream {
+		t.Error("в запросе не включена потоковая передача")
+	}
+	if strings.Join(deltas, "|") != "Привет|, мир|!" {
+		t.Errorf("фрагменты %q", deltas)
+	}
+	if completion.Text != "Привет, мир!" || completion.CompletionTokens != 12 || completion.ModelVersion != "23.10.2024" {
+		t.Errorf("итоговый ответ %+v", completion)
+	}
+}
+
+func TestGenerateWithoutStream(t *testing.T) {
+	var stream bool
+	client := newYandexGPTStub(t, func(w http.ResponseWriter, r *http.Request) {
+		var request YandexGPTRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		stream = request.CompletionOptions.Stream
+		fmt.Fprint(w, yandexGPTChunk("Готово", "ALTERNATIVE_STATUS_FINAL"))
+	})
+
+	completion, err := client.Generate([]YandexGPTMessage{{Role: "user", Text: "привет"}}, DefaultGenerationOptions())
+	if err != nil || completion.Text != "Готово" {
+		t.Fatalf("Generate = %+v, %v", completion, err)
+	}
+	if stream {
+		t.Error("Generate не должен включать потоковую передачу")
+	}
+}
+
+func TestGenerateStreamErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"ошибка API", http.StatusTooManyRequests, `{"error":"quota"}`, "API вернул ошибку 429"},
+		{"обрыв потока", http.StatusOK, yandexGPTChunk("При", "ALTERNATIVE_STATUS_PARTIAL") + "\n{\"result\":", "ошибка парсинга JSON ответа"},
+		{"пустой ответ", http.StatusOK, "", "пустой ответ от API"},
+	}
+	for _, tt := range tests {
+		client := newYandexGPTStub(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		})
+		_, err := client.GenerateStream([]YandexGPTMessage{{Role: "user", Text: "привет"}}, DefaultGenerationOptions(), func(string) {})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: ошибка %v, ожидалась %q", tt.name, err, tt.wantErr)
+		}
+	}
+}